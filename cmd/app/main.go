@@ -1,14 +1,21 @@
 package main
 
 import (
+	"HezzlTestTask/internal/auth"
+	"HezzlTestTask/internal/logging"
+	"HezzlTestTask/internal/outbox"
 	"HezzlTestTask/internal/repository"
 	"HezzlTestTask/internal/service"
 	externalHttp "HezzlTestTask/internal/transport/http"
 	"HezzlTestTask/pkg/cache"
+	"HezzlTestTask/pkg/health"
 	"HezzlTestTask/pkg/logger"
+	"HezzlTestTask/pkg/ratelimit"
+	"HezzlTestTask/pkg/tracing"
 	"context"
 	"database/sql"
 	"fmt"
+	_ "github.com/ClickHouse/clickhouse-go"
 	"github.com/go-redis/redis/v8"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -16,15 +23,22 @@ import (
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 	nats "github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 )
 
 func main() {
+	// структурированный JSON-лог; стандартный пакет log тоже пишет через него
+	if err := logging.Setup(os.Stdout, "app", os.Getenv("LOG_LEVEL")); err != nil {
+		log.Fatalf("invalid LOG_LEVEL: %v", err)
+	}
 	// читаем переменные окружения
 	dbHost := os.Getenv("DB_HOST")
 	dbPort := os.Getenv("DB_PORT")
@@ -40,51 +54,273 @@ func main() {
 	if natsSubject == "" {
 		natsSubject = "goods"
 	}
+	natsStream := os.Getenv("NATS_STREAM")
+	if natsStream == "" {
+		natsStream = "GOODS"
+	}
 	redisAddr := os.Getenv("REDIS_ADDR")
-	// подключаем Postgres
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", dbUser, dbPassword, dbHost, dbPort, dbName)
-	db, err := sql.Open("postgres", dsn)
+	// ClickHouse используется только для чтения истории изменений; схему создаёт consumer
+	clickhouseDSN := os.Getenv("CLICKHOUSE_DSN")
+	// параметры локального уровня кэша; CACHE_LOCAL_SIZE=0 отключает его
+	cacheLocalSize := 0
+	if v := os.Getenv("CACHE_LOCAL_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid CACHE_LOCAL_SIZE: %v", err)
+		}
+		cacheLocalSize = n
+	}
+	cacheLocalTTL := time.Second
+	if v := os.Getenv("CACHE_LOCAL_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid CACHE_LOCAL_TTL: %v", err)
+		}
+		cacheLocalTTL = d
+	}
+	cacheInvalidationSubject := os.Getenv("CACHE_INVALIDATION_SUBJECT")
+	if cacheInvalidationSubject == "" {
+		cacheInvalidationSubject = "cache.invalidate"
+	}
+	// таймаут проверки каждой зависимости в /readyz
+	readinessTimeout := 2 * time.Second
+	if v := os.Getenv("READINESS_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid READINESS_TIMEOUT: %v", err)
+		}
+		readinessTimeout = d
+	}
+	// параметры relay-воркера outbox
+	outboxInterval := time.Second
+	if v := os.Getenv("OUTBOX_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid OUTBOX_INTERVAL: %v", err)
+		}
+		outboxInterval = d
+	}
+	outboxBatchSize := 100
+	if v := os.Getenv("OUTBOX_BATCH_SIZE"); v != "" {
+		bs, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid OUTBOX_BATCH_SIZE: %v", err)
+		}
+		outboxBatchSize = bs
+	}
+	outboxRetries := 3
+	if v := os.Getenv("OUTBOX_RETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid OUTBOX_RETRIES: %v", err)
+		}
+		outboxRetries = n
+	}
+	// аренда выборки relay: другие реплики не берут закреплённые события, пока она не истекла
+	outboxLease := time.Minute
+	if v := os.Getenv("OUTBOX_LEASE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("invalid OUTBOX_LEASE: %q", v)
+		}
+		outboxLease = d
+	}
+	outboxMaxAttempts := 20
+	if v := os.Getenv("OUTBOX_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatalf("invalid OUTBOX_MAX_ATTEMPTS: %q", v)
+		}
+		outboxMaxAttempts = n
+	}
+	outboxRetention := 24 * time.Hour
+	if v := os.Getenv("OUTBOX_RETENTION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("invalid OUTBOX_RETENTION: %q", v)
+		}
+		outboxRetention = d
+	}
+	// лимиты частоты запросов по маршрутам, например "default=100/1m,/goods/list=20/1s"; без них лимиты отключены
+	rateLimits, err := ratelimit.ParseRules(os.Getenv("RATE_LIMITS"))
 	if err != nil {
-		log.Fatalf("failed to connect to Postgres: %v", err)
+		log.Fatalf("invalid RATE_LIMITS: %v", err)
 	}
-	defer func() { _ = db.Close() }()
-	if err := db.Ping(); err != nil {
-		log.Fatalf("failed to ping Postgres: %v", err)
+	// сколько хранятся ответы запросов с заголовком Idempotency-Key
+	idempotencyTTL := 24 * time.Hour
+	if v := os.Getenv("IDEMPOTENCY_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid IDEMPOTENCY_TTL: %v", err)
+		}
+		idempotencyTTL = d
 	}
-
-	// Применяем миграции Postgres с помощью golang-migrate
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	// аутентификация включается переменными AUTH_API_KEYS и AUTH_JWT_*; без них API доступно анонимно
+	authenticator, err := auth.NewFromEnv()
 	if err != nil {
-		log.Fatalf("failed to create migrate driver: %v", err)
+		log.Fatalf("failed to configure authentication: %v", err)
 	}
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://migrations/postgres", "postgres", driver,
-	)
+	// трассировка OpenTelemetry включается переменной OTEL_EXPORTER_OTLP_ENDPOINT
+	shutdownTracing, err := tracing.Init(context.Background(), "app")
 	if err != nil {
-		log.Fatalf("failed to create migrate instance: %v", err)
+		log.Fatalf("failed to init tracing: %v", err)
 	}
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		log.Fatalf("failed to apply migrations: %v", err)
+	// STORAGE=memory запускает API без Postgres, Redis и NATS: данные, кэш и события хранятся в памяти процесса
+	storage := os.Getenv("STORAGE")
+	if storage == "" {
+		storage = "postgres"
 	}
+	var (
+		repo        service.Repo
+		projectRepo service.ProjectRepo
+		outboxStore outbox.Store
+		appCache    service.Cache
+		eventLogger outbox.Publisher
+		rClient     *redis.Client
+		nc          *nats.Conn
+		limiter     externalHttp.RateLimiter
+		idempotency externalHttp.IdempotencyStore
+	)
+	// /readyz возвращает 503 при недоступности Postgres или Redis: без Redis кэш и лимиты запросов обходятся,
+	// но хранить ответы запросов с Idempotency-Key негде и они отклоняются с 503; события без NATS копятся в outbox
+	readiness := health.NewChecker(readinessTimeout)
+	switch storage {
+	case "memory":
+		log.Printf("STORAGE=memory: данные хранятся в памяти процесса и теряются при перезапуске")
+		store := repository.NewMemoryStore()
+		repo, projectRepo, outboxStore = store, store, store
+		memoryCache := cache.NewMemoryCache()
+		appCache, idempotency = memoryCache, memoryCache
+		eventLogger = logger.NewMemoryLogger(1000)
+		limiter = ratelimit.NewMemoryLimiter()
+	case "postgres":
+		// подключаем Postgres
+		dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", dbUser, dbPassword, dbHost, dbPort, dbName)
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			log.Fatalf("failed to connect to Postgres: %v", err)
+		}
+		defer func() { _ = db.Close() }()
+		if err := db.Ping(); err != nil {
+			log.Fatalf("failed to ping Postgres: %v", err)
+		}
 
-	// подключаем Redis
-	rClient := redis.NewClient(&redis.Options{Addr: redisAddr})
-	cacheClient := cache.NewRedisClient(rClient.Options())
-	// подключаем NATS
-	nc, err := nats.Connect(natsURL)
-	if err != nil {
-		log.Fatalf("failed to connect to NATS: %v", err)
+		// Применяем миграции Postgres с помощью golang-migrate
+		driver, err := postgres.WithInstance(db, &postgres.Config{})
+		if err != nil {
+			log.Fatalf("failed to create migrate driver: %v", err)
+		}
+		m, err := migrate.NewWithDatabaseInstance(
+			"file://migrations/postgres", "postgres", driver,
+		)
+		if err != nil {
+			log.Fatalf("failed to create migrate instance: %v", err)
+		}
+		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+			log.Fatalf("failed to apply migrations: %v", err)
+		}
+
+		// подключаем Redis
+		rClient = redis.NewClient(&redis.Options{Addr: redisAddr})
+		cacheClient := cache.NewRedisClient(rClient.Options())
+		// лимиты запросов хранятся в Redis и действуют суммарно по всем репликам
+		limiter = ratelimit.NewRedisLimiter(rClient)
+		// ответы идемпотентных запросов хранятся только в Redis, без локального уровня кэша:
+		// повтор может прийти на другую реплику
+		idempotency = cacheClient
+		// подключаем NATS
+		nc, err = nats.Connect(natsURL)
+		if err != nil {
+			log.Fatalf("failed to connect to NATS: %v", err)
+		}
+		// публикуем через JetStream: стрим хранит события, пока consumer недоступен
+		js, err := nc.JetStream()
+		if err != nil {
+			log.Fatalf("failed to create JetStream context: %v", err)
+		}
+		if err := logger.EnsureStream(js, natsStream, natsSubject); err != nil {
+			log.Fatalf("failed to ensure stream %s: %v", natsStream, err)
+		}
+		eventLogger = logger.NewClient(logger.NewJetStreamConn(js), natsSubject)
+		// локальный уровень кэша в памяти реплики; инвалидация рассылается через NATS без JetStream,
+		// так как потерянное сообщение лишь продлевает жизнь локальной копии до CACHE_LOCAL_TTL
+		appCache = cacheClient
+		if cacheLocalSize > 0 {
+			tiered := cache.NewTieredCache(cacheClient, cacheLocalSize, cacheLocalTTL, nc, cacheInvalidationSubject)
+			if _, err := nc.Subscribe(cacheInvalidationSubject, func(msg *nats.Msg) {
+				if err := tiered.HandleInvalidation(msg.Data); err != nil {
+					log.Printf("failed to handle cache invalidation: %v", err)
+				}
+			}); err != nil {
+				log.Fatalf("failed to subscribe to %s: %v", cacheInvalidationSubject, err)
+			}
+			appCache = tiered
+		}
+		readiness.Add("postgres", true, db.PingContext)
+		readiness.Add("redis", true, func(ctx context.Context) error { return rClient.Ping(ctx).Err() })
+		readiness.Add("nats", false, nc.FlushWithContext)
+		repo = repository.NewGoodRepository(db)
+		projectRepo = repository.NewProjectRepository(db)
+		outboxStore = repository.NewOutboxRepository(db)
+	default:
+		log.Fatalf("invalid STORAGE %q: expected postgres or memory", storage)
 	}
-	loggerClient := logger.NewClient(nc, natsSubject)
-	// создаем репозиторий и сервис
-	repo := repository.NewGoodRepository(db)
-	srv := service.NewGoodsService(repo, cacheClient, loggerClient)
+	// создаем сервисы
+	srv := service.NewGoodsService(repo, appCache)
+	projectSrv := service.NewProjectsService(projectRepo, appCache)
+	// запускаем relay-воркер, публикующий события из outbox в NATS
+	relay := outbox.NewRelay(outboxStore, eventLogger, outbox.Config{
+		BatchSize:   outboxBatchSize,
+		Retries:     outboxRetries,
+		Backoff:     100 * time.Millisecond,
+		Lease:       outboxLease,
+		MaxAttempts: outboxMaxAttempts,
+		Retention:   outboxRetention,
+	})
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	relayDone := make(chan struct{})
+	go func() {
+		relay.Run(relayCtx, outboxInterval)
+		close(relayDone)
+	}()
 	// настраиваем HTTP маршруты
-	// подключаем middleware для логирования HTTP-запросов
+	// подключаем middleware: идентификатор запроса нужен трассировке и логу, поэтому он подключается первым
 	r := mux.NewRouter()
-	r.Use(externalHttp.LoggingMiddleware(loggerClient))
-	h := externalHttp.NewHandler(srv)
+	r.Use(externalHttp.RequestIDMiddleware())
+	r.Use(externalHttp.TracingMiddleware())
+	r.Use(externalHttp.LoggingMiddleware(slog.Default()))
+	r.Use(externalHttp.MetricsMiddleware())
+	if authenticator != nil {
+		r.Use(externalHttp.AuthMiddleware(authenticator))
+	} else {
+		log.Printf("AUTH_API_KEYS и AUTH_JWT_* не заданы, аутентификация отключена")
+	}
+	// лимиты считаются по клиенту из AuthMiddleware, поэтому подключаются после неё
+	if rateLimits.Default.Requests > 0 || len(rateLimits.Routes) > 0 {
+		r.Use(externalHttp.RateLimitMiddleware(limiter, rateLimits))
+	} else {
+		log.Printf("RATE_LIMITS не задан, ограничение частоты запросов отключено")
+	}
+	// ключи идемпотентности разделены по клиентам, поэтому middleware подключается после AuthMiddleware,
+	// а после лимитов — чтобы повторы тоже учитывались в квоте клиента
+	r.Use(externalHttp.IdempotencyMiddleware(idempotency, idempotencyTTL))
+	// метрики Prometheus
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	h := externalHttp.NewHandler(srv, projectSrv)
+	h.SetReadiness(readiness)
 	h.RegisterRoutes(r)
+	// история изменений доступна, только если задан CLICKHOUSE_DSN
+	var chDB *sql.DB
+	if clickhouseDSN != "" {
+		chDB, err = sql.Open("clickhouse", clickhouseDSN)
+		if err != nil {
+			log.Fatalf("failed to connect to ClickHouse: %v", err)
+		}
+		readiness.Add("clickhouse", false, chDB.PingContext)
+		externalHttp.NewHistoryHandler(repository.NewClickhouseRepo(chDB)).RegisterRoutes(r)
+	} else {
+		log.Printf("CLICKHOUSE_DSN не задан, API истории изменений отключено")
+	}
 	// запускаем HTTP сервер с поддержкой graceful shutdown
 	addr := ":8080"
 	srvHttp := &http.Server{Addr: addr, Handler: r}
@@ -107,13 +343,28 @@ func main() {
 		log.Fatalf("server shutdown failed: %v", err)
 	}
 	log.Printf("server exited properly")
+	// останавливаем relay до закрытия NATS; неотправленные события останутся в outbox
+	stopRelay()
+	<-relayDone
+	// закрываем соединение с ClickHouse
+	if chDB != nil {
+		_ = chDB.Close()
+	}
 	// закрываем Redis-клиент
-	if err := rClient.Close(); err != nil {
-		log.Printf("failed to close Redis client: %v", err)
+	if rClient != nil {
+		if err := rClient.Close(); err != nil {
+			log.Printf("failed to close Redis client: %v", err)
+		}
 	}
 	// корректно дренируем и закрываем NATS-соединение
-	if err := nc.Drain(); err != nil {
-		log.Printf("failed to drain NATS connection: %v", err)
+	if nc != nil {
+		if err := nc.Drain(); err != nil {
+			log.Printf("failed to drain NATS connection: %v", err)
+		}
+		nc.Close()
+	}
+	// отправляем оставшиеся спаны
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("failed to shutdown tracing: %v", err)
 	}
-	nc.Close()
 }
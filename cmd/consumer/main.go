@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -16,16 +17,150 @@ import (
 	"github.com/golang-migrate/migrate/v4/database/clickhouse"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/propagation"
 
 	"HezzlTestTask/internal/consumer"
+	"HezzlTestTask/internal/logging"
 	"HezzlTestTask/internal/repository"
+	"HezzlTestTask/pkg/health"
+	"HezzlTestTask/pkg/logger"
+	"HezzlTestTask/pkg/tracing"
 	_ "github.com/ClickHouse/clickhouse-go"
 )
 
+// fetchWait — максимальное время ожидания пачки сообщений из JetStream
+const fetchWait = time.Second
+
+// nakDelay — пауза перед повторной доставкой пачки, отклонённой ClickHouse
+const nakDelay = 5 * time.Second
+
+// jsAck адаптирует *nats.Msg к интерфейсу consumer.Ack
+type jsAck struct {
+	msg *nats.Msg
+}
+
+func (a jsAck) Ack() error  { return a.msg.Ack() }
+func (a jsAck) Nak() error  { return a.msg.NakWithDelay(nakDelay) }
+func (a jsAck) Term() error { return a.msg.Term() }
+
+// Deliveries возвращает номер доставки из метаданных JetStream
+func (a jsAck) Deliveries() int {
+	meta, err := a.msg.Metadata()
+	if err != nil {
+		return 1
+	}
+	return int(meta.NumDelivered)
+}
+
 func main() {
+	// Структурированный JSON-лог; стандартный пакет log тоже пишет через него
+	if err := logging.Setup(os.Stdout, "consumer", os.Getenv("LOG_LEVEL")); err != nil {
+		log.Fatalf("invalid LOG_LEVEL: %v", err)
+	}
 	// Читаем конфигурацию из окружения
 	natsURL := os.Getenv("NATS_URL")
 	subject := os.Getenv("NATS_SUBJECT")
+	if subject == "" {
+		subject = "goods"
+	}
+	stream := os.Getenv("NATS_STREAM")
+	if stream == "" {
+		stream = "GOODS"
+	}
+	durable := os.Getenv("NATS_DURABLE")
+	if durable == "" {
+		durable = "goods-consumer"
+	}
+	flushInterval := time.Second
+	if v := os.Getenv("FLUSH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid FLUSH_INTERVAL: %v", err)
+		}
+		flushInterval = d
+	}
+	maxBatchBytes := 1 << 20
+	if v := os.Getenv("MAX_BATCH_BYTES"); v != "" {
+		mb, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid MAX_BATCH_BYTES: %v", err)
+		}
+		maxBatchBytes = mb
+	}
+	maxDeliveries := 5
+	if v := os.Getenv("MAX_DELIVERIES"); v != "" {
+		md, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid MAX_DELIVERIES: %v", err)
+		}
+		maxDeliveries = md
+	}
+	// параметры повторных попыток записи пакета в ClickHouse
+	insertAttempts := 5
+	if v := os.Getenv("INSERT_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid INSERT_MAX_ATTEMPTS: %v", err)
+		}
+		insertAttempts = n
+	}
+	insertBackoff := 100 * time.Millisecond
+	if v := os.Getenv("INSERT_BACKOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid INSERT_BACKOFF: %v", err)
+		}
+		insertBackoff = d
+	}
+	insertMaxBackoff := 5 * time.Second
+	if v := os.Getenv("INSERT_MAX_BACKOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid INSERT_MAX_BACKOFF: %v", err)
+		}
+		insertMaxBackoff = d
+	}
+	insertTimeout := 2 * time.Second
+	if v := os.Getenv("INSERT_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid INSERT_TIMEOUT: %v", err)
+		}
+		// без таймаута длительность попытки не ограничена и проверка ACK_WAIT ниже теряет смысл
+		if d <= 0 {
+			log.Fatalf("INSERT_TIMEOUT (%s) must be positive", d)
+		}
+		insertTimeout = d
+	}
+	insertQueueSize := 2
+	if v := os.Getenv("INSERT_QUEUE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid INSERT_QUEUE_SIZE: %v", err)
+		}
+		insertQueueSize = n
+	}
+	ackWait := 30 * time.Second
+	if v := os.Getenv("ACK_WAIT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid ACK_WAIT: %v", err)
+		}
+		ackWait = d
+	}
+	// неполная пачка должна записаться и подтвердиться раньше, чем JetStream начнёт повторную доставку
+	if flushInterval >= ackWait {
+		log.Fatalf("FLUSH_INTERVAL (%s) must be less than ACK_WAIT (%s)", flushInterval, ackWait)
+	}
+	readinessTimeout := 2 * time.Second
+	if v := os.Getenv("READINESS_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid READINESS_TIMEOUT: %v", err)
+		}
+		readinessTimeout = d
+	}
 	dsn := os.Getenv("CLICKHOUSE_DSN")
 	batchSize := 10
 	if v := os.Getenv("BATCH_SIZE"); v != "" {
@@ -36,12 +171,38 @@ func main() {
 		}
 	}
 
+	// Трассировка OpenTelemetry включается переменной OTEL_EXPORTER_OTLP_ENDPOINT
+	shutdownTracing, err := tracing.Init(context.Background(), "consumer")
+	if err != nil {
+		log.Fatalf("failed to init tracing: %v", err)
+	}
+
 	// Подключаемся к NATS
 	nc, err := nats.Connect(natsURL)
 	if err != nil {
 		log.Fatalf("failed to connect to NATS: %v", err)
 	}
 	defer nc.Close()
+	// Создаём стрим и durable pull-консьюмер JetStream, если их ещё нет, и обновляем настройки существующего консьюмера
+	js, err := nc.JetStream()
+	if err != nil {
+		log.Fatalf("failed to create JetStream context: %v", err)
+	}
+	if err := logger.EnsureStream(js, stream, subject); err != nil {
+		log.Fatalf("failed to ensure stream %s: %v", stream, err)
+	}
+	// число доставок брокером не ограничивается: после MAX_DELIVERIES событие переносится в dead letter самим consumer,
+	// а если dead letter недоступен, сообщение должно остаться в стриме
+	err = logger.EnsureConsumer(js, stream, &nats.ConsumerConfig{
+		Durable:       durable,
+		FilterSubject: subject,
+		AckPolicy:     nats.AckExplicitPolicy,
+		AckWait:       ackWait,
+		MaxDeliver:    -1,
+	})
+	if err != nil {
+		log.Fatalf("failed to ensure consumer %s: %v", durable, err)
+	}
 
 	// Подключаемся к ClickHouse (appdb должна быть создана SQL-скриптами)
 	db, err := sql.Open("clickhouse", dsn)
@@ -70,7 +231,30 @@ func main() {
 
 	// Создаём репозиторий и консьюмера
 	repo := repository.NewClickhouseRepo(db)
-	cons := consumer.NewConsumer(repo, batchSize)
+	retryRepo := consumer.NewRetryRepo(repo, insertAttempts, insertBackoff, insertMaxBackoff, insertTimeout, insertQueueSize)
+	// все попытки записи с паузами тоже должны укладываться в ACK_WAIT, иначе пакет будет доставлен повторно до окончания попыток;
+	// пакеты пишутся по одному, поэтому событие может дождаться записи предыдущего пакета и затем своего
+	writeTime := 2 * retryRepo.MaxDuration()
+	if flushInterval+writeTime >= ackWait {
+		log.Fatalf("FLUSH_INTERVAL (%s) plus two batch writes with retries (%s) must be less than ACK_WAIT (%s)", flushInterval, writeTime, ackWait)
+	}
+	// запускаем очередь повторных попыток записи в ClickHouse
+	retryCtx, stopRetry := context.WithCancel(context.Background())
+	retryDone := make(chan struct{})
+	go func() {
+		retryRepo.Run(retryCtx)
+		close(retryDone)
+	}()
+	cons := consumer.NewConsumer(retryRepo, batchSize, maxBatchBytes)
+	// необработанные сообщения сохраняются в events_dead_letter
+	cons.SetDeadLetter(repo, maxDeliveries)
+	// запускаем цикл записи неполных пачек по FLUSH_INTERVAL
+	flushCtx, stopFlush := context.WithCancel(context.Background())
+	flushDone := make(chan struct{})
+	go func() {
+		cons.Run(flushCtx, flushInterval)
+		close(flushDone)
+	}()
 
 	// Запускаем HTTP-сервер для healthz и readyz
 	port := os.Getenv("CONSUMER_PORT")
@@ -83,11 +267,13 @@ func main() {
 		w.WriteHeader(http.StatusOK)
 		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 	})
-	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
-	})
+	// без NATS или ClickHouse consumer не может обрабатывать события, поэтому обе зависимости критичны
+	readiness := health.NewChecker(readinessTimeout)
+	readiness.Add("nats", true, nc.FlushWithContext)
+	readiness.Add("clickhouse", true, db.PingContext)
+	mux.Handle("/readyz", readiness)
+	// метрики Prometheus
+	mux.Handle("/metrics", promhttp.Handler())
 	// создаем HTTP сервер для health
 	healthSrv := &http.Server{Addr: ":" + port, Handler: mux}
 	go func() {
@@ -97,16 +283,32 @@ func main() {
 		}
 	}()
 
-	// Подписываемся на тему NATS
-	sub, err := nc.Subscribe(subject, func(msg *nats.Msg) {
-		// Обрабатываем сообщение в контексте Background
-		if err := cons.HandleMessage(context.Background(), msg.Data); err != nil {
-			log.Printf("failed to handle message: %v", err)
-		}
-	})
+	// Привязываемся к существующему durable-консьюмеру: при остановке он не удаляется,
+	// и неподтверждённые сообщения будут доставлены после перезапуска
+	sub, err := js.PullSubscribe(subject, durable, nats.Bind(stream, durable))
 	if err != nil {
 		log.Fatalf("failed to subscribe to subject %s: %v", subject, err)
 	}
+	fetchCtx, stopFetch := context.WithCancel(context.Background())
+	fetchDone := make(chan struct{})
+	go func() {
+		defer close(fetchDone)
+		for fetchCtx.Err() == nil {
+			msgs, err := sub.Fetch(batchSize, nats.MaxWait(fetchWait))
+			if err != nil && !errors.Is(err, nats.ErrTimeout) {
+				log.Printf("failed to fetch messages: %v", err)
+				time.Sleep(fetchWait)
+				continue
+			}
+			for _, msg := range msgs {
+				// продолжаем трассировку публикации из заголовков сообщения
+				msgCtx := tracing.Extract(context.Background(), propagation.HeaderCarrier(msg.Header))
+				if err := cons.HandleMessage(msgCtx, msg.Data, jsAck{msg: msg}); err != nil {
+					log.Printf("failed to handle message: %v", err)
+				}
+			}
+		}
+	}()
 	// Ждём сигнала завершения
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
@@ -121,11 +323,16 @@ func main() {
 		log.Printf("health server shutdown failed: %v", err)
 	}
 
-	// Отписываемся и сбрасываем оставшиеся события
-	if err := sub.Unsubscribe(); err != nil {
-		log.Printf("failed to unsubscribe: %v", err)
-	}
-	if err := cons.Flush(context.Background()); err != nil {
-		log.Printf("failed to flush consumer events: %v", err)
+	// Останавливаем выборку, затем цикл записи — он сбрасывает оставшиеся события,
+	// и только после этого очередь повторных попыток
+	stopFetch()
+	<-fetchDone
+	stopFlush()
+	<-flushDone
+	stopRetry()
+	<-retryDone
+	// отправляем оставшиеся спаны
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("failed to shutdown tracing: %v", err)
 	}
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"HezzlTestTask/internal/consumer"
+	"HezzlTestTask/internal/model"
+	"HezzlTestTask/internal/repository"
+	_ "github.com/ClickHouse/clickhouse-go"
+)
+
+// jsPublisher публикует сообщение в JetStream без Nats-Msg-Id,
+// чтобы переотправка не была отброшена стримом как дубликат исходной публикации
+type jsPublisher struct {
+	js      nats.JetStreamContext
+	subject string
+}
+
+func (p jsPublisher) PublishLog(ctx context.Context, data []byte) error {
+	_, err := p.js.Publish(p.subject, data, nats.Context(ctx))
+	return err
+}
+
+// Команда replay переотправляет записи из events_dead_letter в тему consumer:
+//
+//	replay -stage=insert -limit=100
+//	replay -dry-run
+func main() {
+	stage := flag.String("stage", "", "этап dead letter для переотправки: decode, insert или пусто для всех")
+	limit := flag.Int("limit", 100, "максимальное число переотправляемых записей")
+	dryRun := flag.Bool("dry-run", false, "только вывести записи, не переотправляя их")
+	flag.Parse()
+	if *stage != "" && *stage != model.DeadLetterDecode && *stage != model.DeadLetterInsert {
+		log.Fatalf("invalid -stage %q", *stage)
+	}
+
+	// Читаем конфигурацию из окружения, как и consumer
+	natsURL := os.Getenv("NATS_URL")
+	subject := os.Getenv("NATS_SUBJECT")
+	if subject == "" {
+		subject = "goods"
+	}
+	dsn := os.Getenv("CLICKHOUSE_DSN")
+
+	db, err := sql.Open("clickhouse", dsn)
+	if err != nil {
+		log.Fatalf("failed to connect to ClickHouse: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	repo := repository.NewClickhouseRepo(db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if *dryRun {
+		letters, err := repo.ListDeadLetters(ctx, *stage, *limit)
+		if err != nil {
+			log.Fatalf("failed to list dead letters: %v", err)
+		}
+		for _, l := range letters {
+			log.Printf("%s %s %s deliveries=%d error=%q payload=%s", l.ID, l.FailedAt.Format(time.RFC3339), l.Stage, l.Deliveries, l.Error, l.Payload)
+		}
+		log.Printf("найдено %d записей dead letter", len(letters))
+		return
+	}
+
+	nc, err := nats.Connect(natsURL)
+	if err != nil {
+		log.Fatalf("failed to connect to NATS: %v", err)
+	}
+	defer nc.Close()
+	js, err := nc.JetStream()
+	if err != nil {
+		log.Fatalf("failed to create JetStream context: %v", err)
+	}
+
+	n, err := consumer.Replay(ctx, repo, jsPublisher{js: js, subject: subject}, *stage, *limit)
+	log.Printf("переотправлено %d записей dead letter", n)
+	if err != nil {
+		log.Fatalf("replay failed: %v", err)
+	}
+}
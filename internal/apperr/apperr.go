@@ -0,0 +1,169 @@
+// Пакет apperr описывает доменные ошибки, общие для репозиториев, сервисов и транспорта
+// Вид ошибки (Kind) определяет ответ API: HTTP-статус и код ошибки выбираются по нему в одном месте,
+// поэтому слои ниже транспорта сообщают только, что произошло, а не как об этом ответить клиенту
+package apperr
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+)
+
+// Kind — вид доменной ошибки
+type Kind int
+
+const (
+	// KindInternal — непредвиденная ошибка, подробности которой не раскрываются клиенту
+	KindInternal Kind = iota
+	// KindNotFound — запрошенная запись не существует
+	KindNotFound
+	// KindValidation — некорректные входные данные; Error.Fields описывает ошибки отдельных полей
+	KindValidation
+	// KindConflict — операция противоречит текущему состоянию данных
+	KindConflict
+	// KindForbidden — у вызывающего нет прав на операцию
+	KindForbidden
+	// KindUnavailable — зависимость (база данных, брокер, кэш) временно недоступна
+	KindUnavailable
+	// KindUnauthorized — вызывающий не аутентифицирован или его учётные данные недействительны
+	KindUnauthorized
+	// KindRateLimited — вызывающий превысил допустимую частоту запросов
+	KindRateLimited
+	// KindPreconditionFailed — условие запроса на текущее состояние записи (например ожидаемая версия) не выполнено
+	KindPreconditionFailed
+)
+
+// kindNames задаёт имена видов ошибок для Kind.String
+var kindNames = map[Kind]string{
+	KindInternal:           "internal",
+	KindNotFound:           "not found",
+	KindValidation:         "validation",
+	KindConflict:           "conflict",
+	KindForbidden:          "forbidden",
+	KindUnavailable:        "unavailable",
+	KindUnauthorized:       "unauthorized",
+	KindRateLimited:        "rate limited",
+	KindPreconditionFailed: "precondition failed",
+}
+
+// String возвращает имя вида ошибки
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Error — доменная ошибка: вид, сообщение, ошибки полей и исходная ошибка
+type Error struct {
+	Kind    Kind
+	Message string
+	Fields  map[string]string // ошибки отдельных полей запроса: имя поля -> описание
+	Err     error             // исходная ошибка, доступна через errors.Unwrap
+}
+
+// Error возвращает сообщение ошибки, дополненное исходной ошибкой
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Kind.String()
+	}
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap возвращает исходную ошибку
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is сопоставляет ошибку с общими значениями ErrNotFound, ErrValidation и т.п. по виду
+// Конкретные ошибки с сообщением (например repository.ErrNotFound) совпадают только сами с собой
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Message == "" && t.Err == nil && t.Kind == e.Kind
+}
+
+// Общие значения для проверки вида ошибки через errors.Is
+var (
+	ErrNotFound           = &Error{Kind: KindNotFound}
+	ErrValidation         = &Error{Kind: KindValidation}
+	ErrConflict           = &Error{Kind: KindConflict}
+	ErrForbidden          = &Error{Kind: KindForbidden}
+	ErrUnavailable        = &Error{Kind: KindUnavailable}
+	ErrUnauthorized       = &Error{Kind: KindUnauthorized}
+	ErrRateLimited        = &Error{Kind: KindRateLimited}
+	ErrPreconditionFailed = &Error{Kind: KindPreconditionFailed}
+)
+
+// NotFound создаёт ошибку отсутствия записи
+func NotFound(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+// Validation создаёт ошибку входных данных с ошибками отдельных полей fields (может быть nil)
+func Validation(message string, fields map[string]string) *Error {
+	return &Error{Kind: KindValidation, Message: message, Fields: fields}
+}
+
+// Invalid создаёт ошибку одного поля: сообщение "<field> <reason>", в Fields — reason для field
+func Invalid(field, reason string) *Error {
+	return Validation(field+" "+reason, map[string]string{field: reason})
+}
+
+// Conflict создаёт ошибку конфликта с текущим состоянием данных
+func Conflict(message string) *Error {
+	return &Error{Kind: KindConflict, Message: message}
+}
+
+// Forbidden создаёт ошибку отсутствия прав
+func Forbidden(message string) *Error {
+	return &Error{Kind: KindForbidden, Message: message}
+}
+
+// Unauthorized создаёт ошибку отсутствующих или недействительных учётных данных
+func Unauthorized(message string) *Error {
+	return &Error{Kind: KindUnauthorized, Message: message}
+}
+
+// PreconditionFailed создаёт ошибку невыполненного условия на текущее состояние записи
+func PreconditionFailed(message string) *Error {
+	return &Error{Kind: KindPreconditionFailed, Message: message}
+}
+
+// RateLimited создаёт ошибку превышения лимита запросов
+func RateLimited(message string) *Error {
+	return &Error{Kind: KindRateLimited, Message: message}
+}
+
+// Unavailable создаёт ошибку недоступности зависимости, оборачивая исходную ошибку err
+func Unavailable(message string, err error) *Error {
+	return &Error{Kind: KindUnavailable, Message: message, Err: err}
+}
+
+// KindOf возвращает вид ошибки err
+// Ошибки вне пакета считаются внутренними, кроме истёкшего таймаута, сетевых ошибок
+// и разорванного соединения драйвера базы данных — они означают недоступность зависимости
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr) {
+		return KindUnavailable
+	}
+	return KindInternal
+}
+
+// FieldsOf возвращает ошибки полей из err, если она содержит ошибку валидации
+func FieldsOf(err error) map[string]string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Fields
+	}
+	return nil
+}
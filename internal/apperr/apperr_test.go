@@ -0,0 +1,72 @@
+// Пакет apperr содержит unit-тесты доменных ошибок
+package apperr
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+// TestIs проверяет сопоставление с общими значениями по виду и с конкретными ошибками по идентичности
+func TestIs(t *testing.T) {
+	errRecord := NotFound("record not found")
+	wrapped := fmt.Errorf("failed to get good: %w", errRecord)
+
+	if !errors.Is(wrapped, ErrNotFound) {
+		t.Error("expected wrapped error to match ErrNotFound")
+	}
+	if !errors.Is(wrapped, errRecord) {
+		t.Error("expected wrapped error to match itself")
+	}
+	if errors.Is(wrapped, ErrValidation) {
+		t.Error("not found must not match ErrValidation")
+	}
+	if errors.Is(NotFound("project not found"), errRecord) {
+		t.Error("different not found errors must not match each other")
+	}
+}
+
+// TestKindOf проверяет определение вида для доменных, сетевых и посторонних ошибок
+func TestKindOf(t *testing.T) {
+	cases := []struct {
+		err  error
+		want Kind
+	}{
+		{fmt.Errorf("wrap: %w", Invalid("name", "cannot be empty")), KindValidation},
+		{Conflict("version mismatch"), KindConflict},
+		{Forbidden("read only"), KindForbidden},
+		{Unauthorized("token expired"), KindUnauthorized},
+		{RateLimited("too many requests"), KindRateLimited},
+		{PreconditionFailed("version mismatch"), KindPreconditionFailed},
+		{Unavailable("postgres", errors.New("down")), KindUnavailable},
+		{fmt.Errorf("failed to begin transaction: %w", context.DeadlineExceeded), KindUnavailable},
+		{fmt.Errorf("failed to query: %w", driver.ErrBadConn), KindUnavailable},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, KindUnavailable},
+		{errors.New("boom"), KindInternal},
+	}
+	for _, c := range cases {
+		if got := KindOf(c.err); got != c.want {
+			t.Errorf("KindOf(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+// TestInvalid проверяет сообщение и ошибки полей
+func TestInvalid(t *testing.T) {
+	err := fmt.Errorf("create: %w", Invalid("name", "cannot be empty"))
+	if got := FieldsOf(err); got["name"] != "cannot be empty" || len(got) != 1 {
+		t.Errorf("unexpected fields %v", got)
+	}
+	if err.Error() != "create: name cannot be empty" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	if FieldsOf(errors.New("boom")) != nil {
+		t.Error("expected no fields for foreign error")
+	}
+	if got := Unavailable("redis", errors.New("timeout")).Error(); got != "redis: timeout" {
+		t.Errorf("unexpected message %q", got)
+	}
+}
@@ -0,0 +1,238 @@
+// Пакет auth проверяет учётные данные клиентов API: статические API-ключи и JWT (HS256/RS256)
+// Результат проверки — Principal: идентификатор клиента и его роли в проектах
+// Токены проверяются локально по настроенным ключам, без обращения к внешним сервисам
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidCredentials возвращается при неизвестном API-ключе или недействительном токене
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Role — роль клиента в проекте; каждая следующая роль включает права предыдущих
+type Role int
+
+const (
+	// RoleNone — нет доступа
+	RoleNone Role = iota
+	// RoleRead — чтение товаров, проектов и истории
+	RoleRead
+	// RoleWrite — создание, изменение, удаление и перестановка товаров
+	RoleWrite
+	// RoleAdmin — управление проектами
+	RoleAdmin
+)
+
+// roleNames задаёт имена ролей в конфигурации и claims токена
+var roleNames = map[Role]string{RoleNone: "none", RoleRead: "read", RoleWrite: "write", RoleAdmin: "admin"}
+
+// String возвращает имя роли
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseRole разбирает имя роли: read, write или admin
+func ParseRole(s string) (Role, error) {
+	for role, name := range roleNames {
+		if name == s && role != RoleNone {
+			return role, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown role %q", s)
+}
+
+// AllProjects — ключ Principal.Projects с ролью во всех проектах ("*" в конфигурации и токене)
+const AllProjects = 0
+
+// Principal — аутентифицированный клиент API
+type Principal struct {
+	Subject  string       // идентификатор клиента: имя API-ключа или claim sub токена
+	Projects map[int]Role // роль по id проекта; роль AllProjects действует во всех проектах
+}
+
+// Can сообщает, есть ли у клиента роль не ниже need в проекте projectID
+// projectID == AllProjects требует роли во всех проектах (например для списков по всем проектам)
+func (p *Principal) Can(projectID int, need Role) bool {
+	role := p.Projects[AllProjects]
+	if projectID != AllProjects && p.Projects[projectID] > role {
+		role = p.Projects[projectID]
+	}
+	return need != RoleNone && role >= need
+}
+
+// Grants — роли в проектах в виде {"<id проекта>|*": "read|write|admin"}, как в claim projects токена
+type Grants map[string]string
+
+// parse преобразует Grants в роли по id проекта
+func (g Grants) parse() (map[int]Role, error) {
+	projects := make(map[int]Role, len(g))
+	for key, name := range g {
+		role, err := ParseRole(name)
+		if err != nil {
+			return nil, err
+		}
+		id := AllProjects
+		if key != "*" {
+			if id, err = strconv.Atoi(key); err != nil || id <= 0 {
+				return nil, fmt.Errorf("invalid project id %q", key)
+			}
+		}
+		projects[id] = role
+	}
+	return projects, nil
+}
+
+// APIKey описывает клиента со статическим ключом
+type APIKey struct {
+	Subject  string `json:"sub"`
+	Projects Grants `json:"projects"`
+}
+
+// Config задаёт способы аутентификации; пустые поля отключают соответствующий способ
+type Config struct {
+	APIKeys        map[string]APIKey // ключ -> клиент
+	HS256Secret    []byte            // секрет для токенов HS256
+	RS256PublicKey *rsa.PublicKey    // открытый ключ для токенов RS256
+	Issuer         string            // ожидаемый claim iss, если задан
+	Audience       string            // ожидаемый claim aud, если задан
+}
+
+// Authenticator проверяет API-ключи и JWT по Config
+type Authenticator struct {
+	keys     map[[sha256.Size]byte]*Principal
+	cfg      Config
+	methods  []string
+	parseOpt []jwt.ParserOption
+}
+
+// New создаёт Authenticator; ошибка возвращается при некорректных ролях API-ключей
+// API-ключи хранятся в виде SHA-256, поиск по хэшу не зависит от совпадающего префикса ключа
+func New(cfg Config) (*Authenticator, error) {
+	a := &Authenticator{keys: make(map[[sha256.Size]byte]*Principal, len(cfg.APIKeys)), cfg: cfg}
+	for key, k := range cfg.APIKeys {
+		if key == "" || k.Subject == "" {
+			return nil, errors.New("api key and its sub must not be empty")
+		}
+		projects, err := k.Projects.parse()
+		if err != nil {
+			return nil, fmt.Errorf("invalid projects of api key %s: %w", k.Subject, err)
+		}
+		a.keys[sha256.Sum256([]byte(key))] = &Principal{Subject: k.Subject, Projects: projects}
+	}
+	if len(cfg.HS256Secret) > 0 {
+		a.methods = append(a.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.RS256PublicKey != nil {
+		a.methods = append(a.methods, jwt.SigningMethodRS256.Alg())
+	}
+	a.parseOpt = []jwt.ParserOption{jwt.WithValidMethods(a.methods), jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		a.parseOpt = append(a.parseOpt, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		a.parseOpt = append(a.parseOpt, jwt.WithAudience(cfg.Audience))
+	}
+	return a, nil
+}
+
+// NewFromEnv создаёт Authenticator из переменных окружения:
+// AUTH_API_KEYS (JSON {"<ключ>": {"sub": "...", "projects": {"*": "read"}}}), AUTH_JWT_HS256_SECRET,
+// AUTH_JWT_RS256_PUBLIC_KEY_FILE (PEM), AUTH_JWT_ISSUER и AUTH_JWT_AUDIENCE
+// Возвращает nil без ошибки, если не задан ни один способ аутентификации
+func NewFromEnv() (*Authenticator, error) {
+	var cfg Config
+	if v := os.Getenv("AUTH_API_KEYS"); v != "" {
+		if err := json.Unmarshal([]byte(v), &cfg.APIKeys); err != nil {
+			return nil, fmt.Errorf("failed to parse AUTH_API_KEYS: %w", err)
+		}
+	}
+	cfg.HS256Secret = []byte(os.Getenv("AUTH_JWT_HS256_SECRET"))
+	if path := os.Getenv("AUTH_JWT_RS256_PUBLIC_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read RS256 public key: %w", err)
+		}
+		if cfg.RS256PublicKey, err = jwt.ParseRSAPublicKeyFromPEM(data); err != nil {
+			return nil, fmt.Errorf("failed to parse RS256 public key: %w", err)
+		}
+	}
+	if len(cfg.APIKeys) == 0 && len(cfg.HS256Secret) == 0 && cfg.RS256PublicKey == nil {
+		return nil, nil
+	}
+	cfg.Issuer = os.Getenv("AUTH_JWT_ISSUER")
+	cfg.Audience = os.Getenv("AUTH_JWT_AUDIENCE")
+	return New(cfg)
+}
+
+// AuthenticateAPIKey возвращает клиента по статическому API-ключу
+func (a *Authenticator) AuthenticateAPIKey(key string) (*Principal, error) {
+	if p, ok := a.keys[sha256.Sum256([]byte(key))]; ok {
+		return p, nil
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// claims — claims JWT: стандартные и роли в проектах
+type claims struct {
+	jwt.RegisteredClaims
+	Projects Grants `json:"projects"`
+}
+
+// AuthenticateToken проверяет подпись, срок действия, iss и aud токена и возвращает клиента из sub и projects
+func (a *Authenticator) AuthenticateToken(token string) (*Principal, error) {
+	if len(a.methods) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, a.key, a.parseOpt...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no sub", ErrInvalidCredentials)
+	}
+	projects, err := c.Projects.parse()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	return &Principal{Subject: c.Subject, Projects: projects}, nil
+}
+
+// key выбирает ключ проверки подписи по алгоритму токена
+func (a *Authenticator) key(t *jwt.Token) (interface{}, error) {
+	switch t.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return a.cfg.HS256Secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		return a.cfg.RS256PublicKey, nil
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal возвращает контекст с клиентом p; nil означает запрос без учётных данных
+func ContextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext возвращает клиента из контекста
+// ok=false означает, что аутентификация не выполнялась (middleware не подключён);
+// ok=true с nil — запрос без учётных данных
+func PrincipalFromContext(ctx context.Context) (p *Principal, ok bool) {
+	p, ok = ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
@@ -0,0 +1,153 @@
+// Пакет auth содержит unit-тесты проверки API-ключей, JWT и ролей в проектах
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signToken подписывает токен с claims sub, projects, exp и дополнительными extra
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, extra jwt.MapClaims) string {
+	t.Helper()
+	c := jwt.MapClaims{
+		"sub":      "alice",
+		"projects": map[string]string{"1": "write", "*": "read"},
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range extra {
+		c[k] = v
+	}
+	s, err := jwt.NewWithClaims(method, c).SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return s
+}
+
+// TestPrincipal_Can проверяет роли в отдельном проекте и во всех проектах
+func TestPrincipal_Can(t *testing.T) {
+	p := &Principal{Projects: map[int]Role{AllProjects: RoleRead, 1: RoleAdmin}}
+	cases := []struct {
+		project int
+		need    Role
+		want    bool
+	}{
+		{1, RoleAdmin, true},
+		{2, RoleRead, true},
+		{2, RoleWrite, false},
+		{AllProjects, RoleRead, true},
+		{AllProjects, RoleWrite, false},
+	}
+	for _, c := range cases {
+		if got := p.Can(c.project, c.need); got != c.want {
+			t.Errorf("Can(%d, %s) = %v, want %v", c.project, c.need, got, c.want)
+		}
+	}
+	if (&Principal{Projects: map[int]Role{3: RoleWrite}}).Can(AllProjects, RoleRead) {
+		t.Error("project role must not grant access to all projects")
+	}
+}
+
+// TestAuthenticateAPIKey проверяет поиск клиента по ключу и отказ для неизвестного ключа
+func TestAuthenticateAPIKey(t *testing.T) {
+	a, err := New(Config{APIKeys: map[string]APIKey{"k1": {Subject: "ci", Projects: Grants{"*": "admin"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := a.AuthenticateAPIKey("k1")
+	if err != nil || p.Subject != "ci" || p.Projects[AllProjects] != RoleAdmin {
+		t.Fatalf("unexpected principal %+v, %v", p, err)
+	}
+	if _, err := a.AuthenticateAPIKey("k2"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := New(Config{APIKeys: map[string]APIKey{"k": {Subject: "x", Projects: Grants{"1": "owner"}}}}); err == nil {
+		t.Error("expected error for unknown role")
+	}
+}
+
+// TestAuthenticateToken_HS256 проверяет HS256-токен, срок действия, iss и aud
+func TestAuthenticateToken_HS256(t *testing.T) {
+	secret := []byte("s3cret")
+	a, _ := New(Config{HS256Secret: secret, Issuer: "idp", Audience: "goods-api"})
+
+	p, err := a.AuthenticateToken(signToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"iss": "idp", "aud": "goods-api"}))
+	if err != nil || p.Subject != "alice" || p.Projects[1] != RoleWrite || p.Projects[AllProjects] != RoleRead {
+		t.Fatalf("unexpected principal %+v, %v", p, err)
+	}
+
+	invalid := map[string]string{
+		"wrong secret": signToken(t, jwt.SigningMethodHS256, []byte("other"), jwt.MapClaims{"iss": "idp", "aud": "goods-api"}),
+		"expired":      signToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"iss": "idp", "aud": "goods-api", "exp": time.Now().Add(-time.Minute).Unix()}),
+		"no exp":       signToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"iss": "idp", "aud": "goods-api", "exp": nil}),
+		"wrong issuer": signToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"iss": "evil", "aud": "goods-api"}),
+		"wrong aud":    signToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"iss": "idp", "aud": "other"}),
+		"bad role":     signToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"iss": "idp", "aud": "goods-api", "projects": map[string]string{"1": "root"}}),
+		"garbage":      "not.a.token",
+	}
+	for name, token := range invalid {
+		if _, err := a.AuthenticateToken(token); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: expected ErrInvalidCredentials, got %v", name, err)
+		}
+	}
+}
+
+// TestAuthenticateToken_RS256 проверяет RS256-токен и отказ от HS256, если секрет не настроен
+func TestAuthenticateToken_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := New(Config{RS256PublicKey: &key.PublicKey})
+	if p, err := a.AuthenticateToken(signToken(t, jwt.SigningMethodRS256, key, nil)); err != nil || p.Subject != "alice" {
+		t.Fatalf("unexpected principal %+v, %v", p, err)
+	}
+	// HS256-токен, подписанный открытым ключом, не должен приниматься
+	pub, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if _, err := a.AuthenticateToken(signToken(t, jwt.SigningMethodHS256, pub, nil)); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected HS256 token to be rejected, got %v", err)
+	}
+}
+
+// TestNewFromEnv проверяет отключение без настроек и чтение ключей и PEM из окружения
+func TestNewFromEnv(t *testing.T) {
+	for _, name := range []string{"AUTH_API_KEYS", "AUTH_JWT_HS256_SECRET", "AUTH_JWT_RS256_PUBLIC_KEY_FILE", "AUTH_JWT_ISSUER", "AUTH_JWT_AUDIENCE"} {
+		t.Setenv(name, "")
+	}
+	if a, err := NewFromEnv(); a != nil || err != nil {
+		t.Fatalf("expected disabled authentication, got %v, %v", a, err)
+	}
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AUTH_API_KEYS", `{"k1":{"sub":"ci","projects":{"*":"admin"}}}`)
+	t.Setenv("AUTH_JWT_RS256_PUBLIC_KEY_FILE", path)
+	a, err := NewFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AuthenticateAPIKey("k1"); err != nil {
+		t.Errorf("api key from env: %v", err)
+	}
+	if _, err := a.AuthenticateToken(signToken(t, jwt.SigningMethodRS256, key, nil)); err != nil {
+		t.Errorf("RS256 token with key from env: %v", err)
+	}
+
+	t.Setenv("AUTH_API_KEYS", `not json`)
+	if _, err := NewFromEnv(); err == nil {
+		t.Error("expected error for invalid AUTH_API_KEYS")
+	}
+}
@@ -3,71 +3,262 @@ package consumer
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"sync"
+	"time"
 
 	"HezzlTestTask/internal/model"
+	"HezzlTestTask/pkg/tracing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrUnsupportedEvent возвращается для сообщений без типа или с неизвестной версией конверта
+var ErrUnsupportedEvent = errors.New("unsupported event")
+
 // Repo описывает интерфейс репозитория ClickHouse для пакетной записи логов
-// Метод BatchInsertLogs записывает слайс событий model.Good
+// Метод BatchInsertLogs записывает слайс событий model.Event
 // Все комментарии на русском языке
 type Repo interface {
-	BatchInsertLogs(ctx context.Context, events []model.Good) error
+	BatchInsertLogs(ctx context.Context, events []model.Event) error
+}
+
+// DeadLetterSink описывает хранилище сообщений, которые не удалось обработать (таблица events_dead_letter)
+type DeadLetterSink interface {
+	InsertDeadLetters(ctx context.Context, letters []model.DeadLetter) error
+}
+
+// Ack описывает подтверждение сообщения брокера (JetStream):
+// Ack — событие записано в ClickHouse, Nak — запросить повторную доставку,
+// Term — прекратить доставку сообщения, которое невозможно обработать,
+// Deliveries — номер текущей доставки сообщения, начиная с 1
+type Ack interface {
+	Ack() error
+	Nak() error
+	Term() error
+	Deliveries() int
+}
+
+// pendingEvent хранит событие из буфера вместе с исходными байтами, подтверждением сообщения
+// и контекстом спана его обработки, на который ссылается спан записи пакета
+type pendingEvent struct {
+	event model.Event
+	data  []byte
+	ack   Ack
+	span  trace.SpanContext
 }
 
 // Consumer буферизует события и отправляет их пакетно в ClickHouse
 // batchSize определяет макс. количество событий до отправки
-// mutex защищает доступ к буферу events
+// maxBytes ограничивает суммарный размер сообщений в буфере (0 — без ограничения)
+// Сообщения подтверждаются только после успешной записи пакета, при ошибке — возвращаются на повторную доставку
+// Если задан dead letter, неразборчивые сообщения и события, отклонённые ClickHouse на maxDeliveries-й доставке,
+// сохраняются в нём вместо потери
+// mutex защищает доступ к буферу events и счётчику bytes
+// writeMu упорядочивает запись пакетов: пакет забирается из буфера и записывается целиком, пока предыдущий
+// не записан, поэтому в RetryRepo одновременно находится не больше одного пакета этого Consumer
 
 type Consumer struct {
-	repo      Repo
-	batchSize int
-	events    []model.Good
-	mu        sync.Mutex
+	repo          Repo
+	dead          DeadLetterSink
+	maxDeliveries int
+	batchSize     int
+	maxBytes      int
+	events        []pendingEvent
+	bytes         int
+	mu            sync.Mutex
+	writeMu       sync.Mutex
+}
+
+// NewConsumer создаёт Consumer с указанным репозиторием, размером пакета и лимитом размера буфера в байтах
+func NewConsumer(repo Repo, batchSize, maxBytes int) *Consumer {
+	return &Consumer{repo: repo, batchSize: batchSize, maxBytes: maxBytes, events: make([]pendingEvent, 0, batchSize)}
 }
 
-// NewConsumer создаёт Consumer с указанным репозиторием и размером пакета
-func NewConsumer(repo Repo, batchSize int) *Consumer {
-	return &Consumer{repo: repo, batchSize: batchSize, events: make([]model.Good, 0, batchSize)}
+// SetDeadLetter подключает хранилище dead letter; событие из отклонённого пакета
+// попадает туда после maxDeliveries неудачных доставок (0 — только ошибки разбора)
+func (c *Consumer) SetDeadLetter(sink DeadLetterSink, maxDeliveries int) {
+	c.dead = sink
+	c.maxDeliveries = maxDeliveries
 }
 
-// HandleMessage обрабатывает сообщение из NATS: парсит JSON, добавляет событие в буфер и при достижении batchSize отправляет в ClickHouse
-func (c *Consumer) HandleMessage(ctx context.Context, data []byte) error {
-	// логируем получение сообщения
-	log.Printf("Получено сообщение NATS: %s", string(data))
-	// парсим данные в модель Good
-	var g model.Good
-	if err := json.Unmarshal(data, &g); err != nil {
+// Run каждые interval записывает неполную пачку, ограничивая время ожидания события в буфере,
+// и при отмене ctx выполняет последний Flush перед выходом
+func (c *Consumer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// контекст отменён — записываем остаток с независимым контекстом
+			if err := c.Flush(context.Background()); err != nil {
+				slog.Error("failed to flush consumer events", slog.Any("error", err))
+			}
+			return
+		case <-ticker.C:
+			if err := c.Flush(ctx); err != nil {
+				slog.ErrorContext(ctx, "failed to flush consumer events", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// HandleMessage обрабатывает сообщение из NATS: парсит конверт события, добавляет его в буфер и при достижении batchSize или maxBytes отправляет в ClickHouse
+// Некорректные сообщения сохраняются в dead letter и завершаются через ack.Term, чтобы брокер не доставлял их повторно
+// ctx должен содержать контекст трассировки из заголовков сообщения: спан обработки продолжает трассировку публикации
+func (c *Consumer) HandleMessage(ctx context.Context, data []byte, ack Ack) (err error) {
+	ctx, span := tracing.Start(ctx, "Consumer.HandleMessage",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(semconv.MessagingSystemKey.String("nats")),
+	)
+	defer func() { tracing.End(span, err) }()
+	// парсим данные в конверт события
+	var e model.Event
+	if err := json.Unmarshal(data, &e); err != nil {
+		c.reject(ctx, data, ack, err)
 		return err
 	}
-	// логируем распарсенное событие
-	log.Printf("Получено событие для логирования: %+v", g)
-	c.mu.Lock()
-	c.events = append(c.events, g)
-	// если достигли batchSize, сбрасываем буфер
-	if len(c.events) >= c.batchSize {
-		eventsCopy := make([]model.Good, len(c.events))
-		copy(eventsCopy, c.events)
-		c.events = c.events[:0]
-		c.mu.Unlock()
-		// отправляем пакет логов
-		return c.repo.BatchInsertLogs(ctx, eventsCopy)
+	if e.Type == "" || e.Version < 1 || e.Version > model.EventVersion {
+		err := fmt.Errorf("%w: type=%q version=%d", ErrUnsupportedEvent, e.Type, e.Version)
+		c.reject(ctx, data, ack, err)
+		return err
+	}
+	// дальнейшие записи лога содержат идентификатор запроса, вызвавшего изменение
+	if e.RequestID != "" {
+		ctx = model.ContextWithRequestID(ctx, e.RequestID)
 	}
+	slog.DebugContext(ctx, "event received", eventAttrs(e), slog.Int("bytes", len(data)))
+	c.mu.Lock()
+	c.events = append(c.events, pendingEvent{event: e, data: data, ack: ack, span: span.SpanContext()})
+	c.bytes += len(data)
+	bufferDepth.Set(float64(len(c.events)))
+	full := len(c.events) >= c.batchSize || (c.maxBytes > 0 && c.bytes >= c.maxBytes)
 	c.mu.Unlock()
+	// если достигли batchSize или maxBytes, сбрасываем буфер;
+	// пока пишется предыдущий пакет, вызов ждёт его окончания и не принимает новых сообщений
+	if full {
+		return c.Flush(ctx)
+	}
 	return nil
 }
 
 // Flush отправляет все накопленные события, если они есть
+// Если другой пакет ещё записывается, Flush дожидается его и забирает буфер после этого
 func (c *Consumer) Flush(ctx context.Context) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
 	c.mu.Lock()
 	if len(c.events) == 0 {
 		c.mu.Unlock()
 		return nil
 	}
-	eventsCopy := make([]model.Good, len(c.events))
-	copy(eventsCopy, c.events)
-	c.events = c.events[:0]
+	batch := c.takeLocked()
 	c.mu.Unlock()
-	return c.repo.BatchInsertLogs(ctx, eventsCopy)
+	return c.write(ctx, batch)
+}
+
+// takeLocked забирает содержимое буфера; вызывается под c.mu
+func (c *Consumer) takeLocked() []pendingEvent {
+	batch := make([]pendingEvent, len(c.events))
+	copy(batch, c.events)
+	c.events = c.events[:0]
+	c.bytes = 0
+	bufferDepth.Set(0)
+	return batch
+}
+
+// write записывает пакет в ClickHouse и подтверждает сообщения;
+// при ошибке записи сообщения возвращаются брокеру на повторную доставку,
+// а исчерпавшие maxDeliveries — переносятся в dead letter
+// Спан записи ссылается (span links) на спаны обработки всех сообщений пакета
+func (c *Consumer) write(ctx context.Context, batch []pendingEvent) (err error) {
+	events := make([]model.Event, len(batch))
+	links := make([]trace.Link, 0, len(batch))
+	for i, p := range batch {
+		events[i] = p.event
+		if p.span.IsValid() {
+			links = append(links, trace.Link{SpanContext: p.span})
+		}
+	}
+	ctx, span := tracing.Start(ctx, "Consumer.BatchInsertLogs",
+		trace.WithLinks(links...),
+		trace.WithAttributes(semconv.MessagingBatchMessageCount(len(events))),
+	)
+	defer func() { tracing.End(span, err) }()
+	batchEvents.Observe(float64(len(events)))
+	if err := c.repo.BatchInsertLogs(ctx, events); err != nil {
+		var retry, exhausted []pendingEvent
+		for _, p := range batch {
+			if c.dead != nil && c.maxDeliveries > 0 && p.ack.Deliveries() >= c.maxDeliveries {
+				exhausted = append(exhausted, p)
+			} else {
+				retry = append(retry, p)
+			}
+		}
+		if len(exhausted) > 0 {
+			letters := make([]model.DeadLetter, len(exhausted))
+			for i, p := range exhausted {
+				letters[i] = model.NewDeadLetter(model.DeadLetterInsert, p.data, p.ack.Deliveries(), err)
+			}
+			if dlErr := c.dead.InsertDeadLetters(ctx, letters); dlErr != nil {
+				// dead letter недоступен — сообщения остаются в брокере
+				slog.ErrorContext(ctx, "failed to store dead letters", slog.Int("messages", len(letters)), slog.Any("error", dlErr))
+				retry = append(retry, exhausted...)
+			} else {
+				for _, p := range exhausted {
+					terminate(p.ack)
+				}
+			}
+		}
+		for _, p := range retry {
+			if nakErr := p.ack.Nak(); nakErr != nil {
+				slog.ErrorContext(ctx, "failed to nak message", eventAttrs(p.event), slog.Any("error", nakErr))
+			}
+		}
+		return err
+	}
+	for _, p := range batch {
+		if ackErr := p.ack.Ack(); ackErr != nil {
+			slog.ErrorContext(ctx, "failed to ack message", eventAttrs(p.event), slog.Any("error", ackErr))
+		}
+	}
+	return nil
+}
+
+// reject сохраняет сообщение, которое невозможно разобрать, в dead letter и прекращает его доставку
+// Если запись в dead letter не удалась, сообщение возвращается брокеру, чтобы не потерять его
+func (c *Consumer) reject(ctx context.Context, data []byte, ack Ack, cause error) {
+	if c.dead != nil {
+		letter := model.NewDeadLetter(model.DeadLetterDecode, data, ack.Deliveries(), cause)
+		if err := c.dead.InsertDeadLetters(ctx, []model.DeadLetter{letter}); err != nil {
+			slog.ErrorContext(ctx, "failed to store dead letter", slog.Any("error", err))
+			if nakErr := ack.Nak(); nakErr != nil {
+				slog.ErrorContext(ctx, "failed to nak message", slog.Any("error", nakErr))
+			}
+			return
+		}
+	}
+	slog.WarnContext(ctx, "message rejected", slog.Any("error", cause), slog.Int("bytes", len(data)))
+	terminate(ack)
+}
+
+// terminate прекращает доставку сообщения, которое невозможно обработать
+func terminate(ack Ack) {
+	if err := ack.Term(); err != nil {
+		slog.Error("failed to terminate message", slog.Any("error", err))
+	}
+}
+
+// eventAttrs возвращает атрибуты лога, идентифицирующие событие
+func eventAttrs(e model.Event) slog.Attr {
+	return slog.Group("event",
+		slog.String("id", e.ID),
+		slog.String("type", e.Type),
+		slog.Int("project_id", e.ProjectID),
+		slog.Int("entity_id", e.EntityID),
+		slog.String("request_id", e.RequestID),
+	)
 }
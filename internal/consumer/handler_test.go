@@ -4,35 +4,77 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 
 	"HezzlTestTask/internal/model"
 )
 
 // mockRepo реализует интерфейс Repo и сохраняет полученные события для проверки
 type mockRepo struct {
-	received [][]model.Good // полученные батчи событий
-	err      error          // ошибка, которую вернет BatchInsertLogs
+	mu       sync.Mutex
+	received [][]model.Event // полученные батчи событий
+	err      error           // ошибка, которую вернет BatchInsertLogs
 }
 
-func (m *mockRepo) BatchInsertLogs(ctx context.Context, events []model.Good) error {
+func (m *mockRepo) BatchInsertLogs(ctx context.Context, events []model.Event) error {
 	// сохраняем копию слайса для проверки
-	copyBatch := make([]model.Good, len(events))
+	copyBatch := make([]model.Event, len(events))
 	copy(copyBatch, events)
+	m.mu.Lock()
 	m.received = append(m.received, copyBatch)
+	m.mu.Unlock()
 	return m.err
 }
 
+// batches возвращает число полученных батчей, безопасно для вызова из других горутин
+func (m *mockRepo) batches() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.received)
+}
+
+// mockAck реализует интерфейс Ack и считает подтверждения
+// deliveries — номер доставки сообщения (0 трактуется как первая доставка)
+type mockAck struct {
+	acked, naked, terminated int
+	deliveries               int
+}
+
+func (m *mockAck) Ack() error  { m.acked++; return nil }
+func (m *mockAck) Nak() error  { m.naked++; return nil }
+func (m *mockAck) Term() error { m.terminated++; return nil }
+func (m *mockAck) Deliveries() int {
+	if m.deliveries == 0 {
+		return 1
+	}
+	return m.deliveries
+}
+
+// eventData готовит сериализованный конверт события good.updated для товара
+func eventData(t *testing.T, g model.Good) []byte {
+	e, err := model.NewEvent(context.Background(), model.EventGoodUpdated, g.ProjectID, g.ID, nil, g)
+	require.NoError(t, err)
+	data, err := json.Marshal(e)
+	require.NoError(t, err)
+	return data
+}
+
 func TestHandleMessage_NoFlush(t *testing.T) {
 	// тестируем, что при количестве событий меньше batchSize нет записи в репозиторий
 	repo := &mockRepo{}
-	cons := NewConsumer(repo, 3)
+	cons := NewConsumer(repo, 3, 0)
 
 	// готовим событие
-	data, _ := json.Marshal(model.Good{ID: 1, ProjectID: 10, Name: "g1"})
-	err := cons.HandleMessage(context.Background(), data)
+	data := eventData(t, model.Good{ID: 1, ProjectID: 10, Name: "g1"})
+	err := cons.HandleMessage(context.Background(), data, &mockAck{})
 	require.NoError(t, err)
 	// репозиторий не должен был быть вызван
 	require.Len(t, repo.received, 0)
@@ -41,27 +83,27 @@ func TestHandleMessage_NoFlush(t *testing.T) {
 func TestHandleMessage_FlushOnBatch(t *testing.T) {
 	// тестируем, что при достижении batchSize события отправляются репозиторию
 	repo := &mockRepo{}
-	cons := NewConsumer(repo, 2)
+	cons := NewConsumer(repo, 2, 0)
 
 	// два события подряд приводят к одной записи
 	for i := 1; i <= 2; i++ {
-		e := model.Good{ID: i, ProjectID: 5, Name: "name"}
-		data, _ := json.Marshal(e)
-		err := cons.HandleMessage(context.Background(), data)
+		data := eventData(t, model.Good{ID: i, ProjectID: 5, Name: "name"})
+		err := cons.HandleMessage(context.Background(), data, &mockAck{})
 		require.NoError(t, err)
 	}
 	// проверяем, что репозиторий был вызван один раз
 	require.Len(t, repo.received, 1)
 	// проверяем содержимое батча
 	require.Len(t, repo.received[0], 2)
-	require.Equal(t, repo.received[0][0].ID, 1)
-	require.Equal(t, repo.received[0][1].ID, 2)
+	require.Equal(t, repo.received[0][0].EntityID, 1)
+	require.Equal(t, repo.received[0][1].EntityID, 2)
+	require.Equal(t, repo.received[0][0].Type, model.EventGoodUpdated)
 }
 
 func TestFlush_Empty(t *testing.T) {
 	// тестируем, что Flush ничего не делает, если буфер пуст
 	repo := &mockRepo{}
-	cons := NewConsumer(repo, 5)
+	cons := NewConsumer(repo, 5, 0)
 	err := cons.Flush(context.Background())
 	require.NoError(t, err)
 	require.Len(t, repo.received, 0)
@@ -70,13 +112,12 @@ func TestFlush_Empty(t *testing.T) {
 func TestFlush_NonEmpty(t *testing.T) {
 	// тестируем, что Flush отправляет накопленные события
 	repo := &mockRepo{}
-	cons := NewConsumer(repo, 5)
+	cons := NewConsumer(repo, 5, 0)
 
 	// добавляем три события вручную через HandleMessage
 	for i := 1; i <= 3; i++ {
-		e := model.Good{ID: i, ProjectID: 2, Name: "n"}
-		data, _ := json.Marshal(e)
-		err := cons.HandleMessage(context.Background(), data)
+		data := eventData(t, model.Good{ID: i, ProjectID: 2, Name: "n"})
+		err := cons.HandleMessage(context.Background(), data, &mockAck{})
 		require.NoError(t, err)
 	}
 	// репозиторий ещё не должен быть вызван, т.к. batchSize=5
@@ -89,24 +130,272 @@ func TestFlush_NonEmpty(t *testing.T) {
 	require.Len(t, repo.received[0], 3)
 }
 
+// gatedRepo удерживает каждую запись до закрытия release и запоминает наибольшее число одновременных записей
+type gatedRepo struct {
+	mu          sync.Mutex
+	calls       int
+	inFlight    int
+	maxInFlight int
+	release     chan struct{}
+}
+
+func (g *gatedRepo) BatchInsertLogs(ctx context.Context, events []model.Event) error {
+	g.mu.Lock()
+	g.calls++
+	g.inFlight++
+	g.maxInFlight = max(g.maxInFlight, g.inFlight)
+	g.mu.Unlock()
+	<-g.release
+	g.mu.Lock()
+	g.inFlight--
+	g.mu.Unlock()
+	return nil
+}
+
+func (g *gatedRepo) stats() (calls, maxInFlight int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.calls, g.maxInFlight
+}
+
+func TestFlush_SerializesWrites(t *testing.T) {
+	// тестируем, что следующий пакет не уходит в репозиторий, пока не записан предыдущий
+	repo := &gatedRepo{release: make(chan struct{})}
+	cons := NewConsumer(repo, 1, 0)
+	results := make(chan error, 2)
+	go func() {
+		results <- cons.HandleMessage(context.Background(), eventData(t, model.Good{ID: 1, ProjectID: 1}), &mockAck{})
+	}()
+	require.Eventually(t, func() bool { calls, _ := repo.stats(); return calls == 1 }, time.Second, time.Millisecond)
+	go func() {
+		results <- cons.HandleMessage(context.Background(), eventData(t, model.Good{ID: 2, ProjectID: 1}), &mockAck{})
+	}()
+	// второй пакет ждёт окончания записи первого
+	time.Sleep(20 * time.Millisecond)
+	calls, _ := repo.stats()
+	require.Equal(t, 1, calls)
+
+	close(repo.release)
+	require.NoError(t, <-results)
+	require.NoError(t, <-results)
+	calls, maxInFlight := repo.stats()
+	require.Equal(t, 2, calls)
+	require.Equal(t, 1, maxInFlight)
+}
+
 func TestHandleMessage_ParseError(t *testing.T) {
 	// тестируем ошибку парсинга некорректного JSON
 	repo := &mockRepo{}
-	cons := NewConsumer(repo, 1)
-	err := cons.HandleMessage(context.Background(), []byte("not json"))
+	cons := NewConsumer(repo, 1, 0)
+	err := cons.HandleMessage(context.Background(), []byte("not json"), &mockAck{})
 	require.Error(t, err)
 	// репозиторий не вызывался
 	require.Len(t, repo.received, 0)
 }
 
+func TestHandleMessage_ReprioritizePayload(t *testing.T) {
+	// тестируем, что событие перестановки приоритетов (массив в Current) принимается консьюмером
+	repo := &mockRepo{}
+	cons := NewConsumer(repo, 1, 0)
+	updates := []model.PriorityUpdate{{ID: 1, Priority: 2}, {ID: 3, Priority: 1}}
+	e, err := model.NewEvent(context.Background(), model.EventGoodReprioritized, 4, 3, nil, updates)
+	require.NoError(t, err)
+	data, _ := json.Marshal(e)
+	require.NoError(t, cons.HandleMessage(context.Background(), data, &mockAck{}))
+	require.Len(t, repo.received, 1)
+	require.Equal(t, model.EventGoodReprioritized, repo.received[0][0].Type)
+}
+
+func TestHandleMessage_UnsupportedEvent(t *testing.T) {
+	// тестируем отказ для сообщений без конверта (например, «сырой» объект Good) и неизвестной версии
+	repo := &mockRepo{}
+	cons := NewConsumer(repo, 1, 0)
+	legacy := []byte(`{"projectId":1,"name":"raw","priority":1}`)
+	err := cons.HandleMessage(context.Background(), legacy, &mockAck{})
+	require.ErrorIs(t, err, ErrUnsupportedEvent)
+	future, _ := json.Marshal(model.Event{Version: model.EventVersion + 1, Type: model.EventGoodCreated})
+	err = cons.HandleMessage(context.Background(), future, &mockAck{})
+	require.ErrorIs(t, err, ErrUnsupportedEvent)
+	require.Len(t, repo.received, 0)
+}
+
 func TestBatchInsertError_IsPropagated(t *testing.T) {
 	// тестируем, что ошибка из репозитория возвращается при достижении batchSize
 	ex := errors.New("insert failed")
 	repo := &mockRepo{err: ex}
-	cons := NewConsumer(repo, 1)
+	cons := NewConsumer(repo, 1, 0)
 	// batchSize=1, одно сообщение сразу вызывает BatchInsertLogs
-	data, _ := json.Marshal(model.Good{ID: 9, ProjectID: 3, Name: "x"})
-	err := cons.HandleMessage(context.Background(), data)
+	data := eventData(t, model.Good{ID: 9, ProjectID: 3, Name: "x"})
+	err := cons.HandleMessage(context.Background(), data, &mockAck{})
 	require.Error(t, err)
 	require.ErrorIs(t, err, ex)
 }
+
+func TestAck_AfterBatchCommit(t *testing.T) {
+	// тестируем, что сообщения подтверждаются только после успешной записи пакета
+	repo := &mockRepo{}
+	cons := NewConsumer(repo, 2, 0)
+	first, second := &mockAck{}, &mockAck{}
+	require.NoError(t, cons.HandleMessage(context.Background(), eventData(t, model.Good{ID: 1, ProjectID: 1}), first))
+	// пакет ещё не записан — подтверждения нет
+	require.Equal(t, 0, first.acked)
+	require.NoError(t, cons.HandleMessage(context.Background(), eventData(t, model.Good{ID: 2, ProjectID: 1}), second))
+	require.Equal(t, 1, first.acked)
+	require.Equal(t, 1, second.acked)
+	require.Equal(t, 0, first.naked+second.naked)
+}
+
+func TestNak_OnBatchInsertError(t *testing.T) {
+	// тестируем, что при ошибке записи все сообщения пакета возвращаются на повторную доставку
+	repo := &mockRepo{err: errors.New("clickhouse down")}
+	cons := NewConsumer(repo, 5, 0)
+	acks := []*mockAck{{}, {}}
+	for i, ack := range acks {
+		require.NoError(t, cons.HandleMessage(context.Background(), eventData(t, model.Good{ID: i + 1, ProjectID: 1}), ack))
+	}
+	require.Error(t, cons.Flush(context.Background()))
+	for _, ack := range acks {
+		require.Equal(t, 0, ack.acked)
+		require.Equal(t, 1, ack.naked)
+	}
+}
+
+func TestTerm_OnInvalidMessage(t *testing.T) {
+	// тестируем, что некорректные сообщения не доставляются повторно
+	repo := &mockRepo{}
+	cons := NewConsumer(repo, 1, 0)
+	ack := &mockAck{}
+	require.Error(t, cons.HandleMessage(context.Background(), []byte("not json"), ack))
+	unsupported, _ := json.Marshal(model.Event{Version: 1})
+	require.ErrorIs(t, cons.HandleMessage(context.Background(), unsupported, ack), ErrUnsupportedEvent)
+	require.Equal(t, 2, ack.terminated)
+	require.Equal(t, 0, ack.acked+ack.naked)
+}
+
+func TestHandleMessage_FlushOnMaxBytes(t *testing.T) {
+	// тестируем, что при превышении maxBytes буфер сбрасывается раньше batchSize
+	repo := &mockRepo{}
+	first := eventData(t, model.Good{ID: 1, ProjectID: 1, Name: "a"})
+	cons := NewConsumer(repo, 100, len(first)+1)
+	require.NoError(t, cons.HandleMessage(context.Background(), first, &mockAck{}))
+	require.Len(t, repo.received, 0)
+	require.NoError(t, cons.HandleMessage(context.Background(), eventData(t, model.Good{ID: 2, ProjectID: 1, Name: "b"}), &mockAck{}))
+	require.Len(t, repo.received, 1)
+	require.Len(t, repo.received[0], 2)
+
+	// счётчик байт обнуляется вместе с буфером
+	require.NoError(t, cons.HandleMessage(context.Background(), first, &mockAck{}))
+	require.Len(t, repo.received, 1)
+}
+
+func TestRun_FlushesOnInterval(t *testing.T) {
+	// тестируем, что неполная пачка записывается по таймеру
+	repo := &mockRepo{}
+	cons := NewConsumer(repo, 100, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cons.Run(ctx, 10*time.Millisecond)
+	require.NoError(t, cons.HandleMessage(context.Background(), eventData(t, model.Good{ID: 1, ProjectID: 1}), &mockAck{}))
+	require.Eventually(t, func() bool { return repo.batches() == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestRun_FlushesOnStop(t *testing.T) {
+	// тестируем, что при остановке Run записывает остаток буфера и подтверждает сообщения
+	repo := &mockRepo{}
+	cons := NewConsumer(repo, 100, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		cons.Run(ctx, time.Hour)
+		close(done)
+	}()
+	ack := &mockAck{}
+	require.NoError(t, cons.HandleMessage(context.Background(), eventData(t, model.Good{ID: 1, ProjectID: 1}), ack))
+	cancel()
+	<-done
+	require.Equal(t, 1, repo.batches())
+	require.Equal(t, 1, ack.acked)
+}
+
+func TestDeadLetter_OnDecodeError(t *testing.T) {
+	// тестируем, что неразборчивое сообщение сохраняется в dead letter с исходными байтами и ошибкой
+	repo := &mockRepo{}
+	dead := &mockDeadLetters{}
+	cons := NewConsumer(repo, 1, 0)
+	cons.SetDeadLetter(dead, 3)
+	ack := &mockAck{}
+	require.Error(t, cons.HandleMessage(context.Background(), []byte("not json"), ack))
+	require.Len(t, dead.letters, 1)
+	require.Equal(t, model.DeadLetterDecode, dead.letters[0].Stage)
+	require.Equal(t, "not json", string(dead.letters[0].Payload))
+	require.NotEmpty(t, dead.letters[0].Error)
+	require.Equal(t, 1, ack.terminated)
+}
+
+func TestDeadLetter_SinkUnavailable(t *testing.T) {
+	// тестируем, что при недоступном dead letter сообщение возвращается брокеру, а не теряется
+	dead := &mockDeadLetters{insertErr: errors.New("clickhouse down")}
+	cons := NewConsumer(&mockRepo{}, 1, 0)
+	cons.SetDeadLetter(dead, 3)
+	ack := &mockAck{}
+	require.Error(t, cons.HandleMessage(context.Background(), []byte("not json"), ack))
+	require.Equal(t, 0, ack.terminated)
+	require.Equal(t, 1, ack.naked)
+}
+
+func TestDeadLetter_OnExhaustedDeliveries(t *testing.T) {
+	// тестируем, что отклонённые ClickHouse события переносятся в dead letter только на последней доставке
+	repo := &mockRepo{err: errors.New("type mismatch")}
+	dead := &mockDeadLetters{}
+	cons := NewConsumer(repo, 2, 0)
+	cons.SetDeadLetter(dead, 3)
+	fresh, last := &mockAck{deliveries: 1}, &mockAck{deliveries: 3}
+	lastData := eventData(t, model.Good{ID: 2, ProjectID: 1})
+	require.NoError(t, cons.HandleMessage(context.Background(), eventData(t, model.Good{ID: 1, ProjectID: 1}), fresh))
+	require.Error(t, cons.HandleMessage(context.Background(), lastData, last))
+	require.Equal(t, 1, fresh.naked)
+	require.Equal(t, 0, fresh.terminated)
+	require.Equal(t, 1, last.terminated)
+	require.Equal(t, 0, last.naked)
+	require.Len(t, dead.letters, 1)
+	require.Equal(t, model.DeadLetterInsert, dead.letters[0].Stage)
+	require.Equal(t, "type mismatch", dead.letters[0].Error)
+	require.Equal(t, 3, dead.letters[0].Deliveries)
+	require.Equal(t, lastData, dead.letters[0].Payload)
+}
+
+func TestHandleMessage_Tracing(t *testing.T) {
+	// тестируем, что обработка сообщения продолжает трассировку публикации,
+	// а спан записи пакета ссылается на спаны всех сообщений пакета
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	repo := &mockRepo{}
+	cons := NewConsumer(repo, 2, 0)
+	publish := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), publish)
+
+	require.NoError(t, cons.HandleMessage(ctx, eventData(t, model.Good{ID: 1, ProjectID: 10}), &mockAck{}))
+	require.NoError(t, cons.HandleMessage(context.Background(), eventData(t, model.Good{ID: 2, ProjectID: 10}), &mockAck{}))
+
+	byName := map[string][]sdktrace.ReadOnlySpan{}
+	for _, s := range rec.Ended() {
+		byName[s.Name()] = append(byName[s.Name()], s)
+	}
+	handled := byName["Consumer.HandleMessage"]
+	require.Len(t, handled, 2)
+	require.Equal(t, publish.TraceID(), handled[0].SpanContext().TraceID())
+	require.Equal(t, publish.SpanID(), handled[0].Parent().SpanID())
+	require.Equal(t, trace.SpanKindConsumer, handled[0].SpanKind())
+
+	written := byName["Consumer.BatchInsertLogs"]
+	require.Len(t, written, 1)
+	links := written[0].Links()
+	require.Len(t, links, 2)
+	require.Equal(t, handled[0].SpanContext().SpanID(), links[0].SpanContext.SpanID())
+	require.Equal(t, handled[1].SpanContext().SpanID(), links[1].SpanContext.SpanID())
+}
@@ -0,0 +1,24 @@
+package consumer
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// метрики consumer: заполненность буфера, размеры записываемых пачек и время записи в ClickHouse
+var (
+	bufferDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "consumer_buffer_events",
+		Help: "Number of events buffered by the consumer and not yet written to ClickHouse.",
+	})
+	batchEvents = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "consumer_batch_events",
+		Help:    "Number of events in batches written to ClickHouse.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	})
+	insertDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "clickhouse_insert_duration_seconds",
+		Help:    "Latency of a single ClickHouse batch insert attempt by result (ok, error).",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"result"})
+)
@@ -0,0 +1,44 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	"HezzlTestTask/internal/model"
+)
+
+// sampleCount возвращает число наблюдений гистограммы
+func sampleCount(t *testing.T, h prometheus.Observer) uint64 {
+	t.Helper()
+	m := &dto.Metric{}
+	require.NoError(t, h.(prometheus.Metric).Write(m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestConsumer_BufferAndBatchMetrics(t *testing.T) {
+	// тестируем, что глубина буфера растёт с каждым событием и обнуляется после записи пачки
+	cons := NewConsumer(&mockRepo{}, 2, 0)
+	batches := sampleCount(t, batchEvents)
+	require.NoError(t, cons.HandleMessage(context.Background(), eventData(t, model.Good{ID: 1, ProjectID: 1}), &mockAck{}))
+	require.Equal(t, float64(1), testutil.ToFloat64(bufferDepth))
+	require.NoError(t, cons.HandleMessage(context.Background(), eventData(t, model.Good{ID: 2, ProjectID: 1}), &mockAck{}))
+	require.Equal(t, float64(0), testutil.ToFloat64(bufferDepth))
+	require.Equal(t, batches+1, sampleCount(t, batchEvents))
+}
+
+func TestRetryRepo_InsertDurationMetric(t *testing.T) {
+	// тестируем, что каждая попытка записи учитывается с результатом
+	okBefore := sampleCount(t, insertDuration.WithLabelValues("ok"))
+	errBefore := sampleCount(t, insertDuration.WithLabelValues("error"))
+	repo := &flakyRepo{errs: []error{errors.New("timeout")}}
+	r := startRetry(t, repo, 2, 1)
+	require.NoError(t, r.BatchInsertLogs(context.Background(), []model.Event{{ID: "e-1"}}))
+	require.Equal(t, okBefore+1, sampleCount(t, insertDuration.WithLabelValues("ok")))
+	require.Equal(t, errBefore+1, sampleCount(t, insertDuration.WithLabelValues("error")))
+}
@@ -0,0 +1,43 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+
+	"HezzlTestTask/internal/model"
+)
+
+// DeadLetterStore описывает чтение и удаление записей dead letter при переотправке
+type DeadLetterStore interface {
+	ListDeadLetters(ctx context.Context, stage string, limit int) ([]model.DeadLetter, error)
+	DeleteDeadLetters(ctx context.Context, ids []string) error
+}
+
+// Publisher публикует исходное сообщение обратно в тему, которую читает consumer
+type Publisher interface {
+	PublishLog(ctx context.Context, data []byte) error
+}
+
+// Replay переотправляет до limit записей dead letter этапа stage (пустой stage — всех этапов)
+// и удаляет из хранилища успешно опубликованные; возвращает число переотправленных записей
+// Сообщение, снова не прошедшее обработку, consumer повторно запишет в dead letter
+func Replay(ctx context.Context, store DeadLetterStore, pub Publisher, stage string, limit int) (int, error) {
+	letters, err := store.ListDeadLetters(ctx, stage, limit)
+	if err != nil {
+		return 0, err
+	}
+	replayed := make([]string, 0, len(letters))
+	var publishErr error
+	for _, l := range letters {
+		if err := pub.PublishLog(ctx, l.Payload); err != nil {
+			publishErr = fmt.Errorf("failed to replay dead letter %s: %w", l.ID, err)
+			break
+		}
+		replayed = append(replayed, l.ID)
+	}
+	// удаляем то, что уже опубликовано, даже если публикация прервалась
+	if err := store.DeleteDeadLetters(ctx, replayed); err != nil {
+		return len(replayed), err
+	}
+	return len(replayed), publishErr
+}
@@ -0,0 +1,80 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"HezzlTestTask/internal/model"
+)
+
+// mockDeadLetters реализует DeadLetterSink и DeadLetterStore в памяти
+type mockDeadLetters struct {
+	letters   []model.DeadLetter
+	insertErr error
+	deleted   []string
+	stage     string
+}
+
+func (m *mockDeadLetters) InsertDeadLetters(ctx context.Context, letters []model.DeadLetter) error {
+	if m.insertErr != nil {
+		return m.insertErr
+	}
+	m.letters = append(m.letters, letters...)
+	return nil
+}
+
+func (m *mockDeadLetters) ListDeadLetters(ctx context.Context, stage string, limit int) ([]model.DeadLetter, error) {
+	m.stage = stage
+	if len(m.letters) > limit {
+		return m.letters[:limit], nil
+	}
+	return m.letters, nil
+}
+
+func (m *mockDeadLetters) DeleteDeadLetters(ctx context.Context, ids []string) error {
+	m.deleted = append(m.deleted, ids...)
+	return nil
+}
+
+// mockPublisher запоминает опубликованные сообщения и может вернуть ошибку после failAfter публикаций
+type mockPublisher struct {
+	published [][]byte
+	failAfter int
+}
+
+func (m *mockPublisher) PublishLog(_ context.Context, data []byte) error {
+	if m.failAfter > 0 && len(m.published) == m.failAfter {
+		return errors.New("nats down")
+	}
+	m.published = append(m.published, data)
+	return nil
+}
+
+func TestReplay(t *testing.T) {
+	// тестируем, что записи переотправляются как есть и удаляются из dead letter
+	store := &mockDeadLetters{letters: []model.DeadLetter{
+		{ID: "a", Stage: model.DeadLetterDecode, Payload: []byte("raw-1")},
+		{ID: "b", Stage: model.DeadLetterDecode, Payload: []byte("raw-2")},
+		{ID: "c", Stage: model.DeadLetterDecode, Payload: []byte("raw-3")},
+	}}
+	pub := &mockPublisher{}
+	n, err := Replay(context.Background(), store, pub, model.DeadLetterDecode, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, model.DeadLetterDecode, store.stage)
+	require.Equal(t, [][]byte{[]byte("raw-1"), []byte("raw-2")}, pub.published)
+	require.Equal(t, []string{"a", "b"}, store.deleted)
+}
+
+func TestReplay_PublishError(t *testing.T) {
+	// тестируем, что при ошибке публикации удаляются только уже отправленные записи
+	store := &mockDeadLetters{letters: []model.DeadLetter{{ID: "a"}, {ID: "b"}}}
+	pub := &mockPublisher{failAfter: 1}
+	n, err := Replay(context.Background(), store, pub, "", 10)
+	require.Error(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, []string{"a"}, store.deleted)
+}
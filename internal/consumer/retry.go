@@ -0,0 +1,187 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"HezzlTestTask/internal/model"
+)
+
+// ErrRetryStopped возвращается для пакетов, которые не успели записаться до остановки RetryRepo
+var ErrRetryStopped = errors.New("retry queue stopped")
+
+// retryJob — пакет событий в очереди на запись; result получает итог всех попыток
+type retryJob struct {
+	ctx    context.Context
+	events []model.Event
+	result chan error
+}
+
+// RetryRepo оборачивает Repo повторными попытками записи пакета с экспоненциальной паузой и джиттером
+// Пакеты записываются по одному через ограниченную очередь: пока ClickHouse недоступен и очередь заполнена,
+// BatchInsertLogs блокируется, и HandleMessage перестаёт принимать новые сообщения (backpressure),
+// а сообщения остаются в JetStream. Вызывающий получает результат только после записи или исчерпания попыток,
+// поэтому подтверждение сообщений по-прежнему происходит после фиксации пакета
+type RetryRepo struct {
+	repo        Repo
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	timeout     time.Duration
+	queue       chan retryJob
+	stopped     chan struct{}
+	// jitter возвращает фактическую паузу для расчётной задержки; подменяется в тестах
+	jitter func(d time.Duration) time.Duration
+}
+
+// NewRetryRepo создаёт RetryRepo: maxAttempts — число попыток записи одного пакета,
+// backoff — пауза после первой неудачи, удваивается до maxBackoff, timeout — ограничение одной попытки
+// (0 — без ограничения), queueSize — число пакетов в очереди
+// Очередь обрабатывается методом Run, который должен работать, пока используется репозиторий
+func NewRetryRepo(repo Repo, maxAttempts int, backoff, maxBackoff, timeout time.Duration, queueSize int) *RetryRepo {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &RetryRepo{
+		repo:        repo,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		maxBackoff:  maxBackoff,
+		timeout:     timeout,
+		queue:       make(chan retryJob, queueSize),
+		stopped:     make(chan struct{}),
+		jitter:      equalJitter,
+	}
+}
+
+// BatchInsertLogs ставит пакет в очередь и ждёт результата записи
+// Если очередь заполнена, вызов блокируется до освобождения места или отмены ctx
+func (r *RetryRepo) BatchInsertLogs(ctx context.Context, events []model.Event) error {
+	job := retryJob{ctx: ctx, events: events, result: make(chan error, 1)}
+	select {
+	case r.queue <- job:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.stopped:
+		return ErrRetryStopped
+	}
+	select {
+	case err := <-job.result:
+		return err
+	case <-r.stopped:
+		// пакет мог быть обработан последним перед остановкой
+		select {
+		case err := <-job.result:
+			return err
+		default:
+			return ErrRetryStopped
+		}
+	}
+}
+
+// Run записывает пакеты из очереди до отмены ctx; оставшиеся в очереди пакеты получают ErrRetryStopped
+func (r *RetryRepo) Run(ctx context.Context) {
+	defer func() {
+		close(r.stopped)
+		for {
+			select {
+			case job := <-r.queue:
+				job.result <- ErrRetryStopped
+			default:
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-r.queue:
+			job.result <- r.insert(ctx, job)
+		}
+	}
+}
+
+// MaxDelay возвращает наибольшее суммарное время пауз между попытками записи одного пакета
+func (r *RetryRepo) MaxDelay() time.Duration {
+	var total time.Duration
+	delay := r.backoff
+	for attempt := 1; attempt < r.maxAttempts; attempt++ {
+		total += delay
+		delay = r.next(delay)
+	}
+	return total
+}
+
+// MaxDuration возвращает наибольшее время записи одного пакета: все попытки с таймаутом и паузы между ними
+// При timeout = 0 длительность попыток не ограничена и не учитывается
+func (r *RetryRepo) MaxDuration() time.Duration {
+	return r.MaxDelay() + time.Duration(r.maxAttempts)*r.timeout
+}
+
+// insert выполняет до maxAttempts попыток записи пакета, каждая ограничена timeout;
+// ожидание прерывается отменой контекста вызывающего или остановкой Run
+func (r *RetryRepo) insert(ctx context.Context, job retryJob) error {
+	delay := r.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if ctxErr := job.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		start := time.Now()
+		err = r.attempt(job)
+		if err == nil {
+			insertDuration.WithLabelValues("ok").Observe(time.Since(start).Seconds())
+			return nil
+		}
+		insertDuration.WithLabelValues("error").Observe(time.Since(start).Seconds())
+		if attempt >= r.maxAttempts {
+			return err
+		}
+		slog.WarnContext(job.ctx, "failed to insert events batch",
+			slog.Int("events", len(job.events)), slog.Int("attempt", attempt), slog.Int("max_attempts", r.maxAttempts), slog.Any("error", err))
+		timer := time.NewTimer(r.jitter(delay))
+		select {
+		case <-job.ctx.Done():
+			timer.Stop()
+			return job.ctx.Err()
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay = r.next(delay)
+	}
+}
+
+// attempt выполняет одну попытку записи пакета с ограничением timeout
+func (r *RetryRepo) attempt(job retryJob) error {
+	ctx := job.ctx
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	return r.repo.BatchInsertLogs(ctx, job.events)
+}
+
+// next удваивает задержку, не превышая maxBackoff
+func (r *RetryRepo) next(delay time.Duration) time.Duration {
+	delay *= 2
+	if r.maxBackoff > 0 && delay > r.maxBackoff {
+		delay = r.maxBackoff
+	}
+	return delay
+}
+
+// equalJitter выбирает паузу случайно в диапазоне [d/2, d], чтобы реплики не повторяли запись одновременно
+func equalJitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(d-half+1)
+}
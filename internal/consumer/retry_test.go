@@ -0,0 +1,183 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"HezzlTestTask/internal/model"
+)
+
+// flakyRepo возвращает ошибки из errs по очереди, затем записывает успешно
+// block, если задан, удерживает каждый вызов до закрытия канала
+type flakyRepo struct {
+	mu    sync.Mutex
+	errs  []error
+	calls int
+	block chan struct{}
+}
+
+func (f *flakyRepo) BatchInsertLogs(ctx context.Context, events []model.Event) error {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return err
+	}
+	return nil
+}
+
+func (f *flakyRepo) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// startRetry создаёт RetryRepo без случайных пауз и запускает обработку очереди до конца теста
+func startRetry(t *testing.T, repo Repo, maxAttempts, queueSize int) *RetryRepo {
+	r := NewRetryRepo(repo, maxAttempts, time.Millisecond, 4*time.Millisecond, 0, queueSize)
+	r.jitter = func(d time.Duration) time.Duration { return d }
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return r
+}
+
+func TestRetryRepo_RetriesTransientError(t *testing.T) {
+	// тестируем, что временные ошибки ClickHouse преодолеваются повторными попытками
+	ex := errors.New("connection reset")
+	repo := &flakyRepo{errs: []error{ex, ex}}
+	r := startRetry(t, repo, 3, 1)
+	require.NoError(t, r.BatchInsertLogs(context.Background(), []model.Event{{ID: "e-1"}}))
+	require.Equal(t, 3, repo.callCount())
+}
+
+func TestRetryRepo_MaxAttempts(t *testing.T) {
+	// тестируем, что после maxAttempts неудач возвращается последняя ошибка
+	first, last := errors.New("first"), errors.New("last")
+	repo := &flakyRepo{errs: []error{first, last, errors.New("unused")}}
+	r := startRetry(t, repo, 2, 1)
+	require.ErrorIs(t, r.BatchInsertLogs(context.Background(), []model.Event{{ID: "e-1"}}), last)
+	require.Equal(t, 2, repo.callCount())
+}
+
+func TestRetryRepo_CallerCancel(t *testing.T) {
+	// тестируем, что отмена контекста вызывающего прерывает ожидание между попытками
+	repo := &flakyRepo{errs: []error{errors.New("down"), errors.New("down")}}
+	r := NewRetryRepo(repo, 3, time.Hour, time.Hour, 0, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+	callCtx, callCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer callCancel()
+	require.ErrorIs(t, r.BatchInsertLogs(callCtx, nil), context.DeadlineExceeded)
+	require.Equal(t, 1, repo.callCount())
+}
+
+func TestRetryRepo_Backpressure(t *testing.T) {
+	// тестируем, что при заполненной очереди вызов блокируется, пока ClickHouse не ответит
+	repo := &flakyRepo{block: make(chan struct{})}
+	r := startRetry(t, repo, 1, 1)
+	results := make(chan error, 2)
+	// первый пакет забирается воркером и зависает в ClickHouse, второй занимает очередь
+	for i := 0; i < 2; i++ {
+		go func() { results <- r.BatchInsertLogs(context.Background(), nil) }()
+	}
+	require.Eventually(t, func() bool { return len(r.queue) == 1 }, time.Second, time.Millisecond)
+
+	// третьему пакету места нет — вызов ждёт до отмены контекста
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, r.BatchInsertLogs(ctx, nil), context.DeadlineExceeded)
+
+	// после восстановления ClickHouse оба пакета записываются
+	close(repo.block)
+	require.NoError(t, <-results)
+	require.NoError(t, <-results)
+	require.Equal(t, 2, repo.callCount())
+}
+
+func TestRetryRepo_Stopped(t *testing.T) {
+	// тестируем, что после остановки Run запись отклоняется, а не зависает
+	r := NewRetryRepo(&flakyRepo{}, 1, time.Millisecond, time.Millisecond, 0, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.Run(ctx)
+	require.ErrorIs(t, r.BatchInsertLogs(context.Background(), nil), ErrRetryStopped)
+}
+
+func TestRetryRepo_MaxDelay(t *testing.T) {
+	// тестируем расчёт суммарной паузы: 100ms + 200ms + 300ms (ограничение maxBackoff)
+	r := NewRetryRepo(&flakyRepo{}, 4, 100*time.Millisecond, 300*time.Millisecond, 0, 1)
+	require.Equal(t, 600*time.Millisecond, r.MaxDelay())
+	require.Equal(t, time.Duration(0), NewRetryRepo(&flakyRepo{}, 1, time.Second, time.Second, 0, 1).MaxDelay())
+}
+
+func TestRetryRepo_MaxDuration(t *testing.T) {
+	// тестируем, что время записи пакета включает таймауты всех попыток и паузы между ними
+	r := NewRetryRepo(&flakyRepo{}, 4, 100*time.Millisecond, 300*time.Millisecond, time.Second, 1)
+	require.Equal(t, 4600*time.Millisecond, r.MaxDuration())
+}
+
+// hangingRepo зависает до отмены контекста попытки
+type hangingRepo struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (h *hangingRepo) BatchInsertLogs(ctx context.Context, events []model.Event) error {
+	h.mu.Lock()
+	h.calls++
+	h.mu.Unlock()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRetryRepo_AttemptTimeout(t *testing.T) {
+	// тестируем, что зависшая попытка прерывается по таймауту и повторяется, а не блокирует очередь
+	repo := &hangingRepo{}
+	r := NewRetryRepo(repo, 2, time.Millisecond, time.Millisecond, 10*time.Millisecond, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+	require.ErrorIs(t, r.BatchInsertLogs(context.Background(), nil), context.DeadlineExceeded)
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	require.Equal(t, 2, repo.calls)
+}
+
+func TestEqualJitter(t *testing.T) {
+	// тестируем, что пауза с джиттером лежит в диапазоне [d/2, d]
+	for i := 0; i < 100; i++ {
+		d := equalJitter(100 * time.Millisecond)
+		require.GreaterOrEqual(t, d, 50*time.Millisecond)
+		require.LessOrEqual(t, d, 100*time.Millisecond)
+	}
+	require.Equal(t, time.Duration(0), equalJitter(0))
+}
+
+func TestConsumer_WithRetryRepo(t *testing.T) {
+	// тестируем, что сообщения подтверждаются после успешной повторной попытки, без nak
+	repo := &flakyRepo{errs: []error{errors.New("timeout")}}
+	cons := NewConsumer(startRetry(t, repo, 2, 1), 1, 0)
+	ack := &mockAck{}
+	require.NoError(t, cons.HandleMessage(context.Background(), eventData(t, model.Good{ID: 1, ProjectID: 1}), ack))
+	require.Equal(t, 1, ack.acked)
+	require.Equal(t, 0, ack.naked)
+}
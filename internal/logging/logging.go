@@ -0,0 +1,66 @@
+// Пакет logging настраивает структурированный JSON-лог (log/slog) сервисов
+// Записи, сделанные с контекстом (slog.InfoContext и т.п.), дополняются идентификатором запроса и трассировки
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"HezzlTestTask/internal/model"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ParseLevel разбирает уровень логирования (debug, info, warn, error); пустая строка означает info
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: %w", s, err)
+	}
+	return level, nil
+}
+
+// Setup делает JSON-логгер с уровнем level логгером по умолчанию для slog и стандартного пакета log
+// Каждая запись содержит имя сервиса service
+func Setup(w io.Writer, service, level string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(NewHandler(w, lvl)).With(slog.String("service", service)))
+	return nil
+}
+
+// NewHandler создаёт JSON-обработчик, добавляющий к записям request_id, trace_id и span_id из контекста
+func NewHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	return contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})}
+}
+
+// contextHandler дополняет записи атрибутами из контекста вызова
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle добавляет идентификаторы запроса и трассировки, если они есть в ctx
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := model.RequestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
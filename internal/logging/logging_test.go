@@ -0,0 +1,69 @@
+// Пакет logging содержит unit-тесты для JSON-логгера с атрибутами из контекста
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"HezzlTestTask/internal/model"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestParseLevel проверяет разбор уровней и уровень по умолчанию
+func TestParseLevel(t *testing.T) {
+	cases := map[string]slog.Level{"": slog.LevelInfo, "debug": slog.LevelDebug, "WARN": slog.LevelWarn, "error": slog.LevelError}
+	for in, want := range cases {
+		got, err := ParseLevel(in)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+// TestHandler_ContextAttrs проверяет request_id, trace_id и span_id в записи и фильтрацию по уровню
+func TestHandler_ContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, slog.LevelInfo)).With(slog.String("service", "app"))
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}})
+	ctx := trace.ContextWithSpanContext(model.ContextWithRequestID(context.Background(), "req-1"), sc)
+
+	logger.DebugContext(ctx, "skipped")
+	logger.InfoContext(ctx, "good created", slog.Int("good_id", 7))
+
+	var rec map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("expected single JSON record, got %q: %v", buf.String(), err)
+	}
+	want := map[string]interface{}{
+		"level":      "INFO",
+		"msg":        "good created",
+		"service":    "app",
+		"good_id":    float64(7),
+		"request_id": "req-1",
+		"trace_id":   sc.TraceID().String(),
+		"span_id":    sc.SpanID().String(),
+	}
+	for k, v := range want {
+		if rec[k] != v {
+			t.Errorf("expected %s=%v, got %v", k, v, rec[k])
+		}
+	}
+
+	// без контекста запроса атрибуты не добавляются
+	buf.Reset()
+	logger.Info("started")
+	rec = nil
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if _, ok := rec["request_id"]; ok {
+		t.Errorf("unexpected request_id without context: %v", rec)
+	}
+}
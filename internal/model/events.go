@@ -0,0 +1,148 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// EventVersion — текущая версия формата конверта события
+const EventVersion = 1
+
+// Типы событий изменения сущностей
+const (
+	EventGoodCreated       = "good.created"
+	EventGoodUpdated       = "good.updated"
+	EventGoodRemoved       = "good.removed"
+	EventGoodReprioritized = "good.reprioritized"
+	EventProjectCreated    = "project.created"
+	EventProjectUpdated    = "project.updated"
+	EventProjectRemoved    = "project.removed"
+)
+
+// Этапы обработки, на которых сообщение может попасть в dead letter
+const (
+	DeadLetterDecode = "decode" // сообщение не удалось разобрать или версия конверта не поддерживается
+	DeadLetterInsert = "insert" // ClickHouse отклонял пакет с событием на всех попытках доставки
+)
+
+// DeadLetter — сообщение, которое consumer не смог обработать, с причиной отказа
+// Payload хранит исходные байты сообщения для повторной отправки
+type DeadLetter struct {
+	ID         string    `json:"id"`
+	Stage      string    `json:"stage"`
+	Error      string    `json:"error"`
+	Payload    []byte    `json:"payload"`
+	Deliveries int       `json:"deliveries"`
+	FailedAt   time.Time `json:"failedAt"`
+}
+
+// NewDeadLetter создаёт запись dead letter с новым идентификатором и текущим временем
+func NewDeadLetter(stage string, payload []byte, deliveries int, cause error) DeadLetter {
+	return DeadLetter{
+		ID:         newEventID(),
+		Stage:      stage,
+		Error:      cause.Error(),
+		Payload:    payload,
+		Deliveries: deliveries,
+		FailedAt:   time.Now().UTC(),
+	}
+}
+
+// Event — версионированный конверт события изменения, публикуемый в NATS
+// EntityID — идентификатор изменённой сущности (товара или проекта)
+// Previous и Current содержат состояние до и после изменения:
+// - good.created/updated/removed — объект Good (Previous отсутствует для created)
+// - good.reprioritized — массив PriorityUpdate всех затронутых товаров
+// - project.* — объект Project
+type Event struct {
+	Version    int             `json:"version"`
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	ProjectID  int             `json:"projectId"`
+	EntityID   int             `json:"entityId"`
+	RequestID  string          `json:"requestId,omitempty"`
+	Actor      string          `json:"actor,omitempty"`
+	Previous   json.RawMessage `json:"previous,omitempty"`
+	Current    json.RawMessage `json:"current,omitempty"`
+}
+
+// NewEvent создаёт конверт события с новым идентификатором и текущим временем
+// RequestID и Actor берутся из контекста, если они в нём заданы; nil-состояния не сериализуются
+func NewEvent(ctx context.Context, typ string, projectID, entityID int, previous, current interface{}) (Event, error) {
+	e := Event{
+		Version:    EventVersion,
+		ID:         newEventID(),
+		Type:       typ,
+		OccurredAt: time.Now().UTC(),
+		ProjectID:  projectID,
+		EntityID:   entityID,
+		RequestID:  RequestIDFromContext(ctx),
+		Actor:      ActorFromContext(ctx),
+	}
+	var err error
+	if e.Previous, err = marshalState(previous); err != nil {
+		return Event{}, fmt.Errorf("failed to marshal previous state: %w", err)
+	}
+	if e.Current, err = marshalState(current); err != nil {
+		return Event{}, fmt.Errorf("failed to marshal current state: %w", err)
+	}
+	return e, nil
+}
+
+// marshalState сериализует состояние сущности, возвращая nil для отсутствующего состояния
+func marshalState(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// newEventID генерирует случайный идентификатор события в формате UUID v4
+func newEventID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// ключи контекста для метаданных события
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	actorKey
+)
+
+// ContextWithRequestID возвращает контекст с идентификатором запроса
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext возвращает идентификатор запроса из контекста или пустую строку
+func RequestIDFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(requestIDKey).(string)
+	return v
+}
+
+// ContextWithActor возвращает контекст с идентификатором инициатора изменения
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFromContext возвращает инициатора изменения из контекста или пустую строку
+func ActorFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(actorKey).(string)
+	return v
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestNewEvent(t *testing.T) {
+	// готовим контекст с метаданными запроса
+	ctx := ContextWithActor(ContextWithRequestID(context.Background(), "req-1"), "user-7")
+	prev := &Good{ID: 3, ProjectID: 1, Name: "old"}
+	curr := &Good{ID: 3, ProjectID: 1, Name: "new"}
+	e, err := NewEvent(ctx, EventGoodUpdated, 1, 3, prev, curr)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	// проверяем заполнение служебных полей конверта
+	if e.Version != EventVersion || e.Type != EventGoodUpdated || e.ProjectID != 1 || e.EntityID != 3 {
+		t.Errorf("некорректные поля конверта: %+v", e)
+	}
+	if e.RequestID != "req-1" || e.Actor != "user-7" {
+		t.Errorf("метаданные контекста не перенесены: %+v", e)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(e.ID) {
+		t.Errorf("идентификатор события не в формате UUID v4: %s", e.ID)
+	}
+	if e.OccurredAt.IsZero() {
+		t.Error("время события не заполнено")
+	}
+	// проверяем состояние до и после изменения
+	var gotPrev, gotCurr Good
+	_ = json.Unmarshal(e.Previous, &gotPrev)
+	_ = json.Unmarshal(e.Current, &gotCurr)
+	if gotPrev.Name != "old" || gotCurr.Name != "new" {
+		t.Errorf("состояния сериализованы некорректно: %s, %s", e.Previous, e.Current)
+	}
+}
+
+func TestNewEvent_NilState(t *testing.T) {
+	// для created предыдущего состояния нет — поле previous не сериализуется
+	var prev *Good
+	e, err := NewEvent(context.Background(), EventGoodCreated, 1, 2, prev, &Good{ID: 2})
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if e.Previous != nil {
+		t.Errorf("ожидалось пустое previous, получили %s", e.Previous)
+	}
+	data, _ := json.Marshal(e)
+	var raw map[string]interface{}
+	_ = json.Unmarshal(data, &raw)
+	if _, ok := raw["previous"]; ok {
+		t.Errorf("поле previous не должно попадать в JSON: %s", data)
+	}
+	if _, ok := raw["requestId"]; ok {
+		t.Errorf("пустой requestId не должен попадать в JSON: %s", data)
+	}
+}
+
+func TestNewDeadLetter(t *testing.T) {
+	// запись dead letter сохраняет исходные байты и текст ошибки
+	payload := []byte("not json")
+	dl := NewDeadLetter(DeadLetterDecode, payload, 2, errors.New("invalid character"))
+	if dl.ID == "" || dl.Stage != DeadLetterDecode || dl.Error != "invalid character" || dl.Deliveries != 2 {
+		t.Errorf("некорректные поля dead letter: %+v", dl)
+	}
+	if string(dl.Payload) != "not json" || dl.FailedAt.IsZero() {
+		t.Errorf("некорректные payload или время: %+v", dl)
+	}
+}
@@ -4,12 +4,14 @@ import "time"
 
 // Project представляет проект (таблица projects)
 type Project struct {
-	ID        int       `db:"id"`
-	Name      string    `db:"name"`
-	CreatedAt time.Time `db:"created_at"`
+	ID        int       `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	Removed   bool      `db:"removed" json:"removed"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
 }
 
 // Good представляет сущность товара (таблица goods)
+// Version увеличивается при каждом изменении товара и служит для оптимистической блокировки (ETag / If-Match)
 type Good struct {
 	ID          int       `db:"id" json:"id"`
 	ProjectID   int       `db:"project_id" json:"projectId"`
@@ -18,6 +20,7 @@ type Good struct {
 	Priority    int       `db:"priority" json:"priority"`
 	Removed     bool      `db:"removed" json:"removed"`
 	CreatedAt   time.Time `db:"created_at" json:"createdAt"`
+	Version     int       `db:"version" json:"version"`
 }
 
 // PriorityUpdate представляет изменение приоритета товара
@@ -26,3 +29,109 @@ type PriorityUpdate struct {
 	ID       int `db:"id" json:"id"`
 	Priority int `db:"priority" json:"priority"`
 }
+
+// GoodInput — данные нового товара в пакетном создании
+type GoodInput struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+}
+
+// GoodUpdate — изменение товара в пакетном обновлении; Version — ожидаемая версия товара (0 — без проверки)
+type GoodUpdate struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Version     int     `json:"version,omitempty"`
+}
+
+// GoodRemoval — товар в пакетном удалении; Version — ожидаемая версия товара (0 — без проверки)
+type GoodRemoval struct {
+	ID      int `json:"id"`
+	Version int `json:"version,omitempty"`
+}
+
+// BulkResult — результат элемента пакетной операции: изменённый товар или ошибка элемента
+// Good и Err пусты у элемента, который не применён, потому что атомарная операция отменена из-за другого элемента
+type BulkResult struct {
+	Good *Good
+	Err  error
+}
+
+// OutboxMessage представляет неотправленное событие из таблицы outbox
+// Payload — сериализованный конверт Event, Attempts — число неудачных попыток публикации
+// TraceContext — заголовки трассировки запроса, изменившего данные (пусто, если запрос не трассировался)
+type OutboxMessage struct {
+	ID           int64             `db:"id"`
+	EventID      string            `db:"event_id"`
+	EventType    string            `db:"event_type"`
+	Payload      []byte            `db:"payload"`
+	TraceContext map[string]string `db:"trace_context"`
+	Attempts     int               `db:"attempts"`
+	CreatedAt    time.Time         `db:"created_at"`
+}
+
+// Режимы учёта удалённых товаров в GoodsFilter.Removed
+const (
+	RemovedInclude = "include" // возвращать все товары (по умолчанию)
+	RemovedExclude = "exclude" // только не удалённые товары
+	RemovedOnly    = "only"    // только удалённые товары
+)
+
+// Поля сортировки в GoodsFilter.Sort
+const (
+	SortByID        = "id"
+	SortByPriority  = "priority"
+	SortByCreatedAt = "createdAt"
+	SortByName      = "name"
+)
+
+// GoodsCursor задаёт позицию keyset-пагинации: последний товар предыдущей страницы
+// Используется только при сортировке по id или priority (порядок (priority, id) или (id))
+type GoodsCursor struct {
+	Priority int `json:"p"`
+	ID       int `json:"i"`
+}
+
+// GoodsFilter задаёт условия выборки списка товаров
+// ProjectID=0 означает выборку по всем проектам, Name — поиск по подстроке без учёта регистра,
+// CreatedFrom/CreatedTo — полуинтервал [from, to) по created_at
+// After включает keyset-пагинацию (Offset игнорируется), SkipCounts отключает подсчёт total/removed
+type GoodsFilter struct {
+	ProjectID   int          `json:"projectId"`
+	Removed     string       `json:"removed"`
+	Name        string       `json:"name"`
+	CreatedFrom *time.Time   `json:"createdFrom,omitempty"`
+	CreatedTo   *time.Time   `json:"createdTo,omitempty"`
+	Sort        string       `json:"sort"`
+	Desc        bool         `json:"desc"`
+	Limit       int          `json:"limit"`
+	Offset      int          `json:"offset"`
+	After       *GoodsCursor `json:"after,omitempty"`
+	SkipCounts  bool         `json:"skipCounts"`
+}
+
+// Типы сущностей в HistoryFilter.EntityType
+const (
+	HistoryGood    = "good"
+	HistoryProject = "project"
+)
+
+// HistoryCursor задаёт позицию keyset-пагинации истории: последнее событие предыдущей страницы
+type HistoryCursor struct {
+	OccurredAt time.Time `json:"t"`
+	EventID    string    `json:"i"`
+}
+
+// HistoryFilter задаёт условия выборки истории изменений из events_log
+// EntityType ограничивает события типом сущности (пустая строка — все события проекта, включая товары),
+// EntityID=0 — все сущности, From/To — полуинтервал [from, to) по времени события
+// События упорядочены по времени и идентификатору; After продолжает выборку после указанного события
+type HistoryFilter struct {
+	ProjectID  int            `json:"projectId"`
+	EntityType string         `json:"entityType"`
+	EntityID   int            `json:"entityId"`
+	From       *time.Time     `json:"from,omitempty"`
+	To         *time.Time     `json:"to,omitempty"`
+	Limit      int            `json:"limit"`
+	After      *HistoryCursor `json:"after,omitempty"`
+}
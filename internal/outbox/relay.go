@@ -0,0 +1,168 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"HezzlTestTask/internal/model"
+	"HezzlTestTask/pkg/tracing"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Store описывает хранилище outbox (Postgres), из которого relay забирает неотправленные события
+// Все комментарии на русском языке
+// ClaimPending закрепляет за вызывающим relay до limit неотправленных событий на время lease:
+// другие реплики не выбирают их, пока аренда не истекла
+// MarkFailed возвращает true, если событие исчерпало maxAttempts попыток и больше не будет выбираться
+type Store interface {
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]model.OutboxMessage, error)
+	MarkSent(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, reason string, maxAttempts int) (bool, error)
+	DeleteSent(ctx context.Context, olderThan time.Duration, limit int) (int64, error)
+}
+
+// Publisher описывает публикацию сообщения в брокер (реализуется logger.NATSClient)
+// Контекст трассировки из ctx передаётся получателям вместе с сообщением
+type Publisher interface {
+	PublishLog(ctx context.Context, data []byte) error
+}
+
+// Config задаёт параметры Relay
+type Config struct {
+	BatchSize int           // размер выборки из outbox
+	Retries   int           // число повторных попыток публикации одного события за проход
+	Backoff   time.Duration // начальная пауза между попытками, удваивается с каждой попыткой
+	// Lease — на сколько выборка закрепляется за relay; должна превышать время публикации пачки,
+	// иначе другая реплика выберет те же события повторно. Неудачное событие повторяется после её истечения
+	Lease time.Duration
+	// MaxAttempts — после стольких неудачных проходов событие отмечается неотправляемым (failed_at)
+	// и больше не задерживает следующие; 0 — без ограничения
+	MaxAttempts int
+	// Retention — сколько хранятся отправленные события; 0 — не удаляются
+	Retention time.Duration
+}
+
+// cleanupInterval задаёт, как часто удаляются отправленные события старше Config.Retention
+const cleanupInterval = time.Minute
+
+// cleanupBatch ограничивает число событий, удаляемых одним запросом
+const cleanupBatch = 1000
+
+// Relay переносит события из outbox в NATS с гарантией at-least-once:
+// событие отмечается отправленным только после успешной публикации,
+// поэтому при сбое между публикацией и MarkSent оно будет отправлено повторно
+// (получатели могут отбрасывать дубликаты по Event.ID)
+// Relay запускается на каждой реплике; выборки закрепляются за репликой арендой, поэтому реплики
+// публикуют разные события, а порядок сохраняется в пределах выборки
+type Relay struct {
+	store     Store
+	publisher Publisher
+	cfg       Config
+}
+
+// NewRelay создаёт Relay с параметрами cfg
+func NewRelay(store Store, publisher Publisher, cfg Config) *Relay {
+	return &Relay{store: store, publisher: publisher, cfg: cfg}
+}
+
+// Run периодически публикует накопленные события и удаляет старые отправленные до отмены ctx
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var cleanup <-chan time.Time
+	if r.cfg.Retention > 0 {
+		cleanupTicker := time.NewTicker(cleanupInterval)
+		defer cleanupTicker.Stop()
+		cleanup = cleanupTicker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-cleanup:
+			if _, err := r.Cleanup(ctx); err != nil {
+				slog.ErrorContext(ctx, "outbox cleanup failed", slog.Any("error", err))
+			}
+		case <-ticker.C:
+			// выбираем outbox до конца, пока возвращаются полные пачки
+			for {
+				n, err := r.RunOnce(ctx)
+				if err != nil {
+					slog.ErrorContext(ctx, "outbox relay failed", slog.Any("error", err))
+					break
+				}
+				if n < r.cfg.BatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// RunOnce публикует одну пачку неотправленных событий и возвращает число отправленных
+// События публикуются по порядку; при исчерпании попыток пачка прерывается,
+// чтобы следующее событие той же сущности не обогнало неотправленное. Событие, исчерпавшее
+// MaxAttempts проходов, отмечается неотправляемым и пропускается, чтобы не блокировать outbox
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	messages, err := r.store.ClaimPending(ctx, r.cfg.BatchSize, r.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, m := range messages {
+		if err := r.publish(ctx, m); err != nil {
+			dead, markErr := r.store.MarkFailed(ctx, m.ID, err.Error(), r.cfg.MaxAttempts)
+			if markErr != nil {
+				slog.ErrorContext(ctx, "failed to mark outbox message failed", slog.Int64("outbox_id", m.ID), slog.Any("error", markErr))
+			}
+			if dead {
+				slog.ErrorContext(ctx, "outbox message exceeded publish attempts, skipped",
+					slog.Int64("outbox_id", m.ID), slog.String("event_id", m.EventID), slog.Any("error", err))
+				continue
+			}
+			return sent, fmt.Errorf("failed to publish outbox message %d: %w", m.ID, err)
+		}
+		if err := r.store.MarkSent(ctx, m.ID); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// Cleanup удаляет отправленные события старше Retention порциями по cleanupBatch и возвращает число удалённых
+func (r *Relay) Cleanup(ctx context.Context) (int64, error) {
+	var total int64
+	for {
+		n, err := r.store.DeleteSent(ctx, r.cfg.Retention, cleanupBatch)
+		total += n
+		if err != nil || n < cleanupBatch {
+			return total, err
+		}
+	}
+}
+
+// publish отправляет сообщение с повторами и экспоненциально растущей паузой между попытками
+// Публикация продолжает трассировку запроса, записавшего событие в outbox
+func (r *Relay) publish(ctx context.Context, m model.OutboxMessage) error {
+	ctx = tracing.Extract(ctx, propagation.MapCarrier(m.TraceContext))
+	delay := r.cfg.Backoff
+	var err error
+	for attempt := 0; attempt <= r.cfg.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		if err = r.publisher.PublishLog(ctx, m.Payload); err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,247 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"HezzlTestTask/internal/model"
+)
+
+// mockStore реализует Store в памяти и запоминает отметки об отправке и ошибках
+// Аренда не моделируется: выборка возвращает все неотправленные события, кроме отмеченных неотправляемыми
+type mockStore struct {
+	pending  []model.OutboxMessage
+	fetchErr error
+	sent     []int64
+	failed   map[int64]string
+	attempts map[int64]int
+	dead     []int64
+	deletes  []int64 // число удалённых событий, возвращаемое DeleteSent по очереди
+	lease    time.Duration
+}
+
+func (m *mockStore) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]model.OutboxMessage, error) {
+	if m.fetchErr != nil {
+		return nil, m.fetchErr
+	}
+	m.lease = lease
+	var out []model.OutboxMessage
+	for _, msg := range m.pending {
+		if m.isSent(msg.ID) || m.isDead(msg.ID) {
+			continue
+		}
+		out = append(out, msg)
+		if len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (m *mockStore) MarkSent(ctx context.Context, id int64) error {
+	m.sent = append(m.sent, id)
+	return nil
+}
+
+func (m *mockStore) MarkFailed(ctx context.Context, id int64, reason string, maxAttempts int) (bool, error) {
+	if m.failed == nil {
+		m.failed, m.attempts = map[int64]string{}, map[int64]int{}
+	}
+	m.failed[id] = reason
+	m.attempts[id]++
+	if maxAttempts > 0 && m.attempts[id] >= maxAttempts {
+		m.dead = append(m.dead, id)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (m *mockStore) DeleteSent(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	if len(m.deletes) == 0 {
+		return 0, nil
+	}
+	n := m.deletes[0]
+	m.deletes = m.deletes[1:]
+	return n, nil
+}
+
+func (m *mockStore) isDead(id int64) bool {
+	for _, d := range m.dead {
+		if d == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mockStore) isSent(id int64) bool {
+	for _, s := range m.sent {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
+
+// mockPublisher возвращает ошибки из errs по очереди, затем публикует успешно
+type mockPublisher struct {
+	errs      []error
+	published [][]byte
+	spans     []trace.SpanContext // контекст трассировки, переданный с каждой попыткой публикации
+	calls     int
+}
+
+func (m *mockPublisher) PublishLog(ctx context.Context, data []byte) error {
+	m.calls++
+	m.spans = append(m.spans, trace.SpanContextFromContext(ctx))
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		return err
+	}
+	m.published = append(m.published, data)
+	return nil
+}
+
+func messages(ids ...int64) []model.OutboxMessage {
+	out := make([]model.OutboxMessage, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, model.OutboxMessage{ID: id, Payload: []byte{byte('0' + id)}})
+	}
+	return out
+}
+
+func TestRunOnce_PublishesInOrder(t *testing.T) {
+	// тестируем, что события публикуются по порядку и отмечаются отправленными
+	store := &mockStore{pending: messages(1, 2, 3)}
+	pub := &mockPublisher{}
+	relay := NewRelay(store, pub, Config{BatchSize: 2, Retries: 0, Backoff: time.Millisecond})
+
+	n, err := relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []int64{1, 2}, store.sent)
+	require.Equal(t, [][]byte{[]byte("1"), []byte("2")}, pub.published)
+
+	// следующая пачка забирает оставшееся событие
+	n, err = relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, []int64{1, 2, 3}, store.sent)
+}
+
+func TestRunOnce_RetriesThenSucceeds(t *testing.T) {
+	// тестируем, что временная ошибка NATS преодолевается повторными попытками
+	store := &mockStore{pending: messages(1)}
+	pub := &mockPublisher{errs: []error{errors.New("nats down"), errors.New("nats down")}}
+	relay := NewRelay(store, pub, Config{BatchSize: 10, Retries: 2, Backoff: time.Millisecond})
+
+	n, err := relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, 3, pub.calls)
+	require.Empty(t, store.failed)
+}
+
+func TestRunOnce_StopsOnExhaustedRetries(t *testing.T) {
+	// тестируем, что после исчерпания попыток событие остаётся неотправленным, а пачка прерывается
+	ex := errors.New("nats down")
+	store := &mockStore{pending: messages(1, 2)}
+	pub := &mockPublisher{errs: []error{ex, ex}}
+	relay := NewRelay(store, pub, Config{BatchSize: 10, Retries: 1, Backoff: time.Millisecond})
+
+	n, err := relay.RunOnce(context.Background())
+	require.ErrorIs(t, err, ex)
+	require.Equal(t, 0, n)
+	require.Empty(t, store.sent)
+	require.Equal(t, "nats down", store.failed[1])
+	require.Equal(t, 2, pub.calls)
+
+	// после восстановления NATS событие отправляется повторно (at-least-once)
+	n, err = relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []int64{1, 2}, store.sent)
+}
+
+func TestRunOnce_SkipsDeadMessage(t *testing.T) {
+	// тестируем, что событие, исчерпавшее MaxAttempts проходов, пропускается и не блокирует следующие
+	ex := errors.New("nats: maximum payload exceeded")
+	store := &mockStore{pending: messages(1, 2)}
+	pub := &mockPublisher{errs: []error{ex, ex}}
+	relay := NewRelay(store, pub, Config{BatchSize: 10, Lease: time.Minute, MaxAttempts: 2})
+
+	// первый проход: попытка не последняя, пачка прерывается
+	n, err := relay.RunOnce(context.Background())
+	require.ErrorIs(t, err, ex)
+	require.Equal(t, 0, n)
+	require.Equal(t, time.Minute, store.lease)
+	// второй проход: событие отмечается неотправляемым, следующее публикуется
+	n, err = relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, []int64{1}, store.dead)
+	require.Equal(t, []int64{2}, store.sent)
+	require.Equal(t, [][]byte{[]byte("2")}, pub.published)
+}
+
+func TestCleanup_DeletesInBatches(t *testing.T) {
+	// тестируем, что отправленные события удаляются порциями, пока порция заполнена
+	store := &mockStore{deletes: []int64{cleanupBatch, cleanupBatch, 5, 100}}
+	relay := NewRelay(store, &mockPublisher{}, Config{Retention: time.Hour})
+	n, err := relay.Cleanup(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(2*cleanupBatch+5), n)
+	require.Equal(t, []int64{100}, store.deletes)
+}
+
+func TestRunOnce_FetchError(t *testing.T) {
+	// тестируем прокидку ошибки выборки из outbox
+	ex := errors.New("db down")
+	relay := NewRelay(&mockStore{fetchErr: ex}, &mockPublisher{}, Config{BatchSize: 10, Retries: 0, Backoff: time.Millisecond})
+	_, err := relay.RunOnce(context.Background())
+	require.ErrorIs(t, err, ex)
+}
+
+func TestRun_StopsOnCancel(t *testing.T) {
+	// тестируем, что Run публикует события по таймеру и завершается при отмене контекста
+	store := &mockStore{pending: messages(1, 2, 3)}
+	pub := &mockPublisher{}
+	relay := NewRelay(store, pub, Config{BatchSize: 2, Retries: 0, Backoff: time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx, time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("relay did not stop after context cancel")
+	}
+	require.Len(t, pub.published, 3)
+}
+
+func TestRunOnce_ContinuesStoredTrace(t *testing.T) {
+	// тестируем, что публикация продолжает трассировку запроса, сохранённую в outbox
+	msg := model.OutboxMessage{ID: 1, Payload: []byte("1"), TraceContext: map[string]string{
+		"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	}}
+	store := &mockStore{pending: []model.OutboxMessage{msg, {ID: 2, Payload: []byte("2")}}}
+	pub := &mockPublisher{}
+	relay := NewRelay(store, pub, Config{BatchSize: 10, Retries: 0, Backoff: time.Millisecond})
+
+	_, err := relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, pub.spans, 2)
+	require.Equal(t, "0af7651916cd43dd8448eb211c80319c", pub.spans[0].TraceID().String())
+	require.Equal(t, "b7ad6b7169203331", pub.spans[0].SpanID().String())
+	// событие без контекста трассировки публикуется без родительского спана
+	require.False(t, pub.spans[1].IsValid())
+}
@@ -0,0 +1,321 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"HezzlTestTask/internal/apperr"
+	"HezzlTestTask/internal/model"
+
+	"github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// maxRowsPerInsert ограничивает число строк одного многострочного INSERT,
+// чтобы число параметров запроса не превышало предел протокола Postgres (65535)
+const maxRowsPerInsert = 1000
+
+// ErrDuplicateItem возвращается для повторного упоминания товара в одной пакетной операции
+var ErrDuplicateItem = apperr.Invalid("id", "must be unique within the batch")
+
+// valuesPlaceholders возвращает кортежи VALUES для rows строк по cols параметров: ($1, $2), ($3, $4)
+func valuesPlaceholders(rows, cols int) string {
+	var b strings.Builder
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for j := 0; j < cols; j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(i*cols + j + 1))
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// CreateGoods добавляет товары проекта в одной транзакции многострочными INSERT и записывает событие good.created
+// для каждого товара; товары получают приоритеты max(priority)+1, max(priority)+2, ... в порядке items
+// Строка проекта блокируется до конца транзакции: FOR UPDATE конфликтует с блокировкой FOR SHARE,
+// которую берёт одиночная вставка, поэтому приоритеты пакета идут подряд; архивированный проект — ErrProjectArchived
+func (r *GoodRepository) CreateGoods(ctx context.Context, projectID int, items []model.GoodInput) (_ []model.Good, err error) {
+	ctx, span := startSpan(ctx, "GoodRepository.CreateGoods", semconv.DBSystemPostgreSQL)
+	defer func() { endSpan(span, err) }()
+	for _, item := range items {
+		if item.Name == "" {
+			return nil, ErrEmptyName
+		}
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := lockProject(ctx, tx, projectID, lockUpdate); err != nil {
+		return nil, err
+	}
+	var maxPriority int
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(priority), 0) FROM goods WHERE project_id=$1`, projectID).Scan(&maxPriority)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select max priority: %w", err)
+	}
+	goods := make([]model.Good, 0, len(items))
+	for start := 0; start < len(items); start += maxRowsPerInsert {
+		chunk := items[start:min(len(items), start+maxRowsPerInsert)]
+		inserted, err := insertGoods(ctx, tx, projectID, maxPriority+start+1, chunk)
+		if err != nil {
+			return nil, err
+		}
+		goods = append(goods, inserted...)
+	}
+	events := make([]model.Event, len(goods))
+	for i := range goods {
+		events[i], err = model.NewEvent(ctx, model.EventGoodCreated, projectID, goods[i].ID, nil, goods[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := insertOutboxEvents(ctx, tx, events); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return goods, nil
+}
+
+// insertGoods вставляет товары проекта одним многострочным INSERT с приоритетами firstPriority, firstPriority+1, ...
+// и возвращает их в порядке items
+func insertGoods(ctx context.Context, tx *sql.Tx, projectID, firstPriority int, items []model.GoodInput) ([]model.Good, error) {
+	// приоритет задаётся явно: триггер set_goods_priority вычисляет его только для нулевого значения
+	args := make([]interface{}, 0, 4*len(items))
+	for i, item := range items {
+		args = append(args, projectID, item.Name, item.Description, firstPriority+i)
+	}
+	query := `INSERT INTO goods(project_id, name, description, priority) VALUES` + valuesPlaceholders(len(items), 4) +
+		` RETURNING id, priority, removed, created_at, version`
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert goods: %w", err)
+	}
+	defer rows.Close()
+	// порядок строк RETURNING не гарантирован: товар сопоставляется с элементом по назначенному приоритету
+	goods := make([]model.Good, len(items))
+	for rows.Next() {
+		var g model.Good
+		if err := rows.Scan(&g.ID, &g.Priority, &g.Removed, &g.CreatedAt, &g.Version); err != nil {
+			return nil, fmt.Errorf("failed to scan inserted good: %w", err)
+		}
+		i := g.Priority - firstPriority
+		if i < 0 || i >= len(items) {
+			return nil, fmt.Errorf("failed to insert goods: unexpected priority %d", g.Priority)
+		}
+		g.ProjectID = projectID
+		g.Name = items[i].Name
+		g.Description = items[i].Description
+		goods[i] = g
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate inserted goods: %w", err)
+	}
+	return goods, nil
+}
+
+// UpdateGoods обновляет name и description товаров проекта в одной транзакции, увеличивает их версии
+// и записывает событие good.updated с состоянием до и после для каждого изменённого товара
+// Результаты соответствуют items по индексу; ошибки элемента — ErrNotFound, ErrVersionMismatch, ErrEmptyName
+// и ErrDuplicateItem. В атомарном режиме (atomic) при ошибке хотя бы одного элемента ничего не изменяется,
+// иначе изменяются все элементы без ошибок; товары архивированного проекта не изменяются (ErrProjectArchived)
+func (r *GoodRepository) UpdateGoods(ctx context.Context, projectID int, items []model.GoodUpdate, atomic bool) (_ []model.BulkResult, err error) {
+	ctx, span := startSpan(ctx, "GoodRepository.UpdateGoods", semconv.DBSystemPostgreSQL)
+	defer func() { endSpan(span, err) }()
+	if len(items) == 0 {
+		return nil, nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := lockProject(ctx, tx, projectID, lockShare); err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	current, err := lockGoods(ctx, tx, projectID, ids)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]model.BulkResult, len(items))
+	seen := make(map[int]bool, len(items))
+	failed := false
+	for i, item := range items {
+		results[i].Err = checkBulkItem(seen, current, item.ID, item.Version)
+		if results[i].Err == nil && item.Name == "" {
+			results[i].Err = ErrEmptyName
+		}
+		failed = failed || results[i].Err != nil
+	}
+	if failed && atomic {
+		return results, nil
+	}
+	var names []string
+	var descriptions []*string
+	var events []model.Event
+	ids = ids[:0]
+	for i, item := range items {
+		if results[i].Err != nil {
+			continue
+		}
+		previous := current[item.ID]
+		g := previous
+		g.Name = item.Name
+		g.Description = item.Description
+		g.Version++
+		results[i].Good = &g
+		ids = append(ids, item.ID)
+		names = append(names, item.Name)
+		descriptions = append(descriptions, item.Description)
+		event, err := model.NewEvent(ctx, model.EventGoodUpdated, projectID, item.ID, previous, g)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if len(ids) > 0 {
+		query := `UPDATE goods AS g SET name=u.name, description=u.description, version=g.version+1
+			FROM unnest($1::int[], $2::text[], $3::text[]) AS u(id, name, description)
+			WHERE g.id=u.id AND g.project_id=$4`
+		_, err = tx.ExecContext(ctx, query, pq.Array(ids), pq.Array(names), pq.Array(descriptions), projectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update goods: %w", err)
+		}
+		if err := insertOutboxEvents(ctx, tx, events); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return results, nil
+}
+
+// RemoveGoods помечает товары проекта удалёнными в одной транзакции, увеличивает их версии
+// и записывает событие good.removed с состоянием до и после для каждого товара
+// Результаты соответствуют items по индексу и содержат удалённые товары; ошибки элемента — ErrNotFound,
+// ErrVersionMismatch и ErrDuplicateItem. Атомарный режим (atomic) — как у UpdateGoods
+func (r *GoodRepository) RemoveGoods(ctx context.Context, projectID int, items []model.GoodRemoval, atomic bool) (_ []model.BulkResult, err error) {
+	ctx, span := startSpan(ctx, "GoodRepository.RemoveGoods", semconv.DBSystemPostgreSQL)
+	defer func() { endSpan(span, err) }()
+	if len(items) == 0 {
+		return nil, nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	current, err := lockGoods(ctx, tx, projectID, ids)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]model.BulkResult, len(items))
+	seen := make(map[int]bool, len(items))
+	failed := false
+	for i, item := range items {
+		results[i].Err = checkBulkItem(seen, current, item.ID, item.Version)
+		failed = failed || results[i].Err != nil
+	}
+	if failed && atomic {
+		return results, nil
+	}
+	var events []model.Event
+	ids = ids[:0]
+	for i, item := range items {
+		if results[i].Err != nil {
+			continue
+		}
+		previous := current[item.ID]
+		g := previous
+		g.Removed = true
+		g.Version++
+		results[i].Good = &g
+		ids = append(ids, item.ID)
+		event, err := model.NewEvent(ctx, model.EventGoodRemoved, projectID, item.ID, previous, g)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if len(ids) > 0 {
+		_, err = tx.ExecContext(ctx, `UPDATE goods SET removed=true, version=version+1 WHERE project_id=$1 AND id = ANY($2)`,
+			projectID, pq.Array(ids))
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove goods: %w", err)
+		}
+		if err := insertOutboxEvents(ctx, tx, events); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return results, nil
+}
+
+// lockGoods выбирает товары проекта с идентификаторами ids и блокирует их строки в порядке id,
+// чтобы пакеты с пересекающимися товарами не блокировали друг друга взаимно
+func lockGoods(ctx context.Context, tx *sql.Tx, projectID int, ids []int) (map[int]model.Good, error) {
+	query := `SELECT id, project_id, name, description, priority, removed, created_at, version
+		FROM goods WHERE project_id=$1 AND id = ANY($2) ORDER BY id FOR UPDATE`
+	rows, err := tx.QueryContext(ctx, query, projectID, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to select goods for update: %w", err)
+	}
+	defer rows.Close()
+	goods := make(map[int]model.Good, len(ids))
+	for rows.Next() {
+		var g model.Good
+		if err := rows.Scan(&g.ID, &g.ProjectID, &g.Name, &g.Description, &g.Priority, &g.Removed, &g.CreatedAt, &g.Version); err != nil {
+			return nil, fmt.Errorf("failed to scan good: %w", err)
+		}
+		goods[g.ID] = g
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate goods: %w", err)
+	}
+	return goods, nil
+}
+
+// checkBulkItem проверяет элемент пакетной операции над товаром id с ожидаемой версией version:
+// товар не встречался в пакете раньше (seen), существует в current и имеет ожидаемую версию
+func checkBulkItem(seen map[int]bool, current map[int]model.Good, id, version int) error {
+	if seen[id] {
+		return ErrDuplicateItem
+	}
+	seen[id] = true
+	g, ok := current[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if version != 0 && version != g.Version {
+		return ErrVersionMismatch
+	}
+	return nil
+}
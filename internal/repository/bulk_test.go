@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+
+	"HezzlTestTask/internal/model"
+)
+
+// goodColumns — столбцы выборки товара с блокировкой в пакетных операциях
+var goodColumns = []string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "version"}
+
+// lockGoodsQuery — выборка товаров пакета с блокировкой строк
+var lockGoodsQuery = regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods WHERE project_id=$1 AND id = ANY($2) ORDER BY id FOR UPDATE")
+
+func TestValuesPlaceholders(t *testing.T) {
+	if got := valuesPlaceholders(2, 3); got != "($1, $2, $3), ($4, $5, $6)" {
+		t.Errorf("unexpected placeholders %q", got)
+	}
+	if got := valuesPlaceholders(1, 1); got != "($1)" {
+		t.Errorf("unexpected placeholders %q", got)
+	}
+}
+
+// TestCreateGoods: блокировка проекта, приоритеты max+1.. в порядке элементов при любом порядке RETURNING,
+// событие good.created на каждый товар одним INSERT в outbox
+func TestCreateGoods(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	expectProjectLock(mock, 2, lockUpdate, false)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(priority), 0) FROM goods WHERE project_id=$1")).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(7))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO goods(project_id, name, description, priority) VALUES($1, $2, $3, $4), ($5, $6, $7, $8) RETURNING id, priority, removed, created_at, version")).
+		WithArgs(2, "a", nil, 8, 2, "b", "desc", 9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "priority", "removed", "created_at", "version"}).
+			AddRow(21, 9, false, now, 1).
+			AddRow(20, 8, false, now, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context) VALUES($1, $2, $3, $4), ($5, $6, $7, $8)")).
+		WithArgs(sqlmock.AnyArg(), model.EventGoodCreated, eventArg{typ: model.EventGoodCreated, check: func(e model.Event) bool {
+			return e.EntityID == 20
+		}}, nil, sqlmock.AnyArg(), model.EventGoodCreated, eventArg{typ: model.EventGoodCreated, check: func(e model.Event) bool {
+			return e.EntityID == 21
+		}}, nil).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	goods, err := repo.CreateGoods(context.Background(), 2, []model.GoodInput{{Name: "a"}, {Name: "b", Description: ptr("desc")}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(goods) != 2 || goods[0].ID != 20 || goods[0].Name != "a" || goods[0].Priority != 8 ||
+		goods[1].ID != 21 || *goods[1].Description != "desc" || goods[1].ProjectID != 2 {
+		t.Errorf("unexpected goods %+v", goods)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestCreateGoods_Errors: пустое имя отклоняется без запросов, отсутствие проекта — ErrProjectNotFound
+func TestCreateGoods_Errors(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+
+	if _, err := repo.CreateGoods(context.Background(), 1, []model.GoodInput{{Name: "a"}, {}}); !errors.Is(err, ErrEmptyName) {
+		t.Errorf("expected ErrEmptyName, got %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT removed FROM projects WHERE id=$1 FOR UPDATE")).
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"removed"}))
+	mock.ExpectRollback()
+	if _, err := repo.CreateGoods(context.Background(), 9, []model.GoodInput{{Name: "a"}}); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("expected ErrProjectNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestUpdateGoods_BestEffort: элементы с ошибками пропускаются, остальные обновляются одним UPDATE
+func TestUpdateGoods_BestEffort(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+
+	mock.ExpectBegin()
+	expectProjectLock(mock, 1, lockShare, false)
+	mock.ExpectQuery(lockGoodsQuery).
+		WithArgs(1, pq.Array([]int{1, 2, 3, 1})).
+		WillReturnRows(sqlmock.NewRows(goodColumns).
+			AddRow(1, 1, "a", nil, 1, false, time.Now(), 1).
+			AddRow(2, 1, "b", nil, 2, false, time.Now(), 5))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods AS g SET name=u.name, description=u.description, version=g.version+1 FROM unnest($1::int[], $2::text[], $3::text[]) AS u(id, name, description) WHERE g.id=u.id AND g.project_id=$4")).
+		WithArgs(pq.Array([]int{1}), pq.Array([]string{"a2"}), pq.Array([]*string{ptr("d")}), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context) VALUES($1, $2, $3, $4)")).
+		WithArgs(sqlmock.AnyArg(), model.EventGoodUpdated, eventArg{typ: model.EventGoodUpdated, check: func(e model.Event) bool {
+			var before, after model.Good
+			_ = json.Unmarshal(e.Previous, &before)
+			_ = json.Unmarshal(e.Current, &after)
+			return before.Name == "a" && after.Name == "a2" && after.Version == 2
+		}}, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	results, err := repo.UpdateGoods(context.Background(), 1, []model.GoodUpdate{
+		{ID: 1, Name: "a2", Description: ptr("d"), Version: 1},
+		{ID: 2, Name: "b2", Version: 4},
+		{ID: 3, Name: "c2"},
+		{ID: 1, Name: "a3"},
+	}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Err != nil || results[0].Good == nil || results[0].Good.Version != 2 {
+		t.Errorf("item 0: unexpected result %+v", results[0])
+	}
+	for i, want := range []error{ErrVersionMismatch, ErrNotFound, ErrDuplicateItem} {
+		if res := results[i+1]; !errors.Is(res.Err, want) || res.Good != nil {
+			t.Errorf("item %d: expected %v, got %+v", i+1, want, res)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestUpdateGoods_Atomic: ошибка одного элемента откатывает транзакцию, остальные элементы не применяются
+func TestUpdateGoods_Atomic(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+
+	mock.ExpectBegin()
+	expectProjectLock(mock, 1, lockShare, false)
+	mock.ExpectQuery(lockGoodsQuery).
+		WithArgs(1, pq.Array([]int{1, 2})).
+		WillReturnRows(sqlmock.NewRows(goodColumns).AddRow(1, 1, "a", nil, 1, false, time.Now(), 1))
+	mock.ExpectRollback()
+
+	results, err := repo.UpdateGoods(context.Background(), 1, []model.GoodUpdate{{ID: 1, Name: "a2"}, {ID: 2, Name: "b2"}}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Good != nil || results[0].Err != nil || !errors.Is(results[1].Err, ErrNotFound) {
+		t.Errorf("unexpected results %+v", results)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestRemoveGoods: товары помечаются удалёнными одним UPDATE, событие good.removed на каждый товар
+func TestRemoveGoods(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockGoodsQuery).
+		WithArgs(1, pq.Array([]int{2, 1})).
+		WillReturnRows(sqlmock.NewRows(goodColumns).
+			AddRow(1, 1, "a", nil, 1, false, time.Now(), 1).
+			AddRow(2, 1, "b", nil, 2, false, time.Now(), 3))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET removed=true, version=version+1 WHERE project_id=$1 AND id = ANY($2)")).
+		WithArgs(1, pq.Array([]int{2, 1})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context) VALUES($1, $2, $3, $4), ($5, $6, $7, $8)")).
+		WithArgs(sqlmock.AnyArg(), model.EventGoodRemoved, eventArg{typ: model.EventGoodRemoved, check: func(e model.Event) bool {
+			return e.EntityID == 2
+		}}, nil, sqlmock.AnyArg(), model.EventGoodRemoved, eventArg{typ: model.EventGoodRemoved}, nil).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	results, err := repo.RemoveGoods(context.Background(), 1, []model.GoodRemoval{{ID: 2, Version: 3}, {ID: 1}}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, res := range results {
+		if res.Err != nil || res.Good == nil || !res.Good.Removed {
+			t.Errorf("item %d: unexpected result %+v", i, res)
+		}
+	}
+	if results[0].Good.Version != 4 {
+		t.Errorf("expected version 4, got %d", results[0].Good.Version)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestRemoveGoods_ExecError: ошибка UPDATE откатывает транзакцию и возвращается целиком
+func TestRemoveGoods_ExecError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockGoodsQuery).
+		WithArgs(1, pq.Array([]int{1})).
+		WillReturnRows(sqlmock.NewRows(goodColumns).AddRow(1, 1, "a", nil, 1, false, time.Now(), 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET removed=true")).
+		WillReturnError(errors.New("remove failed"))
+	mock.ExpectRollback()
+
+	_, err := repo.RemoveGoods(context.Background(), 1, []model.GoodRemoval{{ID: 1}}, false)
+	if err == nil || !strings.Contains(err.Error(), "remove failed") {
+		t.Errorf("expected exec error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -3,13 +3,18 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"log"
-	"time"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
 
 	"HezzlTestTask/internal/model"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
-// ClickhouseRepo реализует пакетную запись событий логов в ClickHouse
+// ClickhouseRepo реализует пакетную запись событий логов в ClickHouse и чтение истории изменений
 // Все комментарии на русском языке
 type ClickhouseRepo struct {
 	db *sql.DB
@@ -20,18 +25,33 @@ func NewClickhouseRepo(db *sql.DB) *ClickhouseRepo {
 	return &ClickhouseRepo{db: db}
 }
 
-// BatchInsertLogs записывает пакет логов событий в таблицу events_log в ClickHouse
-// Событие содержит данные из модели Good и время события теперь
-func (r *ClickhouseRepo) BatchInsertLogs(ctx context.Context, events []model.Good) error {
+// eventLogRow представляет строку таблицы events_log
+// Поля Name/Description/Priority/Removed заполняются из текущего состояния сущности в событии
+type eventLogRow struct {
+	id          int
+	projectID   int
+	name        string
+	description string
+	priority    int
+	removed     bool
+}
+
+// BatchInsertLogs записывает пакет событий в таблицу events_log в ClickHouse
+// Время события берётся из конверта (OccurredAt), состояния до и после сохраняются в JSON
+func (r *ClickhouseRepo) BatchInsertLogs(ctx context.Context, events []model.Event) (err error) {
+	ctx, span := startSpan(ctx, "ClickhouseRepo.BatchInsertLogs", semconv.DBSystemClickhouse)
+	span.SetAttributes(attribute.Int("db.batch.size", len(events)))
+	defer func() { endSpan(span, err) }()
 	// начинаем 'транзакцию' для batch insert (clickhouse-go собирает блок при PrepareContext)
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
 	// логируем количество событий для вставки
-	log.Printf("Начало пакетной вставки %d событий в ClickHouse", len(events))
+	slog.DebugContext(ctx, "inserting events batch into ClickHouse", slog.Int("events", len(events)))
 	// PrepareContext для одной строки; clickhouse-go будет собирать несколько Exec в один блок
-	query := `INSERT INTO events_log (Id, ProjectId, Name, Description, Priority, Removed, EventTime) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO events_log (Id, ProjectId, Name, Description, Priority, Removed, EventTime,
+		EventId, EventType, Version, RequestId, Actor, Previous, Current, OccurredAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		_ = tx.Rollback()
@@ -40,14 +60,13 @@ func (r *ClickhouseRepo) BatchInsertLogs(ctx context.Context, events []model.Goo
 	defer func() { _ = stmt.Close() }()
 	// выполняем ExecContext для каждой записи; драйвер соберёт весь пакет
 	for _, e := range events {
-		desc := ""
-		if e.Description != nil {
-			desc = *e.Description
-		}
+		row := eventRow(e)
 		_, err := stmt.ExecContext(ctx,
-			e.ID, e.ProjectID, e.Name,
-			desc, e.Priority, boolToUInt8(e.Removed),
-			time.Now(),
+			row.id, row.projectID, row.name,
+			row.description, row.priority, boolToUInt8(row.removed),
+			e.OccurredAt,
+			e.ID, e.Type, uint8(e.Version), e.RequestID, e.Actor,
+			string(e.Previous), string(e.Current), e.OccurredAt,
 		)
 		if err != nil {
 			_ = tx.Rollback()
@@ -59,10 +78,184 @@ func (r *ClickhouseRepo) BatchInsertLogs(ctx context.Context, events []model.Goo
 		return err
 	}
 	// логируем успешную вставку
-	log.Printf("Успешно вставлено %d событий в ClickHouse", len(events))
+	slog.InfoContext(ctx, "inserted events batch into ClickHouse", slog.Int("events", len(events)))
 	return nil
 }
 
+// historyTimeLayout — формат времени для сравнения с OccurredAt (DateTime64(6)) через toDateTime64(?, 6, 'UTC')
+const historyTimeLayout = "2006-01-02 15:04:05.000000"
+
+// History возвращает историю изменений из events_log в порядке (OccurredAt, EventId) согласно фильтру
+// Строки, записанные до появления конверта события (без EventId), в историю не попадают.
+// Доставка событий at-least-once, поэтому в events_log возможны повторы одного EventId:
+// LIMIT 1 BY оставляет по одной строке на событие до применения LIMIT страницы
+// История товара включает перестановки, сдвинувшие его приоритет: строка good.reprioritized хранит Id
+// переставленного товара, а сдвинутые товары перечислены в Current
+func (r *ClickhouseRepo) History(ctx context.Context, filter model.HistoryFilter) (_ []model.Event, err error) {
+	ctx, span := startSpan(ctx, "ClickhouseRepo.History", semconv.DBSystemClickhouse)
+	span.SetAttributes(attribute.Int("project.id", filter.ProjectID))
+	defer func() { endSpan(span, err) }()
+	query := `SELECT EventId, EventType, Version, OccurredAt, ProjectId, Id, RequestId, Actor, Previous, Current
+		FROM events_log WHERE ProjectId = ? AND EventId != ''`
+	args := []interface{}{filter.ProjectID}
+	if filter.EntityType != "" {
+		query += ` AND startsWith(EventType, ?)`
+		args = append(args, filter.EntityType+".")
+	}
+	if filter.EntityID > 0 {
+		query += ` AND (Id = ? OR (EventType = ? AND arrayExists(u -> JSONExtractInt(u, 'id') = ?, JSONExtractArrayRaw(Current))))`
+		args = append(args, filter.EntityID, model.EventGoodReprioritized, filter.EntityID)
+	}
+	if filter.From != nil {
+		query += ` AND OccurredAt >= toDateTime64(?, 6, 'UTC')`
+		args = append(args, filter.From.UTC().Format(historyTimeLayout))
+	}
+	if filter.To != nil {
+		query += ` AND OccurredAt < toDateTime64(?, 6, 'UTC')`
+		args = append(args, filter.To.UTC().Format(historyTimeLayout))
+	}
+	if filter.After != nil {
+		query += ` AND (OccurredAt, EventId) > (toDateTime64(?, 6, 'UTC'), ?)`
+		args = append(args, filter.After.OccurredAt.UTC().Format(historyTimeLayout), filter.After.EventID)
+	}
+	query += ` ORDER BY OccurredAt, EventId LIMIT 1 BY EventId LIMIT ?`
+	args = append(args, filter.Limit)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select history: %w", err)
+	}
+	defer rows.Close()
+	events := []model.Event{}
+	for rows.Next() {
+		var e model.Event
+		var version uint8
+		var projectID, entityID uint64
+		var previous, current string
+		if err := rows.Scan(&e.ID, &e.Type, &version, &e.OccurredAt, &projectID, &entityID,
+			&e.RequestID, &e.Actor, &previous, &current); err != nil {
+			return nil, fmt.Errorf("failed to scan history event: %w", err)
+		}
+		e.Version, e.ProjectID, e.EntityID = int(version), int(projectID), int(entityID)
+		e.OccurredAt = e.OccurredAt.UTC()
+		if previous != "" {
+			e.Previous = json.RawMessage(previous)
+		}
+		if current != "" {
+			e.Current = json.RawMessage(current)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate history: %w", err)
+	}
+	return events, nil
+}
+
+// InsertDeadLetters записывает пакет необработанных сообщений в таблицу events_dead_letter
+func (r *ClickhouseRepo) InsertDeadLetters(ctx context.Context, letters []model.DeadLetter) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin dead letter batch: %w", err)
+	}
+	query := `INSERT INTO events_dead_letter (Id, Stage, Error, Payload, Deliveries, FailedAt) VALUES (?, ?, ?, ?, ?, ?)`
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to prepare dead letter insert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+	for _, l := range letters {
+		_, err := stmt.ExecContext(ctx, l.ID, l.Stage, l.Error, string(l.Payload), uint32(l.Deliveries), l.FailedAt)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to insert dead letter: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit dead letter batch: %w", err)
+	}
+	slog.WarnContext(ctx, "stored messages in events_dead_letter", slog.Int("messages", len(letters)))
+	return nil
+}
+
+// ListDeadLetters возвращает до limit самых старых записей dead letter; пустой stage — записи всех этапов
+func (r *ClickhouseRepo) ListDeadLetters(ctx context.Context, stage string, limit int) ([]model.DeadLetter, error) {
+	query := `SELECT Id, Stage, Error, Payload, Deliveries, FailedAt FROM events_dead_letter`
+	args := []interface{}{}
+	if stage != "" {
+		query += ` WHERE Stage = ?`
+		args = append(args, stage)
+	}
+	query += ` ORDER BY FailedAt, Id LIMIT ?`
+	args = append(args, limit)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select dead letters: %w", err)
+	}
+	defer rows.Close()
+	var letters []model.DeadLetter
+	for rows.Next() {
+		var l model.DeadLetter
+		var payload string
+		var deliveries uint32
+		if err := rows.Scan(&l.ID, &l.Stage, &l.Error, &payload, &deliveries, &l.FailedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		l.Payload = []byte(payload)
+		l.Deliveries = int(deliveries)
+		letters = append(letters, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate dead letters: %w", err)
+	}
+	return letters, nil
+}
+
+// DeleteDeadLetters удаляет переотправленные записи dead letter (мутация ALTER TABLE ... DELETE)
+func (r *ClickhouseRepo) DeleteDeadLetters(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	query := fmt.Sprintf(`ALTER TABLE events_dead_letter DELETE WHERE Id IN (%s)`, placeholders)
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete dead letters: %w", err)
+	}
+	return nil
+}
+
+// eventRow извлекает плоские поля строки events_log из конверта события
+// Для good.reprioritized в строку попадает новый приоритет целевого товара
+func eventRow(e model.Event) eventLogRow {
+	row := eventLogRow{id: e.EntityID, projectID: e.ProjectID}
+	switch e.Type {
+	case model.EventGoodReprioritized:
+		var updates []model.PriorityUpdate
+		_ = json.Unmarshal(e.Current, &updates)
+		for _, u := range updates {
+			if u.ID == e.EntityID {
+				row.priority = u.Priority
+			}
+		}
+	case model.EventProjectCreated, model.EventProjectUpdated, model.EventProjectRemoved:
+		var p model.Project
+		_ = json.Unmarshal(e.Current, &p)
+		row.name, row.removed = p.Name, p.Removed
+	default:
+		var g model.Good
+		_ = json.Unmarshal(e.Current, &g)
+		row.name, row.priority, row.removed = g.Name, g.Priority, g.Removed
+		if g.Description != nil {
+			row.description = *g.Description
+		}
+	}
+	return row
+}
+
 // boolToUInt8 конвертирует bool в UInt8 (0/1)
 func boolToUInt8(b bool) uint8 {
 	if b {
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/require"
@@ -21,16 +24,18 @@ func TestBatchInsertLogs(t *testing.T) {
 	repo := NewClickhouseRepo(db)
 	defer db.Close()
 
-	events := []model.Good{
-		{ID: 1, ProjectID: 2, Name: "test", Description: ptrString("desc"), Priority: 5, Removed: true},
-	}
+	good := model.Good{ID: 1, ProjectID: 2, Name: "test", Description: ptrString("desc"), Priority: 5, Removed: true}
+	event, err := model.NewEvent(context.Background(), model.EventGoodRemoved, 2, 1, nil, good)
+	require.NoError(t, err)
+	events := []model.Event{event}
 
 	// Ожидаем начало транзакции
 	mock.ExpectBegin()
 	// Ожидаем подготовку запроса
 	mock.ExpectPrepare("INSERT INTO events_log").
 		ExpectExec().
-		WithArgs(1, 2, "test", "desc", 5, uint8(1), sqlmock.AnyArg()).
+		WithArgs(1, 2, "test", "desc", 5, uint8(1), event.OccurredAt,
+			event.ID, model.EventGoodRemoved, uint8(model.EventVersion), "", "", "", string(event.Current), event.OccurredAt).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	// Ожидаем коммит
 	mock.ExpectCommit()
@@ -39,3 +44,135 @@ func TestBatchInsertLogs(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestEventRow(t *testing.T) {
+	// перестановка приоритетов: в строку попадает новый приоритет целевого товара
+	updates := []model.PriorityUpdate{{ID: 4, Priority: 2}, {ID: 7, Priority: 1}}
+	e, err := model.NewEvent(context.Background(), model.EventGoodReprioritized, 3, 7, nil, updates)
+	require.NoError(t, err)
+	row := eventRow(e)
+	require.Equal(t, eventLogRow{id: 7, projectID: 3, priority: 1}, row)
+
+	// событие проекта: имя и флаг архивации берутся из объекта Project
+	e, err = model.NewEvent(context.Background(), model.EventProjectRemoved, 5, 5, nil,
+		model.Project{ID: 5, Name: "p", Removed: true, CreatedAt: time.Now()})
+	require.NoError(t, err)
+	row = eventRow(e)
+	require.Equal(t, eventLogRow{id: 5, projectID: 5, name: "p", removed: true}, row)
+}
+
+func TestHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewClickhouseRepo(db)
+
+	columns := []string{"EventId", "EventType", "Version", "OccurredAt", "ProjectId", "Id", "RequestId", "Actor", "Previous", "Current"}
+	at := time.Date(2026, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	from, to := at.Add(-time.Hour), at.Add(time.Hour)
+	// история товара: фильтр по типу сущности, идентификатору, периоду и курсору
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM events_log WHERE ProjectId = ? AND EventId != '' AND startsWith(EventType, ?)`+
+		` AND (Id = ? OR (EventType = ? AND arrayExists(u -> JSONExtractInt(u, 'id') = ?, JSONExtractArrayRaw(Current))))`+
+		` AND OccurredAt >= toDateTime64(?, 6, 'UTC') AND OccurredAt < toDateTime64(?, 6, 'UTC')`+
+		` AND (OccurredAt, EventId) > (toDateTime64(?, 6, 'UTC'), ?) ORDER BY OccurredAt, EventId LIMIT 1 BY EventId LIMIT ?`)).
+		WithArgs(2, "good.", 7, model.EventGoodReprioritized, 7, "2026-01-02 02:04:05.123456", "2026-01-02 04:04:05.123456", "2026-01-02 03:04:05.123456", "e-0", 10).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("e-1", model.EventGoodUpdated, uint8(1), at, uint64(2), uint64(7), "req-1", "alice", `{"name":"a"}`, `{"name":"b"}`).
+			AddRow("e-2", model.EventGoodCreated, uint8(1), at, uint64(2), uint64(7), "", "", "", `{"name":"c"}`))
+
+	events, err := repo.History(context.Background(), model.HistoryFilter{
+		ProjectID: 2, EntityType: model.HistoryGood, EntityID: 7, From: &from, To: &to, Limit: 10,
+		After: &model.HistoryCursor{OccurredAt: at, EventID: "e-0"},
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, model.Event{Version: 1, ID: "e-1", Type: model.EventGoodUpdated, OccurredAt: at, ProjectID: 2, EntityID: 7,
+		RequestID: "req-1", Actor: "alice", Previous: []byte(`{"name":"a"}`), Current: []byte(`{"name":"b"}`)}, events[0])
+	// пустое состояние не сериализуется
+	require.Nil(t, events[1].Previous)
+
+	// история проекта без дополнительных условий возвращает пустой слайс, а не nil
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM events_log WHERE ProjectId = ? AND EventId != '' ORDER BY OccurredAt, EventId LIMIT 1 BY EventId LIMIT ?`)).
+		WithArgs(3, 50).
+		WillReturnRows(sqlmock.NewRows(columns))
+	events, err = repo.History(context.Background(), model.HistoryFilter{ProjectID: 3, Limit: 50})
+	require.NoError(t, err)
+	require.Equal(t, []model.Event{}, events)
+
+	// история сдвинутого перестановкой товара находит его в Current события good.reprioritized
+	mock.ExpectQuery(regexp.QuoteMeta(`AND (Id = ? OR (EventType = ? AND arrayExists(u -> JSONExtractInt(u, 'id') = ?, JSONExtractArrayRaw(Current))))`)).
+		WithArgs(4, 8, model.EventGoodReprioritized, 8, 50).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("e-3", model.EventGoodReprioritized, uint8(1), at, uint64(4), uint64(7), "", "",
+				`[{"id":7,"priority":1},{"id":8,"priority":2}]`, `[{"id":7,"priority":2},{"id":8,"priority":1}]`))
+	events, err = repo.History(context.Background(), model.HistoryFilter{ProjectID: 4, EntityID: 8, Limit: 50})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, 7, events[0].EntityID)
+
+	// ошибка запроса прокидывается
+	ex := errors.New("clickhouse down")
+	mock.ExpectQuery("FROM events_log").WillReturnError(ex)
+	_, err = repo.History(context.Background(), model.HistoryFilter{ProjectID: 3, Limit: 50})
+	require.ErrorIs(t, err, ex)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertDeadLetters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewClickhouseRepo(db)
+
+	letter := model.NewDeadLetter(model.DeadLetterDecode, []byte("not json"), 1, errors.New("invalid character"))
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO events_dead_letter").
+		ExpectExec().
+		WithArgs(letter.ID, model.DeadLetterDecode, "invalid character", "not json", uint32(1), letter.FailedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.InsertDeadLetters(context.Background(), []model.DeadLetter{letter}))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListDeadLetters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewClickhouseRepo(db)
+
+	failedAt := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT Id, Stage, Error, Payload, Deliveries, FailedAt FROM events_dead_letter WHERE Stage = ? ORDER BY FailedAt, Id LIMIT ?")).
+		WithArgs(model.DeadLetterInsert, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "Stage", "Error", "Payload", "Deliveries", "FailedAt"}).
+			AddRow("a", model.DeadLetterInsert, "boom", `{"id":"e"}`, uint32(5), failedAt))
+
+	letters, err := repo.ListDeadLetters(context.Background(), model.DeadLetterInsert, 10)
+	require.NoError(t, err)
+	require.Equal(t, []model.DeadLetter{{ID: "a", Stage: model.DeadLetterInsert, Error: "boom", Payload: []byte(`{"id":"e"}`), Deliveries: 5, FailedAt: failedAt}}, letters)
+
+	// без фильтра по этапу условие WHERE не добавляется
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT Id, Stage, Error, Payload, Deliveries, FailedAt FROM events_dead_letter ORDER BY FailedAt, Id LIMIT ?")).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "Stage", "Error", "Payload", "Deliveries", "FailedAt"}))
+	letters, err = repo.ListDeadLetters(context.Background(), "", 5)
+	require.NoError(t, err)
+	require.Empty(t, letters)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteDeadLetters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewClickhouseRepo(db)
+
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE events_dead_letter DELETE WHERE Id IN (?, ?)")).
+		WithArgs("a", "b").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	require.NoError(t, repo.DeleteDeadLetters(context.Background(), []string{"a", "b"}))
+	// пустой список не порождает мутацию
+	require.NoError(t, repo.DeleteDeadLetters(context.Background(), nil))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,660 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"HezzlTestTask/internal/model"
+	"HezzlTestTask/pkg/tracing"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// defaultProjectName — имя проекта, который создаёт миграция 0002
+const defaultProjectName = "Первая запись"
+
+// MemoryStore хранит проекты, товары и outbox в памяти процесса и повторяет поведение Postgres:
+// приоритет нового товара вычисляется как у триггера set_goods_priority, Reprioritize сдвигает соседей
+// так же, как GoodRepository, а события изменений товаров и проектов записываются в outbox вместе с изменением
+// Реализует интерфейсы service.Repo, service.ProjectRepo и outbox.Store; данные теряются при перезапуске
+type MemoryStore struct {
+	mu            sync.Mutex
+	projects      map[int]model.Project
+	goods         map[int]model.Good
+	outbox        []model.OutboxMessage // неотправленные события в порядке записи
+	lockedUntil   map[int64]time.Time   // аренда выбранных событий outbox
+	failedOutbox  []model.OutboxMessage // события, исчерпавшие попытки публикации
+	nextProjectID int
+	nextGoodID    int
+	nextOutboxID  int64
+	now           func() time.Time
+}
+
+// NewMemoryStore создаёт хранилище в памяти с проектом по умолчанию, как после миграций Postgres
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		projects:      make(map[int]model.Project),
+		goods:         make(map[int]model.Good),
+		lockedUntil:   make(map[int64]time.Time),
+		nextProjectID: 1,
+		nextGoodID:    1,
+		nextOutboxID:  1,
+		now:           func() time.Time { return time.Now().UTC().Truncate(time.Microsecond) },
+	}
+	s.projects[1] = model.Project{ID: 1, Name: defaultProjectName, CreatedAt: s.now()}
+	s.nextProjectID = 2
+	return s
+}
+
+// CreateGood добавляет товар с приоритетом max(priority)+1 в пределах проекта и записывает событие good.created
+func (s *MemoryStore) CreateGood(ctx context.Context, projectID int, name string, description *string) (*model.Good, error) {
+	if name == "" {
+		return nil, ErrEmptyName
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkProject(projectID); err != nil {
+		return nil, err
+	}
+	priority := 0
+	for _, g := range s.goods {
+		if g.ProjectID == projectID && g.Priority > priority {
+			priority = g.Priority
+		}
+	}
+	good := model.Good{
+		ID:          s.nextGoodID,
+		ProjectID:   projectID,
+		Name:        name,
+		Description: copyString(description),
+		Priority:    priority + 1,
+		CreatedAt:   s.now(),
+		Version:     1,
+	}
+	if err := s.appendOutbox(ctx, model.EventGoodCreated, projectID, good.ID, nil, good); err != nil {
+		return nil, err
+	}
+	s.goods[good.ID] = good
+	s.nextGoodID++
+	return copyGood(good), nil
+}
+
+// GetGood возвращает товар по id и projectID
+func (s *MemoryStore) GetGood(ctx context.Context, projectID, id int) (*model.Good, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.goods[id]
+	if !ok || g.ProjectID != projectID {
+		return nil, ErrNotFound
+	}
+	return copyGood(g), nil
+}
+
+// UpdateGood обновляет name и description товара, увеличивает его версию и записывает событие good.updated
+// с состоянием до и после; version, отличная от 0 и от текущей версии, возвращает ErrVersionMismatch
+func (s *MemoryStore) UpdateGood(ctx context.Context, projectID, id int, name string, description *string, version int) (*model.Good, error) {
+	if name == "" {
+		return nil, ErrEmptyName
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkProject(projectID); err != nil {
+		return nil, err
+	}
+	g, ok := s.goods[id]
+	if !ok || g.ProjectID != projectID {
+		return nil, ErrNotFound
+	}
+	if version != 0 && version != g.Version {
+		return nil, ErrVersionMismatch
+	}
+	previous := g
+	g.Name = name
+	g.Description = copyString(description)
+	g.Version++
+	if err := s.appendOutbox(ctx, model.EventGoodUpdated, projectID, id, previous, g); err != nil {
+		return nil, err
+	}
+	s.goods[id] = g
+	return copyGood(g), nil
+}
+
+// RemoveGood помечает товар удалённым, увеличивает его версию и записывает событие good.removed
+// с состоянием до и после; version, отличная от 0 и от текущей версии, возвращает ErrVersionMismatch
+func (s *MemoryStore) RemoveGood(ctx context.Context, projectID, id, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.goods[id]
+	if !ok || g.ProjectID != projectID {
+		return ErrNotFound
+	}
+	if version != 0 && version != g.Version {
+		return ErrVersionMismatch
+	}
+	removed := g
+	removed.Removed = true
+	removed.Version++
+	if err := s.appendOutbox(ctx, model.EventGoodRemoved, projectID, id, g, removed); err != nil {
+		return err
+	}
+	s.goods[id] = removed
+	return nil
+}
+
+// ListGoods возвращает страницу товаров по фильтру с теми же условиями, сортировкой и пагинацией, что и GoodRepository
+func (s *MemoryStore) ListGoods(ctx context.Context, filter model.GoodsFilter) ([]model.Good, int, int, error) {
+	s.mu.Lock()
+	matched := make([]model.Good, 0, len(s.goods))
+	for _, g := range s.goods {
+		if goodMatches(g, filter) {
+			matched = append(matched, g)
+		}
+	}
+	s.mu.Unlock()
+	var total, removed int
+	if !filter.SkipCounts {
+		total = len(matched)
+		for _, g := range matched {
+			if g.Removed {
+				removed++
+			}
+		}
+	}
+	less := goodsLess(filter)
+	sort.Slice(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+	// условие keyset-пагинации применяется только к выборке страницы
+	offset := filter.Offset
+	if filter.After != nil {
+		page := matched[:0]
+		for _, g := range matched {
+			if goodAfter(g, filter) {
+				page = append(page, g)
+			}
+		}
+		matched = page
+		offset = 0
+	}
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	matched = matched[offset:]
+	if filter.Limit < len(matched) {
+		matched = matched[:filter.Limit]
+	}
+	var goods []model.Good
+	for _, g := range matched {
+		goods = append(goods, *copyGood(g))
+	}
+	return goods, total, removed, nil
+}
+
+// goodMatches проверяет товар на соответствие условиям фильтра (без курсора)
+func goodMatches(g model.Good, filter model.GoodsFilter) bool {
+	if filter.ProjectID > 0 && g.ProjectID != filter.ProjectID {
+		return false
+	}
+	switch filter.Removed {
+	case model.RemovedExclude:
+		if g.Removed {
+			return false
+		}
+	case model.RemovedOnly:
+		if !g.Removed {
+			return false
+		}
+	}
+	if filter.Name != "" && !strings.Contains(strings.ToLower(g.Name), strings.ToLower(filter.Name)) {
+		return false
+	}
+	if filter.CreatedFrom != nil && g.CreatedAt.Before(*filter.CreatedFrom) {
+		return false
+	}
+	if filter.CreatedTo != nil && !g.CreatedAt.Before(*filter.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+// goodAfter проверяет, что товар идёт после курсора filter.After, по тем же правилам, что и goodsKeyset
+func goodAfter(g model.Good, filter model.GoodsFilter) bool {
+	c := cmp.Compare(g.ID, filter.After.ID)
+	if filter.Sort == model.SortByPriority {
+		if p := cmp.Compare(g.Priority, filter.After.Priority); p != 0 {
+			c = p
+		}
+	}
+	if filter.Desc {
+		return c < 0
+	}
+	return c > 0
+}
+
+// goodsLess возвращает порядок товаров для фильтра, как goodsOrderBy: поле сортировки, затем id в том же направлении
+func goodsLess(filter model.GoodsFilter) func(a, b model.Good) bool {
+	return func(a, b model.Good) bool {
+		var c int
+		switch goodsSortColumns[filter.Sort] {
+		case "priority":
+			c = cmp.Compare(a.Priority, b.Priority)
+		case "created_at":
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		case "name":
+			c = strings.Compare(a.Name, b.Name)
+		}
+		if c == 0 {
+			c = cmp.Compare(a.ID, b.ID)
+		}
+		if filter.Desc {
+			return c > 0
+		}
+		return c < 0
+	}
+}
+
+// Reprioritize изменяет приоритет товара, сдвигая приоритеты товаров проекта между старым и новым значением,
+// увеличивает версии затронутых товаров и записывает событие good.reprioritized с приоритетами до и после;
+// version, отличная от 0 и от текущей версии товара, возвращает ErrVersionMismatch
+func (s *MemoryStore) Reprioritize(ctx context.Context, projectID, id, newPriority, version int) ([]model.PriorityUpdate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkProject(projectID); err != nil {
+		return nil, err
+	}
+	target, ok := s.goods[id]
+	if !ok || target.ProjectID != projectID {
+		return nil, ErrNotFound
+	}
+	if version != 0 && version != target.Version {
+		return nil, ErrVersionMismatch
+	}
+	currPriority := target.Priority
+	shifted := make(map[int]model.Good)
+	var updates []model.PriorityUpdate
+	for _, g := range s.goods {
+		if g.ProjectID != projectID || g.ID == id {
+			continue
+		}
+		switch {
+		case newPriority < currPriority && g.Priority >= newPriority && g.Priority < currPriority:
+			g.Priority++
+		case newPriority > currPriority && g.Priority > currPriority && g.Priority <= newPriority:
+			g.Priority--
+		default:
+			continue
+		}
+		g.Version++
+		shifted[g.ID] = g
+		updates = append(updates, model.PriorityUpdate{ID: g.ID, Priority: g.Priority})
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].ID < updates[j].ID })
+	updates = append(updates, model.PriorityUpdate{ID: id, Priority: newPriority})
+	previous := previousPriorities(updates, id, currPriority, newPriority)
+	if err := s.appendOutbox(ctx, model.EventGoodReprioritized, projectID, id, previous, updates); err != nil {
+		return nil, err
+	}
+	for gid, g := range shifted {
+		s.goods[gid] = g
+	}
+	target.Priority = newPriority
+	target.Version++
+	s.goods[id] = target
+	return updates, nil
+}
+
+// CreateGoods добавляет товары проекта с приоритетами max(priority)+1, max(priority)+2, ... в порядке items
+// и записывает событие good.created для каждого товара, как GoodRepository.CreateGoods
+func (s *MemoryStore) CreateGoods(ctx context.Context, projectID int, items []model.GoodInput) ([]model.Good, error) {
+	for _, item := range items {
+		if item.Name == "" {
+			return nil, ErrEmptyName
+		}
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkProject(projectID); err != nil {
+		return nil, err
+	}
+	priority := 0
+	for _, g := range s.goods {
+		if g.ProjectID == projectID && g.Priority > priority {
+			priority = g.Priority
+		}
+	}
+	goods := make([]model.Good, len(items))
+	for i, item := range items {
+		goods[i] = model.Good{
+			ID:          s.nextGoodID + i,
+			ProjectID:   projectID,
+			Name:        item.Name,
+			Description: copyString(item.Description),
+			Priority:    priority + i + 1,
+			CreatedAt:   s.now(),
+			Version:     1,
+		}
+		if err := s.appendOutbox(ctx, model.EventGoodCreated, projectID, goods[i].ID, nil, goods[i]); err != nil {
+			return nil, err
+		}
+	}
+	for i, g := range goods {
+		s.goods[g.ID] = g
+		goods[i] = *copyGood(g)
+	}
+	s.nextGoodID += len(goods)
+	return goods, nil
+}
+
+// UpdateGoods обновляет name и description товаров проекта и записывает событие good.updated для каждого
+// изменённого товара; ошибки элементов и атомарный режим — как у GoodRepository.UpdateGoods
+func (s *MemoryStore) UpdateGoods(ctx context.Context, projectID int, items []model.GoodUpdate, atomic bool) ([]model.BulkResult, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkProject(projectID); err != nil {
+		return nil, err
+	}
+	current := s.projectGoods(projectID)
+	results := make([]model.BulkResult, len(items))
+	seen := make(map[int]bool, len(items))
+	failed := false
+	for i, item := range items {
+		results[i].Err = checkBulkItem(seen, current, item.ID, item.Version)
+		if results[i].Err == nil && item.Name == "" {
+			results[i].Err = ErrEmptyName
+		}
+		failed = failed || results[i].Err != nil
+	}
+	if failed && atomic {
+		return results, nil
+	}
+	updated := make(map[int]model.Good)
+	for i, item := range items {
+		if results[i].Err != nil {
+			continue
+		}
+		previous := current[item.ID]
+		g := previous
+		g.Name = item.Name
+		g.Description = copyString(item.Description)
+		g.Version++
+		if err := s.appendOutbox(ctx, model.EventGoodUpdated, projectID, item.ID, previous, g); err != nil {
+			return nil, err
+		}
+		updated[g.ID] = g
+		results[i].Good = copyGood(g)
+	}
+	for id, g := range updated {
+		s.goods[id] = g
+	}
+	return results, nil
+}
+
+// RemoveGoods помечает товары проекта удалёнными и записывает событие good.removed для каждого товара;
+// ошибки элементов и атомарный режим — как у GoodRepository.RemoveGoods
+func (s *MemoryStore) RemoveGoods(ctx context.Context, projectID int, items []model.GoodRemoval, atomic bool) ([]model.BulkResult, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current := s.projectGoods(projectID)
+	results := make([]model.BulkResult, len(items))
+	seen := make(map[int]bool, len(items))
+	failed := false
+	for i, item := range items {
+		results[i].Err = checkBulkItem(seen, current, item.ID, item.Version)
+		failed = failed || results[i].Err != nil
+	}
+	if failed && atomic {
+		return results, nil
+	}
+	removed := make(map[int]model.Good)
+	for i, item := range items {
+		if results[i].Err != nil {
+			continue
+		}
+		previous := current[item.ID]
+		g := previous
+		g.Removed = true
+		g.Version++
+		if err := s.appendOutbox(ctx, model.EventGoodRemoved, projectID, item.ID, previous, g); err != nil {
+			return nil, err
+		}
+		removed[g.ID] = g
+		results[i].Good = copyGood(g)
+	}
+	for id, g := range removed {
+		s.goods[id] = g
+	}
+	return results, nil
+}
+
+// checkProject проверяет, что проект существует и не архивирован, как lockProject; вызывается под s.mu
+func (s *MemoryStore) checkProject(projectID int) error {
+	p, ok := s.projects[projectID]
+	if !ok {
+		return ErrProjectNotFound
+	}
+	if p.Removed {
+		return ErrProjectArchived
+	}
+	return nil
+}
+
+// projectGoods возвращает товары проекта по id; вызывается под s.mu
+func (s *MemoryStore) projectGoods(projectID int) map[int]model.Good {
+	goods := make(map[int]model.Good)
+	for id, g := range s.goods {
+		if g.ProjectID == projectID {
+			goods[id] = g
+		}
+	}
+	return goods
+}
+
+// CreateProject добавляет новый проект и записывает событие project.created
+func (s *MemoryStore) CreateProject(ctx context.Context, name string) (*model.Project, error) {
+	if name == "" {
+		return nil, ErrEmptyName
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := model.Project{ID: s.nextProjectID, Name: name, CreatedAt: s.now()}
+	if err := s.appendOutbox(ctx, model.EventProjectCreated, p.ID, p.ID, nil, p); err != nil {
+		return nil, err
+	}
+	s.projects[p.ID] = p
+	s.nextProjectID++
+	return &p, nil
+}
+
+// GetProject возвращает проект по id
+func (s *MemoryStore) GetProject(ctx context.Context, id int) (*model.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.projects[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+// UpdateProject переименовывает проект и записывает событие project.updated с состоянием до и после
+func (s *MemoryStore) UpdateProject(ctx context.Context, id int, name string) (*model.Project, error) {
+	if name == "" {
+		return nil, ErrEmptyName
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.projects[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	updated := p
+	updated.Name = name
+	if err := s.appendOutbox(ctx, model.EventProjectUpdated, id, id, p, updated); err != nil {
+		return nil, err
+	}
+	s.projects[id] = updated
+	return &updated, nil
+}
+
+// RemoveProject архивирует проект (removed=true) и записывает событие project.removed с состоянием до и после
+func (s *MemoryStore) RemoveProject(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.projects[id]
+	if !ok {
+		return ErrNotFound
+	}
+	removed := p
+	removed.Removed = true
+	if err := s.appendOutbox(ctx, model.EventProjectRemoved, id, id, p, removed); err != nil {
+		return err
+	}
+	s.projects[id] = removed
+	return nil
+}
+
+// ListProjects возвращает страницу проектов в порядке id и информацию о количестве записей
+func (s *MemoryStore) ListProjects(ctx context.Context, limit, offset int) ([]model.Project, int, int, error) {
+	s.mu.Lock()
+	all := make([]model.Project, 0, len(s.projects))
+	for _, p := range s.projects {
+		all = append(all, p)
+	}
+	s.mu.Unlock()
+	removed := 0
+	for _, p := range all {
+		if p.Removed {
+			removed++
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	if offset > len(all) {
+		offset = len(all)
+	}
+	page := all[offset:]
+	if limit < len(page) {
+		page = page[:limit]
+	}
+	var projects []model.Project
+	projects = append(projects, page...)
+	return projects, len(all), removed, nil
+}
+
+// ClaimPending закрепляет до limit неотправленных событий на время lease и возвращает их в порядке записи
+func (s *MemoryStore) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]model.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var messages []model.OutboxMessage
+	for _, m := range s.outbox {
+		if len(messages) == limit {
+			break
+		}
+		if until, ok := s.lockedUntil[m.ID]; ok && now.Before(until) {
+			continue
+		}
+		s.lockedUntil[m.ID] = now.Add(lease)
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// MarkSent удаляет опубликованное событие из outbox, чтобы память не росла
+func (s *MemoryStore) MarkSent(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeOutbox(id)
+	return nil
+}
+
+// MarkFailed увеличивает счётчик попыток публикации события; исчерпавшее maxAttempts попыток событие
+// переносится из outbox в список неотправляемых и возвращается true
+func (s *MemoryStore) MarkFailed(ctx context.Context, id int64, reason string, maxAttempts int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.outbox {
+		if s.outbox[i].ID != id {
+			continue
+		}
+		s.outbox[i].Attempts++
+		if maxAttempts > 0 && s.outbox[i].Attempts >= maxAttempts {
+			s.failedOutbox = append(s.failedOutbox, s.outbox[i])
+			s.removeOutbox(id)
+			return true, nil
+		}
+		return false, nil
+	}
+	return false, nil
+}
+
+// DeleteSent ничего не делает: отправленные события удаляются из памяти сразу в MarkSent
+func (s *MemoryStore) DeleteSent(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	return 0, nil
+}
+
+// removeOutbox удаляет событие из outbox вместе с его арендой; вызывается под s.mu
+func (s *MemoryStore) removeOutbox(id int64) {
+	delete(s.lockedUntil, id)
+	for i, m := range s.outbox {
+		if m.ID == id {
+			s.outbox = append(s.outbox[:i], s.outbox[i+1:]...)
+			return
+		}
+	}
+}
+
+// appendOutbox формирует конверт события и добавляет его в outbox; вызывается под s.mu до применения изменения,
+// поэтому событие записывается тогда и только тогда, когда записывается само изменение
+func (s *MemoryStore) appendOutbox(ctx context.Context, typ string, projectID, entityID int, previous, current interface{}) error {
+	event, err := model.NewEvent(ctx, typ, projectID, entityID, previous, current)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	msg := model.OutboxMessage{
+		ID:        s.nextOutboxID,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Payload:   payload,
+		CreatedAt: s.now(),
+	}
+	carrier := propagation.MapCarrier{}
+	tracing.Inject(ctx, carrier)
+	if len(carrier) > 0 {
+		msg.TraceContext = carrier
+	}
+	s.outbox = append(s.outbox, msg)
+	s.nextOutboxID++
+	return nil
+}
+
+// copyGood возвращает копию товара, не разделяющую описание с хранилищем
+func copyGood(g model.Good) *model.Good {
+	g.Description = copyString(g.Description)
+	return &g
+}
+
+// copyString копирует строку по указателю
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := *s
+	return &v
+}
@@ -0,0 +1,427 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"HezzlTestTask/internal/model"
+)
+
+// seedGoods создаёт в проекте по умолчанию товары с указанными именами
+func seedGoods(t *testing.T, s *MemoryStore, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if _, err := s.CreateGood(context.Background(), 1, name, nil); err != nil {
+			t.Fatalf("CreateGood(%s): %v", name, err)
+		}
+	}
+}
+
+// TestMemoryStore_CreateGood: приоритет max+1 в пределах проекта, внешний ключ на проект и событие в outbox
+func TestMemoryStore_CreateGood(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	desc := "описание"
+	g, err := s.CreateGood(ctx, 1, "first", &desc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if g.ID != 1 || g.Priority != 1 || g.Removed || *g.Description != desc {
+		t.Errorf("unexpected good: %+v", g)
+	}
+	// приоритет считается по всем товарам проекта, включая удалённые
+	if err := s.RemoveGood(ctx, 1, g.ID, 0); err != nil {
+		t.Fatalf("RemoveGood: %v", err)
+	}
+	p, _ := s.CreateProject(ctx, "second")
+	other, _ := s.CreateGood(ctx, p.ID, "other", nil)
+	next, _ := s.CreateGood(ctx, 1, "next", nil)
+	if other.Priority != 1 || next.Priority != 2 {
+		t.Errorf("expected priorities 1 and 2, got %d and %d", other.Priority, next.Priority)
+	}
+
+	if _, err := s.CreateGood(ctx, 99, "orphan", nil); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("expected ErrProjectNotFound, got %v", err)
+	}
+	if _, err := s.CreateGood(ctx, 1, "", nil); !errors.Is(err, ErrEmptyName) {
+		t.Errorf("expected ErrEmptyName, got %v", err)
+	}
+
+	pending, _ := s.ClaimPending(ctx, 10, 0)
+	// 3 good.created, good.removed и project.created
+	if len(pending) != 5 {
+		t.Fatalf("expected 5 outbox events, got %d", len(pending))
+	}
+	var e model.Event
+	if err := json.Unmarshal(pending[0].Payload, &e); err != nil {
+		t.Fatalf("unmarshal event: %v", err)
+	}
+	if e.Type != model.EventGoodCreated || e.EntityID != 1 || pending[0].EventID != e.ID {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
+
+// TestMemoryStore_GoodNotFound: товар другого проекта и несуществующий товар не находятся
+func TestMemoryStore_GoodNotFound(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	seedGoods(t, s, "a")
+	if _, err := s.GetGood(ctx, 2, 1); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.UpdateGood(ctx, 1, 9, "b", nil, 0); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := s.RemoveGood(ctx, 1, 9, 0); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.Reprioritize(ctx, 1, 9, 1, 0); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// TestMemoryStore_UpdateGood: обновление не разделяет описание с вызывающим и пишет состояние до и после
+func TestMemoryStore_UpdateGood(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	seedGoods(t, s, "a")
+	desc := "new"
+	if _, err := s.UpdateGood(ctx, 1, 1, "b", &desc, 0); err != nil {
+		t.Fatalf("UpdateGood: %v", err)
+	}
+	desc = "changed by caller"
+	g, _ := s.GetGood(ctx, 1, 1)
+	if g.Name != "b" || *g.Description != "new" {
+		t.Errorf("unexpected good: %+v", g)
+	}
+	pending, _ := s.ClaimPending(ctx, 10, 0)
+	var e model.Event
+	_ = json.Unmarshal(pending[1].Payload, &e)
+	var previous model.Good
+	_ = json.Unmarshal(e.Previous, &previous)
+	if e.Type != model.EventGoodUpdated || previous.Name != "a" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
+
+// TestMemoryStore_Version: каждое изменение увеличивает версию, устаревшая версия отклоняется без изменений
+func TestMemoryStore_Version(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	seedGoods(t, s, "a", "b")
+
+	g, _ := s.GetGood(ctx, 1, 1)
+	if g.Version != 1 {
+		t.Fatalf("expected version 1 after create, got %d", g.Version)
+	}
+	g, err := s.UpdateGood(ctx, 1, 1, "a2", nil, 1)
+	if err != nil || g.Version != 2 {
+		t.Fatalf("expected version 2 after update, got %d (%v)", g.Version, err)
+	}
+	if _, err := s.UpdateGood(ctx, 1, 1, "a3", nil, 1); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("expected ErrVersionMismatch, got %v", err)
+	}
+	if g, _ := s.GetGood(ctx, 1, 1); g.Name != "a2" {
+		t.Errorf("stale update must not change the good: %+v", g)
+	}
+
+	// перестановка увеличивает версию и перемещаемого товара, и сдвинутых соседей
+	if _, err := s.Reprioritize(ctx, 1, 2, 1, 2); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("expected ErrVersionMismatch, got %v", err)
+	}
+	if _, err := s.Reprioritize(ctx, 1, 2, 1, 1); err != nil {
+		t.Fatalf("Reprioritize: %v", err)
+	}
+	a, _ := s.GetGood(ctx, 1, 1)
+	b, _ := s.GetGood(ctx, 1, 2)
+	if a.Version != 3 || b.Version != 2 {
+		t.Errorf("expected versions 3 and 2, got %d and %d", a.Version, b.Version)
+	}
+
+	if err := s.RemoveGood(ctx, 1, 2, 1); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("expected ErrVersionMismatch, got %v", err)
+	}
+	if err := s.RemoveGood(ctx, 1, 2, 2); err != nil {
+		t.Fatalf("RemoveGood: %v", err)
+	}
+}
+
+// TestMemoryStore_Bulk: пакетное создание с приоритетами подряд, атомарный и bestEffort режимы обновления и удаления
+func TestMemoryStore_Bulk(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	seedGoods(t, s, "a")
+
+	goods, err := s.CreateGoods(ctx, 1, []model.GoodInput{{Name: "b"}, {Name: "c"}})
+	if err != nil {
+		t.Fatalf("CreateGoods: %v", err)
+	}
+	if len(goods) != 2 || goods[0].ID != 2 || goods[0].Priority != 2 || goods[1].ID != 3 || goods[1].Priority != 3 {
+		t.Errorf("unexpected goods %+v", goods)
+	}
+	if _, err := s.CreateGoods(ctx, 99, []model.GoodInput{{Name: "x"}}); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("expected ErrProjectNotFound, got %v", err)
+	}
+
+	// атомарно: товар 9 не найден, товар 1 не изменяется
+	results, _ := s.UpdateGoods(ctx, 1, []model.GoodUpdate{{ID: 1, Name: "a2"}, {ID: 9, Name: "x"}}, true)
+	if results[0].Good != nil || results[0].Err != nil || !errors.Is(results[1].Err, ErrNotFound) {
+		t.Errorf("unexpected atomic results %+v", results)
+	}
+	if g, _ := s.GetGood(ctx, 1, 1); g.Name != "a" {
+		t.Errorf("atomic batch must not change goods: %+v", g)
+	}
+
+	// bestEffort: устаревшая версия пропускается, остальные применяются
+	results, _ = s.UpdateGoods(ctx, 1, []model.GoodUpdate{{ID: 1, Name: "a2", Version: 1}, {ID: 2, Name: "b2", Version: 7}}, false)
+	if results[0].Good == nil || results[0].Good.Version != 2 || !errors.Is(results[1].Err, ErrVersionMismatch) {
+		t.Errorf("unexpected best effort results %+v", results)
+	}
+
+	results, _ = s.RemoveGoods(ctx, 1, []model.GoodRemoval{{ID: 2}, {ID: 2}, {ID: 3}}, false)
+	if results[0].Good == nil || !results[0].Good.Removed || !errors.Is(results[1].Err, ErrDuplicateItem) || results[2].Good == nil {
+		t.Errorf("unexpected remove results %+v", results)
+	}
+	if g, _ := s.GetGood(ctx, 1, 3); !g.Removed || g.Version != 2 {
+		t.Errorf("expected removed good with version 2: %+v", g)
+	}
+
+	// события: seed, 2 создания, 1 обновление, 2 удаления
+	pending, _ := s.ClaimPending(ctx, 10, 0)
+	if len(pending) != 6 {
+		t.Errorf("expected 6 outbox events, got %d", len(pending))
+	}
+}
+
+// TestMemoryStore_ArchivedProject: товары архивированного проекта не создаются и не изменяются, но удаляются
+func TestMemoryStore_ArchivedProject(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	seedGoods(t, s, "a", "b")
+	if err := s.RemoveProject(ctx, 1); err != nil {
+		t.Fatalf("RemoveProject: %v", err)
+	}
+	calls := map[string]func() error{
+		"CreateGood":   func() error { _, err := s.CreateGood(ctx, 1, "c", nil); return err },
+		"UpdateGood":   func() error { _, err := s.UpdateGood(ctx, 1, 1, "a2", nil, 0); return err },
+		"Reprioritize": func() error { _, err := s.Reprioritize(ctx, 1, 2, 1, 0); return err },
+		"CreateGoods":  func() error { _, err := s.CreateGoods(ctx, 1, []model.GoodInput{{Name: "c"}}); return err },
+		"UpdateGoods": func() error {
+			_, err := s.UpdateGoods(ctx, 1, []model.GoodUpdate{{ID: 1, Name: "a2"}}, false)
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, ErrProjectArchived) {
+			t.Errorf("%s: expected ErrProjectArchived, got %v", name, err)
+		}
+	}
+	if err := s.RemoveGood(ctx, 1, 1, 0); err != nil {
+		t.Errorf("RemoveGood: %v", err)
+	}
+	if pending, _ := s.ClaimPending(ctx, 10, 0); len(pending) != 4 {
+		t.Errorf("expected only create and remove events, got %d", len(pending))
+	}
+}
+
+// TestMemoryStore_Reprioritize: сдвиг соседей вверх и вниз как в GoodRepository
+func TestMemoryStore_Reprioritize(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	seedGoods(t, s, "a", "b", "c", "d")
+
+	// 4 -> 2: товары с приоритетами 2 и 3 сдвигаются на +1
+	updates, err := s.Reprioritize(ctx, 1, 4, 2, 0)
+	if err != nil {
+		t.Fatalf("Reprioritize: %v", err)
+	}
+	want := []model.PriorityUpdate{{ID: 2, Priority: 3}, {ID: 3, Priority: 4}, {ID: 4, Priority: 2}}
+	if !equalUpdates(updates, want) {
+		t.Errorf("expected %v, got %v", want, updates)
+	}
+
+	// 1 -> 3: товары с приоритетами 2 и 3 сдвигаются на -1
+	updates, _ = s.Reprioritize(ctx, 1, 1, 3, 0)
+	want = []model.PriorityUpdate{{ID: 2, Priority: 2}, {ID: 4, Priority: 1}, {ID: 1, Priority: 3}}
+	if !equalUpdates(updates, want) {
+		t.Errorf("expected %v, got %v", want, updates)
+	}
+
+	// без изменения приоритета обновляется только сам товар
+	updates, _ = s.Reprioritize(ctx, 1, 3, 4, 0)
+	if !equalUpdates(updates, []model.PriorityUpdate{{ID: 3, Priority: 4}}) {
+		t.Errorf("unexpected updates: %v", updates)
+	}
+
+	goods, _, _, _ := s.ListGoods(ctx, model.GoodsFilter{ProjectID: 1, Sort: model.SortByPriority, Limit: 10})
+	order := []int{4, 2, 1, 3}
+	for i, g := range goods {
+		if g.ID != order[i] || g.Priority != i+1 {
+			t.Errorf("position %d: unexpected good %+v", i, g)
+		}
+	}
+
+	pending, _ := s.ClaimPending(ctx, 10, 0)
+	var e model.Event
+	_ = json.Unmarshal(pending[4].Payload, &e)
+	var previous []model.PriorityUpdate
+	_ = json.Unmarshal(e.Previous, &previous)
+	wantPrevious := []model.PriorityUpdate{{ID: 2, Priority: 2}, {ID: 3, Priority: 3}, {ID: 4, Priority: 4}}
+	if e.Type != model.EventGoodReprioritized || !equalUpdates(previous, wantPrevious) {
+		t.Errorf("unexpected event: %s %v", e.Type, previous)
+	}
+}
+
+func equalUpdates(a, b []model.PriorityUpdate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestMemoryStore_ListGoods: фильтры, счётчики, сортировка, offset и keyset-пагинация
+func TestMemoryStore_ListGoods(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tick := 0
+	s.now = func() time.Time {
+		tick++
+		return base.Add(time.Duration(tick) * time.Hour)
+	}
+	seedGoods(t, s, "Apple", "banana", "Pineapple", "cherry")
+	_ = s.RemoveGood(ctx, 1, 2, 0)
+
+	goods, total, removed, _ := s.ListGoods(ctx, model.GoodsFilter{ProjectID: 1, Limit: 2, Offset: 1})
+	if total != 4 || removed != 1 || len(goods) != 2 || goods[0].ID != 2 || goods[1].ID != 3 {
+		t.Errorf("unexpected page: %v total=%d removed=%d", goods, total, removed)
+	}
+
+	goods, total, _, _ = s.ListGoods(ctx, model.GoodsFilter{Name: "APPLE", Removed: model.RemovedExclude, Sort: model.SortByName, Limit: 10})
+	if total != 2 || len(goods) != 2 || goods[0].Name != "Apple" || goods[1].Name != "Pineapple" {
+		t.Errorf("unexpected search result: %v", goods)
+	}
+
+	goods, _, _, _ = s.ListGoods(ctx, model.GoodsFilter{Removed: model.RemovedOnly, Limit: 10})
+	if len(goods) != 1 || goods[0].ID != 2 {
+		t.Errorf("expected only removed good, got %v", goods)
+	}
+
+	// полуинтервал [from, to) по created_at; часы сдвигаются и при записи события в outbox,
+	// поэтому товары созданы в base+1h, +3h, +5h и +7h
+	from, to := base.Add(3*time.Hour), base.Add(7*time.Hour)
+	goods, _, _, _ = s.ListGoods(ctx, model.GoodsFilter{CreatedFrom: &from, CreatedTo: &to, Sort: model.SortByCreatedAt, Desc: true, Limit: 10})
+	if len(goods) != 2 || goods[0].ID != 3 || goods[1].ID != 2 {
+		t.Errorf("unexpected created range result: %v", goods)
+	}
+
+	// keyset по (priority, id) в обратном порядке, счётчики отключены
+	goods, total, _, _ = s.ListGoods(ctx, model.GoodsFilter{
+		ProjectID: 1, Sort: model.SortByPriority, Desc: true, Limit: 2, Offset: 5,
+		After: &model.GoodsCursor{Priority: 3, ID: 3}, SkipCounts: true,
+	})
+	if total != 0 || len(goods) != 2 || goods[0].ID != 2 || goods[1].ID != 1 {
+		t.Errorf("unexpected keyset page: %v total=%d", goods, total)
+	}
+
+	// пустая выборка возвращает nil, как GoodRepository
+	goods, _, _, _ = s.ListGoods(ctx, model.GoodsFilter{ProjectID: 7, Limit: 10})
+	if goods != nil {
+		t.Errorf("expected nil slice, got %v", goods)
+	}
+}
+
+// TestMemoryStore_Projects: проект по умолчанию, CRUD и пагинация списка
+func TestMemoryStore_Projects(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	p, err := s.GetProject(ctx, 1)
+	if err != nil || p.Name != defaultProjectName {
+		t.Fatalf("expected default project, got %+v %v", p, err)
+	}
+	created, _ := s.CreateProject(ctx, "Каталог")
+	if created.ID != 2 {
+		t.Errorf("expected id 2, got %d", created.ID)
+	}
+	if _, err := s.CreateProject(ctx, ""); !errors.Is(err, ErrEmptyName) {
+		t.Errorf("expected ErrEmptyName, got %v", err)
+	}
+	updated, _ := s.UpdateProject(ctx, 2, "Склад")
+	if updated.Name != "Склад" {
+		t.Errorf("unexpected project: %+v", updated)
+	}
+	if _, err := s.UpdateProject(ctx, 9, "x"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := s.RemoveProject(ctx, 1); err != nil {
+		t.Fatalf("RemoveProject: %v", err)
+	}
+	if err := s.RemoveProject(ctx, 9); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	// события изменений проектов записываются в outbox, как и события товаров
+	pending, _ := s.ClaimPending(ctx, 10, 0)
+	var types []string
+	for _, m := range pending {
+		types = append(types, m.EventType)
+	}
+	if want := []string{model.EventProjectCreated, model.EventProjectUpdated, model.EventProjectRemoved}; !reflect.DeepEqual(types, want) {
+		t.Errorf("expected events %v, got %v", want, types)
+	}
+	projects, total, removed, _ := s.ListProjects(ctx, 1, 1)
+	if total != 2 || removed != 1 || len(projects) != 1 || projects[0].Name != "Склад" {
+		t.Errorf("unexpected list: %v total=%d removed=%d", projects, total, removed)
+	}
+	projects, _, _, _ = s.ListProjects(ctx, 10, 5)
+	if projects != nil {
+		t.Errorf("expected nil slice, got %v", projects)
+	}
+}
+
+// TestMemoryStore_Outbox: выборка по порядку с арендой, учёт попыток, неотправляемые и отправленные события
+func TestMemoryStore_Outbox(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+	seedGoods(t, s, "a", "b", "c")
+	pending, _ := s.ClaimPending(ctx, 2, time.Minute)
+	if len(pending) != 2 || pending[0].ID != 1 || pending[1].ID != 2 {
+		t.Fatalf("unexpected pending: %v", pending)
+	}
+	// закреплённые события не выбираются повторно до истечения аренды
+	if pending, _ = s.ClaimPending(ctx, 10, time.Minute); len(pending) != 1 || pending[0].ID != 3 {
+		t.Fatalf("expected only unclaimed message, got %v", pending)
+	}
+	if dead, err := s.MarkFailed(ctx, 1, "nats down", 2); dead || err != nil {
+		t.Fatalf("MarkFailed: %v, %v", dead, err)
+	}
+	if err := s.MarkSent(ctx, 2); err != nil {
+		t.Fatalf("MarkSent: %v", err)
+	}
+	now = now.Add(time.Minute)
+	pending, _ = s.ClaimPending(ctx, 10, time.Minute)
+	if len(pending) != 2 || pending[0].Attempts != 1 || pending[1].ID != 3 {
+		t.Errorf("unexpected pending after mark: %v", pending)
+	}
+	// событие, исчерпавшее попытки, больше не выбирается
+	if dead, err := s.MarkFailed(ctx, 1, "nats down", 2); !dead || err != nil {
+		t.Fatalf("expected message to be dead, got %v, %v", dead, err)
+	}
+	now = now.Add(time.Minute)
+	if pending, _ = s.ClaimPending(ctx, 10, time.Minute); len(pending) != 1 || pending[0].ID != 3 {
+		t.Errorf("unexpected pending after dead letter: %v", pending)
+	}
+	if len(s.failedOutbox) != 1 || s.failedOutbox[0].ID != 1 {
+		t.Errorf("unexpected failed messages %v", s.failedOutbox)
+	}
+}
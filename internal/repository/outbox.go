@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"HezzlTestTask/internal/model"
+	"HezzlTestTask/pkg/tracing"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// OutboxRepository реализует доступ к таблице outbox для relay-воркера
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+// NewOutboxRepository создает новый репозиторий outbox
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// ClaimPending закрепляет за вызывающим relay до limit неотправленных событий на время lease
+// и возвращает их в порядке записи. Строки, закреплённые конкурирующим запросом, пропускаются (SKIP LOCKED),
+// а после фиксации аренда (locked_until) исключает их из выборок других реплик до её истечения
+func (r *OutboxRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]model.OutboxMessage, error) {
+	query := `UPDATE outbox SET locked_until = now() + $2 * interval '1 millisecond'
+		WHERE id IN (SELECT id FROM outbox
+			WHERE sent_at IS NULL AND failed_at IS NULL AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED)
+		RETURNING id, event_id, event_type, payload, trace_context, attempts, created_at`
+	rows, err := r.db.QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending outbox messages: %w", err)
+	}
+	defer rows.Close()
+	var messages []model.OutboxMessage
+	for rows.Next() {
+		var m model.OutboxMessage
+		var traceContext []byte
+		if err := rows.Scan(&m.ID, &m.EventID, &m.EventType, &m.Payload, &traceContext, &m.Attempts, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		if len(traceContext) > 0 {
+			if err := json.Unmarshal(traceContext, &m.TraceContext); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal outbox trace context: %w", err)
+			}
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate outbox messages: %w", err)
+	}
+	// RETURNING не гарантирует порядок строк
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, nil
+}
+
+// MarkSent отмечает событие как успешно опубликованное
+func (r *OutboxRepository) MarkSent(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE outbox SET sent_at=now() WHERE id=$1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message sent: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed увеличивает счётчик попыток и сохраняет текст последней ошибки публикации
+// Когда попыток становится maxAttempts (0 — без ограничения), событие отмечается failed_at и возвращается true
+// Аренда не снимается: следующая попытка будет после её истечения
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int64, reason string, maxAttempts int) (bool, error) {
+	var dead bool
+	err := r.db.QueryRowContext(ctx, `UPDATE outbox SET attempts=attempts+1, last_error=$1,
+		failed_at = CASE WHEN $3::int > 0 AND attempts+1 >= $3::int THEN now() END
+		WHERE id=$2 RETURNING failed_at IS NOT NULL`, reason, id, maxAttempts).Scan(&dead)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark outbox message failed: %w", err)
+	}
+	return dead, nil
+}
+
+// DeleteSent удаляет до limit событий, отправленных раньше чем olderThan назад, и возвращает их число
+func (r *OutboxRepository) DeleteSent(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM outbox WHERE id IN (SELECT id FROM outbox
+		WHERE sent_at < now() - $1 * interval '1 millisecond' LIMIT $2)`, olderThan.Milliseconds(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sent outbox messages: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sent outbox messages: %w", err)
+	}
+	return n, nil
+}
+
+// insertOutboxEvent формирует конверт события и записывает его в outbox в рамках транзакции tx,
+// поэтому событие фиксируется тогда и только тогда, когда фиксируется само изменение
+// Вместе с событием сохраняется контекст трассировки из ctx, чтобы relay продолжил трассировку запроса
+func insertOutboxEvent(ctx context.Context, tx *sql.Tx, typ string, projectID, entityID int, previous, current interface{}) error {
+	event, err := model.NewEvent(ctx, typ, projectID, entityID, previous, current)
+	if err != nil {
+		return err
+	}
+	return insertOutboxEvents(ctx, tx, []model.Event{event})
+}
+
+// insertOutboxEvents записывает события в outbox в рамках транзакции tx многострочными INSERT
+// по maxRowsPerInsert событий; все события получают контекст трассировки из ctx
+func insertOutboxEvents(ctx context.Context, tx *sql.Tx, events []model.Event) error {
+	var traceContext interface{}
+	carrier := propagation.MapCarrier{}
+	tracing.Inject(ctx, carrier)
+	if len(carrier) > 0 {
+		data, err := json.Marshal(carrier)
+		if err != nil {
+			return fmt.Errorf("failed to marshal trace context: %w", err)
+		}
+		traceContext = string(data)
+	}
+	for len(events) > 0 {
+		chunk := events[:min(len(events), maxRowsPerInsert)]
+		events = events[len(chunk):]
+		args := make([]interface{}, 0, 4*len(chunk))
+		for _, event := range chunk {
+			payload, err := json.Marshal(event)
+			if err != nil {
+				return fmt.Errorf("failed to marshal event: %w", err)
+			}
+			// payload передаётся строкой: []byte драйвер lib/pq кодирует как bytea
+			args = append(args, event.ID, event.Type, string(payload), traceContext)
+		}
+		query := `INSERT INTO outbox(event_id, event_type, payload, trace_context) VALUES` + valuesPlaceholders(len(chunk), 4)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to insert outbox event: %w", err)
+		}
+	}
+	return nil
+}
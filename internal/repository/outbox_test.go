@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"HezzlTestTask/internal/model"
+)
+
+// TestClaimPending: события закрепляются арендой с SKIP LOCKED и возвращаются в порядке записи
+func TestClaimPending(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewOutboxRepository(db)
+	createdAt := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE outbox SET locked_until = now() + $2 * interval '1 millisecond'`)+
+		`.*`+regexp.QuoteMeta(`WHERE sent_at IS NULL AND failed_at IS NULL AND (locked_until IS NULL OR locked_until < now())`)+
+		`.*`+regexp.QuoteMeta(`ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED) RETURNING id, event_id, event_type, payload, trace_context, attempts, created_at`)).
+		WithArgs(2, int64(60000)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_id", "event_type", "payload", "trace_context", "attempts", "created_at"}).
+			AddRow(2, "e2", "good.updated", []byte(`{"id":"e2"}`), nil, 3, createdAt).
+			AddRow(1, "e1", "good.created", []byte(`{"id":"e1"}`), []byte(`{"traceparent":"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}`), 0, createdAt))
+
+	messages, err := repo.ClaimPending(context.Background(), 2, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(messages) != 2 || messages[0].ID != 1 || messages[1].EventType != "good.updated" || messages[1].Attempts != 3 {
+		t.Errorf("unexpected messages %+v", messages)
+	}
+	if string(messages[0].Payload) != `{"id":"e1"}` {
+		t.Errorf("unexpected payload %s", messages[0].Payload)
+	}
+	if messages[0].TraceContext["traceparent"] != "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01" || messages[1].TraceContext != nil {
+		t.Errorf("unexpected trace context %v, %v", messages[0].TraceContext, messages[1].TraceContext)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestClaimPending_QueryError: ошибка выборки прокидывается с контекстом
+func TestClaimPending_QueryError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewOutboxRepository(db)
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE outbox SET locked_until")).
+		WillReturnError(errors.New("timeout"))
+	_, err := repo.ClaimPending(context.Background(), 10, time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("expected query error, got %v", err)
+	}
+}
+
+// TestMarkSentAndFailed: отметка успешной публикации и неудачной попытки с ограничением числа попыток
+func TestMarkSentAndFailed(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewOutboxRepository(db)
+	markFailed := regexp.QuoteMeta("UPDATE outbox SET attempts=attempts+1, last_error=$1,") +
+		`\s+` + regexp.QuoteMeta("failed_at = CASE WHEN $3::int > 0 AND attempts+1 >= $3::int THEN now() END") +
+		`\s+` + regexp.QuoteMeta("WHERE id=$2 RETURNING failed_at IS NOT NULL")
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE outbox SET sent_at=now() WHERE id=$1")).
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(markFailed).
+		WithArgs("nats: no responders", int64(8), 5).
+		WillReturnRows(sqlmock.NewRows([]string{"dead"}).AddRow(false))
+	mock.ExpectQuery(markFailed).
+		WithArgs("nats: maximum payload exceeded", int64(8), 5).
+		WillReturnRows(sqlmock.NewRows([]string{"dead"}).AddRow(true))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE outbox SET sent_at=now() WHERE id=$1")).
+		WithArgs(int64(9)).
+		WillReturnError(errors.New("conn closed"))
+
+	if err := repo.MarkSent(context.Background(), 7); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if dead, err := repo.MarkFailed(context.Background(), 8, "nats: no responders", 5); dead || err != nil {
+		t.Errorf("unexpected result %v, %v", dead, err)
+	}
+	if dead, err := repo.MarkFailed(context.Background(), 8, "nats: maximum payload exceeded", 5); !dead || err != nil {
+		t.Errorf("expected dead message, got %v, %v", dead, err)
+	}
+	if err := repo.MarkSent(context.Background(), 9); err == nil || !strings.Contains(err.Error(), "conn closed") {
+		t.Errorf("expected exec error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestDeleteSent: удаление порции отправленных событий старше срока хранения
+func TestDeleteSent(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewOutboxRepository(db)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM outbox WHERE id IN (SELECT id FROM outbox")+
+		`\s+`+regexp.QuoteMeta("WHERE sent_at < now() - $1 * interval '1 millisecond' LIMIT $2)")).
+		WithArgs(int64(3600000), 1000).
+		WillReturnResult(sqlmock.NewResult(0, 42))
+	mock.ExpectExec("DELETE FROM outbox").WillReturnError(errors.New("conn closed"))
+
+	if n, err := repo.DeleteSent(context.Background(), time.Hour, 1000); n != 42 || err != nil {
+		t.Errorf("unexpected result %d, %v", n, err)
+	}
+	if _, err := repo.DeleteSent(context.Background(), time.Hour, 1000); err == nil || !strings.Contains(err.Error(), "conn closed") {
+		t.Errorf("expected exec error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// traceContextArg сопоставляет аргумент trace_context вставки в outbox с заголовком traceparent спана
+type traceContextArg struct {
+	traceID string
+}
+
+func (a traceContextArg) Match(v driver.Value) bool {
+	data, ok := v.(string)
+	if !ok {
+		return false
+	}
+	var carrier map[string]string
+	if err := json.Unmarshal([]byte(data), &carrier); err != nil {
+		return false
+	}
+	return strings.Contains(carrier["traceparent"], a.traceID)
+}
+
+// TestInsertOutboxEvent_TraceContext: контекст трассировки запроса сохраняется вместе с событием
+func TestInsertOutboxEvent_TraceContext(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	provider := sdktrace.NewTracerProvider()
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context)")).
+		WithArgs(sqlmock.AnyArg(), model.EventGoodCreated, eventArg{typ: model.EventGoodCreated},
+			traceContextArg{traceID: span.SpanContext().TraceID().String()}).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if err := insertOutboxEvent(ctx, tx, model.EventGoodCreated, 1, 10, nil, model.Good{ID: 10}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -5,25 +5,69 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"HezzlTestTask/internal/apperr"
 	"HezzlTestTask/internal/model"
+	"HezzlTestTask/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ErrNotFound возвращается при отсутствии записи
-var ErrNotFound = errors.New("record not found")
+var ErrNotFound = apperr.NotFound("record not found")
+
+// ErrProjectNotFound возвращается при изменении товаров несуществующего проекта
+var ErrProjectNotFound = apperr.NotFound("project not found")
+
+// ErrProjectArchived возвращается при создании и изменении товаров архивированного проекта;
+// товары такого проекта можно только читать и удалять
+var ErrProjectArchived = apperr.Conflict("project is archived")
 
 // ErrEmptyName возвращается при попытке создания или обновления с пустым именем
-var ErrEmptyName = &emptyNameError{}
+var ErrEmptyName = apperr.Invalid("name", "cannot be empty")
 
-type emptyNameError struct{}
+// ErrVersionMismatch возвращается, когда версия товара не совпадает с ожидаемой клиентом (If-Match)
+var ErrVersionMismatch = apperr.PreconditionFailed("good version mismatch")
 
-func (e *emptyNameError) Error() string {
-	return "name cannot be empty"
+// Режимы блокировки строки проекта в lockProject
+const (
+	// lockShare не даёт архивировать проект до конца транзакции, изменяющей его товары
+	lockShare = "FOR SHARE"
+	// lockUpdate дополнительно исключает параллельное создание товаров проекта (CreateGoods)
+	lockUpdate = "FOR UPDATE"
+)
+
+// startSpan начинает клиентский спан запроса к базе данных system
+func startSpan(ctx context.Context, name string, system attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(system))
 }
 
-func (e *emptyNameError) Is(target error) bool {
-	return target != nil && target.Error() == e.Error()
+// endSpan завершает спан запроса; отсутствие записи не считается ошибкой
+func endSpan(span trace.Span, err error) {
+	if errors.Is(err, apperr.ErrNotFound) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+// lockProject блокирует строку проекта в транзакции tx в режиме lock и проверяет, что проект существует
+// и не архивирован: архивирование (RemoveProject) ждёт завершения транзакции и не пропускает изменения товаров
+func lockProject(ctx context.Context, tx *sql.Tx, projectID int, lock string) error {
+	var removed bool
+	if err := tx.QueryRowContext(ctx, `SELECT removed FROM projects WHERE id=$1 `+lock, projectID).Scan(&removed); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrProjectNotFound
+		}
+		return fmt.Errorf("failed to lock project: %w", err)
+	}
+	if removed {
+		return ErrProjectArchived
+	}
+	return nil
 }
 
 // GoodRepository реализует доступ к таблице goods
@@ -36,23 +80,34 @@ func NewGoodRepository(db *sql.DB) *GoodRepository {
 	return &GoodRepository{db: db}
 }
 
-// CreateGood добавляет новый товар в таблицу goods
-func (r *GoodRepository) CreateGood(ctx context.Context, projectID int, name string, description *string) (*model.Good, error) {
+// CreateGood добавляет новый товар в таблицу goods и записывает событие good.created в outbox в той же транзакции
+// Товар создаётся только в существующем неархивированном проекте (lockProject)
+func (r *GoodRepository) CreateGood(ctx context.Context, projectID int, name string, description *string) (_ *model.Good, err error) {
+	ctx, span := startSpan(ctx, "GoodRepository.CreateGood", semconv.DBSystemPostgreSQL)
+	defer func() { endSpan(span, err) }()
 	if name == "" {
 		return nil, ErrEmptyName
 	}
-	// вставляем запись, priority, removed и created_at обрабатываются триггером и дефолтами в БД
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := lockProject(ctx, tx, projectID, lockShare); err != nil {
+		return nil, err
+	}
+	// вставляем запись, priority, removed, created_at и version обрабатываются триггером и дефолтами в БД
 	query := `INSERT INTO goods(project_id, name, description) VALUES($1, $2, $3)
-		RETURNING id, priority, removed, created_at`
-	var id, priority int
+		RETURNING id, priority, removed, created_at, version`
+	var id, priority, version int
 	var removed bool
 	var createdAt time.Time
-	err := r.db.QueryRowContext(ctx, query, projectID, name, description).
-		Scan(&id, &priority, &removed, &createdAt)
+	err = tx.QueryRowContext(ctx, query, projectID, name, description).
+		Scan(&id, &priority, &removed, &createdAt, &version)
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert good: %w", err)
 	}
-	return &model.Good{
+	good := &model.Good{
 		ID:          id,
 		ProjectID:   projectID,
 		Name:        name,
@@ -60,16 +115,26 @@ func (r *GoodRepository) CreateGood(ctx context.Context, projectID int, name str
 		Priority:    priority,
 		Removed:     removed,
 		CreatedAt:   createdAt,
-	}, nil
+		Version:     version,
+	}
+	if err := insertOutboxEvent(ctx, tx, model.EventGoodCreated, projectID, id, nil, good); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return good, nil
 }
 
 // GetGood возвращает товар по id и projectID
-func (r *GoodRepository) GetGood(ctx context.Context, projectID, id int) (*model.Good, error) {
-	query := `SELECT id, project_id, name, description, priority, removed, created_at
+func (r *GoodRepository) GetGood(ctx context.Context, projectID, id int) (_ *model.Good, err error) {
+	ctx, span := startSpan(ctx, "GoodRepository.GetGood", semconv.DBSystemPostgreSQL)
+	defer func() { endSpan(span, err) }()
+	query := `SELECT id, project_id, name, description, priority, removed, created_at, version
 		FROM goods WHERE id=$1 AND project_id=$2`
 	row := r.db.QueryRowContext(ctx, query, id, projectID)
 	var g model.Good
-	err := row.Scan(&g.ID, &g.ProjectID, &g.Name, &g.Description, &g.Priority, &g.Removed, &g.CreatedAt)
+	err = row.Scan(&g.ID, &g.ProjectID, &g.Name, &g.Description, &g.Priority, &g.Removed, &g.CreatedAt, &g.Version)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
@@ -79,8 +144,13 @@ func (r *GoodRepository) GetGood(ctx context.Context, projectID, id int) (*model
 	return &g, nil
 }
 
-// UpdateGood обновляет поля name и description товара, с блокировкой и транзакцией
-func (r *GoodRepository) UpdateGood(ctx context.Context, projectID, id int, name string, description *string) (*model.Good, error) {
+// UpdateGood обновляет поля name и description товара, с блокировкой и транзакцией, и увеличивает его версию
+// Если version не 0 и не совпадает с текущей версией товара, возвращается ErrVersionMismatch
+// В той же транзакции в outbox записывается событие good.updated с состоянием до и после;
+// товары архивированного проекта не изменяются (ErrProjectArchived)
+func (r *GoodRepository) UpdateGood(ctx context.Context, projectID, id int, name string, description *string, version int) (_ *model.Good, err error) {
+	ctx, span := startSpan(ctx, "GoodRepository.UpdateGood", semconv.DBSystemPostgreSQL)
+	defer func() { endSpan(span, err) }()
 	if name == "" {
 		return nil, ErrEmptyName
 	}
@@ -89,73 +159,127 @@ func (r *GoodRepository) UpdateGood(ctx context.Context, projectID, id int, name
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
+	if err := lockProject(ctx, tx, projectID, lockShare); err != nil {
+		return nil, err
+	}
 	// выборка с блокировкой
-	selectQuery := `SELECT id, project_id, name, description, priority, removed, created_at
+	selectQuery := `SELECT id, project_id, name, description, priority, removed, created_at, version
 		FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE`
 	row := tx.QueryRowContext(ctx, selectQuery, id, projectID)
 	var g model.Good
-	err = row.Scan(&g.ID, &g.ProjectID, &g.Name, &g.Description, &g.Priority, &g.Removed, &g.CreatedAt)
+	err = row.Scan(&g.ID, &g.ProjectID, &g.Name, &g.Description, &g.Priority, &g.Removed, &g.CreatedAt, &g.Version)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to select good for update: %w", err)
 	}
+	if version != 0 && version != g.Version {
+		return nil, ErrVersionMismatch
+	}
 	// обновление полей
-	updateQuery := `UPDATE goods SET name=$1, description=$2 WHERE id=$3 AND project_id=$4`
+	updateQuery := `UPDATE goods SET name=$1, description=$2, version=version+1 WHERE id=$3 AND project_id=$4`
 	_, err = tx.ExecContext(ctx, updateQuery, name, description, id, projectID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update good: %w", err)
 	}
+	previous := g
+	g.Name = name
+	g.Description = description
+	g.Version++
+	if err := insertOutboxEvent(ctx, tx, model.EventGoodUpdated, projectID, id, previous, g); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	// возвращаем обновленную запись
-	g.Name = name
-	g.Description = description
 	return &g, nil
 }
 
-// RemoveGood устанавливает removed=true для записи товара с блокировкой и транзакцией
-func (r *GoodRepository) RemoveGood(ctx context.Context, projectID, id int) error {
+// RemoveGood устанавливает removed=true для записи товара с блокировкой и транзакцией и увеличивает его версию
+// Если version не 0 и не совпадает с текущей версией товара, возвращается ErrVersionMismatch
+// В той же транзакции в outbox записывается событие good.removed с состоянием до и после
+func (r *GoodRepository) RemoveGood(ctx context.Context, projectID, id, version int) (err error) {
+	ctx, span := startSpan(ctx, "GoodRepository.RemoveGood", semconv.DBSystemPostgreSQL)
+	defer func() { endSpan(span, err) }()
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
-	// проверка существования с блокировкой
-	selectQuery := `SELECT id FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE`
+	// выборка с блокировкой
+	selectQuery := `SELECT id, project_id, name, description, priority, removed, created_at, version
+		FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE`
 	row := tx.QueryRowContext(ctx, selectQuery, id, projectID)
-	var existingID int
-	if err := row.Scan(&existingID); err != nil {
+	var g model.Good
+	err = row.Scan(&g.ID, &g.ProjectID, &g.Name, &g.Description, &g.Priority, &g.Removed, &g.CreatedAt, &g.Version)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return ErrNotFound
 		}
 		return fmt.Errorf("failed to select good for remove: %w", err)
 	}
+	if version != 0 && version != g.Version {
+		return ErrVersionMismatch
+	}
 	// установка removed
-	_, err = tx.ExecContext(ctx, `UPDATE goods SET removed=true WHERE id=$1 AND project_id=$2`, id, projectID)
+	_, err = tx.ExecContext(ctx, `UPDATE goods SET removed=true, version=version+1 WHERE id=$1 AND project_id=$2`, id, projectID)
 	if err != nil {
 		return fmt.Errorf("failed to remove good: %w", err)
 	}
+	removed := g
+	removed.Removed = true
+	removed.Version++
+	if err := insertOutboxEvent(ctx, tx, model.EventGoodRemoved, projectID, id, g, removed); err != nil {
+		return err
+	}
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	return nil
 }
 
-// ListGoods возвращает список товаров с пагинацией и информацию о количестве записей
-func (r *GoodRepository) ListGoods(ctx context.Context, limit, offset int) ([]model.Good, int, int, error) {
-	// получаем общее число записей и число удаленных
+// goodsSortColumns сопоставляет поля сортировки API со столбцами таблицы goods
+var goodsSortColumns = map[string]string{
+	model.SortByID:        "id",
+	model.SortByPriority:  "priority",
+	model.SortByCreatedAt: "created_at",
+	model.SortByName:      "name",
+}
+
+// ListGoods возвращает список товаров по фильтру с пагинацией и информацию о количестве записей
+// total и removed считаются для тех же условий фильтра, что и сама выборка (без учёта курсора);
+// при filter.SkipCounts подсчёт не выполняется и возвращаются нули
+// при filter.After страница выбирается по ключу (priority, id) или (id) вместо OFFSET
+func (r *GoodRepository) ListGoods(ctx context.Context, filter model.GoodsFilter) (_ []model.Good, _, _ int, err error) {
+	ctx, span := startSpan(ctx, "GoodRepository.ListGoods", semconv.DBSystemPostgreSQL)
+	defer func() { endSpan(span, err) }()
+	where, args := goodsWhere(filter)
+	// получаем общее число записей и число удаленных одним запросом
 	var total, removed int
-	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM goods`).Scan(&total); err != nil {
-		return nil, 0, 0, fmt.Errorf("failed to count goods: %w", err)
+	if !filter.SkipCounts {
+		countQuery := `SELECT COUNT(*), COUNT(*) FILTER (WHERE removed) FROM goods` + where
+		if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total, &removed); err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to count goods: %w", err)
+		}
 	}
-	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM goods WHERE removed=true`).Scan(&removed); err != nil {
-		return nil, 0, 0, fmt.Errorf("failed to count removed goods: %w", err)
+	// условие keyset-пагинации добавляется только к выборке страницы
+	offset := filter.Offset
+	if filter.After != nil {
+		var cond string
+		cond, args = goodsKeyset(filter, args)
+		if where == "" {
+			where = " WHERE " + cond
+		} else {
+			where += " AND " + cond
+		}
+		offset = 0
 	}
-	// получаем список с пагинацией
-	rows, err := r.db.QueryContext(ctx, `SELECT id, project_id, name, description, priority, removed, created_at FROM goods ORDER BY id LIMIT $1 OFFSET $2`, limit, offset)
+	// получаем список с сортировкой и пагинацией
+	query := fmt.Sprintf(`SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods%s ORDER BY %s LIMIT $%d OFFSET $%d`,
+		where, goodsOrderBy(filter), len(args)+1, len(args)+2)
+	rows, err := r.db.QueryContext(ctx, query, append(args, filter.Limit, offset)...)
 	if err != nil {
 		return nil, 0, 0, fmt.Errorf("failed to select goods list: %w", err)
 	}
@@ -163,69 +287,185 @@ func (r *GoodRepository) ListGoods(ctx context.Context, limit, offset int) ([]mo
 	var goods []model.Good
 	for rows.Next() {
 		var g model.Good
-		if err := rows.Scan(&g.ID, &g.ProjectID, &g.Name, &g.Description, &g.Priority, &g.Removed, &g.CreatedAt); err != nil {
+		if err := rows.Scan(&g.ID, &g.ProjectID, &g.Name, &g.Description, &g.Priority, &g.Removed, &g.CreatedAt, &g.Version); err != nil {
 			return nil, 0, 0, fmt.Errorf("failed to scan good: %w", err)
 		}
 		goods = append(goods, g)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to iterate goods: %w", err)
+	}
 	return goods, total, removed, nil
 }
 
-// Reprioritize изменяет приоритет товара и сдвигает приоритеты других записей
-func (r *GoodRepository) Reprioritize(ctx context.Context, projectID, id, newPriority int) ([]model.PriorityUpdate, error) {
+// goodsWhere строит условие WHERE и позиционные аргументы для фильтра списка товаров
+func goodsWhere(filter model.GoodsFilter) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.ProjectID > 0 {
+		add("project_id=$%d", filter.ProjectID)
+	}
+	switch filter.Removed {
+	case model.RemovedExclude:
+		conds = append(conds, "removed=false")
+	case model.RemovedOnly:
+		conds = append(conds, "removed=true")
+	}
+	if filter.Name != "" {
+		add(`name ILIKE '%%' || $%d || '%%'`, escapeLike(filter.Name))
+	}
+	if filter.CreatedFrom != nil {
+		add("created_at >= $%d", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		add("created_at < $%d", *filter.CreatedTo)
+	}
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// goodsKeyset строит условие keyset-пагинации для filter.After и дополняет позиционные аргументы
+// Направление сравнения совпадает с направлением сортировки
+func goodsKeyset(filter model.GoodsFilter, args []interface{}) (string, []interface{}) {
+	op := ">"
+	if filter.Desc {
+		op = "<"
+	}
+	if filter.Sort == model.SortByPriority {
+		args = append(args, filter.After.Priority, filter.After.ID)
+		return fmt.Sprintf("(priority, id) %s ($%d, $%d)", op, len(args)-1, len(args)), args
+	}
+	args = append(args, filter.After.ID)
+	return fmt.Sprintf("id %s $%d", op, len(args)), args
+}
+
+// goodsOrderBy возвращает выражение ORDER BY для фильтра; id добавляется для стабильного порядка
+func goodsOrderBy(filter model.GoodsFilter) string {
+	column, ok := goodsSortColumns[filter.Sort]
+	if !ok {
+		column = "id"
+	}
+	dir := "ASC"
+	if filter.Desc {
+		dir = "DESC"
+	}
+	if column == "id" {
+		return "id " + dir
+	}
+	return fmt.Sprintf("%s %s, id %s", column, dir, dir)
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE, чтобы поиск шёл по буквальной подстроке
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Reprioritize изменяет приоритет товара и сдвигает приоритеты других записей; версии всех затронутых товаров
+// увеличиваются. Если version не 0 и не совпадает с текущей версией товара, возвращается ErrVersionMismatch
+// В той же транзакции в outbox записывается событие good.reprioritized с приоритетами до и после;
+// приоритеты товаров архивированного проекта не изменяются (ErrProjectArchived)
+func (r *GoodRepository) Reprioritize(ctx context.Context, projectID, id, newPriority, version int) (_ []model.PriorityUpdate, err error) {
+	ctx, span := startSpan(ctx, "GoodRepository.Reprioritize", semconv.DBSystemPostgreSQL)
+	defer func() { endSpan(span, err) }()
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
-	// получаем текущий приоритет с блокировкой
-	var currPriority int
-	row := tx.QueryRowContext(ctx, `SELECT priority FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE`, id, projectID)
-	if err := row.Scan(&currPriority); err != nil {
+	if err := lockProject(ctx, tx, projectID, lockShare); err != nil {
+		return nil, err
+	}
+	// получаем текущий приоритет и версию с блокировкой
+	var currPriority, currVersion int
+	row := tx.QueryRowContext(ctx, `SELECT priority, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE`, id, projectID)
+	if err := row.Scan(&currPriority, &currVersion); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to select good for reprioritize: %w", err)
 	}
+	if version != 0 && version != currVersion {
+		return nil, ErrVersionMismatch
+	}
 	var updates []model.PriorityUpdate
 	// сдвигаем приоритеты в зависимости от нового значения
 	if newPriority < currPriority {
 		// сдвигаем +1 для тех, чей priority в [newPriority, currPriority)
-		rows, err := tx.QueryContext(ctx, `UPDATE goods SET priority = priority + 1 WHERE project_id=$1 AND priority >= $2 AND priority < $3 RETURNING id, priority`, projectID, newPriority, currPriority)
+		updates, err = shiftPriorities(ctx, tx, `UPDATE goods SET priority = priority + 1, version = version + 1 WHERE project_id=$1 AND priority >= $2 AND priority < $3 RETURNING id, priority`, projectID, newPriority, currPriority)
 		if err != nil {
 			return nil, fmt.Errorf("failed to shift priorities up: %w", err)
 		}
-		defer rows.Close()
-		for rows.Next() {
-			var pu model.PriorityUpdate
-			if err := rows.Scan(&pu.ID, &pu.Priority); err != nil {
-				return nil, fmt.Errorf("failed to scan shifted priority: %w", err)
-			}
-			updates = append(updates, pu)
-		}
 	} else if newPriority > currPriority {
 		// сдвигаем -1 для тех, чей priority в (currPriority, newPriority]
-		rows, err := tx.QueryContext(ctx, `UPDATE goods SET priority = priority - 1 WHERE project_id=$1 AND priority > $2 AND priority <= $3 RETURNING id, priority`, projectID, currPriority, newPriority)
+		updates, err = shiftPriorities(ctx, tx, `UPDATE goods SET priority = priority - 1, version = version + 1 WHERE project_id=$1 AND priority > $2 AND priority <= $3 RETURNING id, priority`, projectID, currPriority, newPriority)
 		if err != nil {
 			return nil, fmt.Errorf("failed to shift priorities down: %w", err)
 		}
-		defer rows.Close()
-		for rows.Next() {
-			var pu model.PriorityUpdate
-			if err := rows.Scan(&pu.ID, &pu.Priority); err != nil {
-				return nil, fmt.Errorf("failed to scan shifted priority: %w", err)
-			}
-			updates = append(updates, pu)
-		}
 	}
 	// обновляем приоритет текущего товара
-	_, err = tx.ExecContext(ctx, `UPDATE goods SET priority=$1 WHERE id=$2 AND project_id=$3`, newPriority, id, projectID)
+	_, err = tx.ExecContext(ctx, `UPDATE goods SET priority=$1, version=version+1 WHERE id=$2 AND project_id=$3`, newPriority, id, projectID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update priority of good: %w", err)
 	}
 	updates = append(updates, model.PriorityUpdate{ID: id, Priority: newPriority})
+	previous := previousPriorities(updates, id, currPriority, newPriority)
+	if err := insertOutboxEvent(ctx, tx, model.EventGoodReprioritized, projectID, id, previous, updates); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	return updates, nil
 }
+
+// shiftPriorities выполняет сдвиг приоритетов запросом UPDATE ... RETURNING id, priority и возвращает новые приоритеты
+// Курсор закрывается до возврата, а не отложенно: следующие запросы транзакции не должны выполняться при открытом курсоре
+func shiftPriorities(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]model.PriorityUpdate, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var updates []model.PriorityUpdate
+	for rows.Next() {
+		var pu model.PriorityUpdate
+		if err := rows.Scan(&pu.ID, &pu.Priority); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan shifted priority: %w", err)
+		}
+		updates = append(updates, pu)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("failed to iterate shifted priorities: %w", err)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close shifted priorities: %w", err)
+	}
+	return updates, nil
+}
+
+// previousPriorities восстанавливает приоритеты затронутых товаров до перестановки:
+// целевой товар имел приоритет oldPriority, остальные были сдвинуты на единицу навстречу ему
+func previousPriorities(updates []model.PriorityUpdate, id, oldPriority, newPriority int) []model.PriorityUpdate {
+	shift := 0
+	if newPriority < oldPriority {
+		shift = -1
+	} else if newPriority > oldPriority {
+		shift = 1
+	}
+	previous := make([]model.PriorityUpdate, 0, len(updates))
+	for _, u := range updates {
+		if u.ID == id {
+			previous = append(previous, model.PriorityUpdate{ID: u.ID, Priority: oldPriority})
+			continue
+		}
+		previous = append(previous, model.PriorityUpdate{ID: u.ID, Priority: u.Priority + shift})
+	}
+	return previous
+}
@@ -4,15 +4,43 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+
+	"HezzlTestTask/internal/apperr"
+	"HezzlTestTask/internal/model"
 )
 
+// eventArg сопоставляет аргумент payload вставки в outbox: конверт события нужного типа,
+// дополнительно проверяемый функцией check
+type eventArg struct {
+	typ   string
+	check func(e model.Event) bool
+}
+
+func (a eventArg) Match(v driver.Value) bool {
+	data, ok := v.(string)
+	if !ok {
+		return false
+	}
+	var e model.Event
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return false
+	}
+	if e.Type != a.typ || e.Version != model.EventVersion || e.ID == "" {
+		return false
+	}
+	return a.check == nil || a.check(e)
+}
+
 // Тест создания товара: проверяем успешную вставку и автогенерацию полей через RETURNING
 func TestCreateGood(t *testing.T) {
 	db, mock, err := sqlmock.New()
@@ -23,11 +51,17 @@ func TestCreateGood(t *testing.T) {
 	repo := NewGoodRepository(db)
 	ctx := context.Background()
 
-	// успешный сценарий
+	// успешный сценарий: INSERT товара и события good.created в одной транзакции
+	mock.ExpectBegin()
+	expectProjectLock(mock, 1, lockShare, false)
 	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO goods(project_id, name, description)")).
 		WithArgs(1, "Название", sqlmock.AnyArg()).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "priority", "removed", "created_at"}).
-			AddRow(10, 1, false, time.Now()))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "priority", "removed", "created_at", "version"}).
+			AddRow(10, 1, false, time.Now(), 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context)")).
+		WithArgs(sqlmock.AnyArg(), model.EventGoodCreated, eventArg{typ: model.EventGoodCreated}, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	good, err := repo.CreateGood(ctx, 1, "Название", nil)
 	if err != nil {
@@ -39,7 +73,7 @@ func TestCreateGood(t *testing.T) {
 
 	// ошибка при пустом имени
 	_, err = repo.CreateGood(ctx, 1, "", nil)
-	if !errors.Is(err, errors.New("name cannot be empty")) {
+	if !errors.Is(err, ErrEmptyName) || !errors.Is(err, apperr.ErrValidation) {
 		t.Error("expected name empty error")
 	}
 
@@ -55,9 +89,12 @@ func TestCreateGood_InsertError(t *testing.T) {
 	repo := NewGoodRepository(db)
 	ctx := context.Background()
 	mockErr := errors.New("insert failed")
+	mock.ExpectBegin()
+	expectProjectLock(mock, 1, lockShare, false)
 	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO goods(project_id, name, description)")).
 		WithArgs(1, "Name", sqlmock.AnyArg()).
 		WillReturnError(mockErr)
+	mock.ExpectRollback()
 	_, err := repo.CreateGood(ctx, 1, "Name", nil)
 	if err == nil || !strings.Contains(err.Error(), mockErr.Error()) {
 		t.Errorf("expected insert error, got %v", err)
@@ -67,6 +104,66 @@ func TestCreateGood_InsertError(t *testing.T) {
 	}
 }
 
+// TestCreateGood_MissingProject проверяет, что товар не создаётся в несуществующем проекте
+func TestCreateGood_MissingProject(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT removed FROM projects WHERE id=$1 FOR SHARE")).
+		WithArgs(99).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	_, err := repo.CreateGood(context.Background(), 99, "Name", nil)
+	if !errors.Is(err, ErrProjectNotFound) || !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("expected ErrProjectNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestArchivedProject: товары архивированного проекта не создаются и не изменяются, транзакция откатывается
+func TestArchivedProject(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+	ctx := context.Background()
+	calls := map[string]func() error{
+		"CreateGood":   func() error { _, err := repo.CreateGood(ctx, 7, "Name", nil); return err },
+		"UpdateGood":   func() error { _, err := repo.UpdateGood(ctx, 7, 1, "Name", nil, 0); return err },
+		"Reprioritize": func() error { _, err := repo.Reprioritize(ctx, 7, 1, 2, 0); return err },
+		"UpdateGoods": func() error {
+			_, err := repo.UpdateGoods(ctx, 7, []model.GoodUpdate{{ID: 1, Name: "Name"}}, false)
+			return err
+		},
+	}
+	for name, call := range calls {
+		mock.ExpectBegin()
+		expectProjectLock(mock, 7, lockShare, true)
+		mock.ExpectRollback()
+		if err := call(); !errors.Is(err, ErrProjectArchived) || !errors.Is(err, apperr.ErrConflict) {
+			t.Errorf("%s: expected ErrProjectArchived, got %v", name, err)
+		}
+	}
+	mock.ExpectBegin()
+	expectProjectLock(mock, 7, lockUpdate, true)
+	mock.ExpectRollback()
+	if _, err := repo.CreateGoods(ctx, 7, []model.GoodInput{{Name: "Name"}}); !errors.Is(err, ErrProjectArchived) {
+		t.Errorf("CreateGoods: expected ErrProjectArchived, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// expectProjectLock ожидает блокировку строки проекта в режиме lock; removed — признак архивирования проекта
+func expectProjectLock(mock sqlmock.Sqlmock, projectID int, lock string, removed bool) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT removed FROM projects WHERE id=$1 " + lock)).
+		WithArgs(projectID).
+		WillReturnRows(sqlmock.NewRows([]string{"removed"}).AddRow(removed))
+}
+
 // Тест получения товара по идентификатору:
 // 1) Успешное чтение данных из БД
 // 2) Обработка случая, когда запись не найдена (ErrNotFound)
@@ -78,10 +175,10 @@ func TestGetGood(t *testing.T) {
 
 	// успешный сценарий
 	createdAt := time.Now()
-	columns := []string{"id", "project_id", "name", "description", "priority", "removed", "created_at"}
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at FROM goods WHERE id=$1 AND project_id=$2")).
+	columns := []string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "version"}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods WHERE id=$1 AND project_id=$2")).
 		WithArgs(1, 2).
-		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 2, "Name", "Desc", 3, false, createdAt))
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 2, "Name", "Desc", 3, false, createdAt, 1))
 
 	good, err := repo.GetGood(ctx, 2, 1)
 	if err != nil {
@@ -92,7 +189,7 @@ func TestGetGood(t *testing.T) {
 	}
 
 	// не найдено
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at FROM goods WHERE id=$1 AND project_id=$2")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods WHERE id=$1 AND project_id=$2")).
 		WithArgs(3, 4).
 		WillReturnError(sql.ErrNoRows)
 
@@ -113,7 +210,7 @@ func TestGetGood_QueryError(t *testing.T) {
 	repo := NewGoodRepository(db)
 	ctx := context.Background()
 	mockErr := errors.New("timeout")
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at FROM goods WHERE id=$1 AND project_id=$2")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods WHERE id=$1 AND project_id=$2")).
 		WithArgs(2, 1).
 		WillReturnError(mockErr)
 	_, err := repo.GetGood(ctx, 1, 2)
@@ -123,7 +220,7 @@ func TestGetGood_QueryError(t *testing.T) {
 }
 
 // Тест обновления товара (UpdateGood):
-// 1) Успешный сценарий: SELECT FOR UPDATE + UPDATE + COMMIT
+// 1) Успешный сценарий: SELECT FOR UPDATE + UPDATE + INSERT outbox + COMMIT
 // 2) Обработка пустого имени (валидация)
 // 3) Обработка отсутствия записи (ErrNotFound)
 func TestUpdateGood(t *testing.T) {
@@ -134,16 +231,25 @@ func TestUpdateGood(t *testing.T) {
 
 	// успешный сценарий
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
+	expectProjectLock(mock, 1, lockShare, false)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
 		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "description", "priority", "removed", "created_at"}).
-			AddRow(1, 1, "Old", "OldDesc", 2, false, time.Now()))
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET name=$1, description=$2 WHERE id=$3 AND project_id=$4")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "version"}).
+			AddRow(1, 1, "Old", "OldDesc", 2, false, time.Now(), 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET name=$1, description=$2, version=version+1 WHERE id=$3 AND project_id=$4")).
 		WithArgs("New", "NewDesc", 1, 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context)")).
+		WithArgs(sqlmock.AnyArg(), model.EventGoodUpdated, eventArg{typ: model.EventGoodUpdated, check: func(e model.Event) bool {
+			var before, after model.Good
+			_ = json.Unmarshal(e.Previous, &before)
+			_ = json.Unmarshal(e.Current, &after)
+			return before.Name == "Old" && after.Name == "New" && e.EntityID == 1
+		}}, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	good, err := repo.UpdateGood(ctx, 1, 1, "New", ptr("NewDesc"))
+	good, err := repo.UpdateGood(ctx, 1, 1, "New", ptr("NewDesc"), 0)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -152,18 +258,19 @@ func TestUpdateGood(t *testing.T) {
 	}
 
 	// пустое имя
-	_, err = repo.UpdateGood(ctx, 1, 1, "", ptr("d"))
-	if !errors.Is(err, errors.New("name cannot be empty")) {
+	_, err = repo.UpdateGood(ctx, 1, 1, "", ptr("d"), 0)
+	if !errors.Is(err, ErrEmptyName) || !errors.Is(err, apperr.ErrValidation) {
 		t.Error("expected empty name error")
 	}
 
 	// not found
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
+	expectProjectLock(mock, 2, lockShare, false)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
 		WithArgs(2, 2).
 		WillReturnError(sql.ErrNoRows)
 
-	_, err = repo.UpdateGood(ctx, 2, 2, "N", nil)
+	_, err = repo.UpdateGood(ctx, 2, 2, "N", nil, 0)
 	if !errors.Is(err, ErrNotFound) {
 		t.Error("expected ErrNotFound")
 	}
@@ -180,15 +287,16 @@ func TestUpdateGood_ExecError(t *testing.T) {
 	repo := NewGoodRepository(db)
 	ctx := context.Background()
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
+	expectProjectLock(mock, 1, lockShare, false)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
 		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "description", "priority", "removed", "created_at"}).
-			AddRow(1, 1, "Old", nil, 2, false, time.Now()))
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET name=$1, description=$2 WHERE id=$3 AND project_id=$4")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "version"}).
+			AddRow(1, 1, "Old", nil, 2, false, time.Now(), 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET name=$1, description=$2, version=version+1 WHERE id=$3 AND project_id=$4")).
 		WithArgs("New", nil, 1, 1).
 		WillReturnError(errors.New("exec failed"))
 	mock.ExpectRollback()
-	_, err := repo.UpdateGood(ctx, 1, 1, "New", nil)
+	_, err := repo.UpdateGood(ctx, 1, 1, "New", nil, 0)
 	if err == nil || !strings.Contains(err.Error(), "exec failed") {
 		t.Errorf("expected exec error, got %v", err)
 	}
@@ -201,22 +309,67 @@ func TestUpdateGood_CommitError(t *testing.T) {
 	repo := NewGoodRepository(db)
 	ctx := context.Background()
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
+	expectProjectLock(mock, 1, lockShare, false)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
 		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "description", "priority", "removed", "created_at"}).
-			AddRow(1, 1, "Old", "Desc", 2, false, time.Now()))
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET name=$1, description=$2 WHERE id=$3 AND project_id=$4")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "version"}).
+			AddRow(1, 1, "Old", "Desc", 2, false, time.Now(), 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET name=$1, description=$2, version=version+1 WHERE id=$3 AND project_id=$4")).
 		WithArgs("New", nil, 1, 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context)")).
+		WithArgs(sqlmock.AnyArg(), model.EventGoodUpdated, eventArg{typ: model.EventGoodUpdated}, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit().WillReturnError(errors.New("commit failed"))
-	_, err := repo.UpdateGood(ctx, 1, 1, "New", nil)
+	_, err := repo.UpdateGood(ctx, 1, 1, "New", nil, 0)
 	if err == nil || !strings.Contains(err.Error(), "commit failed") {
 		t.Errorf("expected commit error, got %v", err)
 	}
 }
 
+// TestVersionMismatch: устаревшая версия отклоняется после блокировки строки, изменения откатываются
+func TestVersionMismatch(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+	ctx := context.Background()
+	selectGood := regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")
+	columns := []string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "version"}
+
+	mock.ExpectBegin()
+	expectProjectLock(mock, 1, lockShare, false)
+	mock.ExpectQuery(selectGood).WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "Old", nil, 1, false, time.Now(), 3))
+	mock.ExpectRollback()
+	if _, err := repo.UpdateGood(ctx, 1, 1, "New", nil, 2); !errors.Is(err, ErrVersionMismatch) || !errors.Is(err, apperr.ErrPreconditionFailed) {
+		t.Errorf("UpdateGood: expected ErrVersionMismatch, got %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectGood).WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "Old", nil, 1, false, time.Now(), 3))
+	mock.ExpectRollback()
+	if err := repo.RemoveGood(ctx, 1, 1, 2); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("RemoveGood: expected ErrVersionMismatch, got %v", err)
+	}
+
+	mock.ExpectBegin()
+	expectProjectLock(mock, 1, lockShare, false)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT priority, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"priority", "version"}).AddRow(1, 3))
+	mock.ExpectRollback()
+	if _, err := repo.Reprioritize(ctx, 1, 1, 2, 2); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("Reprioritize: expected ErrVersionMismatch, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 // Тест удаления товара (RemoveGood):
-// 1) Успешный сценарий: SELECT FOR UPDATE + UPDATE removed=true + COMMIT
+// 1) Успешный сценарий: SELECT FOR UPDATE + UPDATE removed=true + INSERT outbox + COMMIT
 // 2) Обработка случая, когда запись не найдена (ErrNotFound)
 func TestRemoveGood(t *testing.T) {
 	db, mock, _ := sqlmock.New()
@@ -226,26 +379,35 @@ func TestRemoveGood(t *testing.T) {
 
 	// успешный сценарий
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
 		WithArgs(5, 5).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET removed=true WHERE id=$1 AND project_id=$2")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "version"}).
+			AddRow(5, 5, "Name", nil, 1, false, time.Now(), 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET removed=true, version=version+1 WHERE id=$1 AND project_id=$2")).
 		WithArgs(5, 5).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context)")).
+		WithArgs(sqlmock.AnyArg(), model.EventGoodRemoved, eventArg{typ: model.EventGoodRemoved, check: func(e model.Event) bool {
+			var before, after model.Good
+			_ = json.Unmarshal(e.Previous, &before)
+			_ = json.Unmarshal(e.Current, &after)
+			return !before.Removed && after.Removed
+		}}, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err := repo.RemoveGood(ctx, 5, 5)
+	err := repo.RemoveGood(ctx, 5, 5, 0)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 
 	// not found
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
 		WithArgs(6, 6).
 		WillReturnError(sql.ErrNoRows)
 
-	err = repo.RemoveGood(ctx, 6, 6)
+	err = repo.RemoveGood(ctx, 6, 6, 0)
 	if !errors.Is(err, ErrNotFound) {
 		t.Error("expected ErrNotFound")
 	}
@@ -262,14 +424,15 @@ func TestRemoveGood_ExecError(t *testing.T) {
 	repo := NewGoodRepository(db)
 	ctx := context.Background()
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
 		WithArgs(5, 5).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET removed=true WHERE id=$1 AND project_id=$2")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "version"}).
+			AddRow(5, 5, "Name", nil, 1, false, time.Now(), 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET removed=true, version=version+1 WHERE id=$1 AND project_id=$2")).
 		WithArgs(5, 5).
 		WillReturnError(errors.New("remove exec failed"))
 	mock.ExpectRollback()
-	err := repo.RemoveGood(ctx, 5, 5)
+	err := repo.RemoveGood(ctx, 5, 5, 0)
 	if err == nil || !strings.Contains(err.Error(), "remove exec failed") {
 		t.Errorf("expected remove exec error, got %v", err)
 	}
@@ -282,14 +445,18 @@ func TestRemoveGood_CommitError(t *testing.T) {
 	repo := NewGoodRepository(db)
 	ctx := context.Background()
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
 		WithArgs(5, 5).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET removed=true WHERE id=$1 AND project_id=$2")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "version"}).
+			AddRow(5, 5, "Name", nil, 1, false, time.Now(), 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET removed=true, version=version+1 WHERE id=$1 AND project_id=$2")).
 		WithArgs(5, 5).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context)")).
+		WithArgs(sqlmock.AnyArg(), model.EventGoodRemoved, eventArg{typ: model.EventGoodRemoved}, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit().WillReturnError(errors.New("remove commit failed"))
-	err := repo.RemoveGood(ctx, 5, 5)
+	err := repo.RemoveGood(ctx, 5, 5, 0)
 	if err == nil || !strings.Contains(err.Error(), "remove commit failed") {
 		t.Errorf("expected remove commit error, got %v", err)
 	}
@@ -299,3 +466,204 @@ func TestRemoveGood_CommitError(t *testing.T) {
 func ptr(s string) *string {
 	return &s
 }
+
+// TestListGoods_Filter: проверяем построение WHERE/ORDER BY по фильтру и подсчёт total/removed по тем же условиям
+func TestListGoods_Filter(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+	ctx := context.Background()
+	from := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	filter := model.GoodsFilter{
+		ProjectID:   2,
+		Removed:     model.RemovedExclude,
+		Name:        "50%_off",
+		CreatedFrom: &from,
+		Sort:        model.SortByPriority,
+		Desc:        true,
+		Limit:       5,
+		Offset:      10,
+	}
+	where := ` WHERE project_id=$1 AND removed=false AND name ILIKE '%' || $2 || '%' AND created_at >= $3`
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*), COUNT(*) FILTER (WHERE removed) FROM goods"+where)).
+		WithArgs(2, `50\%\_off`, from).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "count"}).AddRow(7, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, project_id, name, description, priority, removed, created_at, version FROM goods"+where+" ORDER BY priority DESC, id DESC LIMIT $4 OFFSET $5")).
+		WithArgs(2, `50\%\_off`, from, 5, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "version"}).
+			AddRow(3, 2, "50%_off", nil, 9, false, time.Now(), 1))
+
+	goods, total, removed, err := repo.ListGoods(ctx, filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total != 7 || removed != 0 || len(goods) != 1 || goods[0].ID != 3 {
+		t.Errorf("unexpected result: %+v total=%d removed=%d", goods, total, removed)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestListGoods_DefaultFilter: без условий выборка идёт по всем товарам в порядке id
+func TestListGoods_DefaultFilter(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*), COUNT(*) FILTER (WHERE removed) FROM goods")).
+		WithArgs().
+		WillReturnRows(sqlmock.NewRows([]string{"count", "count"}).AddRow(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM goods ORDER BY id ASC LIMIT $1 OFFSET $2")).
+		WithArgs(10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "version"}))
+	if _, _, _, err := repo.ListGoods(context.Background(), model.GoodsFilter{Limit: 10}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestListGoods_Keyset: курсор добавляет условие по (priority, id) только к выборке страницы, подсчёт пропускается
+func TestListGoods_Keyset(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+	filter := model.GoodsFilter{
+		ProjectID:  1,
+		Sort:       model.SortByPriority,
+		Limit:      2,
+		Offset:     40,
+		After:      &model.GoodsCursor{Priority: 3, ID: 8},
+		SkipCounts: true,
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM goods WHERE project_id=$1 AND (priority, id) > ($2, $3) ORDER BY priority ASC, id ASC LIMIT $4 OFFSET $5")).
+		WithArgs(1, 3, 8, 2, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "version"}).
+			AddRow(5, 1, "a", nil, 4, false, time.Now(), 1))
+	goods, total, removed, err := repo.ListGoods(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(goods) != 1 || total != 0 || removed != 0 {
+		t.Errorf("unexpected result: %+v total=%d removed=%d", goods, total, removed)
+	}
+
+	// сортировка по id в обратном порядке без других условий
+	mock.ExpectQuery(regexp.QuoteMeta("FROM goods WHERE id < $1 ORDER BY id DESC LIMIT $2 OFFSET $3")).
+		WithArgs(8, 2, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "version"}))
+	filter = model.GoodsFilter{Sort: model.SortByID, Desc: true, Limit: 2, After: &model.GoodsCursor{ID: 8}, SkipCounts: true}
+	if _, _, _, err := repo.ListGoods(context.Background(), filter); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestReprioritize: перестановка приоритетов и событие good.reprioritized с приоритетами до и после в одной транзакции
+func TestReprioritize(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+	ctx := context.Background()
+
+	// товар 3 перемещается с приоритета 3 на 1, товары 1 и 2 сдвигаются на +1
+	mock.ExpectBegin()
+	expectProjectLock(mock, 1, lockShare, false)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT priority, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"priority", "version"}).AddRow(3, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE goods SET priority = priority + 1")).
+		WithArgs(1, 1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "priority"}).AddRow(1, 2).AddRow(2, 3))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET priority=$1, version=version+1 WHERE id=$2 AND project_id=$3")).
+		WithArgs(1, 3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context)")).
+		WithArgs(sqlmock.AnyArg(), model.EventGoodReprioritized, eventArg{typ: model.EventGoodReprioritized, check: func(e model.Event) bool {
+			var before []model.PriorityUpdate
+			_ = json.Unmarshal(e.Previous, &before)
+			want := []model.PriorityUpdate{{ID: 1, Priority: 1}, {ID: 2, Priority: 2}, {ID: 3, Priority: 3}}
+			return reflect.DeepEqual(before, want)
+		}}, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	updates, err := repo.Reprioritize(ctx, 1, 3, 1, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []model.PriorityUpdate{{ID: 1, Priority: 2}, {ID: 2, Priority: 3}, {ID: 3, Priority: 1}}
+	if !reflect.DeepEqual(updates, want) {
+		t.Errorf("unexpected updates %v", updates)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestReprioritize_OutboxError: ошибка записи события в outbox откатывает перестановку
+func TestReprioritize_OutboxError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+	mock.ExpectBegin()
+	expectProjectLock(mock, 1, lockShare, false)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT priority, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"priority", "version"}).AddRow(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE goods SET priority=$1, version=version+1 WHERE id=$2 AND project_id=$3")).
+		WithArgs(1, 1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context)")).
+		WillReturnError(errors.New("outbox failed"))
+	mock.ExpectRollback()
+	_, err := repo.Reprioritize(context.Background(), 1, 1, 1, 0)
+	if err == nil || !strings.Contains(err.Error(), "outbox failed") {
+		t.Errorf("expected outbox error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestReprioritize_ShiftRowError: ошибка при чтении сдвинутых приоритетов откатывает перестановку
+func TestReprioritize_ShiftRowError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewGoodRepository(db)
+	mock.ExpectBegin()
+	expectProjectLock(mock, 1, lockShare, false)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT priority, version FROM goods WHERE id=$1 AND project_id=$2 FOR UPDATE")).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"priority", "version"}).AddRow(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE goods SET priority = priority - 1")).
+		WithArgs(1, 1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "priority"}).AddRow(2, 1).AddRow(3, 2).RowError(1, errors.New("connection lost")))
+	mock.ExpectRollback()
+	_, err := repo.Reprioritize(context.Background(), 1, 1, 3, 0)
+	if err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Errorf("expected row error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestPreviousPriorities проверяет восстановление приоритетов до перестановки в обе стороны
+func TestPreviousPriorities(t *testing.T) {
+	// товар 5 перемещён с 4 на 2: товары с приоритетами 2 и 3 сдвинуты вниз на +1
+	up := []model.PriorityUpdate{{ID: 1, Priority: 3}, {ID: 2, Priority: 4}, {ID: 5, Priority: 2}}
+	want := []model.PriorityUpdate{{ID: 1, Priority: 2}, {ID: 2, Priority: 3}, {ID: 5, Priority: 4}}
+	if got := previousPriorities(up, 5, 4, 2); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	// товар 5 перемещён с 1 на 3: товары с приоритетами 2 и 3 сдвинуты вверх на -1
+	down := []model.PriorityUpdate{{ID: 1, Priority: 1}, {ID: 2, Priority: 2}, {ID: 5, Priority: 3}}
+	want = []model.PriorityUpdate{{ID: 1, Priority: 2}, {ID: 2, Priority: 3}, {ID: 5, Priority: 1}}
+	if got := previousPriorities(down, 5, 1, 3); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"HezzlTestTask/internal/model"
+)
+
+// ProjectRepository реализует доступ к таблице projects
+// События изменений проектов записываются в outbox в транзакции изменения, как у GoodRepository
+type ProjectRepository struct {
+	db *sql.DB
+}
+
+// NewProjectRepository создает новый репозиторий проектов
+func NewProjectRepository(db *sql.DB) *ProjectRepository {
+	return &ProjectRepository{db: db}
+}
+
+// CreateProject добавляет новый проект в таблицу projects и записывает событие project.created в outbox
+// в той же транзакции
+func (r *ProjectRepository) CreateProject(ctx context.Context, name string) (*model.Project, error) {
+	if name == "" {
+		return nil, ErrEmptyName
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	// removed и created_at заполняются значениями по умолчанию в БД
+	query := `INSERT INTO projects(name) VALUES($1) RETURNING id, removed, created_at`
+	p := model.Project{Name: name}
+	if err := tx.QueryRowContext(ctx, query, name).Scan(&p.ID, &p.Removed, &p.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to insert project: %w", err)
+	}
+	if err := insertOutboxEvent(ctx, tx, model.EventProjectCreated, p.ID, p.ID, nil, p); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &p, nil
+}
+
+// GetProject возвращает проект по id
+func (r *ProjectRepository) GetProject(ctx context.Context, id int) (*model.Project, error) {
+	query := `SELECT id, name, removed, created_at FROM projects WHERE id=$1`
+	var p model.Project
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&p.ID, &p.Name, &p.Removed, &p.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	return &p, nil
+}
+
+// UpdateProject переименовывает проект, с блокировкой и транзакцией
+// В той же транзакции в outbox записывается событие project.updated с состоянием до (прочитанным под блокировкой)
+// и после
+func (r *ProjectRepository) UpdateProject(ctx context.Context, id int, name string) (*model.Project, error) {
+	if name == "" {
+		return nil, ErrEmptyName
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	// выборка с блокировкой
+	selectQuery := `SELECT id, name, removed, created_at FROM projects WHERE id=$1 FOR UPDATE`
+	var p model.Project
+	err = tx.QueryRowContext(ctx, selectQuery, id).Scan(&p.ID, &p.Name, &p.Removed, &p.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to select project for update: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE projects SET name=$1 WHERE id=$2`, name, id); err != nil {
+		return nil, fmt.Errorf("failed to update project: %w", err)
+	}
+	updated := p
+	updated.Name = name
+	if err := insertOutboxEvent(ctx, tx, model.EventProjectUpdated, id, id, p, updated); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &updated, nil
+}
+
+// RemoveProject архивирует проект (removed=true) с блокировкой и транзакцией
+// В той же транзакции в outbox записывается событие project.removed с состоянием до (прочитанным под блокировкой)
+// и после
+func (r *ProjectRepository) RemoveProject(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	// выборка с блокировкой
+	selectQuery := `SELECT id, name, removed, created_at FROM projects WHERE id=$1 FOR UPDATE`
+	var p model.Project
+	if err := tx.QueryRowContext(ctx, selectQuery, id).Scan(&p.ID, &p.Name, &p.Removed, &p.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to select project for remove: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE projects SET removed=true WHERE id=$1`, id); err != nil {
+		return fmt.Errorf("failed to remove project: %w", err)
+	}
+	removed := p
+	removed.Removed = true
+	if err := insertOutboxEvent(ctx, tx, model.EventProjectRemoved, id, id, p, removed); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListProjects возвращает список проектов с пагинацией и информацию о количестве записей
+func (r *ProjectRepository) ListProjects(ctx context.Context, limit, offset int) ([]model.Project, int, int, error) {
+	// общее число проектов и число архивированных одним запросом
+	var total, removed int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*), COUNT(*) FILTER (WHERE removed) FROM projects`).Scan(&total, &removed)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to count projects: %w", err)
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT id, name, removed, created_at FROM projects ORDER BY id LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to select projects list: %w", err)
+	}
+	defer rows.Close()
+	var projects []model.Project
+	for rows.Next() {
+		var p model.Project
+		if err := rows.Scan(&p.ID, &p.Name, &p.Removed, &p.CreatedAt); err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to scan project: %w", err)
+		}
+		projects = append(projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to iterate projects: %w", err)
+	}
+	return projects, total, removed, nil
+}
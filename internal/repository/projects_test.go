@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"HezzlTestTask/internal/model"
+)
+
+// projectEventArg сопоставляет payload события проекта типа typ с состоянием проекта до и после,
+// дополнительно проверяемым функцией check
+func projectEventArg(typ string, check func(before, after model.Project) bool) eventArg {
+	return eventArg{typ: typ, check: func(e model.Event) bool {
+		var before, after model.Project
+		if e.Previous != nil {
+			_ = json.Unmarshal(e.Previous, &before)
+		}
+		_ = json.Unmarshal(e.Current, &after)
+		return e.ProjectID == after.ID && e.EntityID == after.ID && check(before, after)
+	}}
+}
+
+// TestCreateProject: вставка проекта и события project.created в одной транзакции, валидация пустого имени
+func TestCreateProject(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewProjectRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO projects(name) VALUES($1) RETURNING id, removed, created_at")).
+		WithArgs("Каталог").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "removed", "created_at"}).AddRow(2, false, time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context)")).
+		WithArgs(sqlmock.AnyArg(), model.EventProjectCreated, projectEventArg(model.EventProjectCreated, func(before, after model.Project) bool {
+			return after.ID == 2 && after.Name == "Каталог"
+		}), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	p, err := repo.CreateProject(ctx, "Каталог")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.ID != 2 || p.Name != "Каталог" || p.Removed {
+		t.Errorf("unexpected project: %+v", p)
+	}
+
+	// пустое имя отклоняется без обращения к БД
+	if _, err := repo.CreateProject(ctx, ""); !errors.Is(err, ErrEmptyName) {
+		t.Errorf("expected ErrEmptyName, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestGetProject: успешное чтение и ErrNotFound
+func TestGetProject(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewProjectRepository(db)
+	ctx := context.Background()
+
+	query := regexp.QuoteMeta("SELECT id, name, removed, created_at FROM projects WHERE id=$1")
+	mock.ExpectQuery(query).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "removed", "created_at"}).AddRow(1, "Первая запись", false, time.Now()))
+	p, err := repo.GetProject(ctx, 1)
+	if err != nil || p.ID != 1 || p.Name != "Первая запись" {
+		t.Fatalf("unexpected result: %+v, %v", p, err)
+	}
+
+	mock.ExpectQuery(query).WithArgs(9).WillReturnError(sql.ErrNoRows)
+	if _, err := repo.GetProject(ctx, 9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestUpdateProject: SELECT FOR UPDATE + UPDATE + INSERT outbox + COMMIT, а также ErrNotFound
+func TestUpdateProject(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewProjectRepository(db)
+	ctx := context.Background()
+
+	selectQuery := regexp.QuoteMeta("SELECT id, name, removed, created_at FROM projects WHERE id=$1 FOR UPDATE")
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectQuery).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "removed", "created_at"}).AddRow(3, "Old", false, time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE projects SET name=$1 WHERE id=$2")).
+		WithArgs("New", 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context)")).
+		WithArgs(sqlmock.AnyArg(), model.EventProjectUpdated, projectEventArg(model.EventProjectUpdated, func(before, after model.Project) bool {
+			return before.Name == "Old" && after.Name == "New"
+		}), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	p, err := repo.UpdateProject(ctx, 3, "New")
+	if err != nil || p.Name != "New" || p.ID != 3 {
+		t.Fatalf("unexpected result: %+v, %v", p, err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectQuery).WithArgs(4).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	if _, err := repo.UpdateProject(ctx, 4, "New"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestRemoveProject: архивирование проекта с событием project.removed, ошибки UPDATE и записи в outbox
+func TestRemoveProject(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewProjectRepository(db)
+	ctx := context.Background()
+
+	selectQuery := regexp.QuoteMeta("SELECT id, name, removed, created_at FROM projects WHERE id=$1 FOR UPDATE")
+	updateQuery := regexp.QuoteMeta("UPDATE projects SET removed=true WHERE id=$1")
+	columns := []string{"id", "name", "removed", "created_at"}
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectQuery).WithArgs(5).WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "a", false, time.Now()))
+	mock.ExpectExec(updateQuery).WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context)")).
+		WithArgs(sqlmock.AnyArg(), model.EventProjectRemoved, projectEventArg(model.EventProjectRemoved, func(before, after model.Project) bool {
+			return !before.Removed && after.Removed && after.Name == "a"
+		}), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	if err := repo.RemoveProject(ctx, 5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectQuery).WithArgs(6).WillReturnRows(sqlmock.NewRows(columns).AddRow(6, "b", false, time.Now()))
+	mock.ExpectExec(updateQuery).WithArgs(6).WillReturnError(errors.New("archive failed"))
+	mock.ExpectRollback()
+	if err := repo.RemoveProject(ctx, 6); err == nil || !strings.Contains(err.Error(), "archive failed") {
+		t.Errorf("expected archive error, got %v", err)
+	}
+
+	// ошибка записи события откатывает архивирование
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectQuery).WithArgs(7).WillReturnRows(sqlmock.NewRows(columns).AddRow(7, "c", false, time.Now()))
+	mock.ExpectExec(updateQuery).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox(event_id, event_type, payload, trace_context)")).
+		WillReturnError(errors.New("outbox failed"))
+	mock.ExpectRollback()
+	if err := repo.RemoveProject(ctx, 7); err == nil || !strings.Contains(err.Error(), "outbox failed") {
+		t.Errorf("expected outbox error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestListProjects: подсчёт total/removed и выборка страницы
+func TestListProjects(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewProjectRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*), COUNT(*) FILTER (WHERE removed) FROM projects")).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "count"}).AddRow(3, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, removed, created_at FROM projects ORDER BY id LIMIT $1 OFFSET $2")).
+		WithArgs(2, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "removed", "created_at"}).
+			AddRow(1, "a", false, time.Now()).
+			AddRow(2, "b", true, time.Now()))
+
+	projects, total, removed, err := repo.ListProjects(ctx, 2, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total != 3 || removed != 1 || len(projects) != 2 || !projects[1].Removed {
+		t.Errorf("unexpected result: %+v total=%d removed=%d", projects, total, removed)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+)
+
+// cacheRequests считает обращения к кэшу по результату: hit — свежая запись, stale — устаревшая запись,
+// отданная во время фонового обновления, miss — значение загружено из репозитория
+var cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cache_requests_total",
+	Help: "Number of cache lookups by cache and result (hit, stale, miss).",
+}, []string{"cache", "result"})
+
+// staleTTL задаёт, сколько после истечения REDIS_TTL запись ещё может отдаваться, пока она обновляется в фоне
+// (stale-while-revalidate); 0 — режим выключен, по умолчанию 0 или из REDIS_STALE_TTL
+var staleTTL time.Duration
+
+// refreshTimeout ограничивает фоновое обновление устаревшей записи
+const refreshTimeout = 5 * time.Second
+
+func init() {
+	if v := os.Getenv("REDIS_STALE_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			staleTTL = d
+		}
+	}
+}
+
+// cacheEntry — закэшированное значение вместе со временем, до которого оно считается свежим
+// Запись хранится в Redis на REDIS_TTL + REDIS_STALE_TTL, чтобы устаревшее значение можно было отдать во время обновления
+type cacheEntry struct {
+	FreshUntil time.Time       `json:"freshUntil"`
+	Value      json.RawMessage `json:"value"`
+}
+
+// cachedLoader читает значения из кэша и объединяет конкурентные промахи по одному ключу:
+// при истечении горячего ключа в базу идёт один запрос, остальные вызовы ждут его результата
+// name используется как метка cache в метриках
+// Инвалидации ключей и тегов загрузчика выполняются через invalidate/invalidateTags: загрузка, начатая до
+// инвалидации, не сохраняет в кэш прочитанное ею значение, а новые вызовы не присоединяются к ней
+type cachedLoader struct {
+	cache  Cache
+	name   string
+	flight singleflight.Group
+
+	mu      sync.Mutex
+	pending map[*pendingLoad]struct{}
+}
+
+// pendingLoad — выполняющаяся загрузка ключа key с тегами tags
+// invalidated выставляется, если ключ или один из тегов инвалидирован во время загрузки
+type pendingLoad struct {
+	key  string
+	tags []string
+
+	mu          sync.Mutex
+	invalidated bool
+}
+
+// load возвращает JSON значения по ключу key, при промахе или устаревании загружая его через fetch
+// и сохраняя в кэш с тегами tags
+// Загрузка выполняется с контекстом без отмены, чтобы отмена запроса, начавшего загрузку, не прервала её для остальных
+func (l *cachedLoader) load(ctx context.Context, key string, tags []string, fetch func(ctx context.Context) (interface{}, error)) ([]byte, error) {
+	if data, err := l.cache.Get(ctx, key); err == nil {
+		var entry cacheEntry
+		if json.Unmarshal(data, &entry) == nil && entry.Value != nil {
+			if time.Now().Before(entry.FreshUntil) {
+				cacheRequests.WithLabelValues(l.name, "hit").Inc()
+				return entry.Value, nil
+			}
+			if staleTTL > 0 {
+				cacheRequests.WithLabelValues(l.name, "stale").Inc()
+				// отдаём устаревшее значение сразу, обновление выполняет одна фоновая горутина
+				refreshCtx := context.WithoutCancel(ctx)
+				l.flight.DoChan(key, func() (interface{}, error) {
+					refreshCtx, cancel := context.WithTimeout(refreshCtx, refreshTimeout)
+					defer cancel()
+					value, err := l.refresh(refreshCtx, key, tags, fetch)
+					if err != nil {
+						slog.WarnContext(refreshCtx, "failed to refresh cache key", slog.String("key", key), slog.Any("error", err))
+					}
+					return value, err
+				})
+				return entry.Value, nil
+			}
+		}
+	}
+	cacheRequests.WithLabelValues(l.name, "miss").Inc()
+	value, err, _ := l.flight.Do(key, func() (interface{}, error) {
+		return l.refresh(context.WithoutCancel(ctx), key, tags, fetch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]byte), nil
+}
+
+// refresh загружает значение через fetch и сохраняет его в кэш; ошибка записи в кэш не считается ошибкой загрузки
+// Если ключ инвалидирован во время загрузки, значение возвращается ожидающим вызовам, но в кэш не пишется
+func (l *cachedLoader) refresh(ctx context.Context, key string, tags []string, fetch func(ctx context.Context) (interface{}, error)) ([]byte, error) {
+	p := l.begin(key, tags)
+	defer l.end(p)
+	v, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cache value: %w", err)
+	}
+	entry, _ := json.Marshal(cacheEntry{FreshUntil: time.Now().Add(cacheTTL), Value: value})
+	// запись выполняется под блокировкой загрузки: инвалидация, пришедшая после проверки,
+	// дождётся записи и удалит её
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.invalidated {
+		return value, nil
+	}
+	if len(tags) > 0 {
+		_ = l.cache.SetTagged(ctx, key, entry, cacheTTL+staleTTL, tags...)
+	} else {
+		_ = l.cache.Set(ctx, key, entry, cacheTTL+staleTTL)
+	}
+	return value, nil
+}
+
+// begin регистрирует выполняющуюся загрузку ключа
+func (l *cachedLoader) begin(key string, tags []string) *pendingLoad {
+	p := &pendingLoad{key: key, tags: tags}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.pending == nil {
+		l.pending = make(map[*pendingLoad]struct{})
+	}
+	l.pending[p] = struct{}{}
+	return p
+}
+
+// end снимает регистрацию завершённой загрузки
+func (l *cachedLoader) end(p *pendingLoad) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.pending, p)
+}
+
+// invalidate удаляет ключ из кэша и отменяет запись значений, загружаемых по нему в этот момент
+func (l *cachedLoader) invalidate(ctx context.Context, key string) error {
+	l.cancelPending(func(p *pendingLoad) bool { return p.key == key })
+	return l.cache.Invalidate(ctx, key)
+}
+
+// invalidateTags удаляет из кэша записи с тегами и отменяет запись значений, загружаемых с ними в этот момент
+func (l *cachedLoader) invalidateTags(ctx context.Context, tags ...string) error {
+	l.cancelPending(func(p *pendingLoad) bool {
+		for _, pt := range p.tags {
+			for _, t := range tags {
+				if pt == t {
+					return true
+				}
+			}
+		}
+		return false
+	})
+	return l.cache.InvalidateTags(ctx, tags...)
+}
+
+// cancelPending помечает подходящие выполняющиеся загрузки инвалидированными и отвязывает их ключи
+// от singleflight, чтобы следующие вызовы начали новую загрузку
+func (l *cachedLoader) cancelPending(match func(p *pendingLoad) bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for p := range l.pending {
+		if !match(p) {
+			continue
+		}
+		p.mu.Lock()
+		p.invalidated = true
+		p.mu.Unlock()
+		l.flight.Forget(p.key)
+	}
+}
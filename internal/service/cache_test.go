@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"HezzlTestTask/internal/model"
+)
+
+// freshEntry сериализует значение в запись кэша, которая ещё не устарела
+func freshEntry(t *testing.T, v interface{}) []byte {
+	return cacheEntryData(t, v, time.Now().Add(time.Minute))
+}
+
+// cacheEntryData сериализует значение в запись кэша со временем свежести freshUntil
+func cacheEntryData(t *testing.T, v interface{}, freshUntil time.Time) []byte {
+	value, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(cacheEntry{FreshUntil: freshUntil, Value: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// withStaleTTL включает stale-while-revalidate на время теста
+func withStaleTTL(t *testing.T, d time.Duration) {
+	prev := staleTTL
+	staleTTL = d
+	t.Cleanup(func() { staleTTL = prev })
+}
+
+// TestGet_CoalescesConcurrentMisses проверяет, что одновременные промахи по ключу дают один запрос в базу
+func TestGet_CoalescesConcurrentMisses(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	repo := &mockRepo{getFn: func(ctx context.Context, projectID, id int) (*model.Good, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &model.Good{ID: id, ProjectID: projectID, Name: "hot"}, nil
+	}}
+	var sets int32
+	cache := &mockCache{set: func(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+		atomic.AddInt32(&sets, 1)
+		return nil
+	}}
+	s := newService(repo, cache)
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g, err := s.Get(context.Background(), 1, 2)
+			if err == nil && g.Name != "hot" {
+				err = errors.New("unexpected good " + g.Name)
+			}
+			errs <- err
+		}()
+	}
+	// ждём, пока первый запрос дойдёт до базы, и даём остальным присоединиться к нему
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected 1 repository call, got %d", got)
+	}
+	if got := atomic.LoadInt32(&sets); got != 1 {
+		t.Fatalf("expected 1 cache write, got %d", got)
+	}
+}
+
+// TestGet_CacheEntryTTL проверяет, что запись хранится в Redis на REDIS_TTL + REDIS_STALE_TTL
+func TestGet_CacheEntryTTL(t *testing.T) {
+	withStaleTTL(t, 30*time.Second)
+	repo := &mockRepo{}
+	var gotTTL time.Duration
+	var entry cacheEntry
+	cache := &mockCache{set: func(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+		gotTTL = ttl
+		return json.Unmarshal(value, &entry)
+	}}
+	if _, err := newService(repo, cache).Get(context.Background(), 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if gotTTL != cacheTTL+30*time.Second {
+		t.Fatalf("unexpected ttl %s", gotTTL)
+	}
+	if until := time.Until(entry.FreshUntil); until <= 0 || until > cacheTTL {
+		t.Fatalf("unexpected freshUntil %s", entry.FreshUntil)
+	}
+}
+
+// TestGet_StaleWhileRevalidate проверяет, что устаревшее значение отдаётся сразу, а обновляется в фоне
+func TestGet_StaleWhileRevalidate(t *testing.T) {
+	withStaleTTL(t, time.Minute)
+	stale := cacheEntryData(t, &model.Good{ID: 2, ProjectID: 1, Name: "old"}, time.Now().Add(-time.Second))
+	refreshed := make(chan []byte, 1)
+	cache := &mockCache{
+		get: func(ctx context.Context, key string) ([]byte, error) { return stale, nil },
+		set: func(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+			refreshed <- value
+			return nil
+		},
+	}
+	repo := &mockRepo{getFn: func(ctx context.Context, projectID, id int) (*model.Good, error) {
+		return &model.Good{ID: id, ProjectID: projectID, Name: "new"}, nil
+	}}
+	reqCtx, cancel := context.WithCancel(context.Background())
+	g, err := newService(repo, cache).Get(reqCtx, 1, 2)
+	// отмена запроса не прерывает фоновое обновление
+	cancel()
+	if err != nil || g.Name != "old" {
+		t.Fatalf("expected stale value, got %v, %v", g, err)
+	}
+	select {
+	case data := <-refreshed:
+		var entry cacheEntry
+		_ = json.Unmarshal(data, &entry)
+		var good model.Good
+		_ = json.Unmarshal(entry.Value, &good)
+		if good.Name != "new" {
+			t.Fatalf("unexpected refreshed value %+v", good)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stale entry was not refreshed")
+	}
+}
+
+// TestGet_StaleDisabled проверяет, что без REDIS_STALE_TTL устаревшее значение перечитывается синхронно
+func TestGet_StaleDisabled(t *testing.T) {
+	withStaleTTL(t, 0)
+	stale := cacheEntryData(t, &model.Good{ID: 2, ProjectID: 1, Name: "old"}, time.Now().Add(-time.Second))
+	cache := &mockCache{get: func(ctx context.Context, key string) ([]byte, error) { return stale, nil }}
+	repo := &mockRepo{getFn: func(ctx context.Context, projectID, id int) (*model.Good, error) {
+		return &model.Good{ID: id, ProjectID: projectID, Name: "new"}, nil
+	}}
+	g, err := newService(repo, cache).Get(context.Background(), 1, 2)
+	if err != nil || g.Name != "new" {
+		t.Fatalf("expected refreshed value, got %v, %v", g, err)
+	}
+}
+
+// TestGet_LegacyCacheEntry проверяет, что запись в старом формате (без обёртки) считается промахом
+func TestGet_LegacyCacheEntry(t *testing.T) {
+	legacy, _ := json.Marshal(model.Good{ID: 2, ProjectID: 1, Name: "legacy"})
+	cache := &mockCache{get: func(ctx context.Context, key string) ([]byte, error) { return legacy, nil }}
+	repo := &mockRepo{getFn: func(ctx context.Context, projectID, id int) (*model.Good, error) {
+		return &model.Good{ID: id, ProjectID: projectID, Name: "db"}, nil
+	}}
+	g, err := newService(repo, cache).Get(context.Background(), 1, 2)
+	if err != nil || g.Name != "db" {
+		t.Fatalf("expected value from repository, got %v, %v", g, err)
+	}
+}
+
+// TestCachedLoader_Metrics проверяет учёт попаданий, устаревших записей и промахов кэша
+func TestCachedLoader_Metrics(t *testing.T) {
+	withStaleTTL(t, time.Minute)
+	entries := map[string][]byte{
+		"fresh": freshEntry(t, "v"),
+		"stale": cacheEntryData(t, "v", time.Now().Add(-time.Second)),
+	}
+	cache := &mockCache{get: func(ctx context.Context, key string) ([]byte, error) {
+		if data, ok := entries[key]; ok {
+			return data, nil
+		}
+		return nil, errors.New("miss")
+	}}
+	l := &cachedLoader{cache: cache, name: "metrics_test"}
+	fetch := func(ctx context.Context) (interface{}, error) { return "v", nil }
+	for _, key := range []string{"fresh", "fresh", "stale", "missing"} {
+		if _, err := l.load(context.Background(), key, nil, fetch); err != nil {
+			t.Fatalf("load %s: %v", key, err)
+		}
+	}
+	for result, want := range map[string]float64{"hit": 2, "stale": 1, "miss": 1} {
+		if got := testutil.ToFloat64(cacheRequests.WithLabelValues("metrics_test", result)); got != want {
+			t.Errorf("%s: expected %v, got %v", result, want, got)
+		}
+	}
+}
+
+// TestCachedLoader_InvalidateDuringFetch проверяет, что значение, загрузка которого началась до инвалидации ключа
+// или тега, не записывается в кэш, а следующий вызов загружает значение заново
+func TestCachedLoader_InvalidateDuringFetch(t *testing.T) {
+	cases := map[string]func(l *cachedLoader) error{
+		"key": func(l *cachedLoader) error { return l.invalidate(context.Background(), "k") },
+		"tag": func(l *cachedLoader) error { return l.invalidateTags(context.Background(), "other", "t") },
+	}
+	for name, invalidate := range cases {
+		t.Run(name, func(t *testing.T) {
+			var sets []string
+			var mu sync.Mutex
+			record := func(value []byte) {
+				var entry cacheEntry
+				_ = json.Unmarshal(value, &entry)
+				mu.Lock()
+				sets = append(sets, string(entry.Value))
+				mu.Unlock()
+			}
+			cache := &mockCache{
+				set: func(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+					record(value)
+					return nil
+				},
+				setTagged: func(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+					record(value)
+					return nil
+				},
+			}
+			l := &cachedLoader{cache: cache, name: "invalidate_test"}
+			started, release := make(chan struct{}), make(chan struct{})
+			oldFetch := func(ctx context.Context) (interface{}, error) {
+				close(started)
+				<-release
+				return "old", nil
+			}
+			done := make(chan []byte)
+			go func() {
+				v, _ := l.load(context.Background(), "k", []string{"t"}, oldFetch)
+				done <- v
+			}()
+			<-started
+			if err := invalidate(l); err != nil {
+				t.Fatal(err)
+			}
+			// вызов после инвалидации не присоединяется к устаревшей загрузке
+			v, err := l.load(context.Background(), "k", []string{"t"}, func(ctx context.Context) (interface{}, error) {
+				return "new", nil
+			})
+			if err != nil || string(v) != `"new"` {
+				t.Fatalf("expected new value, got %s, %v", v, err)
+			}
+			close(release)
+			if v := <-done; string(v) != `"old"` {
+				t.Fatalf("unexpected value of the invalidated load %s", v)
+			}
+			if !reflect.DeepEqual(sets, []string{`"new"`}) {
+				t.Fatalf("unexpected cache writes %v", sets)
+			}
+		})
+	}
+}
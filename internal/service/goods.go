@@ -3,39 +3,47 @@ package service
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"os"
 	"time"
 
+	"HezzlTestTask/internal/apperr"
 	"HezzlTestTask/internal/model"
+	"HezzlTestTask/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Repo определяет интерфейс репозитория для операций с товарами (CRUD и приоритеты)
 // Реализация может быть на основе базы данных Postgres
 // Методы возвращают сущности model.Good и возможные ошибки
+// version в изменяющих методах — ожидаемая версия товара (0 — без проверки), при несовпадении ErrVersionMismatch
 type Repo interface {
 	CreateGood(ctx context.Context, projectID int, name string, description *string) (*model.Good, error)
 	GetGood(ctx context.Context, projectID, id int) (*model.Good, error)
-	UpdateGood(ctx context.Context, projectID, id int, name string, description *string) (*model.Good, error)
-	RemoveGood(ctx context.Context, projectID, id int) error
-	ListGoods(ctx context.Context, limit, offset int) ([]model.Good, int, int, error)
-	Reprioritize(ctx context.Context, projectID, id, newPriority int) ([]model.PriorityUpdate, error)
+	UpdateGood(ctx context.Context, projectID, id int, name string, description *string, version int) (*model.Good, error)
+	RemoveGood(ctx context.Context, projectID, id, version int) error
+	ListGoods(ctx context.Context, filter model.GoodsFilter) ([]model.Good, int, int, error)
+	Reprioritize(ctx context.Context, projectID, id, newPriority, version int) ([]model.PriorityUpdate, error)
+	CreateGoods(ctx context.Context, projectID int, items []model.GoodInput) ([]model.Good, error)
+	UpdateGoods(ctx context.Context, projectID int, items []model.GoodUpdate, atomic bool) ([]model.BulkResult, error)
+	RemoveGoods(ctx context.Context, projectID int, items []model.GoodRemoval, atomic bool) ([]model.BulkResult, error)
 }
 
 // Cache определяет интерфейс кэширования результатов операций (Redis)
-// Методы позволяют записывать, читать и инвалидировать кэш по ключу
+// Методы позволяют записывать, читать и инвалидировать кэш по ключу,
+// а также помечать записи тегами и атомарно инвалидировать все записи с тегом
 type Cache interface {
 	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	SetTagged(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error
 	Get(ctx context.Context, key string) ([]byte, error)
 	Invalidate(ctx context.Context, key string) error
+	InvalidateTags(ctx context.Context, tags ...string) error
 }
 
-// Logger определяет интерфейс логгирования событий (NATS)
-// Метод PublishLog отправляет лог-сообщение в брокер сообщений
-type Logger interface {
-	PublishLog(data []byte) error
-}
+// errEmptyName возвращается при создании или переименовании товара или проекта с пустым именем
+var errEmptyName = apperr.Invalid("name", "cannot be empty")
 
 // cacheTTL задаёт время жизни записей в кэше (Redis), по умолчанию 1 минута или из REDIS_TTL
 var cacheTTL = time.Minute
@@ -52,182 +60,277 @@ func init() {
 // - проверка входных данных (валидация)
 // - вызовы репозитория для CRUD операций
 // - кэширование результатов и инвалидирование
-// - публикация событий в лог
+// - объединение конкурентных промахов кэша и, при заданном REDIS_STALE_TTL, stale-while-revalidate
+// События изменений записываются репозиторием в outbox в транзакции изменения
+// и публикуются в NATS relay-воркером
 // Все комментарии на русском языке
 
 type GoodsService struct {
-	repo   Repo
-	cache  Cache
-	logger Logger
+	repo       Repo
+	goodLoader *cachedLoader
+	listLoader *cachedLoader
 }
 
 // NewGoodsService создаёт новый сервис для товаров
-func NewGoodsService(r Repo, c Cache, l Logger) *GoodsService {
-	return &GoodsService{repo: r, cache: c, logger: l}
+func NewGoodsService(r Repo, c Cache) *GoodsService {
+	return &GoodsService{
+		repo:       r,
+		goodLoader: &cachedLoader{cache: c, name: "good"},
+		listLoader: &cachedLoader{cache: c, name: "goods_list"},
+	}
 }
 
 // Create создаёт новый товар в базе и возвращает его:
 // 1. Валидирует, что имя не пустое
-// 2. Вызывает метод репозитория CreateGood
-// 3. Инвалидирует кэш списка товаров и кэш конкретного товара
-// 4. Публикует сериализованный в JSON объект товара в лог
-func (s *GoodsService) Create(ctx context.Context, projectID int, name string, description *string) (*model.Good, error) {
+// 2. Вызывает метод репозитория CreateGood (событие good.created пишется в outbox)
+// 3. Инвалидирует кэш списков товаров проекта и кэш конкретного товара
+func (s *GoodsService) Create(ctx context.Context, projectID int, name string, description *string) (_ *model.Good, err error) {
+	ctx, span := startSpan(ctx, "GoodsService.Create", projectID, 0)
+	defer func() { tracing.End(span, err) }()
 	// валидация: имя не должно быть пустым
 	if name == "" {
-		return nil, errors.New("name cannot be empty")
+		return nil, errEmptyName
 	}
 	// создаём товар в БД
 	good, err := s.repo.CreateGood(ctx, projectID, name, description)
 	if err != nil {
 		return nil, err
 	}
-	// инвалидируем кэш для списка и конкретного товара
-	_ = s.cache.Invalidate(ctx, fmt.Sprintf("goods:list"))
-	_ = s.cache.Invalidate(ctx, fmt.Sprintf("good:%d:%d", projectID, good.ID))
-	// публикуем лог события в NATS
-	data, _ := json.Marshal(good)
-	_ = s.logger.PublishLog(data)
+	// инвалидируем кэш списков проекта и конкретного товара
+	s.invalidateLists(ctx, projectID)
+	_ = s.goodLoader.invalidate(ctx, fmt.Sprintf("good:%d:%d", projectID, good.ID))
 	return good, nil
 }
 
 // Get возвращает товар по id и projectID:
 // 1. Пытается получить из кэша Redis
-// 2. При промахе кэша запрашивает из репозитория
+// 2. При промахе кэша запрашивает из репозитория (один запрос на все конкурентные промахи по ключу)
 // 3. Сохраняет результат в кэш
-func (s *GoodsService) Get(ctx context.Context, projectID, id int) (*model.Good, error) {
+func (s *GoodsService) Get(ctx context.Context, projectID, id int) (_ *model.Good, err error) {
+	ctx, span := startSpan(ctx, "GoodsService.Get", projectID, id)
+	defer func() { tracing.End(span, err) }()
 	key := fmt.Sprintf("good:%d:%d", projectID, id)
-	// пытаемся получить из кэша
-	bytes, err := s.cache.Get(ctx, key)
-	if err == nil {
-		var g model.Good
-		_ = json.Unmarshal(bytes, &g)
-		return &g, nil
-	}
-	// при ошибке redis.Nil или ErrCacheMiss получаем из БД
-	good, err := s.repo.GetGood(ctx, projectID, id)
+	data, err := s.goodLoader.load(ctx, key, nil, func(ctx context.Context) (interface{}, error) {
+		return s.repo.GetGood(ctx, projectID, id)
+	})
 	if err != nil {
 		return nil, err
 	}
-	// кэшируем результат
-	data, _ := json.Marshal(good)
-	_ = s.cache.Set(ctx, key, data, cacheTTL)
-	return good, nil
+	var g model.Good
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached good: %w", err)
+	}
+	return &g, nil
 }
 
 // Update обновляет поля товара:
 // 1. Валидирует, что новое имя не пустое
-// 2. Вызывает метод репозитория UpdateGood
-// 3. Инвалидирует кэш и публикует обновлённый объект
-func (s *GoodsService) Update(ctx context.Context, projectID, id int, name string, description *string) (*model.Good, error) {
+// 2. Вызывает метод репозитория UpdateGood с ожидаемой версией version (0 — без проверки);
+// событие good.updated пишется в outbox
+// 3. Инвалидирует кэш
+func (s *GoodsService) Update(ctx context.Context, projectID, id int, name string, description *string, version int) (_ *model.Good, err error) {
+	ctx, span := startSpan(ctx, "GoodsService.Update", projectID, id)
+	defer func() { tracing.End(span, err) }()
 	// валидация: имя не должно быть пустым
 	if name == "" {
-		return nil, errors.New("name cannot be empty")
+		return nil, errEmptyName
 	}
-	good, err := s.repo.UpdateGood(ctx, projectID, id, name, description)
+	good, err := s.repo.UpdateGood(ctx, projectID, id, name, description, version)
 	if err != nil {
 		return nil, err
 	}
-	_ = s.cache.Invalidate(ctx, "goods:list")
-	_ = s.cache.Invalidate(ctx, fmt.Sprintf("good:%d:%d", projectID, id))
-	data, _ := json.Marshal(good)
-	_ = s.logger.PublishLog(data)
+	s.invalidateLists(ctx, projectID)
+	_ = s.goodLoader.invalidate(ctx, fmt.Sprintf("good:%d:%d", projectID, id))
 	return good, nil
 }
 
-// Remove помечает товар как удалённый и публикует полный объект:
-// 1. Получает существующий объект через GetGood
-// 2. Вызывает RemoveGood для логического удаления
-// 3. Инвалидирует кэш списка и объекта
-// 4. Устанавливает флаг Removed и публикует объект в лог
-func (s *GoodsService) Remove(ctx context.Context, projectID, id int) error {
-	// получаем существующий товар
-	good, err := s.repo.GetGood(ctx, projectID, id)
-	if err != nil {
-		return err
-	}
+// Remove помечает товар как удалённый:
+// 1. Вызывает RemoveGood для логического удаления с ожидаемой версией version (0 — без проверки);
+// событие good.removed пишется в outbox
+// 2. Инвалидирует кэш списков проекта и объекта
+func (s *GoodsService) Remove(ctx context.Context, projectID, id, version int) (err error) {
+	ctx, span := startSpan(ctx, "GoodsService.Remove", projectID, id)
+	defer func() { tracing.End(span, err) }()
 	// удаляем товар
-	if err := s.repo.RemoveGood(ctx, projectID, id); err != nil {
+	if err := s.repo.RemoveGood(ctx, projectID, id, version); err != nil {
 		return err
 	}
 	// инвалидируем кэш
-	_ = s.cache.Invalidate(ctx, "goods:list")
-	_ = s.cache.Invalidate(ctx, fmt.Sprintf("good:%d:%d", projectID, id))
-	// помечаем объект как удалённый и отправляем в лог полный объект
-	good.Removed = true
-	data, _ := json.Marshal(good)
-	if err := s.logger.PublishLog(data); err != nil {
-		return err
-	}
+	s.invalidateLists(ctx, projectID)
+	_ = s.goodLoader.invalidate(ctx, fmt.Sprintf("good:%d:%d", projectID, id))
 	return nil
 }
 
-// List возвращает список товаров с метаданными:
-// 1. Пытается получить из кэша по ключу с limit/offset
-// 2. При промахе кэша запрашивает из репозитория
-// 3. Кэширует ответ (массив товаров и мета)
-func (s *GoodsService) List(ctx context.Context, limit, offset int) ([]model.Good, int, int, error) {
-	key := fmt.Sprintf("goods:list:%d:%d", limit, offset)
-	// пытаемся получить из кэша
-	if bytes, err := s.cache.Get(ctx, key); err == nil {
-		var resp struct {
-			Goods []model.Good `json:"goods"`
-			Meta  struct {
-				Total   int `json:"total"`
-				Removed int `json:"removed"`
-				Limit   int `json:"limit"`
-				Offset  int `json:"offset"`
-			} `json:"meta"`
+// goodsListResponse описывает закэшированную страницу списка товаров
+type goodsListResponse struct {
+	Goods []model.Good `json:"goods"`
+	Meta  struct {
+		Total   int `json:"total"`
+		Removed int `json:"removed"`
+		Limit   int `json:"limit"`
+		Offset  int `json:"offset"`
+	} `json:"meta"`
+}
+
+// List возвращает список товаров по фильтру с метаданными:
+// 1. Пытается получить из кэша по ключу, построенному из всех условий фильтра
+// 2. При промахе кэша запрашивает из репозитория (один запрос на все конкурентные промахи по ключу)
+// 3. Кэширует ответ (массив товаров и мета) с тегом списков проекта фильтра
+func (s *GoodsService) List(ctx context.Context, filter model.GoodsFilter) (_ []model.Good, _, _ int, err error) {
+	ctx, span := startSpan(ctx, "GoodsService.List", filter.ProjectID, 0)
+	defer func() { tracing.End(span, err) }()
+	key := goodsListKey(filter)
+	data, err := s.listLoader.load(ctx, key, []string{goodsListTag(filter.ProjectID)}, func(ctx context.Context) (interface{}, error) {
+		goods, total, removed, err := s.repo.ListGoods(ctx, filter)
+		if err != nil {
+			return nil, err
 		}
-		_ = json.Unmarshal(bytes, &resp)
-		return resp.Goods, resp.Meta.Total, resp.Meta.Removed, nil
-	}
-	// из БД
-	goods, total, removed, err := s.repo.ListGoods(ctx, limit, offset)
+		var resp goodsListResponse
+		resp.Goods = goods
+		resp.Meta.Total = total
+		resp.Meta.Removed = removed
+		resp.Meta.Limit = filter.Limit
+		resp.Meta.Offset = filter.Offset
+		return resp, nil
+	})
 	if err != nil {
 		return nil, 0, 0, err
 	}
-	// кэшируем ответ
-	resp := struct {
-		Goods []model.Good `json:"goods"`
-		Meta  struct {
-			Total   int `json:"total"`
-			Removed int `json:"removed"`
-			Limit   int `json:"limit"`
-			Offset  int `json:"offset"`
-		} `json:"meta"`
-	}{
-		Goods: goods,
-		Meta: struct {
-			Total   int `json:"total"`
-			Removed int `json:"removed"`
-			Limit   int `json:"limit"`
-			Offset  int `json:"offset"`
-		}{
-			Total:   total,
-			Removed: removed,
-			Limit:   limit,
-			Offset:  offset,
-		},
-	}
-	data, _ := json.Marshal(resp)
-	_ = s.cache.Set(ctx, key, data, cacheTTL)
-	return goods, total, removed, nil
+	var resp goodsListResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to unmarshal cached goods list: %w", err)
+	}
+	return resp.Goods, resp.Meta.Total, resp.Meta.Removed, nil
+}
+
+// goodsListKey строит ключ кэша страницы списка товаров
+// формат: goods:list:<projectId>:<removed>:<sort>:<desc>:<from>:<to>:<limit>:<offset>:<after>:<skipCounts>:<name>
+func goodsListKey(f model.GoodsFilter) string {
+	from, to, after := "", "", ""
+	if f.CreatedFrom != nil {
+		from = f.CreatedFrom.UTC().Format(time.RFC3339Nano)
+	}
+	if f.CreatedTo != nil {
+		to = f.CreatedTo.UTC().Format(time.RFC3339Nano)
+	}
+	if f.After != nil {
+		after = fmt.Sprintf("%d-%d", f.After.Priority, f.After.ID)
+	}
+	return fmt.Sprintf("goods:list:%d:%s:%s:%t:%s:%s:%d:%d:%s:%t:%s",
+		f.ProjectID, f.Removed, f.Sort, f.Desc, from, to, f.Limit, f.Offset, after, f.SkipCounts, f.Name)
+}
+
+// goodsListTag возвращает тег страниц списка товаров проекта; projectID=0 — страницы по всем проектам
+func goodsListTag(projectID int) string {
+	return fmt.Sprintf("goods:list:project:%d", projectID)
+}
+
+// invalidateLists инвалидирует страницы списка товаров, которые может затронуть изменение в проекте:
+// страницы самого проекта и страницы без фильтра по проекту
+func (s *GoodsService) invalidateLists(ctx context.Context, projectID int) {
+	_ = s.listLoader.invalidateTags(ctx, goodsListTag(projectID), goodsListTag(0))
 }
 
 // Reprioritize изменяет приоритет заданного товара и возвращает обновления:
-// 1. Вызывает метод репозитория Reprioritize
-// 2. Инвалидирует кэш
-// 3. Публикует массив обновлённых приоритетов в лог
-func (s *GoodsService) Reprioritize(ctx context.Context, projectID, id, newPriority int) ([]model.PriorityUpdate, error) {
-	updates, err := s.repo.Reprioritize(ctx, projectID, id, newPriority)
+// 1. Вызывает метод репозитория Reprioritize с ожидаемой версией version (0 — без проверки);
+// событие good.reprioritized пишется в outbox
+// 2. Инвалидирует кэш списков проекта и всех товаров, чей приоритет изменился
+func (s *GoodsService) Reprioritize(ctx context.Context, projectID, id, newPriority, version int) (_ []model.PriorityUpdate, err error) {
+	ctx, span := startSpan(ctx, "GoodsService.Reprioritize", projectID, id)
+	defer func() { tracing.End(span, err) }()
+	updates, err := s.repo.Reprioritize(ctx, projectID, id, newPriority, version)
 	if err != nil {
 		return nil, err
 	}
-	// инвалидируем кэш списка и конкретного товара
-	_ = s.cache.Invalidate(ctx, "goods:list")
-	_ = s.cache.Invalidate(ctx, fmt.Sprintf("good:%d:%d", projectID, id))
-	// публикуем лог изменений
-	data, _ := json.Marshal(updates)
-	_ = s.logger.PublishLog(data)
+	// инвалидируем кэш списков и товаров со сдвинутым приоритетом
+	s.invalidateLists(ctx, projectID)
+	_ = s.goodLoader.invalidate(ctx, fmt.Sprintf("good:%d:%d", projectID, id))
+	for _, u := range updates {
+		if u.ID != id {
+			_ = s.goodLoader.invalidate(ctx, fmt.Sprintf("good:%d:%d", projectID, u.ID))
+		}
+	}
 	return updates, nil
 }
+
+// BulkCreate создаёт товары проекта одной транзакцией и возвращает их в порядке items:
+// 1. Валидирует, что имена не пустые
+// 2. Вызывает метод репозитория CreateGoods (событие good.created для каждого товара пишется в outbox)
+// 3. Инвалидирует кэш списков проекта и созданных товаров
+func (s *GoodsService) BulkCreate(ctx context.Context, projectID int, items []model.GoodInput) (_ []model.Good, err error) {
+	ctx, span := startSpan(ctx, "GoodsService.BulkCreate", projectID, 0)
+	defer func() { tracing.End(span, err) }()
+	span.SetAttributes(attribute.Int("bulk.items", len(items)))
+	for _, item := range items {
+		if item.Name == "" {
+			return nil, errEmptyName
+		}
+	}
+	goods, err := s.repo.CreateGoods(ctx, projectID, items)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateLists(ctx, projectID)
+	for _, g := range goods {
+		_ = s.goodLoader.invalidate(ctx, fmt.Sprintf("good:%d:%d", projectID, g.ID))
+	}
+	return goods, nil
+}
+
+// BulkUpdate обновляет товары проекта одной транзакцией и возвращает результаты в порядке items:
+// 1. Вызывает метод репозитория UpdateGoods; в атомарном режиме (atomic) ошибка одного элемента отменяет все изменения,
+// иначе применяются элементы без ошибок (событие good.updated для каждого изменённого товара пишется в outbox)
+// 2. Инвалидирует кэш списков проекта и изменённых товаров
+func (s *GoodsService) BulkUpdate(ctx context.Context, projectID int, items []model.GoodUpdate, atomic bool) (_ []model.BulkResult, err error) {
+	ctx, span := startSpan(ctx, "GoodsService.BulkUpdate", projectID, 0)
+	defer func() { tracing.End(span, err) }()
+	span.SetAttributes(attribute.Int("bulk.items", len(items)), attribute.Bool("bulk.atomic", atomic))
+	results, err := s.repo.UpdateGoods(ctx, projectID, items, atomic)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateBulk(ctx, projectID, results)
+	return results, nil
+}
+
+// BulkRemove помечает товары проекта удалёнными одной транзакцией и возвращает результаты в порядке items:
+// 1. Вызывает метод репозитория RemoveGoods; атомарный режим — как у BulkUpdate
+// (событие good.removed для каждого удалённого товара пишется в outbox)
+// 2. Инвалидирует кэш списков проекта и удалённых товаров
+func (s *GoodsService) BulkRemove(ctx context.Context, projectID int, items []model.GoodRemoval, atomic bool) (_ []model.BulkResult, err error) {
+	ctx, span := startSpan(ctx, "GoodsService.BulkRemove", projectID, 0)
+	defer func() { tracing.End(span, err) }()
+	span.SetAttributes(attribute.Int("bulk.items", len(items)), attribute.Bool("bulk.atomic", atomic))
+	results, err := s.repo.RemoveGoods(ctx, projectID, items, atomic)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateBulk(ctx, projectID, results)
+	return results, nil
+}
+
+// invalidateBulk инвалидирует кэш списков проекта и товаров, изменённых пакетной операцией
+func (s *GoodsService) invalidateBulk(ctx context.Context, projectID int, results []model.BulkResult) {
+	changed := false
+	for _, res := range results {
+		if res.Good != nil {
+			changed = true
+			_ = s.goodLoader.invalidate(ctx, fmt.Sprintf("good:%d:%d", projectID, res.Good.ID))
+		}
+	}
+	if changed {
+		s.invalidateLists(ctx, projectID)
+	}
+}
+
+// startSpan начинает спан метода сервиса с идентификаторами проекта и товара; нулевые идентификаторы не записываются
+func startSpan(ctx context.Context, name string, projectID, id int) (context.Context, trace.Span) {
+	var attrs []attribute.KeyValue
+	if projectID != 0 {
+		attrs = append(attrs, attribute.Int("project.id", projectID))
+	}
+	if id != 0 {
+		attrs = append(attrs, attribute.Int("good.id", id))
+	}
+	return tracing.Start(ctx, name, trace.WithAttributes(attrs...))
+}
@@ -3,14 +3,20 @@ package service
 import (
 	cachepkg "HezzlTestTask/pkg/cache"
 	"context"
-	"encoding/json"
 	"errors"
 	"reflect"
 	"testing"
 	"time"
 
+	"HezzlTestTask/internal/apperr"
 	"HezzlTestTask/internal/model"
 	"HezzlTestTask/internal/repository"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // mockRepo реализует интерфейс репозитория для тестирования сервиса GoodsService.
@@ -21,13 +27,17 @@ import (
 // - removeFn: поведение RemoveGood
 // - listFn: поведение ListGoods
 // - reprioritizeFn: поведение Reprioritize
+// - createGoodsFn, updateGoodsFn, removeGoodsFn: поведение пакетных CreateGoods, UpdateGoods, RemoveGoods
 type mockRepo struct {
 	createFn       func(ctx context.Context, projectID int, name string, description *string) (*model.Good, error)
 	getFn          func(ctx context.Context, projectID, id int) (*model.Good, error)
-	updateFn       func(ctx context.Context, projectID, id int, name string, description *string) (*model.Good, error)
-	removeFn       func(ctx context.Context, projectID, id int) error
-	listFn         func(ctx context.Context, limit, offset int) ([]model.Good, int, int, error)
-	reprioritizeFn func(ctx context.Context, projectID, id, newPriority int) ([]model.PriorityUpdate, error)
+	updateFn       func(ctx context.Context, projectID, id int, name string, description *string, version int) (*model.Good, error)
+	removeFn       func(ctx context.Context, projectID, id, version int) error
+	listFn         func(ctx context.Context, filter model.GoodsFilter) ([]model.Good, int, int, error)
+	reprioritizeFn func(ctx context.Context, projectID, id, newPriority, version int) ([]model.PriorityUpdate, error)
+	createGoodsFn  func(ctx context.Context, projectID int, items []model.GoodInput) ([]model.Good, error)
+	updateGoodsFn  func(ctx context.Context, projectID int, items []model.GoodUpdate, atomic bool) ([]model.BulkResult, error)
+	removeGoodsFn  func(ctx context.Context, projectID int, items []model.GoodRemoval, atomic bool) ([]model.BulkResult, error)
 }
 
 func (m *mockRepo) CreateGood(ctx context.Context, projectID int, name string, description *string) (*model.Good, error) {
@@ -40,27 +50,40 @@ func (m *mockRepo) GetGood(ctx context.Context, projectID, id int) (*model.Good,
 	// по умолчанию возвращаем объект без ошибки, чтобы не паниковать
 	return &model.Good{ID: id, ProjectID: projectID}, nil
 }
-func (m *mockRepo) UpdateGood(ctx context.Context, projectID, id int, name string, description *string) (*model.Good, error) {
-	return m.updateFn(ctx, projectID, id, name, description)
+func (m *mockRepo) UpdateGood(ctx context.Context, projectID, id int, name string, description *string, version int) (*model.Good, error) {
+	return m.updateFn(ctx, projectID, id, name, description, version)
+}
+func (m *mockRepo) RemoveGood(ctx context.Context, projectID, id, version int) error {
+	return m.removeFn(ctx, projectID, id, version)
+}
+func (m *mockRepo) ListGoods(ctx context.Context, filter model.GoodsFilter) ([]model.Good, int, int, error) {
+	return m.listFn(ctx, filter)
 }
-func (m *mockRepo) RemoveGood(ctx context.Context, projectID, id int) error {
-	return m.removeFn(ctx, projectID, id)
+func (m *mockRepo) Reprioritize(ctx context.Context, projectID, id, newPriority, version int) ([]model.PriorityUpdate, error) {
+	return m.reprioritizeFn(ctx, projectID, id, newPriority, version)
 }
-func (m *mockRepo) ListGoods(ctx context.Context, limit, offset int) ([]model.Good, int, int, error) {
-	return m.listFn(ctx, limit, offset)
+func (m *mockRepo) CreateGoods(ctx context.Context, projectID int, items []model.GoodInput) ([]model.Good, error) {
+	return m.createGoodsFn(ctx, projectID, items)
 }
-func (m *mockRepo) Reprioritize(ctx context.Context, projectID, id, newPriority int) ([]model.PriorityUpdate, error) {
-	return m.reprioritizeFn(ctx, projectID, id, newPriority)
+func (m *mockRepo) UpdateGoods(ctx context.Context, projectID int, items []model.GoodUpdate, atomic bool) ([]model.BulkResult, error) {
+	return m.updateGoodsFn(ctx, projectID, items, atomic)
+}
+func (m *mockRepo) RemoveGoods(ctx context.Context, projectID int, items []model.GoodRemoval, atomic bool) ([]model.BulkResult, error) {
+	return m.removeGoodsFn(ctx, projectID, items, atomic)
 }
 
 // mockCache симулирует кэш Redis с настраиваемым поведением методов
 // - set: сохраняет данные
 // - get: получает данные
 // - inval: инвалидирует ключ
+// - setTagged: сохраняет данные с тегами
+// - invalTags: инвалидирует записи по тегам
 type mockCache struct {
-	set   func(ctx context.Context, key string, value []byte, ttl time.Duration) error
-	get   func(ctx context.Context, key string) ([]byte, error)
-	inval func(ctx context.Context, key string) error
+	set       func(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	get       func(ctx context.Context, key string) ([]byte, error)
+	inval     func(ctx context.Context, key string) error
+	setTagged func(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error
+	invalTags func(ctx context.Context, tags []string) error
 }
 
 func (m *mockCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
@@ -81,19 +104,29 @@ func (m *mockCache) Invalidate(ctx context.Context, key string) error {
 	}
 	return m.inval(ctx, key)
 }
-
-// mockLogger симулирует логгер, принимает данные для публикации
-// pub: функция, записывающая переданное сообщение
-type mockLogger struct {
-	pub func(data []byte) error
+func (m *mockCache) SetTagged(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	if m.setTagged == nil {
+		return nil
+	}
+	return m.setTagged(ctx, key, value, ttl, tags)
+}
+func (m *mockCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	if m.invalTags == nil {
+		return nil
+	}
+	return m.invalTags(ctx, tags)
 }
 
-func (m *mockLogger) PublishLog(data []byte) error {
-	return m.pub(data)
+// recordingCache возвращает mockCache, записывающий инвалидированные ключи и теги
+func recordingCache(keys, tags *[]string) *mockCache {
+	return &mockCache{
+		inval:     func(ctx context.Context, key string) error { *keys = append(*keys, key); return nil },
+		invalTags: func(ctx context.Context, t []string) error { *tags = append(*tags, t...); return nil },
+	}
 }
 
-func newService(repo *mockRepo, cache *mockCache, logger *mockLogger) *GoodsService {
-	return &GoodsService{repo: repo, cache: cache, logger: logger}
+func newService(repo *mockRepo, cache *mockCache) *GoodsService {
+	return NewGoodsService(repo, cache)
 }
 
 // TestCreate_Success проверяет сценарий успешного создания товара
@@ -108,38 +141,22 @@ func TestCreate_Success(t *testing.T) {
 		// Возвращаем заранее подготовленный объект без ошибки
 		return good, nil
 	}}
-	// Arrange: готовим срез для проверки ключей, которые инвалидируются в кеше
-	var keysInvalidated []string
-	cache := &mockCache{
-		inval: func(ctx context.Context, key string) error {
-			// накапливаем переданные ключи
-			keysInvalidated = append(keysInvalidated, key)
-			return nil
-		},
-	}
-	// Arrange: настраиваем логгер-заглушку, записывающую публикуемые данные
-	var logged []byte
-	logger := &mockLogger{pub: func(data []byte) error {
-		// сохраняем payload для последующей проверки
-		logged = data
-		return nil
-	}}
+	// Arrange: готовим срезы для проверки ключей и тегов, которые инвалидируются в кеше
+	var keysInvalidated, tagsInvalidated []string
+	cache := recordingCache(&keysInvalidated, &tagsInvalidated)
 	// Act: создаём сервис и вызываем Create
-	s := newService(repo, cache, logger)
+	s := newService(repo, cache)
 	r, err := s.Create(context.Background(), 10, "n", ptr("d"))
 	// Assert: проверяем, что ошибки нет и возвращён правильный объект
 	if err != nil || !reflect.DeepEqual(r, good) {
 		t.Fatalf("Create returned %v, %v, want %v, nil", r, err, good)
 	}
-	// Assert: проверяем, что кэш инвалидировался дважды (список и конкретный товар)
-	if len(keysInvalidated) != 2 {
-		t.Fatalf("expected 2 cache invalidations, got %d", len(keysInvalidated))
+	// Assert: проверяем, что инвалидированы товар и списки только его проекта (и списки по всем проектам)
+	if !reflect.DeepEqual(keysInvalidated, []string{"good:10:1"}) {
+		t.Fatalf("unexpected key invalidations %v", keysInvalidated)
 	}
-	// Assert: проверяем содержимое лог-сообщения
-	var out model.Good
-	_ = json.Unmarshal(logged, &out)
-	if out.ID != good.ID || out.Name != good.Name {
-		t.Fatalf("logged payload mismatch, got %+v", out)
+	if !reflect.DeepEqual(tagsInvalidated, []string{"goods:list:project:10", "goods:list:project:0"}) {
+		t.Fatalf("unexpected tag invalidations %v", tagsInvalidated)
 	}
 }
 
@@ -147,11 +164,10 @@ func TestCreate_Success(t *testing.T) {
 func TestCreate_EmptyName(t *testing.T) {
 	repo := &mockRepo{createFn: nil}
 	cache := &mockCache{}
-	logger := &mockLogger{}
-	s := newService(repo, cache, logger)
+	s := newService(repo, cache)
 	_, err := s.Create(context.Background(), 1, "", nil)
-	if err == nil {
-		t.Fatal("expected error for empty name")
+	if !errors.Is(err, apperr.ErrValidation) || apperr.FieldsOf(err)["name"] == "" {
+		t.Fatalf("expected validation error for name, got %v", err)
 	}
 }
 
@@ -171,12 +187,8 @@ func TestGet_Success(t *testing.T) {
 		// эмулируем cache miss
 		return nil, cachepkg.ErrCacheMiss
 	}}
-	logger := &mockLogger{pub: func(data []byte) error {
-		// при cache miss лог не вызывается для Get
-		return nil
-	}}
 	// Act: вызываем Get
-	s := newService(repo, cache, logger)
+	s := newService(repo, cache)
 	g, err := s.Get(context.Background(), 3, 2)
 	// Assert: проверяем, что данные из репозитория возвращены без ошибок
 	if err != nil || !reflect.DeepEqual(g, repoData) {
@@ -188,19 +200,14 @@ func TestGet_Success(t *testing.T) {
 func TestGet_FromCache(t *testing.T) {
 	// Arrange: сериализуем ожидаемый объект в JSON и настраиваем кэш-заглушку
 	exp := &model.Good{ID: 5, ProjectID: 1, Name: "c"}
-	data, _ := json.Marshal(exp)
+	data := freshEntry(t, exp)
 	repo := &mockRepo{} // репозиторий не должен вызываться
 	cache := &mockCache{get: func(ctx context.Context, key string) ([]byte, error) {
 		// возвращаем заранее сериализованный объект
 		return data, nil
 	}}
-	logger := &mockLogger{pub: func(data []byte) error {
-		// проверяем, что лог при чтении из кэша не вызывается
-		t.Fatal("logger should not be called on cache hit")
-		return nil
-	}}
 	// Act: вызываем Get, должно вернуть объект из кэша
-	s := newService(repo, cache, logger)
+	s := newService(repo, cache)
 	g, err := s.Get(context.Background(), 1, 5)
 	// Assert: объект и ошибка
 	if err != nil || !reflect.DeepEqual(g, exp) {
@@ -215,30 +222,50 @@ func TestGet_Error(t *testing.T) {
 		return nil, testErr
 	}}
 	cache := &mockCache{get: func(ctx context.Context, key string) ([]byte, error) { return nil, cachepkg.ErrCacheMiss }}
-	logger := &mockLogger{}
-	s := newService(repo, cache, logger)
+	s := newService(repo, cache)
 	_, err := s.Get(context.Background(), 1, 1)
 	if err != testErr {
 		t.Fatalf("expected error %v, got %v", testErr, err)
 	}
 }
 
+// TestGet_Span проверяет спан метода сервиса: идентификаторы в атрибутах и отметку ошибки репозитория
+func TestGet_Span(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	repo := &mockRepo{getFn: func(ctx context.Context, projectID, id int) (*model.Good, error) {
+		return nil, errors.New("repo error")
+	}}
+	cache := &mockCache{get: func(ctx context.Context, key string) ([]byte, error) { return nil, cachepkg.ErrCacheMiss }}
+	_, _ = newService(repo, cache).Get(context.Background(), 1, 7)
+
+	spans := rec.Ended()
+	if len(spans) != 1 || spans[0].Name() != "GoodsService.Get" {
+		t.Fatalf("unexpected spans %v", spans)
+	}
+	want := []attribute.KeyValue{attribute.Int("project.id", 1), attribute.Int("good.id", 7)}
+	if !reflect.DeepEqual(spans[0].Attributes(), want) {
+		t.Errorf("expected attributes %v, got %v", want, spans[0].Attributes())
+	}
+	if spans[0].Status().Code != codes.Error || spans[0].Status().Description != "repo error" {
+		t.Errorf("expected error status, got %+v", spans[0].Status())
+	}
+}
+
 // TestUpdate_Success проверяет сценарий успешного обновления товара
 func TestUpdate_Success(t *testing.T) {
 	exp := &model.Good{ID: 3, ProjectID: 4, Name: "u"}
-	repo := &mockRepo{updateFn: func(ctx context.Context, projectID, id int, name string, description *string) (*model.Good, error) {
+	repo := &mockRepo{updateFn: func(ctx context.Context, projectID, id int, name string, description *string, version int) (*model.Good, error) {
 		return exp, nil
 	}}
-	var inv []string
-	cache := &mockCache{inval: func(ctx context.Context, key string) error { inv = append(inv, key); return nil }}
-	logger := &mockLogger{pub: func(data []byte) error { return nil }}
-	s := newService(repo, cache, logger)
-	g, err := s.Update(context.Background(), 4, 3, "u", nil)
+	var inv, tags []string
+	s := newService(repo, recordingCache(&inv, &tags))
+	g, err := s.Update(context.Background(), 4, 3, "u", nil, 0)
 	if err != nil || !reflect.DeepEqual(g, exp) {
 		t.Fatal("Update failed")
 	}
-	if len(inv) != 2 {
-		t.Fatal("invalidate")
+	if !reflect.DeepEqual(inv, []string{"good:4:3"}) || !reflect.DeepEqual(tags, []string{"goods:list:project:4", "goods:list:project:0"}) {
+		t.Fatalf("unexpected invalidations %v %v", inv, tags)
 	}
 }
 
@@ -246,75 +273,47 @@ func TestUpdate_Success(t *testing.T) {
 func TestUpdate_EmptyName(t *testing.T) {
 	repo := &mockRepo{}
 	cache := &mockCache{}
-	logger := &mockLogger{}
-	s := newService(repo, cache, logger)
-	_, err := s.Update(context.Background(), 1, 1, "", nil)
-	if err == nil {
-		t.Fatal("empty name")
+	s := newService(repo, cache)
+	_, err := s.Update(context.Background(), 1, 1, "", nil, 0)
+	if !errors.Is(err, apperr.ErrValidation) {
+		t.Fatalf("expected validation error, got %v", err)
 	}
 }
 
 // TestUpdate_NotFound проверяет возврат ErrNotFound при обновлении несуществующего товара
 func TestUpdate_NotFound(t *testing.T) {
-	repo := &mockRepo{updateFn: func(ctx context.Context, projectID, id int, name string, description *string) (*model.Good, error) {
+	repo := &mockRepo{updateFn: func(ctx context.Context, projectID, id int, name string, description *string, version int) (*model.Good, error) {
 		return nil, repository.ErrNotFound
 	}}
 	cache := &mockCache{}
-	logger := &mockLogger{}
-	s := newService(repo, cache, logger)
-	_, err := s.Update(context.Background(), 1, 1, "name", nil)
+	s := newService(repo, cache)
+	_, err := s.Update(context.Background(), 1, 1, "name", nil, 0)
 	if err != repository.ErrNotFound {
 		t.Fatalf("expected ErrNotFound, got %v", err)
 	}
 }
 
-// TestRemove_Success проверяет успешное логическое удаление товара и публикацию лога
+// TestRemove_Success проверяет успешное логическое удаление товара и инвалидирование кэша
 func TestRemove_Success(t *testing.T) {
-	repo := &mockRepo{removeFn: func(ctx context.Context, projectID, id int) error { return nil }}
-	var inv []string
-	cache := &mockCache{inval: func(ctx context.Context, key string) error { inv = append(inv, key); return nil }}
-	var logged []byte
-	logger := &mockLogger{pub: func(data []byte) error { logged = data; return nil }}
-	s := newService(repo, cache, logger)
-	err := s.Remove(context.Background(), 7, 8)
+	repo := &mockRepo{removeFn: func(ctx context.Context, projectID, id, version int) error { return nil }}
+	var inv, tags []string
+	s := newService(repo, recordingCache(&inv, &tags))
+	err := s.Remove(context.Background(), 7, 8, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(inv) != 2 {
-		t.Fatal("invalidate")
-	}
-	var out model.Good
-	_ = json.Unmarshal(logged, &out)
-	if !out.Removed {
-		t.Fatal("log removed")
-	}
-}
-
-// TestRemove_GetError проверяет ситуацию, когда не удалось получить товар для удаления
-func TestRemove_GetError(t *testing.T) {
-	testErr := errors.New("get error")
-	repo := &mockRepo{getFn: func(ctx context.Context, projectID, id int) (*model.Good, error) {
-		return nil, testErr
-	}}
-	s := newService(repo, &mockCache{}, &mockLogger{})
-	err := s.Remove(context.Background(), 1, 1)
-	if err != testErr {
-		t.Fatalf("expected error %v, got %v", testErr, err)
+	if !reflect.DeepEqual(inv, []string{"good:7:8"}) || !reflect.DeepEqual(tags, []string{"goods:list:project:7", "goods:list:project:0"}) {
+		t.Fatalf("unexpected invalidations %v %v", inv, tags)
 	}
 }
 
 // TestRemove_RemoveError проверяет обработку ошибки удаления товара в репозитории
 func TestRemove_RemoveError(t *testing.T) {
-	repo := &mockRepo{
-		getFn: func(ctx context.Context, projectID, id int) (*model.Good, error) {
-			return &model.Good{ID: id, ProjectID: projectID}, nil
-		},
-		removeFn: func(ctx context.Context, projectID, id int) error {
-			return errors.New("remove error")
-		},
-	}
-	s := newService(repo, &mockCache{}, &mockLogger{})
-	err := s.Remove(context.Background(), 1, 1)
+	repo := &mockRepo{removeFn: func(ctx context.Context, projectID, id, version int) error {
+		return errors.New("remove error")
+	}}
+	s := newService(repo, &mockCache{})
+	err := s.Remove(context.Background(), 1, 1, 0)
 	if err == nil || err.Error() != "remove error" {
 		t.Fatalf("expected remove error, got %v", err)
 	}
@@ -322,9 +321,9 @@ func TestRemove_RemoveError(t *testing.T) {
 
 // TestRemove_NotFound проверяет возвращаемый ErrNotFound при отсутствии товара
 func TestRemove_NotFound(t *testing.T) {
-	repo := &mockRepo{removeFn: func(ctx context.Context, projectID, id int) error { return repository.ErrNotFound }}
-	s := newService(repo, &mockCache{}, &mockLogger{})
-	err := s.Remove(context.Background(), 1, 1)
+	repo := &mockRepo{removeFn: func(ctx context.Context, projectID, id, version int) error { return repository.ErrNotFound }}
+	s := newService(repo, &mockCache{})
+	err := s.Remove(context.Background(), 1, 1, 0)
 	if err != repository.ErrNotFound {
 		t.Fatal("expected notfound")
 	}
@@ -333,21 +332,36 @@ func TestRemove_NotFound(t *testing.T) {
 // TestList_Success проверяет успешное получение списка товаров и запись в кэш
 func TestList_Success(t *testing.T) {
 	list := []model.Good{{ID: 9, ProjectID: 1, Name: "x"}}
-	repo := &mockRepo{listFn: func(ctx context.Context, limit, offset int) ([]model.Good, int, int, error) { return list, 5, 1, nil }}
+	filter := model.GoodsFilter{ProjectID: 1, Removed: model.RemovedExclude, Sort: model.SortByPriority, Limit: 2, Offset: 3}
+	repo := &mockRepo{listFn: func(ctx context.Context, f model.GoodsFilter) ([]model.Good, int, int, error) {
+		if !reflect.DeepEqual(f, filter) {
+			t.Fatalf("unexpected filter %+v", f)
+		}
+		return list, 5, 1, nil
+	}}
 	var cached []byte
-	cache := &mockCache{set: func(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var cachedKey string
+	var cachedTags []string
+	cache := &mockCache{setTagged: func(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
 		cached = value
+		cachedKey = key
+		cachedTags = tags
 		return nil
 	}}
-	logger := &mockLogger{pub: func(data []byte) error { return nil }}
-	s := newService(repo, cache, logger)
-	goods, total, removed, err := s.List(context.Background(), 2, 3)
+	s := newService(repo, cache)
+	goods, total, removed, err := s.List(context.Background(), filter)
 	if err != nil || total != 5 || removed != 1 || !reflect.DeepEqual(goods, list) {
 		t.Fatal("List failed")
 	}
 	if len(cached) == 0 {
 		t.Fatal("cache set")
 	}
+	if cachedKey != goodsListKey(filter) {
+		t.Fatalf("unexpected cache key %s", cachedKey)
+	}
+	if !reflect.DeepEqual(cachedTags, []string{goodsListTag(filter.ProjectID)}) {
+		t.Fatalf("unexpected cache tags %v", cachedTags)
+	}
 }
 
 // TestList_CacheHit проверяет получение списка товаров из кэша без вызова БД
@@ -368,12 +382,11 @@ func TestList_CacheHit(t *testing.T) {
 	resp.Meta.Removed = 1
 	resp.Meta.Limit = 5
 	resp.Meta.Offset = 0
-	data, _ := json.Marshal(resp)
+	data := freshEntry(t, resp)
 	repo := &mockRepo{}
 	cache := &mockCache{get: func(ctx context.Context, key string) ([]byte, error) { return data, nil }}
-	logger := &mockLogger{}
-	s := newService(repo, cache, logger)
-	gotGoods, total, removed, err := s.List(context.Background(), 5, 0)
+	s := newService(repo, cache)
+	gotGoods, total, removed, err := s.List(context.Background(), model.GoodsFilter{Limit: 5})
 	if err != nil {
 		t.Fatalf("List cache hit returned error: %v", err)
 	}
@@ -385,56 +398,146 @@ func TestList_CacheHit(t *testing.T) {
 // TestList_ServiceError проверяет обработку ошибки репозитория при получении списка
 func TestList_ServiceError(t *testing.T) {
 	testErr := errors.New("service error")
-	repo := &mockRepo{listFn: func(ctx context.Context, limit, offset int) ([]model.Good, int, int, error) {
+	repo := &mockRepo{listFn: func(ctx context.Context, filter model.GoodsFilter) ([]model.Good, int, int, error) {
 		return nil, 0, 0, testErr
 	}}
 	cache := &mockCache{}
-	logger := &mockLogger{}
-	s := newService(repo, cache, logger)
-	_, _, _, err := s.List(context.Background(), 0, 0)
+	s := newService(repo, cache)
+	_, _, _, err := s.List(context.Background(), model.GoodsFilter{})
 	if err == nil || err.Error() != testErr.Error() {
 		t.Fatalf("expected error %v, got %v", testErr, err)
 	}
 }
 
-// TestReprioritize_Success проверяет успешное изменение приоритетов и публикацию лога
+// TestGoodsListKey_DistinctFilters проверяет, что разные фильтры дают разные ключи кэша
+func TestGoodsListKey_DistinctFilters(t *testing.T) {
+	from := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	filters := []model.GoodsFilter{
+		{Limit: 10},
+		{ProjectID: 1, Limit: 10},
+		{ProjectID: 1, Removed: model.RemovedOnly, Limit: 10},
+		{ProjectID: 1, Name: "phone", Limit: 10},
+		{ProjectID: 1, CreatedFrom: &from, Limit: 10},
+		{ProjectID: 1, Sort: model.SortByName, Desc: true, Limit: 10},
+		{ProjectID: 1, Limit: 10, Offset: 10},
+		{ProjectID: 1, Limit: 10, SkipCounts: true},
+		{ProjectID: 1, Limit: 10, After: &model.GoodsCursor{Priority: 3, ID: 7}},
+		{ProjectID: 1, Limit: 10, After: &model.GoodsCursor{Priority: 37, ID: 0}},
+	}
+	seen := map[string]bool{}
+	for _, f := range filters {
+		key := goodsListKey(f)
+		if seen[key] {
+			t.Fatalf("duplicate cache key %s for filter %+v", key, f)
+		}
+		seen[key] = true
+	}
+}
+
+// TestReprioritize_Success проверяет успешное изменение приоритетов и инвалидирование кэша
 func TestReprioritize_Success(t *testing.T) {
-	exp := []model.PriorityUpdate{{ID: 1, Priority: 2}}
-	repo := &mockRepo{reprioritizeFn: func(ctx context.Context, projectID, id, new int) ([]model.PriorityUpdate, error) { return exp, nil }}
-	var inv []string
-	cache := &mockCache{inval: func(ctx context.Context, key string) error { inv = append(inv, key); return nil }}
-	var logged []byte
-	logger := &mockLogger{pub: func(data []byte) error { logged = data; return nil }}
-	s := newService(repo, cache, logger)
-	ups, err := s.Reprioritize(context.Background(), 2, 3, 4)
+	exp := []model.PriorityUpdate{{ID: 1, Priority: 2}, {ID: 3, Priority: 1}}
+	repo := &mockRepo{reprioritizeFn: func(ctx context.Context, projectID, id, new, version int) ([]model.PriorityUpdate, error) {
+		return exp, nil
+	}}
+	var inv, tags []string
+	s := newService(repo, recordingCache(&inv, &tags))
+	ups, err := s.Reprioritize(context.Background(), 2, 3, 4, 0)
 	if err != nil || !reflect.DeepEqual(ups, exp) {
 		t.Fatal("repr failed")
 	}
-	if len(inv) != 2 {
-		t.Fatal("invalidate repr")
-	}
-	// log содержит JSON массив
-	var arr []model.PriorityUpdate
-	_ = json.Unmarshal(logged, &arr)
-	if !reflect.DeepEqual(arr, exp) {
-		t.Fatal("log repr")
+	// инвалидируются целевой товар и товары со сдвинутым приоритетом
+	if !reflect.DeepEqual(inv, []string{"good:2:3", "good:2:1"}) || !reflect.DeepEqual(tags, []string{"goods:list:project:2", "goods:list:project:0"}) {
+		t.Fatalf("unexpected invalidations %v %v", inv, tags)
 	}
 }
 
 // TestReprioritize_Error проверяет обработку ошибки при пересортировке приоритетов
 func TestReprioritize_Error(t *testing.T) {
 	testErr := errors.New("repr error")
-	repo := &mockRepo{reprioritizeFn: func(ctx context.Context, projectID, id, newPriority int) ([]model.PriorityUpdate, error) {
+	repo := &mockRepo{reprioritizeFn: func(ctx context.Context, projectID, id, newPriority, version int) ([]model.PriorityUpdate, error) {
 		return nil, testErr
 	}}
 	cache := &mockCache{}
-	logger := &mockLogger{}
-	s := newService(repo, cache, logger)
-	_, err := s.Reprioritize(context.Background(), 1, 1, 2)
+	s := newService(repo, cache)
+	_, err := s.Reprioritize(context.Background(), 1, 1, 2, 0)
 	if err != testErr {
 		t.Fatalf("expected error %v, got %v", testErr, err)
 	}
 }
 
+// TestBulkCreate проверяет пакетное создание и инвалидирование кэша списков и созданных товаров
+func TestBulkCreate(t *testing.T) {
+	exp := []model.Good{{ID: 5, ProjectID: 2, Name: "a"}, {ID: 6, ProjectID: 2, Name: "b"}}
+	repo := &mockRepo{createGoodsFn: func(ctx context.Context, projectID int, items []model.GoodInput) ([]model.Good, error) {
+		return exp, nil
+	}}
+	var inv, tags []string
+	s := newService(repo, recordingCache(&inv, &tags))
+	goods, err := s.BulkCreate(context.Background(), 2, []model.GoodInput{{Name: "a"}, {Name: "b"}})
+	if err != nil || !reflect.DeepEqual(goods, exp) {
+		t.Fatalf("unexpected result %v %v", goods, err)
+	}
+	if !reflect.DeepEqual(inv, []string{"good:2:5", "good:2:6"}) || !reflect.DeepEqual(tags, []string{"goods:list:project:2", "goods:list:project:0"}) {
+		t.Fatalf("unexpected invalidations %v %v", inv, tags)
+	}
+}
+
+// TestBulkCreate_EmptyName проверяет, что пакет с пустым именем отклоняется без обращения к репозиторию
+func TestBulkCreate_EmptyName(t *testing.T) {
+	s := newService(&mockRepo{}, &mockCache{})
+	_, err := s.BulkCreate(context.Background(), 1, []model.GoodInput{{Name: "a"}, {Name: ""}})
+	if !errors.Is(err, apperr.ErrValidation) || apperr.FieldsOf(err)["name"] == "" {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
+
+// TestBulkUpdate проверяет, что инвалидируются только изменённые товары
+func TestBulkUpdate(t *testing.T) {
+	exp := []model.BulkResult{{Good: &model.Good{ID: 1, ProjectID: 2}}, {Err: repository.ErrNotFound}}
+	repo := &mockRepo{updateGoodsFn: func(ctx context.Context, projectID int, items []model.GoodUpdate, atomic bool) ([]model.BulkResult, error) {
+		if atomic {
+			t.Error("expected bestEffort mode")
+		}
+		return exp, nil
+	}}
+	var inv, tags []string
+	s := newService(repo, recordingCache(&inv, &tags))
+	res, err := s.BulkUpdate(context.Background(), 2, []model.GoodUpdate{{ID: 1}, {ID: 9}}, false)
+	if err != nil || !reflect.DeepEqual(res, exp) {
+		t.Fatalf("unexpected result %v %v", res, err)
+	}
+	if !reflect.DeepEqual(inv, []string{"good:2:1"}) || len(tags) != 2 {
+		t.Fatalf("unexpected invalidations %v %v", inv, tags)
+	}
+}
+
+// TestBulkRemove_NothingChanged проверяет, что без изменённых товаров кэш не инвалидируется
+func TestBulkRemove_NothingChanged(t *testing.T) {
+	repo := &mockRepo{removeGoodsFn: func(ctx context.Context, projectID int, items []model.GoodRemoval, atomic bool) ([]model.BulkResult, error) {
+		return []model.BulkResult{{Err: repository.ErrVersionMismatch}, {}}, nil
+	}}
+	var inv, tags []string
+	s := newService(repo, recordingCache(&inv, &tags))
+	if _, err := s.BulkRemove(context.Background(), 2, []model.GoodRemoval{{ID: 1}, {ID: 2}}, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(inv) != 0 || len(tags) != 0 {
+		t.Fatalf("unexpected invalidations %v %v", inv, tags)
+	}
+}
+
+// TestBulkRemove_Error проверяет проброс ошибки репозитория
+func TestBulkRemove_Error(t *testing.T) {
+	testErr := errors.New("bulk error")
+	repo := &mockRepo{removeGoodsFn: func(ctx context.Context, projectID int, items []model.GoodRemoval, atomic bool) ([]model.BulkResult, error) {
+		return nil, testErr
+	}}
+	s := newService(repo, &mockCache{})
+	if _, err := s.BulkRemove(context.Background(), 2, []model.GoodRemoval{{ID: 1}}, false); !errors.Is(err, testErr) {
+		t.Fatalf("expected %v, got %v", testErr, err)
+	}
+}
+
 // helper
 func ptr(s string) *string { return &s }
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"HezzlTestTask/internal/model"
+)
+
+// ProjectRepo определяет интерфейс репозитория для операций с проектами
+// Реализация может быть на основе базы данных Postgres
+// Изменяющие методы записывают события project.* в outbox в транзакции изменения
+type ProjectRepo interface {
+	CreateProject(ctx context.Context, name string) (*model.Project, error)
+	GetProject(ctx context.Context, id int) (*model.Project, error)
+	UpdateProject(ctx context.Context, id int, name string) (*model.Project, error)
+	RemoveProject(ctx context.Context, id int) error
+	ListProjects(ctx context.Context, limit, offset int) ([]model.Project, int, int, error)
+}
+
+// ProjectsService реализует бизнес-логику для сущности проекта
+// по аналогии с GoodsService: валидация и кэширование; события изменений записываются репозиторием в outbox
+// и публикуются в NATS relay-воркером
+type ProjectsService struct {
+	repo  ProjectRepo
+	cache Cache
+}
+
+// NewProjectsService создаёт новый сервис для проектов
+func NewProjectsService(r ProjectRepo, c Cache) *ProjectsService {
+	return &ProjectsService{repo: r, cache: c}
+}
+
+// projectsListTag — тег всех закэшированных страниц списка проектов
+const projectsListTag = "projects:list"
+
+// projectsListResponse описывает закэшированную страницу списка проектов
+type projectsListResponse struct {
+	Projects []model.Project `json:"projects"`
+	Meta     struct {
+		Total   int `json:"total"`
+		Removed int `json:"removed"`
+		Limit   int `json:"limit"`
+		Offset  int `json:"offset"`
+	} `json:"meta"`
+}
+
+// Create создаёт новый проект (событие project.created пишется в outbox) и инвалидирует кэш списка
+func (s *ProjectsService) Create(ctx context.Context, name string) (*model.Project, error) {
+	// валидация: имя не должно быть пустым
+	if name == "" {
+		return nil, errEmptyName
+	}
+	project, err := s.repo.CreateProject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.cache.InvalidateTags(ctx, projectsListTag)
+	return project, nil
+}
+
+// Get возвращает проект по id, сначала пытаясь прочитать его из кэша
+func (s *ProjectsService) Get(ctx context.Context, id int) (*model.Project, error) {
+	key := fmt.Sprintf("project:%d", id)
+	if bytes, err := s.cache.Get(ctx, key); err == nil {
+		var p model.Project
+		_ = json.Unmarshal(bytes, &p)
+		return &p, nil
+	}
+	project, err := s.repo.GetProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := json.Marshal(project)
+	_ = s.cache.Set(ctx, key, data, cacheTTL)
+	return project, nil
+}
+
+// Update переименовывает проект (событие project.updated с состоянием до и после пишется в outbox)
+// и инвалидирует кэш
+func (s *ProjectsService) Update(ctx context.Context, id int, name string) (*model.Project, error) {
+	// валидация: имя не должно быть пустым
+	if name == "" {
+		return nil, errEmptyName
+	}
+	project, err := s.repo.UpdateProject(ctx, id, name)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.cache.InvalidateTags(ctx, projectsListTag)
+	_ = s.cache.Invalidate(ctx, fmt.Sprintf("project:%d", id))
+	return project, nil
+}
+
+// Remove архивирует проект (событие project.removed с состоянием до и после пишется в outbox)
+// и инвалидирует кэш
+func (s *ProjectsService) Remove(ctx context.Context, id int) error {
+	if err := s.repo.RemoveProject(ctx, id); err != nil {
+		return err
+	}
+	_ = s.cache.InvalidateTags(ctx, projectsListTag)
+	_ = s.cache.Invalidate(ctx, fmt.Sprintf("project:%d", id))
+	return nil
+}
+
+// List возвращает страницу проектов с метаданными, кэшируя результат по limit/offset с тегом списка проектов
+func (s *ProjectsService) List(ctx context.Context, limit, offset int) ([]model.Project, int, int, error) {
+	key := fmt.Sprintf("projects:list:%d:%d", limit, offset)
+	if bytes, err := s.cache.Get(ctx, key); err == nil {
+		var resp projectsListResponse
+		_ = json.Unmarshal(bytes, &resp)
+		return resp.Projects, resp.Meta.Total, resp.Meta.Removed, nil
+	}
+	projects, total, removed, err := s.repo.ListProjects(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	var resp projectsListResponse
+	resp.Projects = projects
+	resp.Meta.Total = total
+	resp.Meta.Removed = removed
+	resp.Meta.Limit = limit
+	resp.Meta.Offset = offset
+	data, _ := json.Marshal(resp)
+	_ = s.cache.SetTagged(ctx, key, data, cacheTTL, projectsListTag)
+	return projects, total, removed, nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"HezzlTestTask/internal/apperr"
+	"HezzlTestTask/internal/model"
+	"HezzlTestTask/internal/repository"
+)
+
+// mockProjectRepo реализует ProjectRepo для тестирования ProjectsService
+type mockProjectRepo struct {
+	createFn func(ctx context.Context, name string) (*model.Project, error)
+	getFn    func(ctx context.Context, id int) (*model.Project, error)
+	updateFn func(ctx context.Context, id int, name string) (*model.Project, error)
+	removeFn func(ctx context.Context, id int) error
+	listFn   func(ctx context.Context, limit, offset int) ([]model.Project, int, int, error)
+}
+
+func (m *mockProjectRepo) CreateProject(ctx context.Context, name string) (*model.Project, error) {
+	return m.createFn(ctx, name)
+}
+func (m *mockProjectRepo) GetProject(ctx context.Context, id int) (*model.Project, error) {
+	if m.getFn != nil {
+		return m.getFn(ctx, id)
+	}
+	return &model.Project{ID: id}, nil
+}
+func (m *mockProjectRepo) UpdateProject(ctx context.Context, id int, name string) (*model.Project, error) {
+	return m.updateFn(ctx, id, name)
+}
+func (m *mockProjectRepo) RemoveProject(ctx context.Context, id int) error {
+	return m.removeFn(ctx, id)
+}
+func (m *mockProjectRepo) ListProjects(ctx context.Context, limit, offset int) ([]model.Project, int, int, error) {
+	return m.listFn(ctx, limit, offset)
+}
+
+// TestProjectsCreate_Success проверяет создание проекта и инвалидацию списка
+func TestProjectsCreate_Success(t *testing.T) {
+	exp := &model.Project{ID: 2, Name: "catalog"}
+	repo := &mockProjectRepo{createFn: func(ctx context.Context, name string) (*model.Project, error) {
+		if name != "catalog" {
+			t.Fatalf("unexpected name %s", name)
+		}
+		return exp, nil
+	}}
+	var inv, tags []string
+	s := NewProjectsService(repo, recordingCache(&inv, &tags))
+	p, err := s.Create(context.Background(), "catalog")
+	if err != nil || !reflect.DeepEqual(p, exp) {
+		t.Fatalf("Create returned %v, %v", p, err)
+	}
+	if len(inv) != 0 || !reflect.DeepEqual(tags, []string{"projects:list"}) {
+		t.Fatalf("unexpected invalidations %v %v", inv, tags)
+	}
+}
+
+// TestProjectsCreate_EmptyName проверяет ошибку валидации пустого имени
+func TestProjectsCreate_EmptyName(t *testing.T) {
+	s := NewProjectsService(&mockProjectRepo{}, &mockCache{})
+	if _, err := s.Create(context.Background(), ""); !errors.Is(err, apperr.ErrValidation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
+
+// TestProjectsGet_FromCache проверяет получение проекта из кэша без обращения к репозиторию
+func TestProjectsGet_FromCache(t *testing.T) {
+	exp := &model.Project{ID: 4, Name: "c"}
+	data, _ := json.Marshal(exp)
+	repo := &mockProjectRepo{getFn: func(ctx context.Context, id int) (*model.Project, error) {
+		t.Fatal("repo should not be called on cache hit")
+		return nil, nil
+	}}
+	cache := &mockCache{get: func(ctx context.Context, key string) ([]byte, error) {
+		if key != "project:4" {
+			t.Fatalf("unexpected key %s", key)
+		}
+		return data, nil
+	}}
+	s := NewProjectsService(repo, cache)
+	p, err := s.Get(context.Background(), 4)
+	if err != nil || !reflect.DeepEqual(p, exp) {
+		t.Fatalf("Get returned %v, %v", p, err)
+	}
+}
+
+// TestProjectsGet_NotFound проверяет прокидку ErrNotFound из репозитория при промахе кэша
+func TestProjectsGet_NotFound(t *testing.T) {
+	repo := &mockProjectRepo{getFn: func(ctx context.Context, id int) (*model.Project, error) {
+		return nil, repository.ErrNotFound
+	}}
+	s := NewProjectsService(repo, &mockCache{})
+	if _, err := s.Get(context.Background(), 1); err != repository.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// TestProjectsUpdate_Success проверяет переименование проекта и инвалидацию кэша
+func TestProjectsUpdate_Success(t *testing.T) {
+	exp := &model.Project{ID: 3, Name: "renamed"}
+	repo := &mockProjectRepo{updateFn: func(ctx context.Context, id int, name string) (*model.Project, error) { return exp, nil }}
+	var inv, tags []string
+	s := NewProjectsService(repo, recordingCache(&inv, &tags))
+	p, err := s.Update(context.Background(), 3, "renamed")
+	if err != nil || !reflect.DeepEqual(p, exp) {
+		t.Fatalf("Update returned %v, %v", p, err)
+	}
+	if !reflect.DeepEqual(inv, []string{"project:3"}) || !reflect.DeepEqual(tags, []string{"projects:list"}) {
+		t.Fatalf("unexpected invalidations %v %v", inv, tags)
+	}
+}
+
+// TestProjectsRemove_Success проверяет архивирование проекта и инвалидацию кэша
+func TestProjectsRemove_Success(t *testing.T) {
+	repo := &mockProjectRepo{removeFn: func(ctx context.Context, id int) error { return nil }}
+	var inv, tags []string
+	s := NewProjectsService(repo, recordingCache(&inv, &tags))
+	if err := s.Remove(context.Background(), 7); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(inv, []string{"project:7"}) || !reflect.DeepEqual(tags, []string{"projects:list"}) {
+		t.Fatalf("unexpected invalidations %v %v", inv, tags)
+	}
+}
+
+// TestProjectsRemove_Error проверяет прокидку ошибки репозитория при архивировании без инвалидации кэша
+func TestProjectsRemove_Error(t *testing.T) {
+	testErr := errors.New("remove error")
+	repo := &mockProjectRepo{removeFn: func(ctx context.Context, id int) error { return testErr }}
+	var inv, tags []string
+	s := NewProjectsService(repo, recordingCache(&inv, &tags))
+	if err := s.Remove(context.Background(), 1); err != testErr || len(inv) != 0 || len(tags) != 0 {
+		t.Fatalf("expected %v without invalidations, got %v %v %v", testErr, err, inv, tags)
+	}
+}
+
+// TestProjectsList_Success проверяет получение страницы проектов и запись в кэш
+func TestProjectsList_Success(t *testing.T) {
+	list := []model.Project{{ID: 1, Name: "a"}}
+	repo := &mockProjectRepo{listFn: func(ctx context.Context, limit, offset int) ([]model.Project, int, int, error) {
+		return list, 4, 1, nil
+	}}
+	var cachedKey string
+	var cachedTags []string
+	cache := &mockCache{setTagged: func(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+		cachedKey, cachedTags = key, tags
+		return nil
+	}}
+	s := NewProjectsService(repo, cache)
+	projects, total, removed, err := s.List(context.Background(), 10, 0)
+	if err != nil || total != 4 || removed != 1 || !reflect.DeepEqual(projects, list) {
+		t.Fatal("List failed")
+	}
+	if cachedKey != "projects:list:10:0" || !reflect.DeepEqual(cachedTags, []string{projectsListTag}) {
+		t.Fatalf("unexpected cache key %s with tags %v", cachedKey, cachedTags)
+	}
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"HezzlTestTask/internal/apperr"
+	"HezzlTestTask/internal/auth"
+	"HezzlTestTask/internal/model"
+
+	"github.com/gorilla/mux"
+)
+
+// APIKeyHeader — заголовок со статическим API-ключом
+const APIKeyHeader = "X-API-Key"
+
+// AuthMiddleware аутентифицирует запрос по заголовку X-API-Key или Authorization: Bearer <JWT>
+// Клиент кладётся в контекст запроса, его идентификатор — в инициатора изменений (model.ContextWithActor)
+// Запрос без учётных данных проходит дальше без клиента: публичные маршруты (/healthz, /readyz, /metrics)
+// доступны всем, а остальные отвечают 401 при проверке прав (authorize)
+// Недействительные учётные данные сразу отклоняются с 401
+func AuthMiddleware(a *auth.Authenticator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var (
+				p   *auth.Principal
+				err error
+			)
+			if key := r.Header.Get(APIKeyHeader); key != "" {
+				p, err = a.AuthenticateAPIKey(key)
+			} else if h := r.Header.Get("Authorization"); h != "" {
+				token, ok := strings.CutPrefix(h, "Bearer ")
+				if !ok {
+					writeDomainError(w, r, apperr.Unauthorized("unsupported authorization scheme"))
+					return
+				}
+				p, err = a.AuthenticateToken(token)
+			}
+			if err != nil {
+				writeDomainError(w, r, apperr.Unauthorized("invalid credentials"))
+				return
+			}
+			ctx := auth.ContextWithPrincipal(r.Context(), p)
+			if p != nil {
+				ctx = model.ContextWithActor(ctx, p.Subject)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authorize проверяет, что у клиента запроса есть роль не ниже need в проекте projectID
+// (auth.AllProjects — во всех проектах). Без AuthMiddleware аутентификация отключена и доступ разрешён
+func authorize(r *http.Request, projectID int, need auth.Role) error {
+	p, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		return nil
+	}
+	if p == nil {
+		return apperr.Unauthorized("authentication required")
+	}
+	if !p.Can(projectID, need) {
+		if projectID == auth.AllProjects {
+			return apperr.Forbidden(fmt.Sprintf("%s access to all projects required", need))
+		}
+		return apperr.Forbidden(fmt.Sprintf("%s access to project %d required", need, projectID))
+	}
+	return nil
+}
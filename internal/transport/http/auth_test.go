@@ -0,0 +1,148 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+
+	"HezzlTestTask/internal/auth"
+	"HezzlTestTask/internal/model"
+)
+
+// newAuthRouter создаёт маршрутизатор с AuthMiddleware: ключ "writer" даёт write в проекте 1,
+// "reader" — read во всех проектах, "admin" — admin во всех проектах; токены подписываются секретом "s3cret"
+func newAuthRouter(t *testing.T, ms *mockService, ps ProjectsService) *mux.Router {
+	t.Helper()
+	a, err := auth.New(auth.Config{
+		APIKeys: map[string]auth.APIKey{
+			"writer": {Subject: "writer", Projects: auth.Grants{"1": "write"}},
+			"reader": {Subject: "reader", Projects: auth.Grants{"*": "read"}},
+			"admin":  {Subject: "admin", Projects: auth.Grants{"*": "admin"}},
+		},
+		HS256Secret: []byte("s3cret"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := mux.NewRouter()
+	r.Use(AuthMiddleware(a))
+	NewHandler(ms, ps).RegisterRoutes(r)
+	return r
+}
+
+// TestAuth_GoodsAccess проверяет 401 без учётных данных и с неверным ключом, 403 без роли и доступ с ролью
+func TestAuth_GoodsAccess(t *testing.T) {
+	var actor string
+	ms := &mockService{
+		CreateFn: func(projectID int, name string, description *string) (*model.Good, error) {
+			return &model.Good{ID: 1, ProjectID: projectID, Name: name}, nil
+		},
+	}
+	r := newAuthRouter(t, ms, nil)
+	// инициатор изменения берётся из контекста запроса
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			actor = model.ActorFromContext(req.Context())
+			next.ServeHTTP(w, req)
+		})
+	})
+
+	cases := []struct {
+		name, target, key string
+		status            int
+	}{
+		{"anonymous", "/good/create?projectId=1", "", http.StatusUnauthorized},
+		{"unknown key", "/good/create?projectId=1", "nope", http.StatusUnauthorized},
+		{"reader cannot write", "/good/create?projectId=1", "reader", http.StatusForbidden},
+		{"writer of another project", "/good/create?projectId=2", "writer", http.StatusForbidden},
+		{"writer", "/good/create?projectId=1", "writer", http.StatusOK},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.target, bytes.NewBufferString(`{"name":"n"}`))
+		if c.key != "" {
+			req.Header.Set(APIKeyHeader, c.key)
+		}
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != c.status {
+			t.Errorf("%s: expected %d, got %d (%s)", c.name, c.status, rw.Code, rw.Body.String())
+		}
+		if c.status == http.StatusUnauthorized && rw.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expected WWW-Authenticate header", c.name)
+		}
+	}
+	if actor != "writer" {
+		t.Errorf("expected actor writer, got %q", actor)
+	}
+}
+
+// TestAuth_BearerToken проверяет доступ по JWT и отказ для неподдерживаемой схемы и истёкшего токена
+func TestAuth_BearerToken(t *testing.T) {
+	ms := &mockService{GetFn: func(projectID, id int) (*model.Good, error) {
+		return &model.Good{ID: id, ProjectID: projectID}, nil
+	}}
+	r := newAuthRouter(t, ms, nil)
+	sign := func(exp time.Time) string {
+		s, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": "alice", "projects": map[string]string{"5": "read"}, "exp": exp.Unix(),
+		}).SignedString([]byte("s3cret"))
+		return s
+	}
+	cases := []struct {
+		name, target, authorization string
+		status                      int
+	}{
+		{"valid token", "/good/get?projectId=5&id=1", "Bearer " + sign(time.Now().Add(time.Hour)), http.StatusOK},
+		{"other project", "/good/get?projectId=6&id=1", "Bearer " + sign(time.Now().Add(time.Hour)), http.StatusForbidden},
+		{"expired token", "/good/get?projectId=5&id=1", "Bearer " + sign(time.Now().Add(-time.Hour)), http.StatusUnauthorized},
+		{"basic scheme", "/good/get?projectId=5&id=1", "Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.target, nil)
+		req.Header.Set("Authorization", c.authorization)
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != c.status {
+			t.Errorf("%s: expected %d, got %d", c.name, c.status, rw.Code)
+		}
+	}
+}
+
+// TestAuth_Projects проверяет, что управление и список проектов требуют ролей во всех проектах,
+// а проверки здоровья доступны без учётных данных
+func TestAuth_Projects(t *testing.T) {
+	ps := &mockProjectsService{
+		ListFn: func(limit, offset int) ([]model.Project, int, int, error) { return nil, 0, 0, nil },
+		CreateFn: func(name string) (*model.Project, error) {
+			return &model.Project{ID: 2, Name: name}, nil
+		},
+	}
+	r := newAuthRouter(t, &mockService{}, ps)
+	cases := []struct {
+		method, target, key string
+		status              int
+	}{
+		{http.MethodGet, "/healthz", "", http.StatusOK},
+		{http.MethodGet, "/readyz", "", http.StatusOK},
+		{http.MethodGet, "/projects/list", "reader", http.StatusOK},
+		{http.MethodGet, "/projects/list", "writer", http.StatusForbidden},
+		{http.MethodPost, "/project/create", "reader", http.StatusForbidden},
+		{http.MethodPost, "/project/create", "admin", http.StatusOK},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.target, bytes.NewBufferString(`{"name":"p"}`))
+		if c.key != "" {
+			req.Header.Set(APIKeyHeader, c.key)
+		}
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != c.status {
+			t.Errorf("%s %s as %q: expected %d, got %d", c.method, c.target, c.key, c.status, rw.Code)
+		}
+	}
+}
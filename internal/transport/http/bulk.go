@@ -0,0 +1,189 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"HezzlTestTask/internal/auth"
+	"HezzlTestTask/internal/model"
+)
+
+// bulkItemResult — результат элемента пакетного запроса в ответе
+// Status — HTTP-статус, который получил бы элемент отдельным запросом; 424 — элемент не применён,
+// потому что атомарный запрос отменён из-за ошибки другого элемента
+type bulkItemResult struct {
+	Index  int            `json:"index"`
+	Status int            `json:"status"`
+	Good   *model.Good    `json:"good,omitempty"`
+	Error  *ErrorResponse `json:"error,omitempty"`
+}
+
+// bulkResponse — ответ пакетного запроса: результаты в порядке элементов запроса и их количество по исходу
+type bulkResponse struct {
+	Results []bulkItemResult `json:"results"`
+	Meta    struct {
+		Succeeded int `json:"succeeded"`
+		Failed    int `json:"failed"`
+	} `json:"meta"`
+}
+
+// BulkCreate обрабатывает POST /goods/bulk/create
+// 1. Извлекает projectId, декодирует и проверяет тело с режимом mode и массивом items (bulkCreateRequest)
+// 2. Вызывает сервис BulkCreate для элементов без ошибок: все товары создаются одной транзакцией
+// 3. Возвращает результаты элементов через writeBulkResults
+func (h *Handler) BulkCreate(w http.ResponseWriter, r *http.Request) {
+	var v validator
+	pid := v.queryID(r.URL.Query(), "projectId")
+	var req bulkCreateRequest
+	v.body(w, r, &req)
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	if err := authorize(r, pid, auth.RoleWrite); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	results, pending := pendingItems(req.itemErrs)
+	if len(pending) > 0 {
+		items := make([]model.GoodInput, len(pending))
+		for j, i := range pending {
+			items[j] = model.GoodInput{Name: req.Items[i].Name, Description: req.Items[i].Description}
+		}
+		goods, err := h.srv.BulkCreate(r.Context(), pid, items)
+		if err != nil {
+			writeDomainError(w, r, err)
+			return
+		}
+		for j, i := range pending {
+			results[i].Good = &goods[j]
+		}
+	}
+	writeBulkResults(w, r, results, http.StatusCreated, req.atomic())
+}
+
+// BulkUpdate обрабатывает PATCH /goods/bulk/update
+// 1. Извлекает projectId, декодирует и проверяет тело с режимом mode и массивом items (bulkUpdateRequest)
+// 2. Вызывает сервис BulkUpdate для элементов без ошибок: изменения применяются одной транзакцией
+// 3. Возвращает результаты элементов через writeBulkResults
+func (h *Handler) BulkUpdate(w http.ResponseWriter, r *http.Request) {
+	var v validator
+	pid := v.queryID(r.URL.Query(), "projectId")
+	var req bulkUpdateRequest
+	v.body(w, r, &req)
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	if err := authorize(r, pid, auth.RoleWrite); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	results, pending := pendingItems(req.itemErrs)
+	if len(pending) > 0 {
+		items := make([]model.GoodUpdate, len(pending))
+		for j, i := range pending {
+			it := req.Items[i]
+			items[j] = model.GoodUpdate{ID: it.ID, Name: it.Name, Description: it.Description, Version: it.Version}
+		}
+		updated, err := h.srv.BulkUpdate(r.Context(), pid, items, req.atomic())
+		if err != nil {
+			writeDomainError(w, r, err)
+			return
+		}
+		for j, i := range pending {
+			results[i] = updated[j]
+		}
+	}
+	writeBulkResults(w, r, results, http.StatusOK, req.atomic())
+}
+
+// BulkRemove обрабатывает DELETE /goods/bulk/remove
+// 1. Извлекает projectId, декодирует и проверяет тело с режимом mode и массивом items (bulkRemoveRequest)
+// 2. Вызывает сервис BulkRemove для элементов без ошибок: товары удаляются одной транзакцией
+// 3. Возвращает результаты элементов с удалёнными товарами через writeBulkResults
+func (h *Handler) BulkRemove(w http.ResponseWriter, r *http.Request) {
+	var v validator
+	pid := v.queryID(r.URL.Query(), "projectId")
+	var req bulkRemoveRequest
+	v.body(w, r, &req)
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	if err := authorize(r, pid, auth.RoleWrite); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	results, pending := pendingItems(req.itemErrs)
+	if len(pending) > 0 {
+		items := make([]model.GoodRemoval, len(pending))
+		for j, i := range pending {
+			items[j] = model.GoodRemoval{ID: req.Items[i].ID, Version: req.Items[i].Version}
+		}
+		removed, err := h.srv.BulkRemove(r.Context(), pid, items, req.atomic())
+		if err != nil {
+			writeDomainError(w, r, err)
+			return
+		}
+		for j, i := range pending {
+			results[i] = removed[j]
+		}
+	}
+	writeBulkResults(w, r, results, http.StatusOK, req.atomic())
+}
+
+// pendingItems возвращает результаты элементов, заполненные ошибками проверки itemErrs,
+// и индексы элементов без ошибок, которые передаются сервису
+func pendingItems(itemErrs []error) ([]model.BulkResult, []int) {
+	results := make([]model.BulkResult, len(itemErrs))
+	var pending []int
+	for i, err := range itemErrs {
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		pending = append(pending, i)
+	}
+	return results, pending
+}
+
+// writeBulkResults отвечает результатами элементов пакетного запроса; ошибка элемента описывается так же,
+// как ответ на отдельный запрос (domainErrorResponse)
+// Статус ответа: okStatus, если все элементы выполнены; в атомарном режиме — статус первого элемента с ошибкой
+// (ничего не применено); в режиме bestEffort — 207 Multi-Status, если часть элементов не выполнена
+func writeBulkResults(w http.ResponseWriter, r *http.Request, results []model.BulkResult, okStatus int, atomic bool) {
+	var resp bulkResponse
+	resp.Results = make([]bulkItemResult, len(results))
+	failedStatus := 0
+	for i, res := range results {
+		item := bulkItemResult{Index: i, Status: okStatus, Good: res.Good}
+		switch {
+		case res.Err != nil:
+			status, e := domainErrorResponse(r, res.Err)
+			item.Status, item.Error = status, &e
+			if failedStatus == 0 {
+				failedStatus = status
+			}
+		case res.Good == nil:
+			item.Status = http.StatusFailedDependency
+		}
+		if item.Status == okStatus {
+			resp.Meta.Succeeded++
+		} else {
+			resp.Meta.Failed++
+		}
+		resp.Results[i] = item
+	}
+	status := okStatus
+	switch {
+	case resp.Meta.Failed == 0:
+	case atomic && failedStatus != 0:
+		status = failedStatus
+	default:
+		status = http.StatusMultiStatus
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
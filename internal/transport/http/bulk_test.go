@@ -0,0 +1,177 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"HezzlTestTask/internal/model"
+	"HezzlTestTask/internal/repository"
+)
+
+// decodeBulkResponse разбирает тело ответа пакетного запроса в статусы элементов и meta
+func decodeBulkResponse(t *testing.T, rw *httptest.ResponseRecorder) ([]int, bulkResponse) {
+	t.Helper()
+	var resp bulkResponse
+	if err := json.NewDecoder(rw.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode bulk response: %v", err)
+	}
+	statuses := make([]int, len(resp.Results))
+	for i, res := range resp.Results {
+		if res.Index != i {
+			t.Fatalf("result %d has index %d", i, res.Index)
+		}
+		statuses[i] = res.Status
+	}
+	return statuses, resp
+}
+
+// TestBulkCreate_Success проверяет создание всех элементов и ответ 201 с товарами в порядке запроса
+func TestBulkCreate_Success(t *testing.T) {
+	ms := &mockService{BulkCreateFn: func(projectID int, items []model.GoodInput) ([]model.Good, error) {
+		if projectID != 2 || len(items) != 2 || items[0].Name != "a" || *items[1].Description != "d" {
+			t.Fatalf("unexpected args %d %+v", projectID, items)
+		}
+		return []model.Good{{ID: 1, ProjectID: 2, Name: "a", Priority: 1}, {ID: 2, ProjectID: 2, Name: "b", Priority: 2}}, nil
+	}}
+	rw := serveGoods(ms, nil, http.MethodPost, "/goods/bulk/create?projectId=2",
+		`{"items":[{"name":" a "},{"name":"b","description":"d"}]}`)
+	if rw.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rw.Code)
+	}
+	statuses, resp := decodeBulkResponse(t, rw)
+	if !reflect.DeepEqual(statuses, []int{201, 201}) || resp.Meta.Succeeded != 2 || resp.Meta.Failed != 0 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if resp.Results[0].Good.ID != 1 || resp.Results[1].Good.ID != 2 {
+		t.Errorf("unexpected goods %+v", resp.Results)
+	}
+}
+
+// TestBulkCreate_AtomicViolations проверяет, что в атомарном режиме нарушения элементов отклоняют весь запрос
+func TestBulkCreate_AtomicViolations(t *testing.T) {
+	rw := serveGoods(&mockService{}, nil, http.MethodPost, "/goods/bulk/create?projectId=1",
+		`{"mode":"atomic","items":[{"name":"a"},{"name":"  "}]}`)
+	resp := decodeErrorResponse(t, rw)
+	want := map[string]string{"items[1].name": "must not be empty"}
+	if rw.Code != http.StatusBadRequest || !reflect.DeepEqual(resp.Details, want) {
+		t.Errorf("unexpected response %d %+v", rw.Code, resp)
+	}
+}
+
+// TestBulkCreate_BestEffortViolations проверяет, что в режиме bestEffort сервису передаются только корректные элементы
+func TestBulkCreate_BestEffortViolations(t *testing.T) {
+	ms := &mockService{BulkCreateFn: func(projectID int, items []model.GoodInput) ([]model.Good, error) {
+		if len(items) != 1 || items[0].Name != "b" {
+			t.Fatalf("unexpected items %+v", items)
+		}
+		return []model.Good{{ID: 7, ProjectID: 1, Name: "b"}}, nil
+	}}
+	rw := serveGoods(ms, nil, http.MethodPost, "/goods/bulk/create?projectId=1",
+		`{"mode":"bestEffort","items":[{"name":""},{"name":"b"}]}`)
+	if rw.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d", rw.Code)
+	}
+	statuses, resp := decodeBulkResponse(t, rw)
+	if !reflect.DeepEqual(statuses, []int{400, 201}) || resp.Meta.Succeeded != 1 || resp.Meta.Failed != 1 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if e := resp.Results[0].Error; e == nil || e.Code != CodeValidation || resp.Results[1].Good.ID != 7 {
+		t.Errorf("unexpected results %+v", resp.Results)
+	}
+}
+
+// TestBulk_InvalidRequest проверяет отказ на неизвестный режим и недопустимое число элементов
+func TestBulk_InvalidRequest(t *testing.T) {
+	tooMany := "[" + strings.TrimSuffix(strings.Repeat(`{"id":1},`, maxBulkItems+1), ",") + "]"
+	cases := []struct {
+		name, body, field string
+	}{
+		{"unknown mode", `{"mode":"partial","items":[{"id":1}]}`, "mode"},
+		{"no items", `{"items":[]}`, "items"},
+		{"too many items", `{"items":` + tooMany + `}`, "items"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rw := serveGoods(&mockService{}, nil, http.MethodDelete, "/goods/bulk/remove?projectId=1", c.body)
+			resp := decodeErrorResponse(t, rw)
+			if rw.Code != http.StatusBadRequest || resp.Details.(map[string]string)[c.field] == "" {
+				t.Errorf("unexpected response %d %+v", rw.Code, resp)
+			}
+		})
+	}
+}
+
+// TestBulkUpdate_AtomicAbort проверяет, что отменённый атомарный запрос получает статус первой ошибки,
+// а не применённые элементы — 424
+func TestBulkUpdate_AtomicAbort(t *testing.T) {
+	ms := &mockService{BulkUpdateFn: func(projectID int, items []model.GoodUpdate, atomic bool) ([]model.BulkResult, error) {
+		if !atomic || len(items) != 3 || items[1].Version != 4 {
+			t.Fatalf("unexpected args %v %+v", atomic, items)
+		}
+		return []model.BulkResult{{}, {Err: repository.ErrVersionMismatch}, {Err: repository.ErrNotFound}}, nil
+	}}
+	rw := serveGoods(ms, nil, http.MethodPatch, "/goods/bulk/update?projectId=1",
+		`{"items":[{"id":1,"name":"a"},{"id":2,"name":"b","version":4},{"id":3,"name":"c"}]}`)
+	if rw.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412, got %d", rw.Code)
+	}
+	statuses, resp := decodeBulkResponse(t, rw)
+	if !reflect.DeepEqual(statuses, []int{424, 412, 404}) || resp.Meta.Succeeded != 0 || resp.Meta.Failed != 3 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+// TestBulkUpdate_BestEffort проверяет ответ 207 с результатом каждого элемента
+func TestBulkUpdate_BestEffort(t *testing.T) {
+	ms := &mockService{BulkUpdateFn: func(projectID int, items []model.GoodUpdate, atomic bool) ([]model.BulkResult, error) {
+		if atomic {
+			t.Fatal("expected bestEffort mode")
+		}
+		return []model.BulkResult{{Good: &model.Good{ID: 1, Name: "a", Version: 2}}, {Err: repository.ErrNotFound}}, nil
+	}}
+	rw := serveGoods(ms, nil, http.MethodPatch, "/goods/bulk/update?projectId=1",
+		`{"mode":"bestEffort","items":[{"id":1,"name":"a"},{"id":9,"name":"b"}]}`)
+	statuses, resp := decodeBulkResponse(t, rw)
+	if rw.Code != http.StatusMultiStatus || !reflect.DeepEqual(statuses, []int{200, 404}) {
+		t.Fatalf("unexpected response %d %+v", rw.Code, resp)
+	}
+	if resp.Results[0].Good.Version != 2 || resp.Results[1].Error.Code != CodeNotFound {
+		t.Errorf("unexpected results %+v", resp.Results)
+	}
+}
+
+// TestBulkRemove_Success проверяет ответ 200, когда удалены все товары
+func TestBulkRemove_Success(t *testing.T) {
+	ms := &mockService{BulkRemoveFn: func(projectID int, items []model.GoodRemoval, atomic bool) ([]model.BulkResult, error) {
+		results := make([]model.BulkResult, len(items))
+		for i, it := range items {
+			results[i].Good = &model.Good{ID: it.ID, ProjectID: projectID, Removed: true}
+		}
+		return results, nil
+	}}
+	rw := serveGoods(ms, nil, http.MethodDelete, "/goods/bulk/remove?projectId=3", `{"items":[{"id":4},{"id":5,"version":1}]}`)
+	statuses, resp := decodeBulkResponse(t, rw)
+	if rw.Code != http.StatusOK || !reflect.DeepEqual(statuses, []int{200, 200}) || resp.Meta.Succeeded != 2 {
+		t.Fatalf("unexpected response %d %+v", rw.Code, resp)
+	}
+	if !resp.Results[1].Good.Removed || resp.Results[1].Good.ID != 5 {
+		t.Errorf("unexpected results %+v", resp.Results)
+	}
+}
+
+// TestBulkRemove_ServiceError проверяет, что ошибка сервиса возвращается как ответ на весь запрос
+func TestBulkRemove_ServiceError(t *testing.T) {
+	ms := &mockService{BulkRemoveFn: func(projectID int, items []model.GoodRemoval, atomic bool) ([]model.BulkResult, error) {
+		return nil, fmt.Errorf("failed to remove goods: %w", errors.New("pq: deadlock detected"))
+	}}
+	rw := serveGoods(ms, nil, http.MethodDelete, "/goods/bulk/remove?projectId=1", `{"items":[{"id":1}]}`)
+	if resp := decodeErrorResponse(t, rw); rw.Code != http.StatusInternalServerError || resp.Code != CodeInternal {
+		t.Errorf("unexpected response %d %+v", rw.Code, resp)
+	}
+}
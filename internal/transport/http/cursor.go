@@ -0,0 +1,68 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"HezzlTestTask/internal/apperr"
+	"HezzlTestTask/internal/model"
+)
+
+// errInvalidCursor возвращается при невозможности разобрать курсор или его несовпадении с сортировкой
+var errInvalidCursor = apperr.Validation("invalid cursor", map[string]string{"cursor": "must be a nextCursor value issued for the same sort"})
+
+// goodsCursorPayload — содержимое непрозрачного курсора списка товаров
+// Помимо позиции хранит сортировку, чтобы курсор нельзя было применить к другому порядку выборки
+type goodsCursorPayload struct {
+	Sort string `json:"s"`
+	Desc bool   `json:"d"`
+	model.GoodsCursor
+}
+
+// encodeGoodsCursor кодирует позицию последнего товара страницы в строку base64url
+func encodeGoodsCursor(filter model.GoodsFilter, last model.Good) string {
+	data, _ := json.Marshal(goodsCursorPayload{
+		Sort:        filter.Sort,
+		Desc:        filter.Desc,
+		GoodsCursor: model.GoodsCursor{Priority: last.Priority, ID: last.ID},
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeGoodsCursor разбирает курсор и проверяет, что он выдан для той же сортировки, что и filter
+func decodeGoodsCursor(s string, filter model.GoodsFilter) (*model.GoodsCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var p goodsCursorPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, errInvalidCursor
+	}
+	if p.Sort != filter.Sort || p.Desc != filter.Desc || p.ID <= 0 {
+		return nil, errInvalidCursor
+	}
+	return &p.GoodsCursor, nil
+}
+
+// encodeHistoryCursor кодирует позицию последнего события страницы истории в строку base64url
+func encodeHistoryCursor(last model.Event) string {
+	data, _ := json.Marshal(model.HistoryCursor{OccurredAt: last.OccurredAt, EventID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeHistoryCursor разбирает курсор истории изменений
+func decodeHistoryCursor(s string) (*model.HistoryCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var c model.HistoryCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, errInvalidCursor
+	}
+	if c.EventID == "" || c.OccurredAt.IsZero() {
+		return nil, errInvalidCursor
+	}
+	return &c, nil
+}
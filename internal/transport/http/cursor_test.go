@@ -0,0 +1,55 @@
+package http
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"HezzlTestTask/internal/model"
+)
+
+// TestGoodsCursor_RoundTrip проверяет, что закодированный курсор декодируется в ту же позицию
+func TestGoodsCursor_RoundTrip(t *testing.T) {
+	filter := model.GoodsFilter{Sort: model.SortByPriority, Desc: true}
+	s := encodeGoodsCursor(filter, model.Good{ID: 12, Priority: 5})
+	got, err := decodeGoodsCursor(s, filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (&model.GoodsCursor{Priority: 5, ID: 12}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+// TestGoodsCursor_Invalid проверяет отказ для повреждённого курсора и курсора другой сортировки
+func TestGoodsCursor_Invalid(t *testing.T) {
+	filter := model.GoodsFilter{Sort: model.SortByPriority}
+	if _, err := decodeGoodsCursor("%%%", filter); err != errInvalidCursor {
+		t.Fatalf("expected errInvalidCursor for garbage, got %v", err)
+	}
+	s := encodeGoodsCursor(model.GoodsFilter{Sort: model.SortByID}, model.Good{ID: 3})
+	if _, err := decodeGoodsCursor(s, filter); err != errInvalidCursor {
+		t.Fatalf("expected errInvalidCursor for sort mismatch, got %v", err)
+	}
+	s = encodeGoodsCursor(model.GoodsFilter{Sort: model.SortByPriority, Desc: true}, model.Good{ID: 3})
+	if _, err := decodeGoodsCursor(s, filter); err != errInvalidCursor {
+		t.Fatalf("expected errInvalidCursor for direction mismatch, got %v", err)
+	}
+}
+
+// TestHistoryCursor_RoundTrip проверяет, что курсор истории сохраняет время с микросекундами и идентификатор события
+func TestHistoryCursor_RoundTrip(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	got, err := decodeHistoryCursor(encodeHistoryCursor(model.Event{ID: "e-1", OccurredAt: at}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.OccurredAt.Equal(at) || got.EventID != "e-1" {
+		t.Fatalf("got %+v", got)
+	}
+	for _, s := range []string{"%%%", encodeHistoryCursor(model.Event{OccurredAt: at})} {
+		if _, err := decodeHistoryCursor(s); err != errInvalidCursor {
+			t.Fatalf("expected errInvalidCursor for %q, got %v", s, err)
+		}
+	}
+}
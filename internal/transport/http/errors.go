@@ -0,0 +1,97 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"HezzlTestTask/internal/apperr"
+)
+
+// Коды ошибок API; значения стабильны и не зависят от текста сообщения
+const (
+	CodeValidation         = 1
+	CodeInternal           = 2
+	CodeNotFound           = 3
+	CodeConflict           = 4
+	CodeForbidden          = 5
+	CodeUnavailable        = 6
+	CodeUnauthorized       = 7
+	CodeRateLimited        = 8
+	CodePreconditionFailed = 9
+)
+
+// errorMapping описывает ответ API для вида доменной ошибки
+// Пустой message означает, что клиенту возвращается сообщение самой ошибки
+type errorMapping struct {
+	status  int
+	code    int
+	message string
+}
+
+// errorMappings сопоставляет виды доменных ошибок с HTTP-статусом, кодом и сообщением ответа
+// Сообщения ошибок отсутствия записи, недоступности и внутренних ошибок заменяются общими,
+// чтобы не раскрывать клиенту подробности хранилища
+var errorMappings = map[apperr.Kind]errorMapping{
+	apperr.KindValidation:         {http.StatusBadRequest, CodeValidation, ""},
+	apperr.KindNotFound:           {http.StatusNotFound, CodeNotFound, "errors.common.notFound"},
+	apperr.KindConflict:           {http.StatusConflict, CodeConflict, ""},
+	apperr.KindForbidden:          {http.StatusForbidden, CodeForbidden, ""},
+	apperr.KindUnavailable:        {http.StatusServiceUnavailable, CodeUnavailable, "errors.common.unavailable"},
+	apperr.KindInternal:           {http.StatusInternalServerError, CodeInternal, "errors.common.internal"},
+	apperr.KindUnauthorized:       {http.StatusUnauthorized, CodeUnauthorized, "errors.common.unauthorized"},
+	apperr.KindRateLimited:        {http.StatusTooManyRequests, CodeRateLimited, "errors.common.tooManyRequests"},
+	apperr.KindPreconditionFailed: {http.StatusPreconditionFailed, CodePreconditionFailed, ""},
+}
+
+// ErrorResponse модель ошибки API
+// Details содержит ошибки отдельных полей запроса (имя поля -> описание), для остальных ошибок пуст
+type ErrorResponse struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details"`
+}
+
+func writeError(w http.ResponseWriter, status int, resp ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// writeDomainError отвечает на ошибку err по её виду (apperr.KindOf)
+// Единственное место, где доменные ошибки превращаются в HTTP-ответ; статус, код и details выбирает domainErrorResponse
+func writeDomainError(w http.ResponseWriter, r *http.Request, err error) {
+	if apperr.KindOf(err) == apperr.KindUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	status, resp := domainErrorResponse(r, err)
+	writeError(w, status, resp)
+}
+
+// domainErrorResponse возвращает HTTP-статус и тело ответа на ошибку err по её виду;
+// внутренние ошибки и недоступность зависимостей дополнительно пишутся в лог с request_id запроса
+// Используется и для ошибок отдельных элементов пакетных запросов
+func domainErrorResponse(r *http.Request, err error) (int, ErrorResponse) {
+	kind := apperr.KindOf(err)
+	m, ok := errorMappings[kind]
+	if !ok {
+		kind, m = apperr.KindInternal, errorMappings[apperr.KindInternal]
+	}
+	if kind == apperr.KindInternal || kind == apperr.KindUnavailable {
+		slog.ErrorContext(r.Context(), "request failed", slog.String("kind", kind.String()), slog.Any("error", err))
+	}
+	message := m.message
+	var e *apperr.Error
+	if message == "" && errors.As(err, &e) {
+		message = e.Message
+	}
+	if message == "" {
+		message = kind.String()
+	}
+	details := map[string]string{}
+	for field, reason := range apperr.FieldsOf(err) {
+		details[field] = reason
+	}
+	return m.status, ErrorResponse{m.code, message, details}
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"HezzlTestTask/internal/apperr"
+	"HezzlTestTask/internal/model"
+	"HezzlTestTask/internal/repository"
+)
+
+// decodeErrorResponse разбирает тело ответа с ошибкой API
+func decodeErrorResponse(t *testing.T, rw *httptest.ResponseRecorder) ErrorResponse {
+	t.Helper()
+	var resp struct {
+		Code    int               `json:"code"`
+		Message string            `json:"message"`
+		Details map[string]string `json:"details"`
+	}
+	if err := json.NewDecoder(rw.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error response: %v", err)
+	}
+	return ErrorResponse{resp.Code, resp.Message, resp.Details}
+}
+
+// TestWriteDomainError проверяет статус, код, сообщение и details для каждого вида ошибки
+func TestWriteDomainError(t *testing.T) {
+	cases := []struct {
+		err     error
+		status  int
+		code    int
+		message string
+	}{
+		{fmt.Errorf("create: %w", apperr.Invalid("name", "cannot be empty")), http.StatusBadRequest, CodeValidation, "name cannot be empty"},
+		{fmt.Errorf("get: %w", repository.ErrNotFound), http.StatusNotFound, CodeNotFound, "errors.common.notFound"},
+		{apperr.Conflict("version mismatch"), http.StatusConflict, CodeConflict, "version mismatch"},
+		{apperr.Forbidden("read-only key"), http.StatusForbidden, CodeForbidden, "read-only key"},
+		{apperr.Unavailable("postgres", errors.New("dial tcp: refused")), http.StatusServiceUnavailable, CodeUnavailable, "errors.common.unavailable"},
+		{apperr.RateLimited("rate limit exceeded"), http.StatusTooManyRequests, CodeRateLimited, "errors.common.tooManyRequests"},
+		{apperr.PreconditionFailed("good version mismatch"), http.StatusPreconditionFailed, CodePreconditionFailed, "good version mismatch"},
+		{errors.New("pq: syntax error"), http.StatusInternalServerError, CodeInternal, "errors.common.internal"},
+	}
+	for _, c := range cases {
+		rw := httptest.NewRecorder()
+		writeDomainError(rw, httptest.NewRequest(http.MethodGet, "/", nil), c.err)
+		resp := decodeErrorResponse(t, rw)
+		if rw.Code != c.status || resp.Code != c.code || resp.Message != c.message {
+			t.Errorf("%v: got %d %+v, want %d code=%d message=%q", c.err, rw.Code, resp, c.status, c.code, c.message)
+		}
+	}
+}
+
+// TestCreate_ValidationDetails проверяет ответ 400 с ошибкой поля name, возвращённой сервисом
+func TestCreate_ValidationDetails(t *testing.T) {
+	ms := &mockService{CreateFn: func(projectID int, name string, description *string) (*model.Good, error) {
+		return nil, apperr.Invalid("name", "cannot be empty")
+	}}
+	r := mux.NewRouter()
+	NewHandler(ms, nil).RegisterRoutes(r)
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/good/create?projectId=1", bytes.NewBufferString(`{"name":"n"}`)))
+	resp := decodeErrorResponse(t, rw)
+	if rw.Code != http.StatusBadRequest || resp.Code != CodeValidation {
+		t.Fatalf("expected 400 with code %d, got %d %+v", CodeValidation, rw.Code, resp)
+	}
+	if details := resp.Details.(map[string]string); details["name"] != "cannot be empty" {
+		t.Errorf("unexpected details %v", details)
+	}
+}
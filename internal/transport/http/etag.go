@@ -0,0 +1,63 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Заголовки условных запросов
+const (
+	ETagHeader        = "ETag"
+	IfMatchHeader     = "If-Match"
+	IfNoneMatchHeader = "If-None-Match"
+)
+
+// unmatchedVersion — ожидаемая версия, которая не совпадает ни с одной версией товара
+// Передаётся в сервис, если метка If-Match заведомо не может совпасть (слабая или не выданная API),
+// чтобы ответ 412 возвращался только для существующего товара, а для отсутствующего — 404
+const unmatchedVersion = -1
+
+// goodETag возвращает сильную метку ETag версии товара: номер версии в кавычках
+func goodETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatch возвращает версию товара, ожидаемую заголовком If-Match: 0 — без проверки (заголовка нет или "*"),
+// unmatchedVersion — метка не может совпасть; список из нескольких меток не поддерживается
+func (v *validator) ifMatch(r *http.Request) int {
+	header := strings.TrimSpace(r.Header.Get(IfMatchHeader))
+	if header == "" || header == "*" {
+		return 0
+	}
+	tags := strings.Split(header, ",")
+	if len(tags) > 1 {
+		v.add(IfMatchHeader, "must contain a single entity tag")
+		return 0
+	}
+	// If-Match сравнивает метки строго: слабая метка не совпадает ни с какой
+	tag := strings.TrimSpace(tags[0])
+	if len(tag) < 3 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return unmatchedVersion
+	}
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version < 1 {
+		return unmatchedVersion
+	}
+	return version
+}
+
+// noneMatch сообщает, совпадает ли etag с одной из меток заголовка If-None-Match или заголовок равен "*"
+// If-None-Match сравнивает метки слабо: префикс W/ не учитывается
+func noneMatch(r *http.Request, etag string) bool {
+	header := strings.TrimSpace(r.Header.Get(IfNoneMatchHeader))
+	if header == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
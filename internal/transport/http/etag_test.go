@@ -0,0 +1,135 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"HezzlTestTask/internal/model"
+	"HezzlTestTask/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// newVersionedRouter возвращает роутер над mockService, который хранит товар 1 проекта 1 с версией 3
+// и, как репозиторий, отклоняет изменение с ожидаемой версией, отличной от текущей
+func newVersionedRouter(t *testing.T) *mux.Router {
+	t.Helper()
+	const current = 3
+	check := func(version int) error {
+		if version != 0 && version != current {
+			return repository.ErrVersionMismatch
+		}
+		return nil
+	}
+	ms := &mockService{
+		GetFn: func(projectID, id int) (*model.Good, error) {
+			return &model.Good{ID: id, ProjectID: projectID, Name: "a", Priority: 1, Version: current}, nil
+		},
+		UpdateFn: func(projectID, id int, name string, description *string, version int) (*model.Good, error) {
+			if err := check(version); err != nil {
+				return nil, err
+			}
+			return &model.Good{ID: id, ProjectID: projectID, Name: name, Priority: 1, Version: current + 1}, nil
+		},
+		RemoveFn: func(projectID, id, version int) error { return check(version) },
+		ReprioritizeFn: func(projectID, id, newPriority, version int) ([]model.PriorityUpdate, error) {
+			if err := check(version); err != nil {
+				return nil, err
+			}
+			return []model.PriorityUpdate{{ID: id, Priority: newPriority}}, nil
+		},
+	}
+	r := mux.NewRouter()
+	NewHandler(ms, nil).RegisterRoutes(r)
+	return r
+}
+
+// TestGet_ETag: GET возвращает версию в ETag, а при совпадении с If-None-Match — 304 без тела
+func TestGet_ETag(t *testing.T) {
+	r := newVersionedRouter(t)
+	cases := []struct {
+		name        string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{"no header", "", http.StatusOK},
+		{"same version", `"3"`, http.StatusNotModified},
+		{"weak tag", `W/"3"`, http.StatusNotModified},
+		{"one of list", `"1", "3"`, http.StatusNotModified},
+		{"any", "*", http.StatusNotModified},
+		{"stale version", `"2"`, http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/good/get?projectId=1&id=1", nil)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set(IfNoneMatchHeader, tc.ifNoneMatch)
+			}
+			rq := httptest.NewRecorder()
+			r.ServeHTTP(rq, req)
+			if rq.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", rq.Code, tc.wantStatus)
+			}
+			if got := rq.Header().Get(ETagHeader); got != `"3"` {
+				t.Errorf("ETag = %q", got)
+			}
+			if tc.wantStatus == http.StatusNotModified && rq.Body.Len() != 0 {
+				t.Errorf("304 must have no body, got %q", rq.Body.String())
+			}
+		})
+	}
+}
+
+// TestIfMatch: изменяющие запросы с устаревшей версией в If-Match отклоняются с 412
+func TestIfMatch(t *testing.T) {
+	r := newVersionedRouter(t)
+	requests := []struct {
+		method, target, body string
+	}{
+		{http.MethodPatch, "/good/update?projectId=1&id=1", `{"name":"b"}`},
+		{http.MethodDelete, "/good/remove?projectId=1&id=1", ""},
+		{http.MethodPatch, "/good/reprioritize?projectId=1&id=1", `{"newPriority":2}`},
+	}
+	cases := []struct {
+		name       string
+		ifMatch    string
+		wantStatus int
+	}{
+		{"no header", "", http.StatusOK},
+		{"any", "*", http.StatusOK},
+		{"current version", `"3"`, http.StatusOK},
+		{"stale version", `"2"`, http.StatusPreconditionFailed},
+		{"weak tag", `W/"3"`, http.StatusPreconditionFailed},
+		{"foreign tag", `"abc"`, http.StatusPreconditionFailed},
+		{"several tags", `"2", "3"`, http.StatusBadRequest},
+	}
+	for _, rt := range requests {
+		for _, tc := range cases {
+			t.Run(rt.target+" "+tc.name, func(t *testing.T) {
+				req := httptest.NewRequest(rt.method, rt.target, bytes.NewBufferString(rt.body))
+				if tc.ifMatch != "" {
+					req.Header.Set(IfMatchHeader, tc.ifMatch)
+				}
+				rq := httptest.NewRecorder()
+				r.ServeHTTP(rq, req)
+				if rq.Code != tc.wantStatus {
+					t.Fatalf("status = %d, want %d: %s", rq.Code, tc.wantStatus, rq.Body.String())
+				}
+			})
+		}
+	}
+}
+
+// TestUpdate_ETag: ответ на обновление содержит ETag новой версии
+func TestUpdate_ETag(t *testing.T) {
+	r := newVersionedRouter(t)
+	req := httptest.NewRequest(http.MethodPatch, "/good/update?projectId=1&id=1", bytes.NewBufferString(`{"name":"b"}`))
+	req.Header.Set(IfMatchHeader, `"3"`)
+	rq := httptest.NewRecorder()
+	r.ServeHTTP(rq, req)
+	if rq.Code != http.StatusOK || rq.Header().Get(ETagHeader) != `"4"` {
+		t.Fatalf("status = %d, ETag = %q", rq.Code, rq.Header().Get(ETagHeader))
+	}
+}
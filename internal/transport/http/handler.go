@@ -5,32 +5,45 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
+	"HezzlTestTask/internal/auth"
 	"HezzlTestTask/internal/model"
-	"HezzlTestTask/internal/repository"
 )
 
 // GoodsService задаёт интерфейс бизнес-логики для HTTP-слоя, используемый хендлером
-// Методы соответствуют CRUD-операциям и управлению приоритетом
+// Методы соответствуют CRUD-операциям, пакетным операциям (Bulk*) и управлению приоритетом;
+// version — ожидаемая версия товара из If-Match (0 — без проверки)
 type GoodsService interface {
 	Create(ctx context.Context, projectID int, name string, description *string) (*model.Good, error)
 	Get(ctx context.Context, projectID, id int) (*model.Good, error)
-	Update(ctx context.Context, projectID, id int, name string, description *string) (*model.Good, error)
-	Remove(ctx context.Context, projectID, id int) error
-	List(ctx context.Context, limit, offset int) ([]model.Good, int, int, error)
-	Reprioritize(ctx context.Context, projectID, id, newPriority int) ([]model.PriorityUpdate, error)
+	Update(ctx context.Context, projectID, id int, name string, description *string, version int) (*model.Good, error)
+	Remove(ctx context.Context, projectID, id, version int) error
+	List(ctx context.Context, filter model.GoodsFilter) ([]model.Good, int, int, error)
+	Reprioritize(ctx context.Context, projectID, id, newPriority, version int) ([]model.PriorityUpdate, error)
+	BulkCreate(ctx context.Context, projectID int, items []model.GoodInput) ([]model.Good, error)
+	BulkUpdate(ctx context.Context, projectID int, items []model.GoodUpdate, atomic bool) ([]model.BulkResult, error)
+	BulkRemove(ctx context.Context, projectID int, items []model.GoodRemoval, atomic bool) ([]model.BulkResult, error)
 }
 
-// Handler содержит зависимости и реализует HTTP-эндпоинты для операций с товарами
+// Handler содержит зависимости и реализует HTTP-эндпоинты для операций с товарами и проектами
 type Handler struct {
-	srv GoodsService
+	srv      GoodsService
+	projects ProjectsService
+	ready    http.Handler // проверка зависимостей для /readyz; nil — сервис считается готовым всегда
 }
 
 // NewHandler создаёт новый HTTP Handler
-func NewHandler(srv GoodsService) *Handler {
-	return &Handler{srv: srv}
+func NewHandler(srv GoodsService, projects ProjectsService) *Handler {
+	return &Handler{srv: srv, projects: projects}
+}
+
+// SetReadiness задаёт обработчик /readyz, проверяющий зависимости сервиса (например *health.Checker)
+func (h *Handler) SetReadiness(ready http.Handler) {
+	h.ready = ready
 }
 
 // RegisterRoutes регистрирует маршруты API
@@ -44,100 +57,92 @@ func (h *Handler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/good/get", h.Get).Methods("GET")
 	r.HandleFunc("/goods/list", h.List).Methods("GET")
 	r.HandleFunc("/good/reprioritize", h.Reprioritize).Methods("PATCH")
-}
-
-// ErrorResponse модель ошибки API
-type ErrorResponse struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Details interface{} `json:"details"`
-}
-
-func writeError(w http.ResponseWriter, status int, resp ErrorResponse) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(resp)
+	r.HandleFunc("/goods/bulk/create", h.BulkCreate).Methods("POST")
+	r.HandleFunc("/goods/bulk/update", h.BulkUpdate).Methods("PATCH")
+	r.HandleFunc("/goods/bulk/remove", h.BulkRemove).Methods("DELETE")
+	r.HandleFunc("/project/create", h.CreateProject).Methods("POST")
+	r.HandleFunc("/project/get", h.GetProject).Methods("GET")
+	r.HandleFunc("/project/update", h.UpdateProject).Methods("PATCH")
+	r.HandleFunc("/project/remove", h.RemoveProject).Methods("DELETE")
+	r.HandleFunc("/projects/list", h.ListProjects).Methods("GET")
 }
 
 // Create обрабатывает POST /good/create
 // 1. Парсит projectId из query
-// 2. Декодирует тело запроса в структуру с полями name и description
+// 2. Декодирует и проверяет тело запроса с полями name и description (goodRequest)
 // 3. Вызывает метод сервиса Create
-// 4. В случае ошибки возвращает соответствующий HTTP-статус
-// 5. При успешном создании возвращает JSON созданного товара
+// 4. В случае ошибки возвращает HTTP-статус по виду ошибки (writeDomainError)
+// 5. При успешном создании возвращает JSON созданного товара и его версию в ETag
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
-	pidStr := r.URL.Query().Get("projectId")
-	pid, err := strconv.Atoi(pidStr)
-	if err != nil || pid <= 0 {
-		writeError(w, http.StatusBadRequest, ErrorResponse{1, "invalid projectId", map[string]interface{}{}})
+	var v validator
+	pid := v.queryID(r.URL.Query(), "projectId")
+	var req goodRequest
+	v.body(w, r, &req)
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
 		return
 	}
-	var req struct {
-		Name        string  `json:"name"`
-		Description *string `json:"description"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, ErrorResponse{1, "invalid request body", map[string]interface{}{}})
+	if err := authorize(r, pid, auth.RoleWrite); err != nil {
+		writeDomainError(w, r, err)
 		return
 	}
 	good, err := h.srv.Create(r.Context(), pid, req.Name, req.Description)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, ErrorResponse{1, err.Error(), map[string]interface{}{}})
+		writeDomainError(w, r, err)
 		return
 	}
+	w.Header().Set(ETagHeader, goodETag(good.Version))
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(good)
 }
 
 // Update обрабатывает PATCH /good/update
-// 1. Извлекает projectId и id через parseIDs
-// 2. Декодирует тело в поля name и description
-// 3. Вызывает сервис Update, обрабатывает ErrNotFound и другие ошибки
-// 4. Возвращает JSON обновлённого товара или ошибку
+// 1. Извлекает projectId и id через parseIDs и ожидаемую версию из If-Match
+// 2. Декодирует и проверяет тело с полями name и description (goodRequest)
+// 3. Вызывает сервис Update, ошибку (в том числе 412 при несовпадении версии) возвращает через writeDomainError
+// 4. Возвращает JSON обновлённого товара и его новую версию в ETag
 func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
-	pid, id, ok := parseIDs(r)
-	if !ok {
-		writeError(w, http.StatusBadRequest, ErrorResponse{1, "invalid projectId or id", map[string]interface{}{}})
+	var v validator
+	pid, id := parseIDs(&v, r)
+	version := v.ifMatch(r)
+	var req goodRequest
+	v.body(w, r, &req)
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
 		return
 	}
-	var req struct {
-		Name        string  `json:"name"`
-		Description *string `json:"description"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, ErrorResponse{1, "invalid request body", map[string]interface{}{}})
+	if err := authorize(r, pid, auth.RoleWrite); err != nil {
+		writeDomainError(w, r, err)
 		return
 	}
-	good, err := h.srv.Update(r.Context(), pid, id, req.Name, req.Description)
+	good, err := h.srv.Update(r.Context(), pid, id, req.Name, req.Description, version)
 	if err != nil {
-		if err == repository.ErrNotFound {
-			writeError(w, http.StatusNotFound, ErrorResponse{3, "errors.common.notFound", map[string]interface{}{}})
-		} else {
-			writeError(w, http.StatusInternalServerError, ErrorResponse{1, err.Error(), map[string]interface{}{}})
-		}
+		writeDomainError(w, r, err)
 		return
 	}
+	w.Header().Set(ETagHeader, goodETag(good.Version))
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(good)
 }
 
 // Remove обрабатывает DELETE /good/remove
-// 1. Извлекает projectId и id через parseIDs
-// 2. Вызывает сервис Remove, обрабатывает ErrNotFound и другие ошибки
+// 1. Извлекает projectId и id через parseIDs и ожидаемую версию из If-Match
+// 2. Вызывает сервис Remove, ошибку (в том числе 412 при несовпадении версии) возвращает через writeDomainError
 // 3. При успешном удалении возвращает JSON {id, campaignId, removed: true}
 func (h *Handler) Remove(w http.ResponseWriter, r *http.Request) {
-	pid, id, ok := parseIDs(r)
-	if !ok {
-		writeError(w, http.StatusBadRequest, ErrorResponse{1, "invalid projectId or id", map[string]interface{}{}})
+	var v validator
+	pid, id := parseIDs(&v, r)
+	version := v.ifMatch(r)
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
 		return
 	}
-	err := h.srv.Remove(r.Context(), pid, id)
-	if err != nil {
-		if err == repository.ErrNotFound {
-			writeError(w, http.StatusNotFound, ErrorResponse{3, "errors.common.notFound", map[string]interface{}{}})
-		} else {
-			writeError(w, http.StatusInternalServerError, ErrorResponse{1, err.Error(), map[string]interface{}{}})
-		}
+	if err := authorize(r, pid, auth.RoleWrite); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	if err := h.srv.Remove(r.Context(), pid, id, version); err != nil {
+		writeDomainError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -145,102 +150,107 @@ func (h *Handler) Remove(w http.ResponseWriter, r *http.Request) {
 }
 
 // Get обрабатывает GET /good/get
-// 1. Парсит id и projectId из query, валидирует
-// 2. Вызывает сервис Get, обрабатывает ErrNotFound и другие ошибки
-// 3. При успехе возвращает JSON товара
+// 1. Извлекает projectId и id через parseIDs
+// 2. Вызывает сервис Get, ошибку возвращает через writeDomainError
+// 3. При успехе возвращает JSON товара и его версию в ETag;
+// если ETag совпадает с If-None-Match, отвечает 304 без тела
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Query().Get("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		writeError(w, http.StatusBadRequest, ErrorResponse{1, "invalid id", map[string]interface{}{}})
+	var v validator
+	pid, id := parseIDs(&v, r)
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
 		return
 	}
-	pidStr := r.URL.Query().Get("projectId")
-	pid, err := strconv.Atoi(pidStr)
-	if err != nil || pid <= 0 {
-		writeError(w, http.StatusBadRequest, ErrorResponse{1, "invalid projectId", map[string]interface{}{}})
+	if err := authorize(r, pid, auth.RoleRead); err != nil {
+		writeDomainError(w, r, err)
 		return
 	}
 	good, err := h.srv.Get(r.Context(), pid, id)
 	if err != nil {
-		if err == repository.ErrNotFound {
-			writeError(w, http.StatusNotFound, ErrorResponse{3, "errors.common.notFound", map[string]interface{}{}})
-		} else {
-			writeError(w, http.StatusInternalServerError, ErrorResponse{1, err.Error(), map[string]interface{}{}})
-		}
+		writeDomainError(w, r, err)
+		return
+	}
+	etag := goodETag(good.Version)
+	w.Header().Set(ETagHeader, etag)
+	if noneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(good)
 }
 
+// goodsListMeta описывает метаданные ответа GET /goods/list
+// total и removed отсутствуют, если подсчёт отключён; offset — в режиме курсора;
+// nextCursor присутствует в режиме курсора, если страница заполнена целиком
+type goodsListMeta struct {
+	Total      *int   `json:"total,omitempty"`
+	Removed    *int   `json:"removed,omitempty"`
+	Limit      int    `json:"limit"`
+	Offset     *int   `json:"offset,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
 // List обрабатывает GET /goods/list
-// 1. Читает optional параметры limit, offset (по умолчанию 10 и 0)
-// 2. Вызывает сервис List, обрабатывает ошибки
-// 3. Возвращает JSON с полем meta (total, removed, limit, offset) и массив goods
+// 1. Читает и проверяет параметры фильтра через parseGoodsFilter (limit, offset по умолчанию 10 и 0)
+// 2. Вызывает сервис List, ошибку возвращает через writeDomainError
+// 3. Возвращает JSON с полем meta (total, removed, limit, offset или nextCursor) и массив goods
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
-	limit, offset := 10, 0
-	if v := r.URL.Query().Get("limit"); v != "" {
-		if i, err := strconv.Atoi(v); err == nil {
-			limit = i
-		}
+	filter, cursorMode, err := parseGoodsFilter(r)
+	if err != nil {
+		writeDomainError(w, r, err)
+		return
 	}
-	if v := r.URL.Query().Get("offset"); v != "" {
-		if i, err := strconv.Atoi(v); err == nil {
-			offset = i
-		}
+	if err := authorize(r, filter.ProjectID, auth.RoleRead); err != nil {
+		writeDomainError(w, r, err)
+		return
 	}
-	goods, total, removed, err := h.srv.List(r.Context(), limit, offset)
+	goods, total, removed, err := h.srv.List(r.Context(), filter)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, ErrorResponse{1, err.Error(), map[string]interface{}{}})
+		writeDomainError(w, r, err)
 		return
 	}
-	resp := struct {
-		Meta struct {
-			Total   int `json:"total"`
-			Removed int `json:"removed"`
-			Limit   int `json:"limit"`
-			Offset  int `json:"offset"`
-		} `json:"meta"`
-		Goods []model.Good `json:"goods"`
-	}{
-		Meta: struct {
-			Total   int `json:"total"`
-			Removed int `json:"removed"`
-			Limit   int `json:"limit"`
-			Offset  int `json:"offset"`
-		}{Total: total, Removed: removed, Limit: limit, Offset: offset},
-		Goods: goods,
+	meta := goodsListMeta{Limit: filter.Limit}
+	if !filter.SkipCounts {
+		meta.Total, meta.Removed = &total, &removed
 	}
+	if cursorMode {
+		if filter.Limit > 0 && len(goods) == filter.Limit {
+			meta.NextCursor = encodeGoodsCursor(filter, goods[len(goods)-1])
+		}
+	} else {
+		meta.Offset = &filter.Offset
+	}
+	resp := struct {
+		Meta  goodsListMeta `json:"meta"`
+		Goods []model.Good  `json:"goods"`
+	}{Meta: meta, Goods: goods}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
 // Reprioritize обрабатывает PATCH /good/reprioritize
-// 1. Извлекает projectId и id через parseIDs
-// 2. Декодирует тело запроса в поле newPriority
-// 3. Вызывает сервис Reprioritize, обрабатывает ErrNotFound и другие ошибки
+// 1. Извлекает projectId и id через parseIDs и ожидаемую версию из If-Match
+// 2. Декодирует и проверяет тело запроса с обязательным полем newPriority >= 1 (reprioritizeRequest)
+// 3. Вызывает сервис Reprioritize, ошибку (в том числе 412 при несовпадении версии) возвращает через writeDomainError
 // 4. Возвращает JSON с полем priorities (массив обновлений)
 func (h *Handler) Reprioritize(w http.ResponseWriter, r *http.Request) {
-	pid, id, ok := parseIDs(r)
-	if !ok {
-		writeError(w, http.StatusBadRequest, ErrorResponse{1, "invalid projectId or id", map[string]interface{}{}})
+	var v validator
+	pid, id := parseIDs(&v, r)
+	version := v.ifMatch(r)
+	var req reprioritizeRequest
+	v.body(w, r, &req)
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
 		return
 	}
-	var req struct {
-		NewPriority int `json:"newPriority"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, ErrorResponse{1, "invalid request body", map[string]interface{}{}})
+	if err := authorize(r, pid, auth.RoleWrite); err != nil {
+		writeDomainError(w, r, err)
 		return
 	}
-	updates, err := h.srv.Reprioritize(r.Context(), pid, id, req.NewPriority)
+	updates, err := h.srv.Reprioritize(r.Context(), pid, id, *req.NewPriority, version)
 	if err != nil {
-		if err == repository.ErrNotFound {
-			writeError(w, http.StatusNotFound, ErrorResponse{3, "errors.common.notFound", map[string]interface{}{}})
-		} else {
-			writeError(w, http.StatusInternalServerError, ErrorResponse{1, err.Error(), map[string]interface{}{}})
-		}
+		writeDomainError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -254,21 +264,105 @@ func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte(`{"status":"ok"}`))
 }
 
-// Readyz возвращает готовность сервиса
+// Readyz возвращает готовность сервиса: отчёт о зависимостях, если задан SetReadiness, иначе всегда ready
 func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	if h.ready != nil {
+		h.ready.ServeHTTP(w, r)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte(`{"status":"ready"}`))
 }
 
-// parseIDs извлекает и валидирует projectId и id из query parameters
-// Возвращает (projectId, id, ok)
-// ok=false при ошибке парсинга или если значения <=0
-func parseIDs(r *http.Request) (int, int, bool) {
-	pid, err1 := strconv.Atoi(r.URL.Query().Get("projectId"))
-	id, err2 := strconv.Atoi(r.URL.Query().Get("id"))
-	if err1 != nil || err2 != nil || pid <= 0 || id <= 0 {
-		return 0, 0, false
+// parseGoodsFilter извлекает параметры фильтра списка товаров из query parameters:
+// projectId, removed (include|exclude|only), name (до maxNameLength символов), createdFrom/createdTo (RFC3339),
+// sort (priority|id|createdAt|name с необязательным суффиксом :asc или :desc),
+// limit (от 1 до maxListLimit, по умолчанию 10), offset (неотрицательный)
+// Режим курсора включается параметром cursor (позиция из meta.nextCursor) или paging=cursor для первой страницы;
+// он поддерживается только для сортировки по id и priority, подсчёт total/removed в нём по умолчанию отключён
+// withCounts=true|false явно включает или отключает подсчёт в любом режиме
+// Возвращает (filter, режим курсора, ошибку валидации со всеми нарушениями)
+func parseGoodsFilter(r *http.Request) (model.GoodsFilter, bool, error) {
+	var v validator
+	q := r.URL.Query()
+	filter := model.GoodsFilter{Removed: model.RemovedInclude, Sort: model.SortByID}
+	filter.Limit = v.queryInt(q, "limit", defaultListLimit, 1, maxListLimit)
+	filter.Offset = v.queryInt(q, "offset", 0, 0, 0)
+	if q.Has("projectId") {
+		filter.ProjectID = v.queryID(q, "projectId")
+	}
+	if s := q.Get("removed"); s != "" {
+		switch s {
+		case model.RemovedInclude, model.RemovedExclude, model.RemovedOnly:
+			filter.Removed = s
+		default:
+			v.add("removed", "must be one of include, exclude, only")
+		}
 	}
-	return pid, id, true
+	filter.Name = q.Get("name")
+	v.text("name", &filter.Name, false, maxNameLength)
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"createdFrom", &filter.CreatedFrom}, {"createdTo", &filter.CreatedTo}} {
+		if s := q.Get(p.name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				v.add(p.name, "must be an RFC3339 timestamp")
+				continue
+			}
+			*p.dst = &t
+		}
+	}
+	if s := q.Get("sort"); s != "" {
+		field, dir, _ := strings.Cut(s, ":")
+		switch field {
+		case model.SortByID, model.SortByPriority, model.SortByCreatedAt, model.SortByName:
+			filter.Sort = field
+		default:
+			v.add("sort", "must be one of priority, id, createdAt, name")
+		}
+		switch dir {
+		case "", "asc":
+		case "desc":
+			filter.Desc = true
+		default:
+			v.add("sort", "direction must be asc or desc")
+		}
+	}
+	if s := q.Get("paging"); s != "" && s != "cursor" && s != "offset" {
+		v.add("paging", "must be cursor or offset")
+	}
+	cursor := q.Get("cursor")
+	cursorMode := cursor != "" || q.Get("paging") == "cursor"
+	if cursorMode {
+		if filter.Sort != model.SortByID && filter.Sort != model.SortByPriority {
+			v.add("sort", "must be id or priority in cursor paging")
+		}
+		filter.Offset = 0
+		filter.SkipCounts = true
+		if cursor != "" {
+			after, err := decodeGoodsCursor(cursor, filter)
+			if err != nil {
+				v.merge(err)
+			}
+			filter.After = after
+		}
+	}
+	if s := q.Get("withCounts"); s != "" {
+		withCounts, err := strconv.ParseBool(s)
+		if err != nil {
+			v.add("withCounts", "must be a boolean")
+		}
+		filter.SkipCounts = !withCounts
+	}
+	return filter, cursorMode, v.err()
+}
+
+// parseIDs извлекает и проверяет обязательные query parameters projectId и id — положительные целые числа
+// Нарушения записываются в v
+func parseIDs(v *validator, r *http.Request) (int, int) {
+	q := r.URL.Query()
+	return v.queryID(q, "projectId"), v.queryID(q, "id")
 }
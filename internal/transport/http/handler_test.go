@@ -16,6 +16,7 @@ import (
 	"github.com/gorilla/mux"
 
 	"HezzlTestTask/internal/model"
+	"HezzlTestTask/pkg/health"
 )
 
 // mockService реализует GoodsService для тестирования HTTP-хендлера.
@@ -26,14 +27,18 @@ import (
 // - RemoveFn: stub для обработки Remove
 // - ListFn: stub для обработки List
 // - ReprioritizeFn: stub для обработки Reprioritize
+// - BulkCreateFn, BulkUpdateFn, BulkRemoveFn: stub для пакетных BulkCreate, BulkUpdate, BulkRemove
 // Во время теста в этих функциях можно проверять переданные аргументы и эмулировать разные сценарии.
 type mockService struct {
 	CreateFn       func(projectID int, name string, description *string) (*model.Good, error)
 	GetFn          func(projectID, id int) (*model.Good, error)
-	UpdateFn       func(projectID, id int, name string, description *string) (*model.Good, error)
-	RemoveFn       func(projectID, id int) error
-	ListFn         func(limit, offset int) ([]model.Good, int, int, error)
-	ReprioritizeFn func(projectID, id, newPriority int) ([]model.PriorityUpdate, error)
+	UpdateFn       func(projectID, id int, name string, description *string, version int) (*model.Good, error)
+	RemoveFn       func(projectID, id, version int) error
+	ListFn         func(filter model.GoodsFilter) ([]model.Good, int, int, error)
+	ReprioritizeFn func(projectID, id, newPriority, version int) ([]model.PriorityUpdate, error)
+	BulkCreateFn   func(projectID int, items []model.GoodInput) ([]model.Good, error)
+	BulkUpdateFn   func(projectID int, items []model.GoodUpdate, atomic bool) ([]model.BulkResult, error)
+	BulkRemoveFn   func(projectID int, items []model.GoodRemoval, atomic bool) ([]model.BulkResult, error)
 }
 
 func (m *mockService) Create(_ context.Context, projectID int, name string, description *string) (*model.Good, error) {
@@ -42,17 +47,26 @@ func (m *mockService) Create(_ context.Context, projectID int, name string, desc
 func (m *mockService) Get(_ context.Context, projectID, id int) (*model.Good, error) {
 	return m.GetFn(projectID, id)
 }
-func (m *mockService) Update(_ context.Context, projectID, id int, name string, description *string) (*model.Good, error) {
-	return m.UpdateFn(projectID, id, name, description)
+func (m *mockService) Update(_ context.Context, projectID, id int, name string, description *string, version int) (*model.Good, error) {
+	return m.UpdateFn(projectID, id, name, description, version)
 }
-func (m *mockService) Remove(_ context.Context, projectID, id int) error {
-	return m.RemoveFn(projectID, id)
+func (m *mockService) Remove(_ context.Context, projectID, id, version int) error {
+	return m.RemoveFn(projectID, id, version)
 }
-func (m *mockService) List(_ context.Context, limit, offset int) ([]model.Good, int, int, error) {
-	return m.ListFn(limit, offset)
+func (m *mockService) List(_ context.Context, filter model.GoodsFilter) ([]model.Good, int, int, error) {
+	return m.ListFn(filter)
 }
-func (m *mockService) Reprioritize(_ context.Context, projectID, id, newPriority int) ([]model.PriorityUpdate, error) {
-	return m.ReprioritizeFn(projectID, id, newPriority)
+func (m *mockService) Reprioritize(_ context.Context, projectID, id, newPriority, version int) ([]model.PriorityUpdate, error) {
+	return m.ReprioritizeFn(projectID, id, newPriority, version)
+}
+func (m *mockService) BulkCreate(_ context.Context, projectID int, items []model.GoodInput) ([]model.Good, error) {
+	return m.BulkCreateFn(projectID, items)
+}
+func (m *mockService) BulkUpdate(_ context.Context, projectID int, items []model.GoodUpdate, atomic bool) ([]model.BulkResult, error) {
+	return m.BulkUpdateFn(projectID, items, atomic)
+}
+func (m *mockService) BulkRemove(_ context.Context, projectID int, items []model.GoodRemoval, atomic bool) ([]model.BulkResult, error) {
+	return m.BulkRemoveFn(projectID, items, atomic)
 }
 
 // TestCreate_Success проверяет корректную обработку успешной операции создания товара через HTTP запрос
@@ -67,7 +81,7 @@ func TestCreate_Success(t *testing.T) {
 		// Act: возврат ожидаемого товара
 		return expected, nil
 	}
-	h := NewHandler(ms)
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	// make request
@@ -90,7 +104,7 @@ func TestGet_NotFound(t *testing.T) {
 	ms := &mockService{}
 	notFound := repository.ErrNotFound
 	ms.GetFn = func(projectID, id int) (*model.Good, error) { return nil, notFound }
-	h := NewHandler(ms)
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodGet, "/good/get?projectId=1&id=10", nil)
@@ -103,7 +117,7 @@ func TestGet_NotFound(t *testing.T) {
 
 // TestCreate_InvalidProjectId проверяет возврат 400 при некорректном projectId в запросе создания товара
 func TestCreate_InvalidProjectId(t *testing.T) {
-	h := NewHandler(&mockService{})
+	h := NewHandler(&mockService{}, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodPost, "/good/create?projectId=abc", nil)
@@ -116,7 +130,7 @@ func TestCreate_InvalidProjectId(t *testing.T) {
 
 // TestCreate_InvalidJSON проверяет возврат 400 при некорректном JSON в теле запроса создания товара
 func TestCreate_InvalidJSON(t *testing.T) {
-	h := NewHandler(&mockService{})
+	h := NewHandler(&mockService{}, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodPost, "/good/create?projectId=1", bytes.NewBufferString(`invalid`))
@@ -133,7 +147,7 @@ func TestCreate_ServiceError(t *testing.T) {
 	ms := &mockService{CreateFn: func(projectID int, name string, description *string) (*model.Good, error) {
 		return nil, errTest
 	}}
-	h := NewHandler(ms)
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	body := `{"name":"n"}`
@@ -147,7 +161,7 @@ func TestCreate_ServiceError(t *testing.T) {
 
 // TestGet_InvalidParams проверяет возврат 400 при некорректных параметрах id или projectId в запросе GET
 func TestGet_InvalidParams(t *testing.T) {
-	h := NewHandler(&mockService{})
+	h := NewHandler(&mockService{}, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodGet, "/good/get?projectId=x&id=y", nil)
@@ -162,7 +176,7 @@ func TestGet_InvalidParams(t *testing.T) {
 func TestGet_ServiceError(t *testing.T) {
 	errTest := errors.New("get fail")
 	ms := &mockService{GetFn: func(projectID, id int) (*model.Good, error) { return nil, errTest }}
-	h := NewHandler(ms)
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodGet, "/good/get?projectId=1&id=1", nil)
@@ -177,7 +191,7 @@ func TestGet_ServiceError(t *testing.T) {
 func TestUpdate_Success(t *testing.T) {
 	ms := &mockService{}
 	expected := &model.Good{ID: 5, ProjectID: 3, Name: "upd", Description: ptr("x"), Priority: 2}
-	ms.UpdateFn = func(projectID, id int, name string, description *string) (*model.Good, error) {
+	ms.UpdateFn = func(projectID, id int, name string, description *string, version int) (*model.Good, error) {
 		// Arrange: ожидаемые значения projectID, id, name и description
 		if projectID != 3 || id != 5 || name != "upd" || *description != "x" {
 			t.Fatalf("unexpected args %d %d %s %v", projectID, id, name, description)
@@ -185,7 +199,7 @@ func TestUpdate_Success(t *testing.T) {
 		// Act: возврат ожидаемого товара
 		return expected, nil
 	}
-	h := NewHandler(ms)
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	body := `{"name":"upd","description":"x"}`
@@ -205,10 +219,10 @@ func TestUpdate_Success(t *testing.T) {
 // TestUpdate_NotFound проверяет возврат 404 при обновлении несуществующего товара
 func TestUpdate_NotFound(t *testing.T) {
 	ms := &mockService{}
-	ms.UpdateFn = func(projectID, id int, name string, description *string) (*model.Good, error) {
+	ms.UpdateFn = func(projectID, id int, name string, description *string, version int) (*model.Good, error) {
 		return nil, repository.ErrNotFound
 	}
-	h := NewHandler(ms)
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodPatch, "/good/update?projectId=1&id=1", bytes.NewBufferString(`{"name":"n","description":"d"}`))
@@ -221,7 +235,7 @@ func TestUpdate_NotFound(t *testing.T) {
 
 // TestUpdate_InvalidParams проверяет возврат 400 при некорректных параметрах projectId или id
 func TestUpdate_InvalidParams(t *testing.T) {
-	h := NewHandler(&mockService{})
+	h := NewHandler(&mockService{}, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodPatch, "/good/update?projectId=a&id=b", nil)
@@ -234,7 +248,7 @@ func TestUpdate_InvalidParams(t *testing.T) {
 
 // TestUpdate_InvalidJSON проверяет возврат 400 при некорректном JSON в теле PATCH запроса
 func TestUpdate_InvalidJSON(t *testing.T) {
-	h := NewHandler(&mockService{})
+	h := NewHandler(&mockService{}, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodPatch, "/good/update?projectId=1&id=1", bytes.NewBufferString(`bad`))
@@ -248,8 +262,10 @@ func TestUpdate_InvalidJSON(t *testing.T) {
 // TestUpdate_ServiceError проверяет возврат 500 при ошибке сервиса Update
 func TestUpdate_ServiceError(t *testing.T) {
 	errTest := errors.New("update fail")
-	ms := &mockService{UpdateFn: func(projectID, id int, name string, description *string) (*model.Good, error) { return nil, errTest }}
-	h := NewHandler(ms)
+	ms := &mockService{UpdateFn: func(projectID, id int, name string, description *string, version int) (*model.Good, error) {
+		return nil, errTest
+	}}
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	body := `{"name":"n"}`
@@ -264,7 +280,7 @@ func TestUpdate_ServiceError(t *testing.T) {
 // TestRemove_Success проверяет корректное логическое удаление товара через HTTP DELETE
 func TestRemove_Success(t *testing.T) {
 	ms := &mockService{}
-	ms.RemoveFn = func(projectID, id int) error {
+	ms.RemoveFn = func(projectID, id, version int) error {
 		// Arrange: ожидаемые значения projectID и id
 		if projectID != 4 || id != 2 {
 			t.Fatal("bad args")
@@ -272,7 +288,7 @@ func TestRemove_Success(t *testing.T) {
 		// Act: успешное удаление товара
 		return nil
 	}
-	h := NewHandler(ms)
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodDelete, "/good/remove?projectId=4&id=2", nil)
@@ -291,8 +307,8 @@ func TestRemove_Success(t *testing.T) {
 // TestRemove_NotFound проверяет возврат 404 при попытке удалить несуществующий товар
 func TestRemove_NotFound(t *testing.T) {
 	ms := &mockService{}
-	ms.RemoveFn = func(projectID, id int) error { return repository.ErrNotFound }
-	h := NewHandler(ms)
+	ms.RemoveFn = func(projectID, id, version int) error { return repository.ErrNotFound }
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodDelete, "/good/remove?projectId=1&id=1", nil)
@@ -305,7 +321,7 @@ func TestRemove_NotFound(t *testing.T) {
 
 // TestRemove_InvalidParams проверяет возврат 400 при некорректных параметрах запроса удаления
 func TestRemove_InvalidParams(t *testing.T) {
-	h := NewHandler(&mockService{})
+	h := NewHandler(&mockService{}, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodDelete, "/good/remove?projectId=x&id=y", nil)
@@ -318,8 +334,8 @@ func TestRemove_InvalidParams(t *testing.T) {
 
 // TestRemove_ServiceError проверяет возврат 500 при ошибке сервиса Remove
 func TestRemove_ServiceError(t *testing.T) {
-	ms := &mockService{RemoveFn: func(projectID, id int) error { return errors.New("remove fail") }}
-	h := NewHandler(ms)
+	ms := &mockService{RemoveFn: func(projectID, id, version int) error { return errors.New("remove fail") }}
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodDelete, "/good/remove?projectId=1&id=1", nil)
@@ -334,8 +350,8 @@ func TestRemove_ServiceError(t *testing.T) {
 func TestList_Success(t *testing.T) {
 	ms := &mockService{}
 	goods := []model.Good{{ID: 1, ProjectID: 1, Name: "a", Priority: 1, Removed: false, CreatedAt: time.Now()}}
-	ms.ListFn = func(limit, offset int) ([]model.Good, int, int, error) { return goods, 10, 2, nil }
-	h := NewHandler(ms)
+	ms.ListFn = func(filter model.GoodsFilter) ([]model.Good, int, int, error) { return goods, 10, 2, nil }
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodGet, "/goods/list?limit=5&offset=1", nil)
@@ -359,10 +375,122 @@ func TestList_Success(t *testing.T) {
 	}
 }
 
+// TestList_Filters проверяет разбор параметров фильтра, поиска и сортировки
+func TestList_Filters(t *testing.T) {
+	ms := &mockService{}
+	ms.ListFn = func(filter model.GoodsFilter) ([]model.Good, int, int, error) {
+		from := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+		want := model.GoodsFilter{
+			ProjectID:   3,
+			Removed:     model.RemovedExclude,
+			Name:        "phone",
+			CreatedFrom: &from,
+			Sort:        model.SortByPriority,
+			Desc:        true,
+			Limit:       10,
+		}
+		if !reflect.DeepEqual(filter, want) {
+			t.Fatalf("filter = %+v, want %+v", filter, want)
+		}
+		return nil, 0, 0, nil
+	}
+	h := NewHandler(ms, nil)
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+	req := httptest.NewRequest(http.MethodGet, "/goods/list?projectId=3&removed=exclude&name=phone&createdFrom=2025-07-01T00:00:00Z&sort=priority:desc", nil)
+	rq := httptest.NewRecorder()
+	r.ServeHTTP(rq, req)
+	if rq.Code != http.StatusOK {
+		t.Fatalf("status = %d", rq.Code)
+	}
+}
+
+// TestList_InvalidFilters проверяет возврат 400 при некорректных параметрах фильтра
+func TestList_InvalidFilters(t *testing.T) {
+	h := NewHandler(&mockService{}, nil)
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+	for _, q := range []string{"projectId=x", "removed=all", "createdTo=yesterday", "sort=price", "sort=name:up"} {
+		req := httptest.NewRequest(http.MethodGet, "/goods/list?"+q, nil)
+		rq := httptest.NewRecorder()
+		r.ServeHTTP(rq, req)
+		if rq.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, rq.Code)
+		}
+	}
+}
+
+// TestList_CursorPaging проверяет режим курсора: первая страница отдаёт nextCursor,
+// следующий запрос передаёт позицию в фильтр и по умолчанию не запрашивает подсчёт
+func TestList_CursorPaging(t *testing.T) {
+	ms := &mockService{}
+	var got []model.GoodsFilter
+	ms.ListFn = func(filter model.GoodsFilter) ([]model.Good, int, int, error) {
+		got = append(got, filter)
+		if filter.After == nil {
+			return []model.Good{{ID: 4, Priority: 1}, {ID: 9, Priority: 2}}, 0, 0, nil
+		}
+		return []model.Good{{ID: 2, Priority: 3}}, 0, 0, nil
+	}
+	h := NewHandler(ms, nil)
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+
+	var out struct {
+		Meta map[string]interface{} `json:"meta"`
+	}
+	req := httptest.NewRequest(http.MethodGet, "/goods/list?projectId=1&sort=priority&paging=cursor&limit=2", nil)
+	rq := httptest.NewRecorder()
+	r.ServeHTTP(rq, req)
+	if rq.Code != http.StatusOK {
+		t.Fatalf("status = %d", rq.Code)
+	}
+	_ = json.Unmarshal(rq.Body.Bytes(), &out)
+	next, _ := out.Meta["nextCursor"].(string)
+	if next == "" {
+		t.Fatalf("expected nextCursor, got meta %v", out.Meta)
+	}
+	if _, ok := out.Meta["total"]; ok {
+		t.Fatalf("total must be omitted in cursor mode, got meta %v", out.Meta)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/goods/list?projectId=1&sort=priority&limit=2&cursor="+next, nil)
+	rq = httptest.NewRecorder()
+	r.ServeHTTP(rq, req)
+	if rq.Code != http.StatusOK {
+		t.Fatalf("status = %d", rq.Code)
+	}
+	if len(got) != 2 || !got[1].SkipCounts || !reflect.DeepEqual(got[1].After, &model.GoodsCursor{Priority: 2, ID: 9}) {
+		t.Fatalf("unexpected filter for second page: %+v", got[1])
+	}
+	out.Meta = nil
+	_ = json.Unmarshal(rq.Body.Bytes(), &out)
+	if _, ok := out.Meta["nextCursor"]; ok {
+		t.Fatalf("last page must not have nextCursor, got meta %v", out.Meta)
+	}
+}
+
+// TestList_CursorInvalid проверяет возврат 400 для некорректного курсора и неподдерживаемой сортировки
+func TestList_CursorInvalid(t *testing.T) {
+	h := NewHandler(&mockService{}, nil)
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+	for _, q := range []string{"cursor=garbage", "paging=cursor&sort=name", "withCounts=maybe"} {
+		req := httptest.NewRequest(http.MethodGet, "/goods/list?"+q, nil)
+		rq := httptest.NewRecorder()
+		r.ServeHTTP(rq, req)
+		if rq.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, rq.Code)
+		}
+	}
+}
+
 // TestList_ServiceError проверяет возврат 500 при ошибке сервиса List
 func TestList_ServiceError(t *testing.T) {
-	ms := &mockService{ListFn: func(limit, offset int) ([]model.Good, int, int, error) { return nil, 0, 0, errors.New("list fail") }}
-	h := NewHandler(ms)
+	ms := &mockService{ListFn: func(filter model.GoodsFilter) ([]model.Good, int, int, error) {
+		return nil, 0, 0, errors.New("list fail")
+	}}
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodGet, "/goods/list?limit=1&offset=0", nil)
@@ -377,7 +505,7 @@ func TestList_ServiceError(t *testing.T) {
 func TestReprioritize_Success(t *testing.T) {
 	ms := &mockService{}
 	updates := []model.PriorityUpdate{{ID: 1, Priority: 2}}
-	ms.ReprioritizeFn = func(projectID, id, newPriority int) ([]model.PriorityUpdate, error) {
+	ms.ReprioritizeFn = func(projectID, id, newPriority, version int) ([]model.PriorityUpdate, error) {
 		// Arrange: ожидаемые значения projectID, id и newPriority
 		if projectID != 7 || id != 3 || newPriority != 5 {
 			t.Fatal("args")
@@ -385,7 +513,7 @@ func TestReprioritize_Success(t *testing.T) {
 		// Act: возврат ожидаемого обновления приоритета
 		return updates, nil
 	}
-	h := NewHandler(ms)
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodPatch, "/good/reprioritize?projectId=7&id=3", bytes.NewBufferString(`{"newPriority":5}`))
@@ -403,7 +531,7 @@ func TestReprioritize_Success(t *testing.T) {
 
 // TestReprioritize_InvalidParams проверяет возврат 400 при некорректных параметрах запроса приоритизации
 func TestReprioritize_InvalidParams(t *testing.T) {
-	h := NewHandler(&mockService{})
+	h := NewHandler(&mockService{}, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodPatch, "/good/reprioritize?projectId=a&id=b", nil)
@@ -416,7 +544,7 @@ func TestReprioritize_InvalidParams(t *testing.T) {
 
 // TestReprioritize_InvalidJSON проверяет возврат 400 при некорректном JSON в теле запроса приоритизации
 func TestReprioritize_InvalidJSON(t *testing.T) {
-	h := NewHandler(&mockService{})
+	h := NewHandler(&mockService{}, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodPatch, "/good/reprioritize?projectId=1&id=1", bytes.NewBufferString(`bad`))
@@ -429,10 +557,10 @@ func TestReprioritize_InvalidJSON(t *testing.T) {
 
 // TestReprioritize_ServiceError проверяет возврат 500 при ошибке сервиса Reprioritize
 func TestReprioritize_ServiceError(t *testing.T) {
-	ms := &mockService{ReprioritizeFn: func(projectID, id, newPriority int) ([]model.PriorityUpdate, error) {
+	ms := &mockService{ReprioritizeFn: func(projectID, id, newPriority, version int) ([]model.PriorityUpdate, error) {
 		return nil, errors.New("repr fail")
 	}}
-	h := NewHandler(ms)
+	h := NewHandler(ms, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	body := `{"newPriority":1}`
@@ -446,7 +574,7 @@ func TestReprioritize_ServiceError(t *testing.T) {
 
 // TestHealthz проверяет корректный ответ эндпоинта /healthz
 func TestHealthz(t *testing.T) {
-	h := NewHandler(&mockService{})
+	h := NewHandler(&mockService{}, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
@@ -463,7 +591,7 @@ func TestHealthz(t *testing.T) {
 
 // TestReadyz проверяет корректный ответ эндпоинта /readyz
 func TestReadyz(t *testing.T) {
-	h := NewHandler(&mockService{})
+	h := NewHandler(&mockService{}, nil)
 	r := mux.NewRouter()
 	h.RegisterRoutes(r)
 	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
@@ -478,5 +606,27 @@ func TestReadyz(t *testing.T) {
 	}
 }
 
+// TestReadyz_Readiness проверяет, что /readyz отдаёт отчёт проверки зависимостей
+func TestReadyz_Readiness(t *testing.T) {
+	h := NewHandler(&mockService{}, nil)
+	checker := health.NewChecker(time.Second)
+	checker.Add("postgres", true, func(ctx context.Context) error { return errors.New("connection refused") })
+	h.SetReadiness(checker)
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+	rq := httptest.NewRecorder()
+	r.ServeHTTP(rq, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rq.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rq.Code)
+	}
+	var report health.Report
+	if err := json.NewDecoder(rq.Body).Decode(&report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if report.Status != health.StatusNotReady || report.Components["postgres"].Error != "connection refused" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
 // helper ptr используется для создания указателя на строку в тестовых данных
 func ptr(s string) *string { return &s }
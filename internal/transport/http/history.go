@@ -0,0 +1,135 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"HezzlTestTask/internal/auth"
+	"HezzlTestTask/internal/model"
+)
+
+// Ограничения размера страницы истории изменений
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// HistoryReader задаёт интерфейс чтения истории изменений (реализуется repository.ClickhouseRepo)
+type HistoryReader interface {
+	History(ctx context.Context, filter model.HistoryFilter) ([]model.Event, error)
+}
+
+// HistoryHandler реализует HTTP-эндпоинты истории изменений товаров и проектов
+type HistoryHandler struct {
+	history HistoryReader
+}
+
+// NewHistoryHandler создаёт HistoryHandler
+func NewHistoryHandler(history HistoryReader) *HistoryHandler {
+	return &HistoryHandler{history: history}
+}
+
+// RegisterRoutes регистрирует маршруты истории изменений
+func (h *HistoryHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/good/history", h.GoodHistory).Methods("GET")
+	r.HandleFunc("/project/history", h.ProjectHistory).Methods("GET")
+}
+
+// historyMeta описывает метаданные ответа истории; nextCursor присутствует, если страница заполнена целиком
+type historyMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// GoodHistory обрабатывает GET /good/history
+// 1. Извлекает projectId и id через parseIDs, период и пагинацию через parseHistoryFilter
+// 2. Возвращает события товара в хронологическом порядке
+func (h *HistoryHandler) GoodHistory(w http.ResponseWriter, r *http.Request) {
+	var v validator
+	pid, id := parseIDs(&v, r)
+	filter := parseHistoryFilter(&v, r)
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	if err := authorize(r, pid, auth.RoleRead); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	filter.ProjectID, filter.EntityType, filter.EntityID = pid, model.HistoryGood, id
+	h.writeHistory(w, r, filter)
+}
+
+// ProjectHistory обрабатывает GET /project/history
+// 1. Парсит projectId, период и пагинацию
+// 2. Возвращает все события проекта (включая изменения его товаров) в хронологическом порядке
+func (h *HistoryHandler) ProjectHistory(w http.ResponseWriter, r *http.Request) {
+	var v validator
+	pid := v.queryID(r.URL.Query(), "projectId")
+	filter := parseHistoryFilter(&v, r)
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	if err := authorize(r, pid, auth.RoleRead); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	filter.ProjectID = pid
+	h.writeHistory(w, r, filter)
+}
+
+// writeHistory выбирает страницу истории и возвращает JSON с полем meta (limit, nextCursor) и массивом events
+func (h *HistoryHandler) writeHistory(w http.ResponseWriter, r *http.Request, filter model.HistoryFilter) {
+	events, err := h.history.History(r.Context(), filter)
+	if err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	meta := historyMeta{Limit: filter.Limit}
+	if len(events) == filter.Limit {
+		meta.NextCursor = encodeHistoryCursor(events[len(events)-1])
+	}
+	resp := struct {
+		Meta   historyMeta   `json:"meta"`
+		Events []model.Event `json:"events"`
+	}{Meta: meta, Events: events}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// parseHistoryFilter извлекает из query parameters период from/to (RFC3339, полуинтервал [from, to)),
+// limit (по умолчанию 50, не более 500) и cursor (позиция из meta.nextCursor)
+// Нарушения записываются в v
+func parseHistoryFilter(v *validator, r *http.Request) model.HistoryFilter {
+	q := r.URL.Query()
+	filter := model.HistoryFilter{Limit: v.queryInt(q, "limit", defaultHistoryLimit, 1, maxHistoryLimit)}
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if s := q.Get(p.name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				v.add(p.name, "must be an RFC3339 timestamp")
+				continue
+			}
+			*p.dst = &t
+		}
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		v.add("from", "must be before to")
+	}
+	if s := q.Get("cursor"); s != "" {
+		after, err := decodeHistoryCursor(s)
+		if err != nil {
+			v.merge(err)
+		}
+		filter.After = after
+	}
+	return filter
+}
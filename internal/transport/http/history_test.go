@@ -0,0 +1,126 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"HezzlTestTask/internal/model"
+)
+
+// mockHistory реализует HistoryReader и запоминает последний фильтр
+type mockHistory struct {
+	HistoryFn func(filter model.HistoryFilter) ([]model.Event, error)
+	filter    model.HistoryFilter
+}
+
+func (m *mockHistory) History(_ context.Context, filter model.HistoryFilter) ([]model.Event, error) {
+	m.filter = filter
+	return m.HistoryFn(filter)
+}
+
+// serveHistory выполняет GET-запрос к маршрутам истории с заданным ридером
+func serveHistory(hr HistoryReader, target string) *httptest.ResponseRecorder {
+	r := mux.NewRouter()
+	NewHistoryHandler(hr).RegisterRoutes(r)
+	rq := httptest.NewRecorder()
+	r.ServeHTTP(rq, httptest.NewRequest(http.MethodGet, target, nil))
+	return rq
+}
+
+// historyResponse — тело ответа эндпоинтов истории
+type historyResponse struct {
+	Meta   historyMeta   `json:"meta"`
+	Events []model.Event `json:"events"`
+}
+
+// TestGoodHistory_Success проверяет фильтр истории товара и выдачу курсора для заполненной страницы
+func TestGoodHistory_Success(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	events := []model.Event{
+		{Version: 1, ID: "e-1", Type: model.EventGoodCreated, OccurredAt: at, ProjectID: 2, EntityID: 7},
+		{Version: 1, ID: "e-2", Type: model.EventGoodUpdated, OccurredAt: at, ProjectID: 2, EntityID: 7, Actor: "alice"},
+	}
+	mh := &mockHistory{HistoryFn: func(model.HistoryFilter) ([]model.Event, error) { return events, nil }}
+	rq := serveHistory(mh, "/good/history?projectId=2&id=7&limit=2&from=2026-01-01T00:00:00Z")
+	if rq.Code != http.StatusOK {
+		t.Fatalf("status = %d", rq.Code)
+	}
+	if mh.filter.ProjectID != 2 || mh.filter.EntityID != 7 || mh.filter.EntityType != model.HistoryGood ||
+		mh.filter.Limit != 2 || mh.filter.From == nil || mh.filter.To != nil {
+		t.Fatalf("unexpected filter %+v", mh.filter)
+	}
+	var resp historyResponse
+	if err := json.NewDecoder(rq.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Events) != 2 || resp.Events[1].Actor != "alice" {
+		t.Fatalf("unexpected events %+v", resp.Events)
+	}
+	if resp.Meta.NextCursor == "" {
+		t.Fatal("expected nextCursor for a full page")
+	}
+
+	// следующая страница продолжается после последнего события
+	mh.HistoryFn = func(model.HistoryFilter) ([]model.Event, error) { return []model.Event{}, nil }
+	rq = serveHistory(mh, "/good/history?projectId=2&id=7&limit=2&cursor="+resp.Meta.NextCursor)
+	if rq.Code != http.StatusOK {
+		t.Fatalf("status = %d", rq.Code)
+	}
+	if mh.filter.After == nil || mh.filter.After.EventID != "e-2" || !mh.filter.After.OccurredAt.Equal(at) {
+		t.Fatalf("unexpected cursor %+v", mh.filter.After)
+	}
+	resp = historyResponse{}
+	_ = json.NewDecoder(rq.Body).Decode(&resp)
+	if resp.Meta.NextCursor != "" || resp.Events == nil {
+		t.Fatalf("unexpected last page %+v", resp)
+	}
+}
+
+// TestProjectHistory_Success проверяет, что история проекта не ограничивается типом сущности
+func TestProjectHistory_Success(t *testing.T) {
+	mh := &mockHistory{HistoryFn: func(model.HistoryFilter) ([]model.Event, error) { return []model.Event{}, nil }}
+	rq := serveHistory(mh, "/project/history?projectId=3&from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z")
+	if rq.Code != http.StatusOK {
+		t.Fatalf("status = %d", rq.Code)
+	}
+	if mh.filter.ProjectID != 3 || mh.filter.EntityType != "" || mh.filter.EntityID != 0 ||
+		mh.filter.Limit != defaultHistoryLimit || mh.filter.From == nil || mh.filter.To == nil {
+		t.Fatalf("unexpected filter %+v", mh.filter)
+	}
+}
+
+// TestHistory_InvalidParams проверяет ответ 400 для некорректных параметров
+func TestHistory_InvalidParams(t *testing.T) {
+	mh := &mockHistory{HistoryFn: func(model.HistoryFilter) ([]model.Event, error) {
+		t.Fatal("history must not be called")
+		return nil, nil
+	}}
+	for _, target := range []string{
+		"/good/history?projectId=2",
+		"/project/history?projectId=x",
+		"/project/history?projectId=1&limit=0",
+		"/project/history?projectId=1&limit=501",
+		"/project/history?projectId=1&from=yesterday",
+		"/project/history?projectId=1&from=2026-02-01T00:00:00Z&to=2026-01-01T00:00:00Z",
+		"/project/history?projectId=1&cursor=%25%25",
+	} {
+		if rq := serveHistory(mh, target); rq.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", target, rq.Code)
+		}
+	}
+}
+
+// TestHistory_Error проверяет ответ 500 при недоступности ClickHouse
+func TestHistory_Error(t *testing.T) {
+	mh := &mockHistory{HistoryFn: func(model.HistoryFilter) ([]model.Event, error) { return nil, errors.New("clickhouse down") }}
+	if rq := serveHistory(mh, "/project/history?projectId=1"); rq.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d", rq.Code)
+	}
+}
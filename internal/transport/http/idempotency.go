@@ -0,0 +1,252 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"HezzlTestTask/internal/apperr"
+	"HezzlTestTask/pkg/cache"
+
+	"github.com/gorilla/mux"
+)
+
+// Заголовки идемпотентных запросов
+const (
+	// IdempotencyKeyHeader — ключ идемпотентности, выбранный клиентом (например UUID) для изменяющего запроса
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader отмечает ответ, повторённый из сохранённого результата
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+const (
+	// maxIdempotencyKeyLength ограничивает длину ключа идемпотентности
+	maxIdempotencyKeyLength = 255
+	// idempotencyKeyPrefix — префикс ключей кэша с сохранёнными ответами
+	idempotencyKeyPrefix = "idempotency:"
+)
+
+// idempotencyLockTTL — сколько живёт отметка о запросе в обработке без продления; пока обработчик выполняется,
+// отметка продлевается каждую треть этого времени, а если реплика упала, не сохранив ответ, по истечении
+// отметки запрос с тем же ключом выполнится заново
+var idempotencyLockTTL = 30 * time.Second
+
+// IdempotencyStore хранит ответы идемпотентных запросов (cache.RedisClient, cache.MemoryCache)
+// Get возвращает cache.ErrCacheMiss для отсутствующего ключа, SetIfAbsent записывает только новый ключ,
+// CompareAndSet и CompareAndDelete изменяют ключ, только если его значение не изменилось
+type IdempotencyStore interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	CompareAndSet(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error)
+	CompareAndDelete(ctx context.Context, key string, old []byte) (bool, error)
+}
+
+// idempotencyRecord — состояние запроса с ключом идемпотентности; пока Done ложно, запрос в обработке
+// Token — случайный идентификатор запроса, занявшего ключ: ответ сохраняется, только если отметка всё ещё его
+type idempotencyRecord struct {
+	Fingerprint string      `json:"fingerprint"`
+	Token       string      `json:"token,omitempty"`
+	Done        bool        `json:"done"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// perRequestHeaders — заголовки, которые относятся к конкретному запросу и не повторяются из сохранённого ответа
+var perRequestHeaders = []string{RequestIDHeader, RateLimitLimitHeader, RateLimitRemainingHeader, RateLimitResetHeader, "Retry-After"}
+
+// IdempotencyMiddleware повторяет ответ изменяющего запроса (POST, PUT, PATCH, DELETE) с заголовком Idempotency-Key
+// Первый запрос с ключом выполняется, его ответ сохраняется в store на ttl; повтор с тем же ключом и тем же
+// запросом (метод, путь, query и тело) получает сохранённый ответ с заголовком Idempotent-Replayed: true
+// Тот же ключ с другим запросом и повтор, пока первый ещё выполняется, отклоняются с 409
+// Ответы 5xx не сохраняются, чтобы запрос можно было повторить; ключи разделены по клиентам, как и лимиты запросов:
+// по идентификатору из AuthMiddleware, а без аутентификации — по IP
+func IdempotencyMiddleware(store IdempotencyStore, ttl time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !isVisibleASCII(key, maxIdempotencyKeyLength) {
+				writeDomainError(w, r, apperr.Invalid(IdempotencyKeyHeader, "must be 1 to 255 visible ASCII characters"))
+				return
+			}
+			fingerprint, err := requestFingerprint(r)
+			if err != nil {
+				writeDomainError(w, r, apperr.Invalid("body", "cannot be read"))
+				return
+			}
+			storeKey := idempotencyKeyPrefix + requestClient(r) + ":" + key
+			lock, _ := json.Marshal(idempotencyRecord{Fingerprint: fingerprint, Token: newRequestID()})
+			// если запись исчезла между SetIfAbsent и Get (ответ 5xx не сохранён), пробуем занять ключ ещё раз
+			for attempt := 0; ; attempt++ {
+				acquired, err := store.SetIfAbsent(r.Context(), storeKey, lock, idempotencyLockTTL)
+				if err != nil {
+					writeDomainError(w, r, apperr.Unavailable("idempotency store unavailable", err))
+					return
+				}
+				if acquired {
+					break
+				}
+				if replayIdempotent(w, r, store, storeKey, fingerprint, attempt > 0) {
+					return
+				}
+			}
+
+			// сохраняем ответ независимо от отмены запроса клиентом: он повторит запрос с тем же ключом
+			ctx := context.WithoutCancel(r.Context())
+			stop, stopped := make(chan struct{}), make(chan struct{})
+			go func() {
+				defer close(stopped)
+				keepIdempotencyLock(ctx, store, storeKey, lock, stop)
+			}()
+			// record — ответ для сохранения; nil, если обработчик ответил 5xx или запаниковал
+			// Продление останавливается и отметка освобождается в defer, чтобы паника обработчика
+			// не оставила ключ занятым до перезапуска процесса
+			var record []byte
+			defer func() {
+				close(stop)
+				<-stopped
+				if record == nil {
+					_, _ = store.CompareAndDelete(ctx, storeKey, lock)
+					return
+				}
+				stored, err := store.CompareAndSet(ctx, storeKey, lock, record, ttl)
+				switch {
+				case err != nil:
+					// без сохранённого ответа отметка истечёт через idempotencyLockTTL и повтор выполнится заново
+					slog.WarnContext(ctx, "failed to store idempotent response", slog.Any("error", err))
+				case !stored:
+					// отметка истекла и ключ занял другой запрос: его запись не перезаписывается
+					slog.WarnContext(ctx, "idempotency lock lost, response not stored")
+				}
+			}()
+			crw := &captureResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(crw, r)
+			if crw.status >= http.StatusInternalServerError {
+				return
+			}
+			header := crw.Header().Clone()
+			for _, name := range perRequestHeaders {
+				header.Del(name)
+			}
+			record, _ = json.Marshal(idempotencyRecord{
+				Fingerprint: fingerprint, Done: true, Status: crw.status, Header: header, Body: crw.body.Bytes(),
+			})
+		})
+	}
+}
+
+// keepIdempotencyLock продлевает отметку lock запроса в обработке, пока не закрыт stop
+// Продление прекращается, если отметки уже нет или её занял другой запрос
+func keepIdempotencyLock(ctx context.Context, store IdempotencyStore, storeKey string, lock []byte, stop <-chan struct{}) {
+	ticker := time.NewTicker(idempotencyLockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ok, err := store.CompareAndSet(ctx, storeKey, lock, lock, idempotencyLockTTL)
+			if err != nil {
+				slog.WarnContext(ctx, "failed to extend idempotency lock", slog.Any("error", err))
+				continue
+			}
+			if !ok {
+				slog.WarnContext(ctx, "idempotency lock lost")
+				return
+			}
+		}
+	}
+}
+
+// replayIdempotent отвечает на повтор запроса с уже использованным ключом storeKey
+// Возвращает false, не записав ответ, если записи о ключе уже нет и final ложно: тогда вызывающий занимает ключ заново
+func replayIdempotent(w http.ResponseWriter, r *http.Request, store IdempotencyStore, storeKey, fingerprint string, final bool) bool {
+	data, err := store.Get(r.Context(), storeKey)
+	if errors.Is(err, cache.ErrCacheMiss) && !final {
+		return false
+	}
+	if errors.Is(err, cache.ErrCacheMiss) {
+		writeDomainError(w, r, apperr.Conflict("request with this Idempotency-Key is in progress, retry later"))
+		return true
+	}
+	if err != nil {
+		writeDomainError(w, r, apperr.Unavailable("idempotency store unavailable", err))
+		return true
+	}
+	var rec idempotencyRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		writeDomainError(w, r, fmt.Errorf("failed to decode idempotent response: %w", err))
+		return true
+	}
+	switch {
+	case rec.Fingerprint != fingerprint:
+		writeDomainError(w, r, apperr.Conflict("Idempotency-Key was already used with a different request"))
+	case !rec.Done:
+		writeDomainError(w, r, apperr.Conflict("request with this Idempotency-Key is in progress, retry later"))
+	default:
+		for name, values := range rec.Header {
+			w.Header()[name] = values
+		}
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.WriteHeader(rec.Status)
+		_, _ = w.Write(rec.Body)
+	}
+	return true
+}
+
+// requestFingerprint возвращает хеш метода, пути, query и тела запроса; тело остаётся доступным обработчику
+// Читается не больше maxBodyBytes+1 байт: более длинное тело всё равно отклонит проверка запроса
+func requestFingerprint(r *http.Request) (string, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	if err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	h := sha256.New()
+	for _, part := range []string{r.Method, r.URL.Path, r.URL.Query().Encode()} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// isMutating сообщает, изменяет ли запрос с методом method данные
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// captureResponseWriter передаёт ответ клиенту и одновременно сохраняет статус и тело
+type captureResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader сохраняет статус и вызывает оригинальный WriteHeader
+func (w *captureResponseWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write сохраняет копию тела и передаёт его клиенту
+func (w *captureResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,243 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"HezzlTestTask/internal/model"
+	"HezzlTestTask/pkg/cache"
+)
+
+// unavailableStore имитирует недоступное хранилище ответов
+type unavailableStore struct{ cache.MemoryCache }
+
+func (*unavailableStore) SetIfAbsent(context.Context, string, []byte, time.Duration) (bool, error) {
+	return false, errors.New("redis: connection refused")
+}
+
+// newIdempotencyRouter создаёт маршрутизатор с IdempotencyMiddleware и считает вызовы Create
+// Товар с именем "fail" создаётся с внутренней ошибкой
+func newIdempotencyRouter(store IdempotencyStore, calls *int) *mux.Router {
+	ms := &mockService{
+		CreateFn: func(projectID int, name string, description *string) (*model.Good, error) {
+			*calls++
+			if name == "fail" {
+				return nil, errors.New("pq: connection reset")
+			}
+			return &model.Good{ID: *calls, ProjectID: projectID, Name: name, Priority: *calls}, nil
+		},
+		GetFn: func(projectID, id int) (*model.Good, error) { return &model.Good{ID: id, ProjectID: projectID}, nil },
+	}
+	r := mux.NewRouter()
+	r.Use(IdempotencyMiddleware(store, time.Hour))
+	NewHandler(ms, nil).RegisterRoutes(r)
+	return r
+}
+
+// doIdempotent выполняет запрос с ключом идемпотентности key
+func doIdempotent(r http.Handler, method, target, body, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	return rw
+}
+
+// TestIdempotency_Replay проверяет повтор сохранённого ответа и 409 для того же ключа с другим телом
+func TestIdempotency_Replay(t *testing.T) {
+	var calls int
+	r := newIdempotencyRouter(cache.NewMemoryCache(), &calls)
+
+	first := doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"a"}`, "k1")
+	if first.Code != http.StatusOK || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("unexpected first response %d %v", first.Code, first.Header())
+	}
+	replay := doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"a"}`, "k1")
+	if replay.Code != http.StatusOK || replay.Body.String() != first.Body.String() ||
+		replay.Header().Get(IdempotentReplayedHeader) != "true" || replay.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected replayed response, got %d %v %s", replay.Code, replay.Header(), replay.Body.String())
+	}
+	if calls != 1 {
+		t.Errorf("expected single Create call, got %d", calls)
+	}
+
+	for _, c := range []struct{ target, body string }{
+		{"/good/create?projectId=1", `{"name":"b"}`},
+		{"/good/create?projectId=2", `{"name":"a"}`},
+	} {
+		rw := doIdempotent(r, http.MethodPost, c.target, c.body, "k1")
+		if resp := decodeErrorResponse(t, rw); rw.Code != http.StatusConflict || !strings.Contains(resp.Message, "different request") {
+			t.Errorf("%s %s: expected 409, got %d %+v", c.target, c.body, rw.Code, resp)
+		}
+	}
+	// другой ключ выполняет запрос заново
+	if rw := doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"a"}`, "k2"); rw.Code != http.StatusOK || calls != 2 {
+		t.Errorf("expected new request for another key, got %d, calls %d", rw.Code, calls)
+	}
+}
+
+// TestIdempotency_ClientsSeparated проверяет, что без аутентификации одинаковые ключи разных IP не пересекаются
+func TestIdempotency_ClientsSeparated(t *testing.T) {
+	var calls int
+	r := newIdempotencyRouter(cache.NewMemoryCache(), &calls)
+	for i, addr := range []string{"192.0.2.1:1000", "192.0.2.2:1000", "192.0.2.1:2000"} {
+		req := httptest.NewRequest(http.MethodPost, "/good/create?projectId=1", bytes.NewBufferString(`{"name":"a"}`))
+		req.Header.Set(IdempotencyKeyHeader, "k")
+		req.RemoteAddr = addr
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		// третий запрос приходит с уже использованного адреса и получает сохранённый ответ
+		wantReplay := i == 2
+		if rw.Code != http.StatusOK || (rw.Header().Get(IdempotentReplayedHeader) == "true") != wantReplay {
+			t.Fatalf("%s: unexpected response %d %v", addr, rw.Code, rw.Header())
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected one Create call per client, got %d", calls)
+	}
+}
+
+// TestIdempotency_ServerErrorNotStored проверяет, что после ответа 5xx запрос с тем же ключом выполняется снова
+func TestIdempotency_ServerErrorNotStored(t *testing.T) {
+	var calls int
+	r := newIdempotencyRouter(cache.NewMemoryCache(), &calls)
+	for i := 1; i <= 2; i++ {
+		if rw := doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"fail"}`, "k"); rw.Code != http.StatusInternalServerError || calls != i {
+			t.Fatalf("attempt %d: expected 500 and %d calls, got %d and %d", i, i, rw.Code, calls)
+		}
+	}
+}
+
+// TestIdempotency_InProgress проверяет 409, пока первый запрос с ключом ещё выполняется
+func TestIdempotency_InProgress(t *testing.T) {
+	var calls int
+	store := cache.NewMemoryCache()
+	r := newIdempotencyRouter(store, &calls)
+
+	fingerprint, _ := requestFingerprint(httptest.NewRequest(http.MethodPost, "/good/create?projectId=1", bytes.NewBufferString(`{"name":"a"}`)))
+	lock, _ := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+	_ = store.Set(context.Background(), idempotencyKeyPrefix+"ip:192.0.2.1:k", lock, time.Minute)
+
+	rw := doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"a"}`, "k")
+	if resp := decodeErrorResponse(t, rw); rw.Code != http.StatusConflict || !strings.Contains(resp.Message, "in progress") || calls != 0 {
+		t.Errorf("expected 409 in progress without Create call, got %d %+v, calls %d", rw.Code, resp, calls)
+	}
+}
+
+// withIdempotencyLockTTL задаёт время жизни отметки о запросе в обработке на время теста
+func withIdempotencyLockTTL(t *testing.T, d time.Duration) {
+	prev := idempotencyLockTTL
+	idempotencyLockTTL = d
+	t.Cleanup(func() { idempotencyLockTTL = prev })
+}
+
+// newSlowIdempotencyRouter создаёт маршрутизатор, в котором Create перед ответом вызывает during
+func newSlowIdempotencyRouter(store IdempotencyStore, during func()) *mux.Router {
+	ms := &mockService{CreateFn: func(projectID int, name string, description *string) (*model.Good, error) {
+		during()
+		return &model.Good{ID: 1, ProjectID: projectID, Name: name}, nil
+	}}
+	r := mux.NewRouter()
+	r.Use(IdempotencyMiddleware(store, time.Hour))
+	NewHandler(ms, nil).RegisterRoutes(r)
+	return r
+}
+
+// TestIdempotency_LockExtended проверяет, что отметка обработчика, работающего дольше idempotencyLockTTL,
+// продлевается и повтор получает 409, а не выполняется второй раз
+func TestIdempotency_LockExtended(t *testing.T) {
+	withIdempotencyLockTTL(t, 30*time.Millisecond)
+	store := cache.NewMemoryCache()
+	var retry *httptest.ResponseRecorder
+	var r *mux.Router
+	r = newSlowIdempotencyRouter(store, func() {
+		time.Sleep(100 * time.Millisecond)
+		if retry == nil {
+			retry = doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"a"}`, "k")
+		}
+	})
+	rw := doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"a"}`, "k")
+	if rw.Code != http.StatusOK || retry.Code != http.StatusConflict {
+		t.Fatalf("expected 200 and 409 for the retry, got %d and %d", rw.Code, retry.Code)
+	}
+	if replay := doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"a"}`, "k"); replay.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("expected stored response, got %d %v", replay.Code, replay.Header())
+	}
+}
+
+// TestIdempotency_LockLost проверяет, что ответ не перезаписывает ключ, отметку которого занял другой запрос
+func TestIdempotency_LockLost(t *testing.T) {
+	store := cache.NewMemoryCache()
+	storeKey := idempotencyKeyPrefix + "ip:192.0.2.1:k"
+	other, _ := json.Marshal(idempotencyRecord{Fingerprint: "other", Token: "other"})
+	r := newSlowIdempotencyRouter(store, func() {
+		// отметка истекла, и ключ занял другой запрос
+		_ = store.Set(context.Background(), storeKey, other, time.Minute)
+	})
+	if rw := doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"a"}`, "k"); rw.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rw.Code)
+	}
+	if got, _ := store.Get(context.Background(), storeKey); !bytes.Equal(got, other) {
+		t.Errorf("expected record of the other request to be kept, got %s", got)
+	}
+}
+
+// TestIdempotency_HandlerPanic проверяет, что паника обработчика освобождает ключ и останавливает продление отметки
+func TestIdempotency_HandlerPanic(t *testing.T) {
+	withIdempotencyLockTTL(t, 30*time.Millisecond)
+	store := cache.NewMemoryCache()
+	calls := 0
+	r := newSlowIdempotencyRouter(store, func() {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+	})
+	func() {
+		defer func() {
+			if rec := recover(); rec != "boom" {
+				t.Fatalf("expected handler panic, got %v", rec)
+			}
+		}()
+		doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"a"}`, "k")
+	}()
+	if _, err := store.Get(context.Background(), idempotencyKeyPrefix+"ip:192.0.2.1:k"); !errors.Is(err, cache.ErrCacheMiss) {
+		t.Fatalf("expected lock to be released, got %v", err)
+	}
+	if rw := doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"a"}`, "k"); rw.Code != http.StatusOK || calls != 2 {
+		t.Errorf("expected retry to run, got %d, calls %d", rw.Code, calls)
+	}
+}
+
+// TestIdempotency_Bypass проверяет запросы без ключа, чтение с ключом, недопустимый ключ и недоступное хранилище
+func TestIdempotency_Bypass(t *testing.T) {
+	var calls int
+	r := newIdempotencyRouter(cache.NewMemoryCache(), &calls)
+	for i := 0; i < 2; i++ {
+		doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"a"}`, "")
+	}
+	if calls != 2 {
+		t.Errorf("expected requests without key to run each time, got %d calls", calls)
+	}
+	if rw := doIdempotent(r, http.MethodGet, "/good/get?projectId=1&id=1", "", "k"); rw.Code != http.StatusOK || rw.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("expected GET to ignore the key, got %d %v", rw.Code, rw.Header())
+	}
+	rw := doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"a"}`, "bad key")
+	if resp := decodeErrorResponse(t, rw); rw.Code != http.StatusBadRequest || resp.Details.(map[string]string)[IdempotencyKeyHeader] == "" {
+		t.Errorf("expected 400 for invalid key, got %d %+v", rw.Code, resp)
+	}
+
+	r = newIdempotencyRouter(&unavailableStore{}, &calls)
+	if rw := doIdempotent(r, http.MethodPost, "/good/create?projectId=1", `{"name":"a"}`, "k"); rw.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 for unavailable store, got %d", rw.Code)
+	}
+}
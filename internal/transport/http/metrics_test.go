@@ -0,0 +1,35 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestMetricsMiddleware проверяет учёт запросов по шаблону маршрута и статусу
+func TestMetricsMiddleware(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(MetricsMiddleware())
+	r.HandleFunc("/metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}).Methods("GET")
+
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/metrics-test/{id}", "200"))
+	for _, path := range []string{"/metrics-test/1", "/metrics-test/2", "/metrics-test/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/metrics-test/{id}", "200")) - before; got != 2 {
+		t.Errorf("expected 2 successful requests, got %v", got)
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/metrics-test/{id}", "404")); got != 1 {
+		t.Errorf("expected 1 not found request, got %v", got)
+	}
+	if n := testutil.CollectAndCount(httpRequestDuration); n == 0 {
+		t.Error("expected latency histogram series")
+	}
+}
@@ -1,13 +1,23 @@
 package http
 
 import (
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"HezzlTestTask/internal/model"
+
 	"github.com/gorilla/mux"
 )
 
+// RequestIDHeader — заголовок с идентификатором запроса во входящем запросе и в ответе
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength ограничивает длину принятого от клиента идентификатора запроса
+const maxRequestIDLength = 128
+
 // statusResponseWriter обёртка для http.ResponseWriter, чтобы захватывать статус-код
 // и передавать его дальше
 type statusResponseWriter struct {
@@ -21,8 +31,50 @@ func (w *statusResponseWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
-// LoggingMiddleware выводит в стандартный лог информацию о каждом HTTP-запросе и панике
-func LoggingMiddleware(_ interface{}) mux.MiddlewareFunc {
+// RequestIDMiddleware принимает идентификатор запроса из X-Request-ID или генерирует новый,
+// возвращает его в ответе и кладёт в контекст запроса: оттуда он попадает в логи и в события изменений
+// Пустой, слишком длинный или содержащий непечатные символы идентификатор заменяется сгенерированным
+func RequestIDMiddleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(model.ContextWithRequestID(r.Context(), id)))
+		})
+	}
+}
+
+// validRequestID проверяет, что идентификатор не пуст, не длиннее maxRequestIDLength и состоит из видимых ASCII-символов
+func validRequestID(id string) bool {
+	return isVisibleASCII(id, maxRequestIDLength)
+}
+
+// isVisibleASCII проверяет, что строка s не пуста, не длиннее maxLen и состоит из видимых ASCII-символов
+func isVisibleASCII(s string, maxLen int) bool {
+	if s == "" || len(s) > maxLen {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '!' || s[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID генерирует случайный идентификатор запроса из 32 шестнадцатеричных символов
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// LoggingMiddleware пишет в logger запись о каждом HTTP-запросе и панике
+// Ответы 5xx и паники пишутся с уровнем error, остальные — info; request_id берётся из контекста запроса
+func LoggingMiddleware(logger *slog.Logger) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -30,14 +82,27 @@ func LoggingMiddleware(_ interface{}) mux.MiddlewareFunc {
 			// обработка паники
 			defer func() {
 				if rec := recover(); rec != nil {
-					dur := time.Since(start).Milliseconds()
-					log.Printf("PANIC %s %s 500 %dms: %v", r.Method, r.URL.Path, dur, rec)
+					logger.LogAttrs(r.Context(), slog.LevelError, "http request panic",
+						slog.String("method", r.Method),
+						slog.String("path", r.URL.Path),
+						slog.Int("status", http.StatusInternalServerError),
+						slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+						slog.Any("panic", rec),
+					)
 					panic(rec)
 				}
 			}()
 			next.ServeHTTP(srw, r)
-			dur := time.Since(start).Milliseconds()
-			log.Printf("%s %s %d %dms", r.Method, r.URL.Path, srw.status, dur)
+			level := slog.LevelInfo
+			if srw.status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(r.Context(), level, "http request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", srw.status),
+				slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+			)
 		})
 	}
 }
@@ -2,23 +2,34 @@ package http
 
 import (
 	"bytes"
-	"log"
+	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"HezzlTestTask/internal/model"
 )
 
-// TestLoggingMiddleware_Success проверяет, что middleware логирует запрос без паники
+// decodeLog разбирает единственную JSON-запись лога из буфера
+func decodeLog(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	var rec map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("ожидалась одна JSON-запись, получили %q: %v", buf.String(), err)
+	}
+	return rec
+}
+
+// TestLoggingMiddleware_Success проверяет структурированную запись о запросе
 func TestLoggingMiddleware_Success(t *testing.T) {
-	// Перенаправляем вывод логов в буфер
+	// Пишем логи в буфер
 	var buf bytes.Buffer
-	orig := log.Writer()
-	log.SetOutput(&buf)
-	defer log.SetOutput(orig)
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
 
 	// Простая цель-обработчик, возвращает 201 и тело
-	handler := LoggingMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := LoggingMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusCreated)
 		_, _ = w.Write([]byte("ok"))
 	}))
@@ -35,24 +46,29 @@ func TestLoggingMiddleware_Success(t *testing.T) {
 		t.Fatalf("ожидалось тело 'ok', получили '%s'", got)
 	}
 
-	// Проверяем, что в логах есть метод, путь и статус
-	out := buf.String()
-	if !strings.Contains(out, "PUT /test-path") {
-		t.Errorf("ожидалось упоминание метода и пути, получили: %s", out)
+	// Проверяем, что в записи есть метод, путь, статус и уровень
+	rec := decodeLog(t, &buf)
+	if rec["method"] != "PUT" || rec["path"] != "/test-path" || rec["status"] != float64(201) || rec["level"] != "INFO" {
+		t.Errorf("неожиданная запись лога: %v", rec)
 	}
-	if !strings.Contains(out, "201") {
-		t.Errorf("ожидалось упоминание статуса 201, получили: %s", out)
+}
+
+// TestLoggingMiddleware_ServerError проверяет уровень error для ответов 5xx
+func TestLoggingMiddleware_ServerError(t *testing.T) {
+	var buf bytes.Buffer
+	h := LoggingMiddleware(slog.New(slog.NewJSONHandler(&buf, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if rec := decodeLog(t, &buf); rec["level"] != "ERROR" || rec["status"] != float64(502) {
+		t.Errorf("неожиданная запись лога: %v", rec)
 	}
 }
 
 // TestLoggingMiddleware_Panic проверяет, что middleware логирует панику и пробрасывает её дальше
 func TestLoggingMiddleware_Panic(t *testing.T) {
 	var buf bytes.Buffer
-	orig := log.Writer()
-	log.SetOutput(&buf)
-	defer log.SetOutput(orig)
-
-	h := LoggingMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := LoggingMiddleware(slog.New(slog.NewJSONHandler(&buf, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		panic("boom error")
 	}))
 
@@ -64,12 +80,42 @@ func TestLoggingMiddleware_Panic(t *testing.T) {
 		if rec := recover(); rec == nil {
 			t.Fatalf("ожидалась паника, но её не было")
 		}
-		out := buf.String()
-		// Должна быть строка с "PANIC" и путь
-		if !strings.Contains(out, "PANIC GET /panic") {
-			t.Errorf("ожидалось логирование паники, получили: %s", out)
+		// Должна быть запись об ошибке с путём и значением паники
+		rec := decodeLog(t, &buf)
+		if rec["level"] != "ERROR" || rec["path"] != "/panic" || rec["panic"] != "boom error" {
+			t.Errorf("ожидалось логирование паники, получили: %v", rec)
 		}
 	}()
 
 	h.ServeHTTP(rw, req)
 }
+
+// TestRequestIDMiddleware проверяет приём, генерацию и возврат X-Request-ID
+func TestRequestIDMiddleware(t *testing.T) {
+	var got string
+	h := RequestIDMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = model.RequestIDFromContext(r.Context())
+	}))
+
+	// идентификатор клиента передаётся в контекст и возвращается в ответе
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "client-req-1")
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	if got != "client-req-1" || rw.Header().Get(RequestIDHeader) != "client-req-1" {
+		t.Errorf("expected client request id, got ctx=%q header=%q", got, rw.Header().Get(RequestIDHeader))
+	}
+
+	// без заголовка и при недопустимом значении генерируется новый идентификатор
+	for _, header := range []string{"", "bad id", strings.Repeat("x", maxRequestIDLength+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(RequestIDHeader, header)
+		}
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, req)
+		if len(got) != 32 || got == header || rw.Header().Get(RequestIDHeader) != got {
+			t.Errorf("header %q: expected generated request id, got ctx=%q header=%q", header, got, rw.Header().Get(RequestIDHeader))
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"HezzlTestTask/internal/auth"
+	"HezzlTestTask/internal/model"
+)
+
+// ProjectsService задаёт интерфейс бизнес-логики проектов для HTTP-слоя
+type ProjectsService interface {
+	Create(ctx context.Context, name string) (*model.Project, error)
+	Get(ctx context.Context, id int) (*model.Project, error)
+	Update(ctx context.Context, id int, name string) (*model.Project, error)
+	Remove(ctx context.Context, id int) error
+	List(ctx context.Context, limit, offset int) ([]model.Project, int, int, error)
+}
+
+// CreateProject обрабатывает POST /project/create
+// 1. Декодирует и проверяет тело запроса с полем name (projectRequest)
+// 2. Вызывает метод сервиса Create
+// 3. Возвращает JSON созданного проекта
+func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	var v validator
+	var req projectRequest
+	v.body(w, r, &req)
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	if err := authorize(r, auth.AllProjects, auth.RoleAdmin); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	project, err := h.projects.Create(r.Context(), req.Name)
+	if err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(project)
+}
+
+// GetProject обрабатывает GET /project/get?id={id}
+func (h *Handler) GetProject(w http.ResponseWriter, r *http.Request) {
+	var v validator
+	id := v.queryID(r.URL.Query(), "id")
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	if err := authorize(r, id, auth.RoleRead); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	project, err := h.projects.Get(r.Context(), id)
+	if err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(project)
+}
+
+// UpdateProject обрабатывает PATCH /project/update?id={id}
+// Тело запроса содержит новое имя проекта
+func (h *Handler) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	var v validator
+	id := v.queryID(r.URL.Query(), "id")
+	var req projectRequest
+	v.body(w, r, &req)
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	if err := authorize(r, id, auth.RoleAdmin); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	project, err := h.projects.Update(r.Context(), id, req.Name)
+	if err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(project)
+}
+
+// RemoveProject обрабатывает DELETE /project/remove?id={id}
+// Проект архивируется (removed=true), ответ {id, removed: true}
+func (h *Handler) RemoveProject(w http.ResponseWriter, r *http.Request) {
+	var v validator
+	id := v.queryID(r.URL.Query(), "id")
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	if err := authorize(r, id, auth.RoleAdmin); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	if err := h.projects.Remove(r.Context(), id); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "removed": true})
+}
+
+// ListProjects обрабатывает GET /projects/list
+// Параметры limit (от 1 до maxListLimit, по умолчанию 10) и offset (неотрицательный, по умолчанию 0),
+// ответ содержит meta и массив projects
+func (h *Handler) ListProjects(w http.ResponseWriter, r *http.Request) {
+	var v validator
+	q := r.URL.Query()
+	limit := v.queryInt(q, "limit", defaultListLimit, 1, maxListLimit)
+	offset := v.queryInt(q, "offset", 0, 0, 0)
+	if err := v.err(); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	if err := authorize(r, auth.AllProjects, auth.RoleRead); err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	projects, total, removed, err := h.projects.List(r.Context(), limit, offset)
+	if err != nil {
+		writeDomainError(w, r, err)
+		return
+	}
+	var resp struct {
+		Meta struct {
+			Total   int `json:"total"`
+			Removed int `json:"removed"`
+			Limit   int `json:"limit"`
+			Offset  int `json:"offset"`
+		} `json:"meta"`
+		Projects []model.Project `json:"projects"`
+	}
+	resp.Meta.Total = total
+	resp.Meta.Removed = removed
+	resp.Meta.Limit = limit
+	resp.Meta.Offset = offset
+	resp.Projects = projects
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,170 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"HezzlTestTask/internal/model"
+	"HezzlTestTask/internal/repository"
+)
+
+// mockProjectsService реализует ProjectsService для тестирования HTTP-хендлеров проектов
+type mockProjectsService struct {
+	CreateFn func(name string) (*model.Project, error)
+	GetFn    func(id int) (*model.Project, error)
+	UpdateFn func(id int, name string) (*model.Project, error)
+	RemoveFn func(id int) error
+	ListFn   func(limit, offset int) ([]model.Project, int, int, error)
+}
+
+func (m *mockProjectsService) Create(_ context.Context, name string) (*model.Project, error) {
+	return m.CreateFn(name)
+}
+func (m *mockProjectsService) Get(_ context.Context, id int) (*model.Project, error) {
+	return m.GetFn(id)
+}
+func (m *mockProjectsService) Update(_ context.Context, id int, name string) (*model.Project, error) {
+	return m.UpdateFn(id, name)
+}
+func (m *mockProjectsService) Remove(_ context.Context, id int) error {
+	return m.RemoveFn(id)
+}
+func (m *mockProjectsService) List(_ context.Context, limit, offset int) ([]model.Project, int, int, error) {
+	return m.ListFn(limit, offset)
+}
+
+// newProjectsRouter создаёт роутер с зарегистрированными маршрутами и заданным сервисом проектов
+func newProjectsRouter(ps ProjectsService) *mux.Router {
+	h := NewHandler(&mockService{}, ps)
+	r := mux.NewRouter()
+	h.RegisterRoutes(r)
+	return r
+}
+
+// TestCreateProject_Success проверяет создание проекта через POST /project/create
+func TestCreateProject_Success(t *testing.T) {
+	ps := &mockProjectsService{CreateFn: func(name string) (*model.Project, error) {
+		if name != "catalog" {
+			t.Fatalf("unexpected name %s", name)
+		}
+		return &model.Project{ID: 2, Name: name}, nil
+	}}
+	req := httptest.NewRequest(http.MethodPost, "/project/create", bytes.NewBufferString(`{"name":"catalog"}`))
+	rq := httptest.NewRecorder()
+	newProjectsRouter(ps).ServeHTTP(rq, req)
+	if rq.Code != http.StatusOK {
+		t.Fatalf("status = %d", rq.Code)
+	}
+	var got model.Project
+	_ = json.Unmarshal(rq.Body.Bytes(), &got)
+	if got.ID != 2 || got.Name != "catalog" {
+		t.Fatalf("unexpected project %+v", got)
+	}
+}
+
+// TestCreateProject_InvalidJSON проверяет возврат 400 при некорректном теле запроса
+func TestCreateProject_InvalidJSON(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/project/create", bytes.NewBufferString(`bad`))
+	rq := httptest.NewRecorder()
+	newProjectsRouter(&mockProjectsService{}).ServeHTTP(rq, req)
+	if rq.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rq.Code)
+	}
+}
+
+// TestGetProject_NotFound проверяет возврат 404 для несуществующего проекта
+func TestGetProject_NotFound(t *testing.T) {
+	ps := &mockProjectsService{GetFn: func(id int) (*model.Project, error) { return nil, repository.ErrNotFound }}
+	req := httptest.NewRequest(http.MethodGet, "/project/get?id=5", nil)
+	rq := httptest.NewRecorder()
+	newProjectsRouter(ps).ServeHTTP(rq, req)
+	if rq.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rq.Code)
+	}
+}
+
+// TestGetProject_InvalidID проверяет возврат 400 при некорректном id
+func TestGetProject_InvalidID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/project/get?id=x", nil)
+	rq := httptest.NewRecorder()
+	newProjectsRouter(&mockProjectsService{}).ServeHTTP(rq, req)
+	if rq.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rq.Code)
+	}
+}
+
+// TestUpdateProject_Success проверяет переименование проекта через PATCH /project/update
+func TestUpdateProject_Success(t *testing.T) {
+	ps := &mockProjectsService{UpdateFn: func(id int, name string) (*model.Project, error) {
+		if id != 3 || name != "renamed" {
+			t.Fatalf("unexpected args %d %s", id, name)
+		}
+		return &model.Project{ID: id, Name: name}, nil
+	}}
+	req := httptest.NewRequest(http.MethodPatch, "/project/update?id=3", bytes.NewBufferString(`{"name":"renamed"}`))
+	rq := httptest.NewRecorder()
+	newProjectsRouter(ps).ServeHTTP(rq, req)
+	if rq.Code != http.StatusOK {
+		t.Fatalf("status = %d", rq.Code)
+	}
+}
+
+// TestRemoveProject_Success проверяет архивирование проекта через DELETE /project/remove
+func TestRemoveProject_Success(t *testing.T) {
+	ps := &mockProjectsService{RemoveFn: func(id int) error { return nil }}
+	req := httptest.NewRequest(http.MethodDelete, "/project/remove?id=4", nil)
+	rq := httptest.NewRecorder()
+	newProjectsRouter(ps).ServeHTTP(rq, req)
+	if rq.Code != http.StatusOK {
+		t.Fatalf("status = %d", rq.Code)
+	}
+	var resp map[string]interface{}
+	_ = json.Unmarshal(rq.Body.Bytes(), &resp)
+	if resp["removed"] != true {
+		t.Fatal("removed flag")
+	}
+}
+
+// TestRemoveProject_ServiceError проверяет возврат 500 при ошибке сервиса
+func TestRemoveProject_ServiceError(t *testing.T) {
+	ps := &mockProjectsService{RemoveFn: func(id int) error { return errors.New("remove fail") }}
+	req := httptest.NewRequest(http.MethodDelete, "/project/remove?id=4", nil)
+	rq := httptest.NewRecorder()
+	newProjectsRouter(ps).ServeHTTP(rq, req)
+	if rq.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rq.Code)
+	}
+}
+
+// TestListProjects_Success проверяет возврат страницы проектов и метаданных
+func TestListProjects_Success(t *testing.T) {
+	ps := &mockProjectsService{ListFn: func(limit, offset int) ([]model.Project, int, int, error) {
+		return []model.Project{{ID: 1, Name: "a"}}, 3, 1, nil
+	}}
+	req := httptest.NewRequest(http.MethodGet, "/projects/list?limit=5&offset=2", nil)
+	rq := httptest.NewRecorder()
+	newProjectsRouter(ps).ServeHTTP(rq, req)
+	if rq.Code != http.StatusOK {
+		t.Fatalf("status = %d", rq.Code)
+	}
+	var out struct {
+		Meta struct {
+			Total   int
+			Removed int
+			Limit   int
+			Offset  int
+		}
+		Projects []model.Project
+	}
+	_ = json.Unmarshal(rq.Body.Bytes(), &out)
+	if out.Meta.Total != 3 || out.Meta.Removed != 1 || out.Meta.Limit != 5 || out.Meta.Offset != 2 || len(out.Projects) != 1 {
+		t.Fatalf("unexpected response %+v", out)
+	}
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"HezzlTestTask/internal/apperr"
+	"HezzlTestTask/internal/auth"
+	"HezzlTestTask/pkg/ratelimit"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Заголовки ответа с состоянием лимита запросов
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+)
+
+// RateLimiter проверяет очередной запрос клиента key против лимита (ratelimit.RedisLimiter, ratelimit.MemoryLimiter)
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error)
+}
+
+// rateLimitExempt — маршруты проверок здоровья и метрик, которые не ограничиваются
+var rateLimitExempt = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// httpRateLimited считает отклонённые по лимиту запросы
+var httpRateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_rate_limited_total",
+	Help: "Number of HTTP requests rejected by the rate limiter by route.",
+}, []string{"route"})
+
+// RateLimitMiddleware ограничивает частоту запросов клиента к маршруту лимитом из rules
+// Клиент — идентификатор из AuthMiddleware, а без аутентификации — IP-адрес, поэтому middleware
+// подключается после AuthMiddleware; у каждого маршрута своя квота
+// Ответ содержит заголовки X-RateLimit-*, превышение лимита отклоняется с 429 и Retry-After
+// Если хранилище лимитов недоступно, запрос пропускается: ограничение не должно останавливать API
+func RateLimitMiddleware(l RateLimiter, rules ratelimit.Rules) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := ""
+			if cur := mux.CurrentRoute(r); cur != nil {
+				route, _ = cur.GetPathTemplate()
+			}
+			limit, ok := rules.For(route)
+			if !ok || rateLimitExempt[route] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			res, err := l.Allow(r.Context(), route+":"+requestClient(r), limit)
+			if err != nil {
+				slog.WarnContext(r.Context(), "rate limit check failed", slog.String("route", route), slog.Any("error", err))
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Set(RateLimitLimitHeader, strconv.Itoa(limit.Requests))
+			h.Set(RateLimitRemainingHeader, strconv.Itoa(res.Remaining))
+			h.Set(RateLimitResetHeader, strconv.Itoa(ceilSeconds(res.Reset)))
+			if !res.Allowed {
+				httpRateLimited.WithLabelValues(route).Inc()
+				h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				writeDomainError(w, r, apperr.RateLimited("rate limit of "+limit.String()+" exceeded"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestClient возвращает ключ клиента: "client:<sub>" для аутентифицированного запроса, иначе "ip:<адрес>"
+// Используется лимитами запросов и ключами идемпотентности
+func requestClient(r *http.Request) string {
+	if p, _ := auth.PrincipalFromContext(r.Context()); p != nil {
+		return "client:" + p.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// ceilSeconds округляет длительность вверх до целых секунд, как требуют Retry-After и X-RateLimit-Reset
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
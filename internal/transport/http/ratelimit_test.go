@@ -0,0 +1,111 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"HezzlTestTask/internal/model"
+	"HezzlTestTask/pkg/ratelimit"
+)
+
+// failingLimiter имитирует недоступное хранилище лимитов
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("redis: connection refused")
+}
+
+// newRateLimitRouter создаёт маршрутизатор с RateLimitMiddleware и лимитами из rules
+func newRateLimitRouter(t *testing.T, l RateLimiter, rules string) *mux.Router {
+	t.Helper()
+	parsed, err := ratelimit.ParseRules(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := &mockService{
+		GetFn:  func(projectID, id int) (*model.Good, error) { return &model.Good{ID: id, ProjectID: projectID}, nil },
+		ListFn: func(filter model.GoodsFilter) ([]model.Good, int, int, error) { return nil, 0, 0, nil },
+	}
+	r := mux.NewRouter()
+	r.Use(RateLimitMiddleware(l, parsed))
+	NewHandler(ms, nil).RegisterRoutes(r)
+	return r
+}
+
+// doFrom выполняет GET-запрос target с адреса клиента remoteAddr
+func doFrom(r http.Handler, target, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = remoteAddr
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	return rw
+}
+
+// TestRateLimit_PerRouteAndClient проверяет заголовки, 429 с Retry-After и раздельные квоты маршрутов и клиентов
+func TestRateLimit_PerRouteAndClient(t *testing.T) {
+	r := newRateLimitRouter(t, ratelimit.NewMemoryLimiter(), "default=100/1m,/goods/list=2/1m")
+
+	for want := []string{"1", "0"}; len(want) > 0; want = want[1:] {
+		rw := doFrom(r, "/goods/list", "10.0.0.1:5000")
+		if rw.Code != http.StatusOK || rw.Header().Get(RateLimitLimitHeader) != "2" || rw.Header().Get(RateLimitRemainingHeader) != want[0] {
+			t.Fatalf("expected 200 with %s remaining, got %d %v", want[0], rw.Code, rw.Header())
+		}
+	}
+	rw := doFrom(r, "/goods/list", "10.0.0.1:5001")
+	if rw.Code != http.StatusTooManyRequests || rw.Header().Get("Retry-After") != "30" || rw.Header().Get(RateLimitResetHeader) != "60" {
+		t.Fatalf("expected 429 with Retry-After 30, got %d %v", rw.Code, rw.Header())
+	}
+	if resp := decodeErrorResponse(t, rw); resp.Code != CodeRateLimited {
+		t.Errorf("unexpected error response %+v", resp)
+	}
+
+	// другой маршрут и другой клиент ограничиваются отдельно
+	if rw := doFrom(r, "/good/get?projectId=1&id=1", "10.0.0.1:5000"); rw.Code != http.StatusOK || rw.Header().Get(RateLimitLimitHeader) != "100" {
+		t.Errorf("expected default limit on /good/get, got %d %v", rw.Code, rw.Header())
+	}
+	if rw := doFrom(r, "/goods/list", "10.0.0.2:5000"); rw.Code != http.StatusOK {
+		t.Errorf("expected other client allowed, got %d", rw.Code)
+	}
+	// проверки здоровья не ограничиваются
+	for i := 0; i < 3; i++ {
+		if rw := doFrom(r, "/healthz", "10.0.0.1:5000"); rw.Code != http.StatusOK || rw.Header().Get(RateLimitLimitHeader) != "" {
+			t.Fatalf("expected /healthz without limit, got %d %v", rw.Code, rw.Header())
+		}
+	}
+}
+
+// TestRateLimit_FailOpen проверяет, что при недоступном хранилище лимитов запрос выполняется
+func TestRateLimit_FailOpen(t *testing.T) {
+	r := newRateLimitRouter(t, failingLimiter{}, "default=1/1m")
+	for i := 0; i < 2; i++ {
+		if rw := doFrom(r, "/goods/list", "10.0.0.1:5000"); rw.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rw.Code)
+		}
+	}
+}
+
+// TestRequestClient проверяет ключ клиента по идентификатору аутентификации и по IP
+func TestRequestClient(t *testing.T) {
+	a := newAuthRouter(t, &mockService{}, nil)
+	var key string
+	a.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			key = requestClient(req)
+			next.ServeHTTP(w, req)
+		})
+	})
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set(APIKeyHeader, "reader")
+	a.ServeHTTP(httptest.NewRecorder(), req)
+	if key != "client:reader" {
+		t.Errorf("expected client key, got %q", key)
+	}
+	if got := requestClient(httptest.NewRequest(http.MethodGet, "/", nil)); got != "ip:192.0.2.1" {
+		t.Errorf("expected ip key, got %q", got)
+	}
+}
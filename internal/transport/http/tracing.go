@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+
+	"HezzlTestTask/pkg/tracing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware начинает серверный спан на каждый запрос, продолжая трассировку из заголовка traceparent
+// Спан называется по методу и шаблону маршрута gorilla/mux; ответы 5xx отмечаются ошибкой
+func TracingMiddleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := r.URL.Path
+			if cur := mux.CurrentRoute(r); cur != nil {
+				if tpl, err := cur.GetPathTemplate(); err == nil {
+					route = tpl
+				}
+			}
+			ctx := tracing.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracing.Start(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			srw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				span.SetAttributes(semconv.HTTPResponseStatusCode(srw.status))
+				var err error
+				if srw.status >= http.StatusInternalServerError {
+					err = errorStatus(srw.status)
+				}
+				tracing.End(span, err)
+			}()
+			next.ServeHTTP(srw, r.WithContext(ctx))
+		})
+	}
+}
+
+// errorStatus — ошибка спана для ответа с кодом 5xx
+type errorStatus int
+
+func (s errorStatus) Error() string {
+	return http.StatusText(int(s))
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestTracingMiddleware проверяет имя серверного спана, продолжение входящей трассировки и отметку 5xx
+func TestTracingMiddleware(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	r := mux.NewRouter()
+	r.Use(TracingMiddleware())
+	var handlerSpan trace.SpanContext
+	r.HandleFunc("/tracing-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		if mux.Vars(r)["id"] == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}).Methods("GET")
+
+	req := httptest.NewRequest(http.MethodGet, "/tracing-test/1", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/tracing-test/fail", nil))
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	ok := spans[0]
+	if ok.Name() != "GET /tracing-test/{id}" || ok.SpanKind() != trace.SpanKindServer {
+		t.Errorf("unexpected span %s (%v)", ok.Name(), ok.SpanKind())
+	}
+	if ok.SpanContext().TraceID().String() != "0af7651916cd43dd8448eb211c80319c" || ok.Parent().SpanID().String() != "b7ad6b7169203331" {
+		t.Errorf("span must continue incoming trace, got %s parent %s", ok.SpanContext().TraceID(), ok.Parent().SpanID())
+	}
+	if ok.Status().Code == codes.Error {
+		t.Errorf("successful request must not be marked as error")
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("expected error status for 500, got %+v", spans[1].Status())
+	}
+	// обработчик получает контекст со спаном запроса
+	if handlerSpan.SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("handler context must contain request span")
+	}
+}
@@ -0,0 +1,282 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"HezzlTestTask/internal/apperr"
+)
+
+// Ограничения входных данных API
+const (
+	maxBodyBytes         = 1 << 20 // максимальный размер тела запроса
+	maxNameLength        = 255     // максимальная длина имени товара или проекта в символах
+	maxDescriptionLength = 4096    // максимальная длина описания товара в символах
+	defaultListLimit     = 10      // размер страницы списков товаров и проектов по умолчанию
+	maxListLimit         = 500     // максимальный размер страницы списков товаров и проектов
+	maxBulkItems         = 1000    // максимальное число элементов пакетного запроса
+)
+
+// requestBody — тело запроса, которое после декодирования нормализует и проверяет свои поля
+type requestBody interface {
+	validate(v *validator)
+}
+
+// validator накапливает нарушения правил по полям запроса, чтобы вернуть клиенту все нарушения сразу
+// Для каждого поля сохраняется первое нарушение
+type validator struct {
+	fields map[string]string
+}
+
+// add записывает нарушение reason для поля field, если у поля ещё нет нарушений
+func (v *validator) add(field, reason string) {
+	if v.fields == nil {
+		v.fields = map[string]string{}
+	}
+	if _, ok := v.fields[field]; !ok {
+		v.fields[field] = reason
+	}
+}
+
+// merge добавляет нарушения полей из ошибки валидации err
+func (v *validator) merge(err error) {
+	for field, reason := range apperr.FieldsOf(err) {
+		v.add(field, reason)
+	}
+}
+
+// err возвращает ошибку валидации со всеми нарушениями или nil
+// Сообщение перечисляет поля с нарушениями, например "invalid name, projectId"
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(v.fields))
+	for field := range v.fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	return apperr.Validation("invalid "+strings.Join(names, ", "), v.fields)
+}
+
+// body строго декодирует JSON-тело запроса в dst и проверяет его правила
+// Тело ограничено maxBodyBytes, неизвестные поля и данные после JSON-объекта считаются ошибкой
+func (v *validator) body(w http.ResponseWriter, r *http.Request, dst requestBody) {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			v.add("body", fmt.Sprintf("must not exceed %d bytes", maxBodyBytes))
+		} else {
+			v.add("body", err.Error())
+		}
+		return
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		v.add("body", "must contain a single JSON object")
+		return
+	}
+	dst.validate(v)
+}
+
+// text обрезает пробелы по краям *s и проверяет обязательность и длину в символах
+func (v *validator) text(field string, s *string, required bool, maxLen int) {
+	*s = strings.TrimSpace(*s)
+	if required && *s == "" {
+		v.add(field, "must not be empty")
+		return
+	}
+	if utf8.RuneCountInString(*s) > maxLen {
+		v.add(field, fmt.Sprintf("must be at most %d characters", maxLen))
+	}
+}
+
+// required проверяет, что поле присутствует в теле запроса
+func (v *validator) required(field string, present bool) bool {
+	if !present {
+		v.add(field, "is required")
+	}
+	return present
+}
+
+// min проверяет, что value не меньше lo
+func (v *validator) min(field string, value, lo int) {
+	if value < lo {
+		v.add(field, fmt.Sprintf("must be at least %d", lo))
+	}
+}
+
+// queryID читает обязательный query-параметр name — положительное целое число
+func (v *validator) queryID(q url.Values, name string) int {
+	id, err := strconv.Atoi(q.Get(name))
+	if err != nil || id <= 0 {
+		v.add(name, "must be a positive integer")
+		return 0
+	}
+	return id
+}
+
+// queryInt читает необязательный целочисленный query-параметр name в диапазоне [lo, hi]
+// При отсутствии параметра возвращается def; hi <= 0 означает отсутствие верхней границы
+func (v *validator) queryInt(q url.Values, name string, def, lo, hi int) int {
+	s := q.Get(name)
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	switch {
+	case err != nil:
+		v.add(name, "must be an integer")
+	case hi > 0 && (n < lo || n > hi):
+		v.add(name, fmt.Sprintf("must be between %d and %d", lo, hi))
+	case n < lo:
+		v.add(name, fmt.Sprintf("must be at least %d", lo))
+	default:
+		return n
+	}
+	return def
+}
+
+// goodRequest — тело запросов создания и изменения товара
+type goodRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+}
+
+func (req *goodRequest) validate(v *validator) {
+	v.text("name", &req.Name, true, maxNameLength)
+	if req.Description != nil {
+		v.text("description", req.Description, false, maxDescriptionLength)
+	}
+}
+
+// reprioritizeRequest — тело запроса изменения приоритета товара
+type reprioritizeRequest struct {
+	NewPriority *int `json:"newPriority"`
+}
+
+func (req *reprioritizeRequest) validate(v *validator) {
+	if v.required("newPriority", req.NewPriority != nil) {
+		v.min("newPriority", *req.NewPriority, 1)
+	}
+}
+
+// projectRequest — тело запросов создания и переименования проекта
+type projectRequest struct {
+	Name string `json:"name"`
+}
+
+func (req *projectRequest) validate(v *validator) {
+	v.text("name", &req.Name, true, maxNameLength)
+}
+
+// Режимы пакетных запросов в поле mode
+const (
+	bulkAtomic     = "atomic"     // все элементы применяются вместе или не применяется ни один (по умолчанию)
+	bulkBestEffort = "bestEffort" // применяются элементы без ошибок
+)
+
+// bulkRequest — общие поля тел пакетных запросов; itemErrs — ошибки проверки элементов в режиме bestEffort
+type bulkRequest struct {
+	Mode     string `json:"mode"`
+	itemErrs []error
+}
+
+// atomic сообщает, что элементы применяются по принципу «всё или ничего»
+func (req *bulkRequest) atomic() bool {
+	return req.Mode != bulkBestEffort
+}
+
+// validateItems проверяет режим, число элементов n и правила каждого элемента функцией item
+// В атомарном режиме нарушения элементов добавляются в v с префиксом items[i] и отклоняют весь запрос,
+// в режиме bestEffort они сохраняются в itemErrs как ошибки отдельных элементов
+func (req *bulkRequest) validateItems(v *validator, n int, item func(i int, iv *validator)) {
+	switch req.Mode {
+	case "":
+		req.Mode = bulkAtomic
+	case bulkAtomic, bulkBestEffort:
+	default:
+		v.add("mode", "must be one of atomic, bestEffort")
+	}
+	if n == 0 || n > maxBulkItems {
+		v.add("items", fmt.Sprintf("must contain 1 to %d items", maxBulkItems))
+		return
+	}
+	req.itemErrs = make([]error, n)
+	for i := 0; i < n; i++ {
+		var iv validator
+		item(i, &iv)
+		if !req.atomic() {
+			req.itemErrs[i] = iv.err()
+			continue
+		}
+		for field, reason := range iv.fields {
+			v.add(fmt.Sprintf("items[%d].%s", i, field), reason)
+		}
+	}
+}
+
+// bulkCreateRequest — тело запроса пакетного создания товаров
+type bulkCreateRequest struct {
+	bulkRequest
+	Items []goodRequest `json:"items"`
+}
+
+func (req *bulkCreateRequest) validate(v *validator) {
+	req.validateItems(v, len(req.Items), func(i int, iv *validator) { req.Items[i].validate(iv) })
+}
+
+// bulkUpdateItem — элемент пакетного обновления: товар, новые name и description и ожидаемая версия
+type bulkUpdateItem struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	Version     int     `json:"version"`
+}
+
+func (item *bulkUpdateItem) validate(v *validator) {
+	v.min("id", item.ID, 1)
+	v.text("name", &item.Name, true, maxNameLength)
+	if item.Description != nil {
+		v.text("description", item.Description, false, maxDescriptionLength)
+	}
+	v.min("version", item.Version, 0)
+}
+
+// bulkUpdateRequest — тело запроса пакетного обновления товаров
+type bulkUpdateRequest struct {
+	bulkRequest
+	Items []bulkUpdateItem `json:"items"`
+}
+
+func (req *bulkUpdateRequest) validate(v *validator) {
+	req.validateItems(v, len(req.Items), func(i int, iv *validator) { req.Items[i].validate(iv) })
+}
+
+// bulkRemoveItem — элемент пакетного удаления: товар и ожидаемая версия
+type bulkRemoveItem struct {
+	ID      int `json:"id"`
+	Version int `json:"version"`
+}
+
+// bulkRemoveRequest — тело запроса пакетного удаления товаров
+type bulkRemoveRequest struct {
+	bulkRequest
+	Items []bulkRemoveItem `json:"items"`
+}
+
+func (req *bulkRemoveRequest) validate(v *validator) {
+	req.validateItems(v, len(req.Items), func(i int, iv *validator) {
+		iv.min("id", req.Items[i].ID, 1)
+		iv.min("version", req.Items[i].Version, 0)
+	})
+}
@@ -0,0 +1,120 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"HezzlTestTask/internal/model"
+)
+
+// serveGoods выполняет запрос к маршрутам Handler с сервисом товаров ms и сервисом проектов ps
+func serveGoods(ms *mockService, ps ProjectsService, method, target, body string) *httptest.ResponseRecorder {
+	r := mux.NewRouter()
+	NewHandler(ms, ps).RegisterRoutes(r)
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, httptest.NewRequest(method, target, bytes.NewBufferString(body)))
+	return rw
+}
+
+// TestCreate_AllViolations проверяет, что нарушения query и тела возвращаются в details все сразу
+func TestCreate_AllViolations(t *testing.T) {
+	long := strings.Repeat("я", maxDescriptionLength+1)
+	rw := serveGoods(&mockService{}, nil, http.MethodPost, "/good/create?projectId=0",
+		`{"name":"   ","description":"`+long+`"}`)
+	resp := decodeErrorResponse(t, rw)
+	want := map[string]string{
+		"projectId":   "must be a positive integer",
+		"name":        "must not be empty",
+		"description": "must be at most 4096 characters",
+	}
+	if rw.Code != http.StatusBadRequest || resp.Message != "invalid description, name, projectId" || !reflect.DeepEqual(resp.Details, want) {
+		t.Errorf("unexpected response %d %+v", rw.Code, resp)
+	}
+}
+
+// TestCreate_TrimsName проверяет, что сервис получает имя и описание без пробелов по краям
+func TestCreate_TrimsName(t *testing.T) {
+	var gotName, gotDesc string
+	ms := &mockService{CreateFn: func(projectID int, name string, description *string) (*model.Good, error) {
+		gotName, gotDesc = name, *description
+		return &model.Good{ID: 1, ProjectID: projectID, Name: name}, nil
+	}}
+	rw := serveGoods(ms, nil, http.MethodPost, "/good/create?projectId=1", `{"name":"  phone ","description":" d "}`)
+	if rw.Code != http.StatusOK || gotName != "phone" || gotDesc != "d" {
+		t.Errorf("expected trimmed values, got %d %q %q", rw.Code, gotName, gotDesc)
+	}
+}
+
+// TestBody_Strict проверяет отказ на неизвестные поля, данные после объекта и превышение размера тела
+func TestBody_Strict(t *testing.T) {
+	bodies := map[string]string{
+		"unknown field": `{"name":"n","price":1}`,
+		"trailing data": `{"name":"n"}{"name":"m"}`,
+		"too large":     `{"name":"` + strings.Repeat("x", maxBodyBytes) + `"}`,
+	}
+	for name, body := range bodies {
+		rw := serveGoods(&mockService{}, nil, http.MethodPost, "/good/create?projectId=1", body)
+		resp := decodeErrorResponse(t, rw)
+		if details := resp.Details.(map[string]string); rw.Code != http.StatusBadRequest || details["body"] == "" {
+			t.Errorf("%s: expected body violation, got %d %+v", name, rw.Code, resp)
+		}
+	}
+}
+
+// TestReprioritize_Validation проверяет обязательность и нижнюю границу newPriority
+func TestReprioritize_Validation(t *testing.T) {
+	for body, reason := range map[string]string{
+		`{}`:                  "is required",
+		`{"newPriority":0}`:   "must be at least 1",
+		`{"newPriority":-5}`:  "must be at least 1",
+		`{"newPriority":"1"}`: "",
+	} {
+		rw := serveGoods(&mockService{}, nil, http.MethodPatch, "/good/reprioritize?projectId=1&id=1", body)
+		resp := decodeErrorResponse(t, rw)
+		details := resp.Details.(map[string]string)
+		if rw.Code != http.StatusBadRequest || (reason != "" && details["newPriority"] != reason) || (reason == "" && details["body"] == "") {
+			t.Errorf("%s: unexpected response %d %+v", body, rw.Code, resp)
+		}
+	}
+}
+
+// TestList_QueryRanges проверяет границы limit и offset списков товаров и проектов
+func TestList_QueryRanges(t *testing.T) {
+	cases := map[string]string{
+		"/goods/list?limit=0":                                      "limit",
+		"/goods/list?limit=501":                                    "limit",
+		"/goods/list?limit=abc":                                    "limit",
+		"/goods/list?offset=-1":                                    "offset",
+		"/goods/list?paging=pages":                                 "paging",
+		"/goods/list?name=" + strings.Repeat("n", maxNameLength+1): "name",
+		"/projects/list?limit=1000":                                "limit",
+		"/projects/list?offset=-3":                                 "offset",
+	}
+	for target, field := range cases {
+		rw := serveGoods(&mockService{}, &mockProjectsService{}, http.MethodGet, target, "")
+		resp := decodeErrorResponse(t, rw)
+		if details := resp.Details.(map[string]string); rw.Code != http.StatusBadRequest || details[field] == "" {
+			t.Errorf("%s: expected violation of %s, got %d %+v", target, field, rw.Code, resp)
+		}
+	}
+}
+
+// TestParseGoodsFilter_AllViolations проверяет, что фильтр списка возвращает все нарушения сразу
+func TestParseGoodsFilter_AllViolations(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/goods/list?limit=0&removed=maybe&sort=price:up&createdFrom=yesterday", nil)
+	_, _, err := parseGoodsFilter(req)
+	resp := httptest.NewRecorder()
+	writeDomainError(resp, req, err)
+	details := decodeErrorResponse(t, resp).Details.(map[string]string)
+	for _, field := range []string{"limit", "removed", "sort", "createdFrom"} {
+		if details[field] == "" {
+			t.Errorf("expected violation of %s, got %v", field, details)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+-- Миграция 0003 (down): удаление полей конверта события из таблицы events_log
+ALTER TABLE events_log
+    DROP COLUMN IF EXISTS `EventId`,
+    DROP COLUMN IF EXISTS `EventType`,
+    DROP COLUMN IF EXISTS `Version`,
+    DROP COLUMN IF EXISTS `RequestId`,
+    DROP COLUMN IF EXISTS `Actor`,
+    DROP COLUMN IF EXISTS `Previous`,
+    DROP COLUMN IF EXISTS `Current`;
@@ -0,0 +1,10 @@
+-- Миграция 0003 (up): добавление полей конверта события в таблицу events_log
+-- Previous/Current хранят состояние сущности до и после изменения в JSON
+ALTER TABLE events_log
+    ADD COLUMN IF NOT EXISTS `EventId` String,        -- идентификатор события (UUID)
+    ADD COLUMN IF NOT EXISTS `EventType` String,      -- тип события, например good.updated
+    ADD COLUMN IF NOT EXISTS `Version` UInt8,         -- версия формата конверта
+    ADD COLUMN IF NOT EXISTS `RequestId` String,      -- идентификатор HTTP-запроса, вызвавшего изменение
+    ADD COLUMN IF NOT EXISTS `Actor` String,          -- инициатор изменения
+    ADD COLUMN IF NOT EXISTS `Previous` String,       -- состояние до изменения (JSON)
+    ADD COLUMN IF NOT EXISTS `Current` String;        -- состояние после изменения (JSON)
@@ -0,0 +1,2 @@
+-- Миграция 0004 (down): удаление таблицы events_dead_letter
+DROP TABLE IF EXISTS events_dead_letter;
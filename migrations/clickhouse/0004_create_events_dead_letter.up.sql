@@ -0,0 +1,14 @@
+-- Миграция 0004 (up): создание таблицы events_dead_letter в ClickHouse
+-- Таблица хранит сообщения, которые consumer не смог разобрать или записать в events_log,
+-- вместе с текстом ошибки; записи переотправляются командой replay
+CREATE TABLE IF NOT EXISTS events_dead_letter (
+    `Id` String,            -- идентификатор записи (UUID, устанавливается приложением)
+    `Stage` String,         -- этап, на котором произошла ошибка: decode или insert
+    `Error` String,         -- текст ошибки
+    `Payload` String,       -- исходное сообщение без изменений
+    `Deliveries` UInt32,    -- число доставок сообщения на момент отказа
+    `FailedAt` DateTime     -- время помещения сообщения в dead letter
+)
+    ENGINE = MergeTree()
+    PARTITION BY toYYYYMM(FailedAt)
+    ORDER BY (FailedAt, Id);
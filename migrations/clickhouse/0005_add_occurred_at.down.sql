@@ -0,0 +1,3 @@
+-- Миграция 0005 (down): удаление точного времени события из таблицы events_log
+ALTER TABLE events_log
+    DROP COLUMN IF EXISTS `OccurredAt`;
@@ -0,0 +1,5 @@
+-- Миграция 0005 (up): точное время события для упорядочивания истории изменений
+-- EventTime хранит время с точностью до секунды, и события одной секунды нельзя упорядочить;
+-- для строк, записанных до миграции, OccurredAt вычисляется из EventTime
+ALTER TABLE events_log
+    ADD COLUMN IF NOT EXISTS `OccurredAt` DateTime64(6) DEFAULT toDateTime64(EventTime, 6);
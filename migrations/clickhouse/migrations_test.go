@@ -45,16 +45,6 @@ func TestClickhouseMigrations(t *testing.T) {
 	).Scan(&existsTable)
 	require.NoError(t, err)
 	require.Equal(t, 1, existsTable, "events_log должна существовать после migrate Up")
-	// проверка полного отката миграций (двух шагов)
-	require.NoError(t, m.Steps(-2), "failed to rollback ClickHouse migrations")
-	err = db.QueryRow(
-		"SELECT count() FROM system.tables WHERE database=currentDatabase() AND name='events_log'",
-	).Scan(&existsTable)
-	require.NoError(t, err)
-	require.Equal(t, 0, existsTable, "events_log должна быть удалена после migrate Down")
-
-	// теперь тест основной логики миграций не нужен, так как проверили up/down
-	return
 
 	// ------------------------- Проверка структуры таблицы -------------------------
 	// Ожидаемые колонки и их типы
@@ -66,6 +56,16 @@ func TestClickhouseMigrations(t *testing.T) {
 		"Priority":    "UInt32",
 		"Removed":     "UInt8",
 		"EventTime":   "DateTime",
+		// поля конверта события (миграция 0003)
+		"EventId":   "String",
+		"EventType": "String",
+		"Version":   "UInt8",
+		"RequestId": "String",
+		"Actor":     "String",
+		"Previous":  "String",
+		"Current":   "String",
+		// точное время события (миграция 0005)
+		"OccurredAt": "DateTime64(6)",
 	}
 
 	// Выбираем колонки из system.columns
@@ -120,4 +120,40 @@ func TestClickhouseMigrations(t *testing.T) {
 	).Scan(&engine)
 	require.NoError(t, err, "ошибка при получении типа движка таблицы events_log")
 	require.Equal(t, "MergeTree", engine, "движок таблицы events_log должен быть MergeTree")
+
+	// ------------------------- Проверка таблицы dead letter (миграция 0004) -------------------------
+	deadLetterColumns := map[string]string{
+		"Id":         "String",
+		"Stage":      "String",
+		"Error":      "String",
+		"Payload":    "String",
+		"Deliveries": "UInt32",
+		"FailedAt":   "DateTime",
+	}
+	dlRows, err := db.Query(
+		"SELECT name, type FROM system.columns WHERE database = currentDatabase() AND table = 'events_dead_letter'",
+	)
+	require.NoError(t, err, "ошибка при получении описания колонок таблицы events_dead_letter")
+	defer dlRows.Close()
+	dlFound := make(map[string]string)
+	for dlRows.Next() {
+		var name, ctype string
+		require.NoError(t, dlRows.Scan(&name, &ctype), "ошибка при сканировании строки system.columns")
+		dlFound[name] = ctype
+	}
+	require.NoError(t, dlRows.Err(), "ошибка после обхода всех строк system.columns")
+	require.Equal(t, deadLetterColumns, dlFound, "структура таблицы events_dead_letter не совпадает с ожидаемой")
+
+	// ------------------------- Проверка полного отката миграций -------------------------
+	require.NoError(t, m.Down(), "failed to rollback ClickHouse migrations")
+	err = db.QueryRow(
+		"SELECT count() FROM system.tables WHERE database=currentDatabase() AND name='events_log'",
+	).Scan(&existsTable)
+	require.NoError(t, err)
+	require.Equal(t, 0, existsTable, "events_log должна быть удалена после migrate Down")
+	err = db.QueryRow(
+		"SELECT count() FROM system.tables WHERE database=currentDatabase() AND name='events_dead_letter'",
+	).Scan(&existsTable)
+	require.NoError(t, err)
+	require.Equal(t, 0, existsTable, "events_dead_letter должна быть удалена после migrate Down")
 }
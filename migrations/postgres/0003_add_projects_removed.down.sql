@@ -0,0 +1,3 @@
+-- Миграция 0003 (down): удаление флага архивации проекта
+
+ALTER TABLE Projects DROP COLUMN IF EXISTS removed;
@@ -0,0 +1,4 @@
+-- Миграция 0003 (up): добавление флага архивации проекта
+-- Проекты не удаляются физически, а помечаются removed=true (по аналогии с Goods)
+
+ALTER TABLE Projects ADD COLUMN IF NOT EXISTS removed BOOLEAN NOT NULL DEFAULT false;  -- логический флаг архивации проекта
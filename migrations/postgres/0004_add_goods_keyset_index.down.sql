@@ -0,0 +1,3 @@
+-- Миграция 0004 (down): удаление составного индекса для списка товаров
+
+DROP INDEX IF EXISTS idx_goods_project_priority_id;
@@ -0,0 +1,4 @@
+-- Миграция 0004 (up): составной индекс для выборки списка товаров проекта
+-- Используется сортировкой по приоритету и keyset-пагинацией по (priority, id)
+
+CREATE INDEX IF NOT EXISTS idx_goods_project_priority_id ON Goods(project_id, priority, id);
@@ -0,0 +1,4 @@
+-- Миграция 0005 (down): удаление таблицы outbox
+
+DROP INDEX IF EXISTS idx_outbox_pending;
+DROP TABLE IF EXISTS Outbox;
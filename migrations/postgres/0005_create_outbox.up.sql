@@ -0,0 +1,16 @@
+-- Миграция 0005 (up): таблица outbox для транзакционной публикации событий в NATS
+-- Событие пишется в одной транзакции с изменением Goods, relay-воркер публикует его и проставляет sent_at
+
+CREATE TABLE IF NOT EXISTS Outbox (
+    id BIGSERIAL PRIMARY KEY,                    -- порядковый номер события, задаёт порядок публикации
+    event_id UUID NOT NULL UNIQUE,               -- идентификатор конверта события (Event.ID)
+    event_type TEXT NOT NULL,                    -- тип события, например good.updated
+    payload JSONB NOT NULL,                      -- сериализованный конверт события
+    attempts INT NOT NULL DEFAULT 0,             -- число неудачных попыток публикации
+    last_error TEXT,                             -- текст последней ошибки публикации
+    created_at TIMESTAMP NOT NULL DEFAULT now(), -- время записи события
+    sent_at TIMESTAMP                            -- время успешной публикации, NULL пока событие не отправлено
+);
+
+-- Частичный индекс по неотправленным событиям для выборки relay-воркером
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON Outbox(id) WHERE sent_at IS NULL;
@@ -0,0 +1,3 @@
+-- Миграция 0006 (down): удаление контекста трассировки из outbox
+
+ALTER TABLE Outbox DROP COLUMN IF EXISTS trace_context;
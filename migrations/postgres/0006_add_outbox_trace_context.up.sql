@@ -0,0 +1,4 @@
+-- Миграция 0006 (up): контекст трассировки события в outbox
+-- Relay продолжает трассировку запроса, изменившего данные, и передаёт её в заголовках сообщения NATS
+
+ALTER TABLE Outbox ADD COLUMN IF NOT EXISTS trace_context JSONB; -- заголовки W3C Trace Context (traceparent, tracestate), NULL вне трассировки
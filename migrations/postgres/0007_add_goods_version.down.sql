@@ -0,0 +1,3 @@
+-- Миграция 0007 (down): удаление версии товара
+
+ALTER TABLE Goods DROP COLUMN IF EXISTS version;
@@ -0,0 +1,4 @@
+-- Миграция 0007 (up): версия товара для оптимистической блокировки
+-- Каждое изменение товара увеличивает version; API отдаёт её в ETag и проверяет в If-Match
+
+ALTER TABLE Goods ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
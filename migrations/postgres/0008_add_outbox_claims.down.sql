@@ -0,0 +1,7 @@
+-- Миграция 0008 (down): удаление аренды выборок и отметки неотправляемых событий outbox
+
+DROP INDEX IF EXISTS idx_outbox_sent_at;
+DROP INDEX IF EXISTS idx_outbox_pending;
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON Outbox(id) WHERE sent_at IS NULL;
+ALTER TABLE Outbox DROP COLUMN IF EXISTS failed_at;
+ALTER TABLE Outbox DROP COLUMN IF EXISTS locked_until;
@@ -0,0 +1,12 @@
+-- Миграция 0008 (up): аренда выборок outbox, неотправляемые события и удаление отправленных
+-- Relay каждой реплики закрепляет выборку за собой до locked_until, события, исчерпавшие попытки публикации,
+-- отмечаются failed_at и больше не выбираются, отправленные события удаляются по sent_at
+
+ALTER TABLE Outbox ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP; -- до какого времени событие закреплено за relay одной из реплик
+ALTER TABLE Outbox ADD COLUMN IF NOT EXISTS failed_at TIMESTAMP;    -- время, когда событие исчерпало попытки публикации
+
+-- Частичный индекс по событиям, которые ещё нужно опубликовать
+DROP INDEX IF EXISTS idx_outbox_pending;
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON Outbox(id) WHERE sent_at IS NULL AND failed_at IS NULL;
+-- Индекс по времени отправки для удаления старых отправленных событий
+CREATE INDEX IF NOT EXISTS idx_outbox_sent_at ON Outbox(sent_at) WHERE sent_at IS NOT NULL;
@@ -128,6 +128,40 @@ func TestPostgresMigrations(t *testing.T) {
 	require.NoError(t, err, "ошибка при проверке индекса idx_goods_name")
 	require.True(t, indexExists, "индекс idx_goods_name должен существовать")
 
+	// Составной индекс для сортировки и keyset-пагинации (миграция 0004)
+	err = db.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM pg_indexes WHERE tablename='goods' AND indexname='idx_goods_project_priority_id')`,
+	).Scan(&indexExists)
+	require.NoError(t, err, "ошибка при проверке индекса idx_goods_project_priority_id")
+	require.True(t, indexExists, "индекс idx_goods_project_priority_id должен существовать")
+
+	// Проверяем таблицу Outbox (миграция 0005) и частичный индекс неотправленных событий
+	err = db.QueryRow(
+		`SELECT EXISTS (SELECT FROM information_schema.tables WHERE table_name='outbox')`,
+	).Scan(&exists)
+	require.NoError(t, err, "ошибка при проверке существования таблицы Outbox")
+	require.True(t, exists, "таблица Outbox должна существовать после миграций")
+	err = db.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM pg_indexes WHERE tablename='outbox' AND indexname='idx_outbox_pending')`,
+	).Scan(&indexExists)
+	require.NoError(t, err, "ошибка при проверке индекса idx_outbox_pending")
+	require.True(t, indexExists, "индекс idx_outbox_pending должен существовать")
+	// Столбец контекста трассировки события (миграция 0006)
+	var columnExists bool
+	err = db.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM information_schema.columns WHERE table_name='outbox' AND column_name='trace_context')`,
+	).Scan(&columnExists)
+	require.NoError(t, err, "ошибка при проверке столбца trace_context в таблице Outbox")
+	require.True(t, columnExists, "в таблице Outbox должен быть столбец trace_context")
+	// Проверяем столбцы аренды и неотправляемых событий outbox (миграция 0008)
+	for _, column := range []string{"locked_until", "failed_at"} {
+		err = db.QueryRow(
+			`SELECT EXISTS(SELECT 1 FROM information_schema.columns WHERE table_name='outbox' AND column_name=$1)`, column,
+		).Scan(&columnExists)
+		require.NoError(t, err, "ошибка при проверке столбца %s в таблице Outbox", column)
+		require.True(t, columnExists, "в таблице Outbox должен быть столбец %s", column)
+	}
+
 	// ------------------------- Проверка работы триггера установки приоритета -------------------------
 
 	// Вставляем первую запись в Goods без явного указания priority, ожидаем priority=1
@@ -176,9 +210,27 @@ func TestPostgresMigrations(t *testing.T) {
 	require.Equal(t, "boolean", dataType, "тип Goods.removed должен быть BOOLEAN")
 	require.Equal(t, "NO", isNullable, "Goods.removed не должен быть NULL")
 
+	// Проверяем столбец Goods.version (миграция 0007): DEFAULT 1, тип INTEGER и NOT NULL
+	err = db.QueryRow(
+		`SELECT column_default, data_type, is_nullable FROM information_schema.columns WHERE table_name='goods' AND column_name='version'`,
+	).Scan(&colDefault, &dataType, &isNullable)
+	require.NoError(t, err, "ошибка при проверке свойства столбца goods.version")
+	require.Equal(t, "1", colDefault, "DEFAULT для Goods.version должен быть 1")
+	require.Equal(t, "integer", dataType, "тип Goods.version должен быть INTEGER")
+	require.Equal(t, "NO", isNullable, "Goods.version не должен быть NULL")
+
+	// Проверяем столбец Projects.removed (миграция 0003): DEFAULT false, тип BOOLEAN и NOT NULL
+	err = db.QueryRow(
+		`SELECT column_default, data_type, is_nullable FROM information_schema.columns WHERE table_name='projects' AND column_name='removed'`,
+	).Scan(&colDefault, &dataType, &isNullable)
+	require.NoError(t, err, "ошибка при проверке свойства столбца projects.removed")
+	require.Contains(t, colDefault, "false", "DEFAULT для Projects.removed должен быть false")
+	require.Equal(t, "boolean", dataType, "тип Projects.removed должен быть BOOLEAN")
+	require.Equal(t, "NO", isNullable, "Projects.removed не должен быть NULL")
+
 	// ------------------------- Проверка отката (down migrations) -------------------------
 	// Откат всех миграций назад
-	if err := m.Steps(-2); err != nil && err != migrate.ErrNoChange {
+	if err := m.Down(); err != nil && err != migrate.ErrNoChange {
 		t.Fatalf("failed to rollback all migrations: %v", err)
 	}
 	// Проверяем, что таблица Projects удалена
@@ -195,4 +247,11 @@ func TestPostgresMigrations(t *testing.T) {
 	).Scan(&exists)
 	require.NoError(t, err, "ошибка при проверке удаления таблицы Goods после отката")
 	require.False(t, exists, "таблица Goods должна быть удалена после отката")
+	// Проверяем, что таблица Outbox удалена
+	exists = false
+	err = db.QueryRow(
+		`SELECT EXISTS (SELECT FROM information_schema.tables WHERE table_name='outbox')`,
+	).Scan(&exists)
+	require.NoError(t, err, "ошибка при проверке удаления таблицы Outbox после отката")
+	require.False(t, exists, "таблица Outbox должна быть удалена после отката")
 }
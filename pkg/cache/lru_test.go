@@ -0,0 +1,66 @@
+// Пакет cache содержит unit-тесты локального LRU-кэша
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLRU_Evicts проверяет вытеснение давно не использованной записи при превышении размера
+func TestLRU_Evicts(t *testing.T) {
+	c := newLRU(2, time.Minute)
+	c.set("a", []byte("1"), 0)
+	c.set("b", []byte("2"), 0)
+	// обращение к a делает вытесняемой запись b
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("expected hit for a")
+	}
+	c.set("c", []byte("3"), 0)
+	if _, ok := c.get("b"); ok {
+		t.Error("b should be evicted")
+	}
+	if v, ok := c.get("a"); !ok || string(v) != "1" {
+		t.Errorf("expected a=1, got %s %v", v, ok)
+	}
+	if c.len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.len())
+	}
+}
+
+// TestLRU_Expires проверяет истечение записи по ttl кэша и по более короткому ttl записи
+func TestLRU_Expires(t *testing.T) {
+	now := time.Now()
+	c := newLRU(10, time.Second)
+	c.now = func() time.Time { return now }
+	c.set("long", []byte("1"), time.Hour)
+	c.set("short", []byte("2"), 100*time.Millisecond)
+	now = now.Add(500 * time.Millisecond)
+	if _, ok := c.get("short"); ok {
+		t.Error("short should expire by its own ttl")
+	}
+	if _, ok := c.get("long"); !ok {
+		t.Error("long should still be cached")
+	}
+	now = now.Add(time.Second)
+	if _, ok := c.get("long"); ok {
+		t.Error("long should expire by cache ttl")
+	}
+	if c.len() != 0 {
+		t.Errorf("expired entries should be removed, got %d", c.len())
+	}
+}
+
+// TestLRU_UpdateAndRemove проверяет перезапись значения и удаление по ключам
+func TestLRU_UpdateAndRemove(t *testing.T) {
+	c := newLRU(10, time.Minute)
+	c.set("a", []byte("1"), 0)
+	c.set("a", []byte("2"), 0)
+	if v, _ := c.get("a"); string(v) != "2" {
+		t.Errorf("expected updated value, got %s", v)
+	}
+	c.set("b", []byte("3"), 0)
+	c.remove("a", "b", "missing")
+	if c.len() != 0 {
+		t.Errorf("expected empty cache, got %d", c.len())
+	}
+}
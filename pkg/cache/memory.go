@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryItem — запись кэша в памяти; нулевой expiresAt означает запись без срока жизни
+type memoryItem struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryCache хранит записи и теги в памяти процесса с той же семантикой, что и RedisClient:
+// промах возвращает ErrCacheMiss, записи истекают по expiration, InvalidateTags удаляет все записи с тегом
+// Предназначен для локального запуска без Redis и для тестов
+type MemoryCache struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+	tags  map[string]map[string]struct{}
+	now   func() time.Time
+}
+
+// NewMemoryCache создаёт пустой кэш в памяти
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		items: make(map[string]memoryItem),
+		tags:  make(map[string]map[string]struct{}),
+		now:   time.Now,
+	}
+}
+
+// Set сохраняет значение под ключом key на время expiration (0 — без срока жизни)
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, expiration)
+	return nil
+}
+
+// SetTagged сохраняет значение и добавляет ключ в множества тегов tags
+func (c *MemoryCache) SetTagged(ctx context.Context, key string, value []byte, expiration time.Duration, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, expiration)
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	return nil
+}
+
+// SetIfAbsent сохраняет значение, только если ключа нет или он истёк, и возвращает true, если значение записано
+func (c *MemoryCache) SetIfAbsent(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if item, ok := c.items[key]; ok && (item.expiresAt.IsZero() || c.now().Before(item.expiresAt)) {
+		return false, nil
+	}
+	c.set(key, value, expiration)
+	return true, nil
+}
+
+// CompareAndSet заменяет значение на value, только если текущее значение неистёкшего ключа равно old
+func (c *MemoryCache) CompareAndSet(ctx context.Context, key string, old, value []byte, expiration time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.holds(key, old) {
+		return false, nil
+	}
+	c.set(key, value, expiration)
+	return true, nil
+}
+
+// CompareAndDelete удаляет ключ, только если значение неистёкшего ключа равно old
+func (c *MemoryCache) CompareAndDelete(ctx context.Context, key string, old []byte) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.holds(key, old) {
+		return false, nil
+	}
+	delete(c.items, key)
+	return true, nil
+}
+
+// Get возвращает значение по ключу или ErrCacheMiss, если ключа нет или он истёк
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	if !item.expiresAt.IsZero() && !c.now().Before(item.expiresAt) {
+		delete(c.items, key)
+		return nil, ErrCacheMiss
+	}
+	return item.value, nil
+}
+
+// Invalidate удаляет ключ key
+func (c *MemoryCache) Invalidate(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+	return nil
+}
+
+// InvalidateTags удаляет все записи, сохранённые с любым из тегов tags, и сами теги
+func (c *MemoryCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			delete(c.items, key)
+		}
+		delete(c.tags, tag)
+	}
+	return nil
+}
+
+// holds сообщает, хранится ли под неистёкшим ключом значение value; вызывается под c.mu
+func (c *MemoryCache) holds(key string, value []byte) bool {
+	item, ok := c.items[key]
+	if !ok || (!item.expiresAt.IsZero() && !c.now().Before(item.expiresAt)) {
+		return false
+	}
+	return bytes.Equal(item.value, value)
+}
+
+// set записывает значение; вызывается под c.mu
+func (c *MemoryCache) set(key string, value []byte, expiration time.Duration) {
+	item := memoryItem{value: value}
+	if expiration > 0 {
+		item.expiresAt = c.now().Add(expiration)
+	}
+	c.items[key] = item
+}
@@ -0,0 +1,114 @@
+// Пакет cache содержит unit-тесты кэша в памяти MemoryCache
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMemoryCache_SetGetInvalidate проверяет запись, чтение, истечение и удаление ключа
+func TestMemoryCache_SetGetInvalidate(t *testing.T) {
+	now := time.Now()
+	c := NewMemoryCache()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := c.Get(ctx, "key"); err != ErrCacheMiss {
+		t.Errorf("expected ErrCacheMiss, got %v", err)
+	}
+	_ = c.Set(ctx, "key", []byte("value"), time.Minute)
+	_ = c.Set(ctx, "forever", []byte("value"), 0)
+	if got, err := c.Get(ctx, "key"); err != nil || string(got) != "value" {
+		t.Errorf("expected value, got %s %v", got, err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := c.Get(ctx, "key"); err != ErrCacheMiss {
+		t.Errorf("expected expired key, got %v", err)
+	}
+	if _, err := c.Get(ctx, "forever"); err != nil {
+		t.Errorf("key without expiration should stay, got %v", err)
+	}
+
+	_ = c.Invalidate(ctx, "forever")
+	if _, err := c.Get(ctx, "forever"); err != ErrCacheMiss {
+		t.Errorf("expected ErrCacheMiss after Invalidate, got %v", err)
+	}
+}
+
+// TestMemoryCache_SetIfAbsent проверяет, что существующий ключ не перезаписывается до истечения
+func TestMemoryCache_SetIfAbsent(t *testing.T) {
+	now := time.Now()
+	c := NewMemoryCache()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if ok, _ := c.SetIfAbsent(ctx, "lock", []byte("a"), time.Minute); !ok {
+		t.Fatal("expected absent key to be set")
+	}
+	if ok, _ := c.SetIfAbsent(ctx, "lock", []byte("b"), time.Minute); ok {
+		t.Fatal("expected existing key to be kept")
+	}
+	if got, _ := c.Get(ctx, "lock"); string(got) != "a" {
+		t.Errorf("expected a, got %s", got)
+	}
+	now = now.Add(time.Minute)
+	if ok, _ := c.SetIfAbsent(ctx, "lock", []byte("c"), time.Minute); !ok {
+		t.Error("expected expired key to be replaced")
+	}
+}
+
+// TestMemoryCache_CompareAndSet проверяет замену и удаление только при совпадении значения неистёкшего ключа
+func TestMemoryCache_CompareAndSet(t *testing.T) {
+	now := time.Now()
+	c := NewMemoryCache()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "lock", []byte("a"), time.Minute)
+	if ok, _ := c.CompareAndSet(ctx, "lock", []byte("x"), []byte("b"), time.Minute); ok {
+		t.Fatal("expected mismatched value to be kept")
+	}
+	if ok, _ := c.CompareAndSet(ctx, "lock", []byte("a"), []byte("b"), time.Minute); !ok {
+		t.Fatal("expected matching value to be replaced")
+	}
+	if ok, _ := c.CompareAndDelete(ctx, "lock", []byte("a")); ok {
+		t.Fatal("expected replaced value not to be deleted")
+	}
+	now = now.Add(time.Minute)
+	if ok, _ := c.CompareAndSet(ctx, "lock", []byte("b"), []byte("c"), time.Minute); ok {
+		t.Fatal("expected expired key not to be replaced")
+	}
+	_ = c.Set(ctx, "lock", []byte("d"), time.Minute)
+	if ok, _ := c.CompareAndDelete(ctx, "lock", []byte("d")); !ok {
+		t.Fatal("expected matching value to be deleted")
+	}
+	if _, err := c.Get(ctx, "lock"); err != ErrCacheMiss {
+		t.Errorf("expected deleted key, got %v", err)
+	}
+}
+
+// TestMemoryCache_Tags проверяет удаление всех записей с тегом без затрагивания других тегов
+func TestMemoryCache_Tags(t *testing.T) {
+	c := NewMemoryCache()
+	ctx := context.Background()
+	_ = c.SetTagged(ctx, "goods:list:1", []byte("a"), time.Minute, "goods:list:project:1")
+	_ = c.SetTagged(ctx, "goods:list:all", []byte("b"), time.Minute, "goods:list:project:0")
+	_ = c.SetTagged(ctx, "goods:list:2", []byte("c"), time.Minute, "goods:list:project:2")
+
+	if err := c.InvalidateTags(ctx, "goods:list:project:1", "goods:list:project:0"); err != nil {
+		t.Fatalf("InvalidateTags error: %v", err)
+	}
+	for _, key := range []string{"goods:list:1", "goods:list:all"} {
+		if _, err := c.Get(ctx, key); err != ErrCacheMiss {
+			t.Errorf("%s should be invalidated, got %v", key, err)
+		}
+	}
+	if _, err := c.Get(ctx, "goods:list:2"); err != nil {
+		t.Errorf("other project page should stay, got %v", err)
+	}
+	if _, ok := c.tags["goods:list:project:1"]; ok {
+		t.Error("tag set should be removed")
+	}
+}
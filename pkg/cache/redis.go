@@ -6,7 +6,12 @@ import (
 	"errors"
 	"time"
 
+	"HezzlTestTask/pkg/tracing"
+
 	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ErrCacheMiss возвращается, когда запрошенный ключ отсутствует в кеше Redis.
@@ -27,7 +32,9 @@ func NewRedisClient(opts *redis.Options) *RedisClient {
 
 // Set сохраняет значение value под ключом key с указанным временем жизни expiration.
 // Возвращает ошибку, если операция записи завершилась неудачей.
-func (r *RedisClient) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+func (r *RedisClient) Set(ctx context.Context, key string, value []byte, expiration time.Duration) (err error) {
+	ctx, span := startSpan(ctx, "RedisClient.Set", attribute.String("cache.key", key))
+	defer func() { tracing.End(span, err) }()
 	// Rely on redis.Client.Set – метод возвращает тип StatusCmd,
 	// .Err() возвращает ошибку, если команда не выполнена успешно.
 	return r.client.Set(ctx, key, value, expiration).Err()
@@ -38,11 +45,16 @@ func (r *RedisClient) Set(ctx context.Context, key string, value []byte, expirat
 // иначе при других ошибках возвращается оригинальная ошибка.
 // В случае успеха возвращается массив байт, сохранённый под ключом.
 func (r *RedisClient) Get(ctx context.Context, key string) ([]byte, error) {
+	ctx, span := startSpan(ctx, "RedisClient.Get", attribute.String("cache.key", key))
 	data, err := r.client.Get(ctx, key).Bytes()
+	// промах кэша не отмечается в спане ошибкой
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
 	if err == redis.Nil {
 		// кэш-промах: ключ отсутствует
+		tracing.End(span, nil)
 		return nil, ErrCacheMiss
 	}
+	tracing.End(span, err)
 	if err != nil {
 		// любая другая ошибка Redis
 		return nil, err
@@ -51,9 +63,123 @@ func (r *RedisClient) Get(ctx context.Context, key string) ([]byte, error) {
 	return data, nil
 }
 
+// SetIfAbsent сохраняет значение value под ключом key, только если ключа ещё нет (SET NX)
+// Возвращает true, если значение записано; используется как блокировка, которую получает только один из клиентов
+func (r *RedisClient) SetIfAbsent(ctx context.Context, key string, value []byte, expiration time.Duration) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "RedisClient.SetIfAbsent", attribute.String("cache.key", key))
+	defer func() { tracing.End(span, err) }()
+	return r.client.SetNX(ctx, key, value, expiration).Result()
+}
+
+// compareAndSetScript заменяет значение KEYS[1] на ARGV[2] со временем жизни ARGV[3] мс (0 — без срока),
+// только если текущее значение равно ARGV[1]; возвращает 1, если значение заменено
+var compareAndSetScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+  return 0
+end
+if tonumber(ARGV[3]) > 0 then
+  redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+else
+  redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// compareAndDeleteScript удаляет KEYS[1], только если его значение равно ARGV[1]; возвращает число удалённых ключей
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+  return 0
+end
+return redis.call('DEL', KEYS[1])
+`)
+
+// CompareAndSet заменяет значение ключа key на value со временем жизни expiration, только если текущее значение
+// равно old; возвращает true, если значение заменено. Используется, чтобы продлить или освободить блокировку,
+// взятую через SetIfAbsent, только её владельцем
+func (r *RedisClient) CompareAndSet(ctx context.Context, key string, old, value []byte, expiration time.Duration) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "RedisClient.CompareAndSet", attribute.String("cache.key", key))
+	defer func() { tracing.End(span, err) }()
+	n, err := compareAndSetScript.Run(ctx, r.client, []string{key}, old, value, expiration.Milliseconds()).Int()
+	return n == 1, err
+}
+
+// CompareAndDelete удаляет ключ key, только если его значение равно old; возвращает true, если ключ удалён
+func (r *RedisClient) CompareAndDelete(ctx context.Context, key string, old []byte) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "RedisClient.CompareAndDelete", attribute.String("cache.key", key))
+	defer func() { tracing.End(span, err) }()
+	n, err := compareAndDeleteScript.Run(ctx, r.client, []string{key}, old).Int()
+	return n == 1, err
+}
+
 // Invalidate удаляет ключ key из кеша Redis.
 // Используется для инвалидирования устаревших или изменённых данных.
 // Возвращает ошибку, если операция удаления не удалась.
-func (r *RedisClient) Invalidate(ctx context.Context, key string) error {
+func (r *RedisClient) Invalidate(ctx context.Context, key string) (err error) {
+	ctx, span := startSpan(ctx, "RedisClient.Invalidate", attribute.String("cache.key", key))
+	defer func() { tracing.End(span, err) }()
 	return r.client.Del(ctx, key).Err()
 }
+
+// tagPrefix — префикс ключей Redis-множеств, хранящих ключи записей с тегом
+const tagPrefix = "tag:"
+
+// invalidateTagsScript удаляет все ключи из множеств тегов KEYS и сами множества одной Lua-командой,
+// поэтому между чтением множества и удалением ключей в него не может попасть новая запись
+// Ключи удаляются порциями, чтобы не превысить лимит аргументов unpack; скрипт возвращает удалённые ключи
+var invalidateTagsScript = redis.NewScript(`
+local deleted = {}
+for _, tag in ipairs(KEYS) do
+  local keys = redis.call('SMEMBERS', tag)
+  for i = 1, #keys, 500 do
+    redis.call('DEL', unpack(keys, i, math.min(i + 499, #keys)))
+  end
+  for _, key in ipairs(keys) do
+    deleted[#deleted + 1] = key
+  end
+  redis.call('DEL', tag)
+end
+return deleted
+`)
+
+// SetTagged сохраняет значение под ключом key и добавляет ключ в множества тегов tags в одной транзакции MULTI/EXEC
+// Время жизни множества тега продлевается до expiration при каждой записи, поэтому при одинаковом TTL записей
+// множество живёт не меньше любого своего ключа
+func (r *RedisClient) SetTagged(ctx context.Context, key string, value []byte, expiration time.Duration, tags ...string) (err error) {
+	ctx, span := startSpan(ctx, "RedisClient.SetTagged", attribute.String("cache.key", key), attribute.StringSlice("cache.tags", tags))
+	defer func() { tracing.End(span, err) }()
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, value, expiration)
+		for _, tag := range tags {
+			pipe.SAdd(ctx, tagPrefix+tag, key)
+			pipe.Expire(ctx, tagPrefix+tag, expiration)
+		}
+		return nil
+	})
+	return err
+}
+
+// InvalidateTags атомарно удаляет все записи, сохранённые с любым из тегов tags, и сами теги
+func (r *RedisClient) InvalidateTags(ctx context.Context, tags ...string) error {
+	_, err := r.invalidateTags(ctx, tags...)
+	return err
+}
+
+// invalidateTags выполняет инвалидацию по тегам и возвращает удалённые ключи записей
+func (r *RedisClient) invalidateTags(ctx context.Context, tags ...string) (_ []string, err error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	ctx, span := startSpan(ctx, "RedisClient.InvalidateTags", attribute.StringSlice("cache.tags", tags))
+	defer func() { tracing.End(span, err) }()
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = tagPrefix + tag
+	}
+	return invalidateTagsScript.Run(ctx, r.client, keys).StringSlice()
+}
+
+// startSpan начинает клиентский спан команды Redis
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attrs, semconv.DBSystemRedis)...))
+}
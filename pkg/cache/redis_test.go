@@ -1,4 +1,4 @@
-// Пакет cache содержит unit-тесты для проверки работы RedisClient: Set, Get и Invalidate
+// Пакет cache содержит unit-тесты для проверки работы RedisClient: Set, Get, Invalidate и теги
 package cache
 
 import (
@@ -10,6 +10,11 @@ import (
 
 	// библиотека-мок для эмуляции Redis клиента
 	redismock "github.com/go-redis/redismock/v8"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // TestSetGetInvalidate проверяет корректную работу методов Set, Get (hit и miss) и Invalidate
@@ -93,3 +98,130 @@ func TestInvalidate_Error(t *testing.T) {
 		t.Errorf("expected invalidate error, got %v", err)
 	}
 }
+
+// TestSetTagged проверяет запись значения и добавление ключа в множества тегов в одной транзакции
+func TestSetTagged(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	client := &RedisClient{client: db}
+	ctx := context.Background()
+	val := []byte("page")
+	mock.ExpectTxPipeline()
+	mock.ExpectSet("goods:list:1", val, time.Minute).SetVal("OK")
+	mock.ExpectSAdd("tag:goods:list:project:1", "goods:list:1").SetVal(1)
+	mock.ExpectExpire("tag:goods:list:project:1", time.Minute).SetVal(true)
+	mock.ExpectTxPipelineExec()
+	if err := client.SetTagged(ctx, "goods:list:1", val, time.Minute, "goods:list:project:1"); err != nil {
+		t.Errorf("SetTagged error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestInvalidateTags проверяет вызов Lua-скрипта с ключами множеств тегов
+func TestInvalidateTags(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	client := &RedisClient{client: db}
+	ctx := context.Background()
+	keys := []string{"tag:goods:list:project:1", "tag:goods:list:project:0"}
+	mock.ExpectEvalSha(invalidateTagsScript.Hash(), keys).SetVal([]interface{}{"goods:list:1", "goods:list:2"})
+	if err := client.InvalidateTags(ctx, "goods:list:project:1", "goods:list:project:0"); err != nil {
+		t.Errorf("InvalidateTags error: %v", err)
+	}
+
+	// ошибка Redis прокидывается
+	mock.ExpectEvalSha(invalidateTagsScript.Hash(), keys[:1]).SetErr(errors.New("eval failed"))
+	err := client.InvalidateTags(ctx, "goods:list:project:1")
+	if err == nil || !strings.Contains(err.Error(), "eval failed") {
+		t.Errorf("expected eval error, got %v", err)
+	}
+
+	// без тегов запрос не выполняется
+	if err := client.InvalidateTags(ctx); err != nil {
+		t.Errorf("InvalidateTags without tags error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestSetIfAbsent проверяет запись отсутствующего ключа, отказ для существующего и прокидывание ошибки
+func TestSetIfAbsent(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	client := &RedisClient{client: db}
+	ctx := context.Background()
+	mock.ExpectSetNX("lock", []byte("v"), time.Minute).SetVal(true)
+	if ok, err := client.SetIfAbsent(ctx, "lock", []byte("v"), time.Minute); !ok || err != nil {
+		t.Errorf("expected key to be set, got %v %v", ok, err)
+	}
+	mock.ExpectSetNX("lock", []byte("v"), time.Minute).SetVal(false)
+	if ok, err := client.SetIfAbsent(ctx, "lock", []byte("v"), time.Minute); ok || err != nil {
+		t.Errorf("expected existing key to be kept, got %v %v", ok, err)
+	}
+	mock.ExpectSetNX("lock", []byte("v"), time.Minute).SetErr(errors.New("setnx failed"))
+	if _, err := client.SetIfAbsent(ctx, "lock", []byte("v"), time.Minute); err == nil {
+		t.Error("expected error")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestCompareAndSetDelete проверяет вызовы Lua-скриптов сравнения со значением и их результат
+func TestCompareAndSetDelete(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	client := &RedisClient{client: db}
+	ctx := context.Background()
+	mock.ExpectEvalSha(compareAndSetScript.Hash(), []string{"lock"}, []byte("a"), []byte("b"), int64(60000)).SetVal(int64(1))
+	if ok, err := client.CompareAndSet(ctx, "lock", []byte("a"), []byte("b"), time.Minute); !ok || err != nil {
+		t.Errorf("expected value to be replaced, got %v %v", ok, err)
+	}
+	mock.ExpectEvalSha(compareAndSetScript.Hash(), []string{"lock"}, []byte("a"), []byte("c"), int64(60000)).SetVal(int64(0))
+	if ok, err := client.CompareAndSet(ctx, "lock", []byte("a"), []byte("c"), time.Minute); ok || err != nil {
+		t.Errorf("expected other value to be kept, got %v %v", ok, err)
+	}
+	mock.ExpectEvalSha(compareAndDeleteScript.Hash(), []string{"lock"}, []byte("b")).SetVal(int64(1))
+	if ok, err := client.CompareAndDelete(ctx, "lock", []byte("b")); !ok || err != nil {
+		t.Errorf("expected key to be deleted, got %v %v", ok, err)
+	}
+	mock.ExpectEvalSha(compareAndDeleteScript.Hash(), []string{"lock"}, []byte("b")).SetErr(errors.New("eval failed"))
+	if _, err := client.CompareAndDelete(ctx, "lock", []byte("b")); err == nil {
+		t.Error("expected error")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestRedisClient_Spans проверяет спаны команд: промах кэша не считается ошибкой, ошибка Redis — считается
+func TestRedisClient_Spans(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	db, mock := redismock.NewClientMock()
+	client := &RedisClient{client: db}
+	ctx := context.Background()
+	mock.ExpectGet("missing").RedisNil()
+	mock.ExpectDel("key").SetErr(errors.New("connection refused"))
+	_, _ = client.Get(ctx, "missing")
+	_ = client.Invalidate(ctx, "key")
+
+	spans := rec.Ended()
+	if len(spans) != 2 || spans[0].Name() != "RedisClient.Get" || spans[1].Name() != "RedisClient.Invalidate" {
+		t.Fatalf("unexpected spans %v", spans)
+	}
+	if spans[0].Status().Code == codes.Error {
+		t.Errorf("cache miss must not be marked as error")
+	}
+	var hit attribute.Value
+	for _, kv := range spans[0].Attributes() {
+		if kv.Key == "cache.hit" {
+			hit = kv.Value
+		}
+	}
+	if hit.Type() != attribute.BOOL || hit.AsBool() {
+		t.Errorf("expected cache.hit=false, got %v", hit)
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("expected error status for failed command, got %+v", spans[1].Status())
+	}
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Publisher отправляет сообщения об инвалидации другим репликам; реализуется *nats.Conn
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// invalidation — сообщение об удалённых ключах, рассылаемое между репликами
+type invalidation struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+}
+
+// TieredCache — двухуровневый кэш: ограниченный LRU в памяти процесса с коротким TTL поверх RedisClient
+// Чтение сначала обращается к локальному уровню и только при промахе идёт в Redis; запись проходит в оба уровня
+// Удаление ключей (Invalidate, InvalidateTags) рассылается через Publisher, и остальные реплики вытесняют
+// эти ключи из своего локального уровня в HandleInvalidation. Доставка рассылки не гарантируется,
+// поэтому устаревание локальной записи ограничено её TTL
+type TieredCache struct {
+	remote    *RedisClient
+	local     *lru
+	publisher Publisher
+	subject   string
+	origin    string // идентификатор реплики, чтобы не обрабатывать собственные рассылки
+}
+
+// NewTieredCache создаёт двухуровневый кэш: size — число записей локального уровня, ttl — их время жизни,
+// subject — тема, в которую публикуются сообщения об инвалидации
+func NewTieredCache(remote *RedisClient, size int, ttl time.Duration, publisher Publisher, subject string) *TieredCache {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return &TieredCache{
+		remote:    remote,
+		local:     newLRU(size, ttl),
+		publisher: publisher,
+		subject:   subject,
+		origin:    hex.EncodeToString(id),
+	}
+}
+
+// Set сохраняет значение в Redis и в локальном уровне
+func (c *TieredCache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	if err := c.remote.Set(ctx, key, value, expiration); err != nil {
+		return err
+	}
+	c.local.set(key, value, expiration)
+	return nil
+}
+
+// SetTagged сохраняет значение с тегами в Redis и в локальном уровне
+func (c *TieredCache) SetTagged(ctx context.Context, key string, value []byte, expiration time.Duration, tags ...string) error {
+	if err := c.remote.SetTagged(ctx, key, value, expiration, tags...); err != nil {
+		return err
+	}
+	c.local.set(key, value, expiration)
+	return nil
+}
+
+// Get возвращает значение из локального уровня, а при промахе — из Redis с сохранением в локальный уровень
+func (c *TieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, ok := c.local.get(key); ok {
+		return value, nil
+	}
+	value, err := c.remote.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	c.local.set(key, value, 0)
+	return value, nil
+}
+
+// Invalidate удаляет ключ из Redis и локального уровня и рассылает инвалидацию остальным репликам
+func (c *TieredCache) Invalidate(ctx context.Context, key string) error {
+	c.local.remove(key)
+	if err := c.remote.Invalidate(ctx, key); err != nil {
+		return err
+	}
+	c.broadcast(ctx, key)
+	return nil
+}
+
+// InvalidateTags удаляет записи с тегами из Redis, вытесняет удалённые ключи из локального уровня
+// и рассылает их остальным репликам
+func (c *TieredCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	keys, err := c.remote.invalidateTags(ctx, tags...)
+	if err != nil {
+		return err
+	}
+	c.local.remove(keys...)
+	c.broadcast(ctx, keys...)
+	return nil
+}
+
+// HandleInvalidation обрабатывает сообщение об инвалидации от другой реплики
+func (c *TieredCache) HandleInvalidation(data []byte) error {
+	var msg invalidation
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to unmarshal cache invalidation: %w", err)
+	}
+	if msg.Origin == c.origin {
+		return nil
+	}
+	c.local.remove(msg.Keys...)
+	return nil
+}
+
+// broadcast публикует удалённые ключи; ошибка публикации только логируется,
+// так как запись в Redis уже удалена, а локальные копии на других репликах истекут по TTL
+func (c *TieredCache) broadcast(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	data, err := json.Marshal(invalidation{Origin: c.origin, Keys: keys})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to marshal cache invalidation", slog.Any("error", err))
+		return
+	}
+	if err := c.publisher.Publish(c.subject, data); err != nil {
+		slog.WarnContext(ctx, "failed to publish cache invalidation", slog.String("subject", c.subject), slog.Any("error", err))
+	}
+}
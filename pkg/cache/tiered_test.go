@@ -0,0 +1,176 @@
+// Пакет cache содержит unit-тесты двухуровневого кэша TieredCache
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	redismock "github.com/go-redis/redismock/v8"
+)
+
+// mockPublisher запоминает опубликованные сообщения
+type mockPublisher struct {
+	subjects []string
+	messages [][]byte
+	err      error
+}
+
+func (p *mockPublisher) Publish(subject string, data []byte) error {
+	p.subjects = append(p.subjects, subject)
+	p.messages = append(p.messages, data)
+	return p.err
+}
+
+// newTiered создаёт TieredCache поверх redismock
+func newTiered(pub *mockPublisher) (*TieredCache, redismock.ClientMock) {
+	db, mock := redismock.NewClientMock()
+	return NewTieredCache(&RedisClient{client: db}, 10, time.Minute, pub, "cache.invalidate"), mock
+}
+
+// TestTieredCache_GetUsesLocal проверяет, что повторное чтение не обращается к Redis
+func TestTieredCache_GetUsesLocal(t *testing.T) {
+	c, mock := newTiered(&mockPublisher{})
+	ctx := context.Background()
+	mock.ExpectGet("good:1").SetVal("v1")
+	for i := 0; i < 3; i++ {
+		got, err := c.Get(ctx, "good:1")
+		if err != nil || string(got) != "v1" {
+			t.Fatalf("expected v1, got %s %v", got, err)
+		}
+	}
+	// промах Redis не кэшируется локально
+	mock.ExpectGet("good:2").RedisNil()
+	if _, err := c.Get(ctx, "good:2"); err != ErrCacheMiss {
+		t.Errorf("expected ErrCacheMiss, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestTieredCache_SetWritesThrough проверяет запись в оба уровня и отсутствие записи в локальный при ошибке Redis
+func TestTieredCache_SetWritesThrough(t *testing.T) {
+	c, mock := newTiered(&mockPublisher{})
+	ctx := context.Background()
+	mock.ExpectSet("good:1", []byte("v1"), time.Minute).SetVal("OK")
+	if err := c.Set(ctx, "good:1", []byte("v1"), time.Minute); err != nil {
+		t.Fatalf("Set error: %v", err)
+	}
+	if got, err := c.Get(ctx, "good:1"); err != nil || string(got) != "v1" {
+		t.Errorf("expected local hit v1, got %s %v", got, err)
+	}
+	mock.ExpectSet("good:2", []byte("v2"), time.Minute).SetErr(errors.New("set failed"))
+	if err := c.Set(ctx, "good:2", []byte("v2"), time.Minute); err == nil {
+		t.Error("expected set error")
+	}
+	if _, ok := c.local.get("good:2"); ok {
+		t.Error("failed write should not be cached locally")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestTieredCache_Invalidate проверяет удаление из обоих уровней и рассылку ключа
+func TestTieredCache_Invalidate(t *testing.T) {
+	pub := &mockPublisher{}
+	c, mock := newTiered(pub)
+	ctx := context.Background()
+	c.local.set("good:1", []byte("v1"), 0)
+	mock.ExpectDel("good:1").SetVal(1)
+	if err := c.Invalidate(ctx, "good:1"); err != nil {
+		t.Fatalf("Invalidate error: %v", err)
+	}
+	if _, ok := c.local.get("good:1"); ok {
+		t.Error("key should be removed locally")
+	}
+	if len(pub.messages) != 1 || pub.subjects[0] != "cache.invalidate" {
+		t.Fatalf("expected one broadcast, got %v", pub.subjects)
+	}
+	var msg invalidation
+	if err := json.Unmarshal(pub.messages[0], &msg); err != nil {
+		t.Fatalf("unmarshal broadcast: %v", err)
+	}
+	if msg.Origin != c.origin || len(msg.Keys) != 1 || msg.Keys[0] != "good:1" {
+		t.Errorf("unexpected broadcast: %+v", msg)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestTieredCache_InvalidateTags проверяет вытеснение и рассылку ключей, удалённых по тегам
+func TestTieredCache_InvalidateTags(t *testing.T) {
+	pub := &mockPublisher{}
+	c, mock := newTiered(pub)
+	ctx := context.Background()
+	c.local.set("goods:list:1", []byte("page"), 0)
+	c.local.set("good:1", []byte("v1"), 0)
+	mock.ExpectEvalSha(invalidateTagsScript.Hash(), []string{"tag:goods:list:project:1"}).
+		SetVal([]interface{}{"goods:list:1"})
+	if err := c.InvalidateTags(ctx, "goods:list:project:1"); err != nil {
+		t.Fatalf("InvalidateTags error: %v", err)
+	}
+	if _, ok := c.local.get("goods:list:1"); ok {
+		t.Error("tagged key should be removed locally")
+	}
+	if _, ok := c.local.get("good:1"); !ok {
+		t.Error("untagged key should stay cached")
+	}
+	var msg invalidation
+	if len(pub.messages) != 1 || json.Unmarshal(pub.messages[0], &msg) != nil || len(msg.Keys) != 1 {
+		t.Fatalf("expected broadcast of deleted key, got %q", pub.messages)
+	}
+
+	// без удалённых ключей рассылки нет
+	mock.ExpectEvalSha(invalidateTagsScript.Hash(), []string{"tag:projects:list"}).SetVal([]interface{}{})
+	if err := c.InvalidateTags(ctx, "projects:list"); err != nil {
+		t.Fatalf("InvalidateTags error: %v", err)
+	}
+	if len(pub.messages) != 1 {
+		t.Errorf("expected no broadcast for empty tag, got %d", len(pub.messages))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestTieredCache_PublishError проверяет, что ошибка рассылки не возвращается вызывающему
+func TestTieredCache_PublishError(t *testing.T) {
+	c, mock := newTiered(&mockPublisher{err: errors.New("nats down")})
+	mock.ExpectDel("good:1").SetVal(1)
+	if err := c.Invalidate(context.Background(), "good:1"); err != nil {
+		t.Errorf("expected publish error to be ignored, got %v", err)
+	}
+}
+
+// TestTieredCache_HandleInvalidation проверяет вытеснение ключей по сообщению другой реплики
+// и игнорирование собственных сообщений
+func TestTieredCache_HandleInvalidation(t *testing.T) {
+	c, _ := newTiered(&mockPublisher{})
+	c.local.set("good:1", []byte("v1"), 0)
+	c.local.set("good:2", []byte("v2"), 0)
+
+	own, _ := json.Marshal(invalidation{Origin: c.origin, Keys: []string{"good:1"}})
+	if err := c.HandleInvalidation(own); err != nil {
+		t.Fatalf("HandleInvalidation error: %v", err)
+	}
+	if _, ok := c.local.get("good:1"); !ok {
+		t.Error("own broadcast should be ignored")
+	}
+
+	other, _ := json.Marshal(invalidation{Origin: "other", Keys: []string{"good:1", "good:2"}})
+	if err := c.HandleInvalidation(other); err != nil {
+		t.Fatalf("HandleInvalidation error: %v", err)
+	}
+	if c.local.len() != 0 {
+		t.Errorf("expected keys evicted, got %d entries", c.local.len())
+	}
+
+	if err := c.HandleInvalidation([]byte("{")); err == nil {
+		t.Error("expected unmarshal error")
+	}
+}
@@ -0,0 +1,146 @@
+// Пакет health проверяет доступность зависимостей сервиса для эндпоинта готовности
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Статусы готовности сервиса в Report.Status
+const (
+	StatusReady    = "ready"     // все зависимости доступны
+	StatusDegraded = "degraded"  // недоступна только некритичная зависимость, сервис обслуживает запросы
+	StatusNotReady = "not ready" // недоступна критичная зависимость
+)
+
+// Статусы отдельной зависимости в ComponentStatus.Status
+const (
+	ComponentUp   = "up"
+	ComponentDown = "down"
+)
+
+// CheckFunc проверяет доступность зависимости; ctx ограничен таймаутом проверки
+type CheckFunc func(ctx context.Context) error
+
+// component — зарегистрированная зависимость и последняя ошибка её проверки
+type component struct {
+	name     string
+	critical bool
+	check    CheckFunc
+
+	mu          sync.Mutex
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// ComponentStatus описывает результат проверки одной зависимости
+// LastError и LastErrorAt сохраняются после восстановления зависимости, чтобы было видно недавние сбои
+type ComponentStatus struct {
+	Status      string     `json:"status"`
+	Critical    bool       `json:"critical"`
+	LatencyMs   float64    `json:"latencyMs"`
+	Error       string     `json:"error,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// Report — ответ эндпоинта готовности
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// Checker параллельно проверяет зарегистрированные зависимости с таймаутом
+// Реализует http.Handler: 200 для ready и degraded, 503 для not ready
+type Checker struct {
+	timeout    time.Duration
+	components []*component
+	now        func() time.Time
+}
+
+// NewChecker создаёт Checker, ограничивающий каждую проверку временем timeout
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, now: time.Now}
+}
+
+// Add регистрирует зависимость name; недоступность критичной зависимости делает сервис неготовым
+// Add вызывается при старте сервиса до первой проверки
+func (c *Checker) Add(name string, critical bool, check CheckFunc) {
+	c.components = append(c.components, &component{name: name, critical: critical, check: check})
+}
+
+// Check проверяет все зависимости и возвращает сводный отчёт
+func (c *Checker) Check(ctx context.Context) Report {
+	statuses := make([]ComponentStatus, len(c.components))
+	var wg sync.WaitGroup
+	for i, comp := range c.components {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = c.checkComponent(ctx, comp)
+		}()
+	}
+	wg.Wait()
+	report := Report{Status: StatusReady, Components: make(map[string]ComponentStatus, len(c.components))}
+	for i, comp := range c.components {
+		st := statuses[i]
+		report.Components[comp.name] = st
+		if st.Status == ComponentUp {
+			continue
+		}
+		if comp.critical {
+			report.Status = StatusNotReady
+		} else if report.Status == StatusReady {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// checkComponent выполняет проверку одной зависимости с таймаутом и обновляет её последнюю ошибку
+// Проверка, не уважающая ctx, не задерживает ответ дольше таймаута
+func (c *Checker) checkComponent(ctx context.Context, comp *component) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := c.now()
+	done := make(chan error, 1)
+	go func() { done <- comp.check(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	st := ComponentStatus{
+		Status:    ComponentUp,
+		Critical:  comp.critical,
+		LatencyMs: float64(c.now().Sub(start).Microseconds()) / 1000,
+	}
+	comp.mu.Lock()
+	defer comp.mu.Unlock()
+	if err != nil {
+		st.Status = ComponentDown
+		st.Error = err.Error()
+		comp.lastError, comp.lastErrorAt = st.Error, c.now().UTC()
+	}
+	if comp.lastError != "" {
+		at := comp.lastErrorAt
+		st.LastError, st.LastErrorAt = comp.lastError, &at
+	}
+	return st
+}
+
+// ServeHTTP отдаёт отчёт о готовности в JSON
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := c.Check(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status == StatusNotReady {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
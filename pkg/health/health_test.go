@@ -0,0 +1,115 @@
+// Пакет health содержит unit-тесты для Checker
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func ok(ctx context.Context) error { return nil }
+
+// TestChecker_Statuses проверяет сводный статус для критичных и некритичных зависимостей
+func TestChecker_Statuses(t *testing.T) {
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+	cases := []struct {
+		name     string
+		postgres CheckFunc
+		redis    CheckFunc
+		want     string
+	}{
+		{"all up", ok, ok, StatusReady},
+		{"non-critical down", ok, down, StatusDegraded},
+		{"critical down", down, ok, StatusNotReady},
+		{"both down", down, down, StatusNotReady},
+	}
+	for _, tc := range cases {
+		c := NewChecker(time.Second)
+		c.Add("postgres", true, tc.postgres)
+		c.Add("redis", false, tc.redis)
+		if got := c.Check(context.Background()).Status; got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+	// без зависимостей сервис готов
+	if got := NewChecker(time.Second).Check(context.Background()).Status; got != StatusReady {
+		t.Errorf("expected ready without components, got %q", got)
+	}
+}
+
+// TestChecker_Timeout проверяет, что зависшая проверка прерывается по таймауту
+func TestChecker_Timeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	c := NewChecker(20 * time.Millisecond)
+	c.Add("nats", true, func(ctx context.Context) error {
+		<-block // проверка не уважает ctx
+		return nil
+	})
+	start := time.Now()
+	st := c.Check(context.Background()).Components["nats"]
+	if time.Since(start) > time.Second {
+		t.Fatalf("check was not bounded by timeout")
+	}
+	if st.Status != ComponentDown || st.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("unexpected status: %+v", st)
+	}
+}
+
+// TestChecker_LastError проверяет, что последняя ошибка сохраняется после восстановления зависимости
+func TestChecker_LastError(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	fail := true
+	c := NewChecker(time.Second)
+	c.now = func() time.Time { return now }
+	c.Add("clickhouse", false, func(ctx context.Context) error {
+		if fail {
+			return errors.New("timeout")
+		}
+		return nil
+	})
+	_ = c.Check(context.Background())
+	fail = false
+	st := c.Check(context.Background()).Components["clickhouse"]
+	if st.Status != ComponentUp || st.Error != "" {
+		t.Errorf("expected component up, got %+v", st)
+	}
+	if st.LastError != "timeout" || st.LastErrorAt == nil || !st.LastErrorAt.Equal(now) {
+		t.Errorf("expected last error to be kept, got %+v", st)
+	}
+}
+
+// TestChecker_ServeHTTP проверяет HTTP-статус и JSON-отчёт
+func TestChecker_ServeHTTP(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("postgres", true, func(ctx context.Context) error { return errors.New("down") })
+	c.Add("redis", false, ok)
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	pg := report.Components["postgres"]
+	if report.Status != StatusNotReady || pg.Status != ComponentDown || !pg.Critical || pg.Error != "down" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if report.Components["redis"].Status != ComponentUp {
+		t.Errorf("expected redis up, got %+v", report.Components["redis"])
+	}
+
+	degraded := NewChecker(time.Second)
+	degraded.Add("redis", false, func(ctx context.Context) error { return errors.New("down") })
+	rec = httptest.NewRecorder()
+	degraded.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for degraded, got %d", rec.Code)
+	}
+}
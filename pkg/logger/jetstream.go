@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+// JetStream определяет минимальный интерфейс JetStream-контекста (реализуется nats.JetStreamContext)
+// PublishMsg ожидает подтверждения сохранения сообщения в стриме (PubAck)
+type JetStream interface {
+	PublishMsg(msg *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error)
+	StreamInfo(stream string, opts ...nats.JSOpt) (*nats.StreamInfo, error)
+	AddStream(cfg *nats.StreamConfig, opts ...nats.JSOpt) (*nats.StreamInfo, error)
+}
+
+// EnsureStream создаёт файловый стрим stream для тем subjects, если он ещё не существует
+func EnsureStream(js JetStream, stream string, subjects ...string) error {
+	_, err := js.StreamInfo(stream)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, nats.ErrStreamNotFound) {
+		return fmt.Errorf("failed to get stream info: %w", err)
+	}
+	_, err = js.AddStream(&nats.StreamConfig{Name: stream, Subjects: subjects, Storage: nats.FileStorage})
+	if err != nil {
+		return fmt.Errorf("failed to add stream: %w", err)
+	}
+	return nil
+}
+
+// JetStreamConsumers определяет методы управления консьюмерами JetStream (реализуется nats.JetStreamContext)
+type JetStreamConsumers interface {
+	ConsumerInfo(stream, name string, opts ...nats.JSOpt) (*nats.ConsumerInfo, error)
+	AddConsumer(stream string, cfg *nats.ConsumerConfig, opts ...nats.JSOpt) (*nats.ConsumerInfo, error)
+	UpdateConsumer(stream string, cfg *nats.ConsumerConfig, opts ...nats.JSOpt) (*nats.ConsumerInfo, error)
+}
+
+// EnsureConsumer создаёт durable-консьюмер cfg.Durable в стриме stream, а если он уже существует,
+// приводит к cfg его тему, политику подтверждения, AckWait и MaxDeliver, чтобы изменённые настройки
+// применялись при перезапуске; остальные параметры существующего консьюмера сохраняются
+func EnsureConsumer(js JetStreamConsumers, stream string, cfg *nats.ConsumerConfig) error {
+	info, err := js.ConsumerInfo(stream, cfg.Durable)
+	if errors.Is(err, nats.ErrConsumerNotFound) {
+		if _, err := js.AddConsumer(stream, cfg); err != nil {
+			return fmt.Errorf("failed to add consumer: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get consumer info: %w", err)
+	}
+	current := info.Config
+	if current.FilterSubject == cfg.FilterSubject && current.AckPolicy == cfg.AckPolicy &&
+		current.AckWait == cfg.AckWait && current.MaxDeliver == cfg.MaxDeliver {
+		return nil
+	}
+	updated := current
+	updated.FilterSubject = cfg.FilterSubject
+	updated.AckPolicy = cfg.AckPolicy
+	updated.AckWait = cfg.AckWait
+	updated.MaxDeliver = cfg.MaxDeliver
+	// часть настроек (например, политику подтверждения) сервер менять не даёт — тогда запуск завершается ошибкой
+	if _, err := js.UpdateConsumer(stream, &updated); err != nil {
+		return fmt.Errorf("failed to update consumer (ack_wait %s -> %s, max_deliver %d -> %d): %w",
+			current.AckWait, cfg.AckWait, current.MaxDeliver, cfg.MaxDeliver, err)
+	}
+	return nil
+}
+
+// JetStreamConn адаптирует JetStream к интерфейсу Conn для NATSClient
+// Если сообщение является конвертом события с полем id, оно передаётся как Nats-Msg-Id,
+// и повторная публикация того же события (например, relay после сбоя) отбрасывается стримом как дубликат
+type JetStreamConn struct {
+	js JetStream
+}
+
+// NewJetStreamConn создаёт Conn, публикующий сообщения в JetStream
+func NewJetStreamConn(js JetStream) *JetStreamConn {
+	return &JetStreamConn{js: js}
+}
+
+// PublishMsg публикует сообщение вместе с заголовками и возвращает ошибку, если стрим не подтвердил запись
+func (c *JetStreamConn) PublishMsg(msg *nats.Msg) error {
+	var opts []nats.PubOpt
+	var envelope struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal(msg.Data, &envelope) == nil && envelope.ID != "" {
+		opts = append(opts, nats.MsgId(envelope.ID))
+	}
+	if _, err := c.js.PublishMsg(msg, opts...); err != nil {
+		return fmt.Errorf("failed to publish to JetStream: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+// Пакет logger содержит unit-тесты для публикации через JetStream, создания стрима и консьюмера
+package logger
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+// mockJetStream реализует интерфейс JetStream и запоминает вызовы
+type mockJetStream struct {
+	publishedSubject string
+	publishedData    []byte
+	publishedOpts    int
+	publishErr       error
+	infoErr          error
+	added            *nats.StreamConfig
+	addErr           error
+}
+
+func (m *mockJetStream) PublishMsg(msg *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	m.publishedSubject = msg.Subject
+	m.publishedData = msg.Data
+	m.publishedOpts = len(opts)
+	if m.publishErr != nil {
+		return nil, m.publishErr
+	}
+	return &nats.PubAck{Stream: "GOODS"}, nil
+}
+
+func (m *mockJetStream) StreamInfo(stream string, opts ...nats.JSOpt) (*nats.StreamInfo, error) {
+	if m.infoErr != nil {
+		return nil, m.infoErr
+	}
+	return &nats.StreamInfo{}, nil
+}
+
+func (m *mockJetStream) AddStream(cfg *nats.StreamConfig, opts ...nats.JSOpt) (*nats.StreamInfo, error) {
+	m.added = cfg
+	return &nats.StreamInfo{}, m.addErr
+}
+
+// TestJetStreamConn_PublishEvent проверяет, что конверт события публикуется с Nats-Msg-Id
+func TestJetStreamConn_PublishEvent(t *testing.T) {
+	js := &mockJetStream{}
+	client := NewClient(NewJetStreamConn(js), "goods")
+	data := []byte(`{"version":1,"id":"e-1","type":"good.created"}`)
+	if err := client.PublishLog(context.Background(), data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if js.publishedSubject != "goods" || string(js.publishedData) != string(data) {
+		t.Errorf("unexpected publish %s %s", js.publishedSubject, js.publishedData)
+	}
+	if js.publishedOpts != 1 {
+		t.Errorf("expected Nats-Msg-Id option, got %d options", js.publishedOpts)
+	}
+}
+
+// TestJetStreamConn_PublishRaw проверяет публикацию произвольных данных без идентификатора
+func TestJetStreamConn_PublishRaw(t *testing.T) {
+	js := &mockJetStream{}
+	if err := NewJetStreamConn(js).PublishMsg(&nats.Msg{Subject: "goods", Data: []byte("payload")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if js.publishedOpts != 0 {
+		t.Errorf("expected no options, got %d", js.publishedOpts)
+	}
+}
+
+// TestJetStreamConn_PublishError проверяет прокидку ошибки, если стрим не подтвердил запись
+func TestJetStreamConn_PublishError(t *testing.T) {
+	js := &mockJetStream{publishErr: nats.ErrNoStreamResponse}
+	err := NewJetStreamConn(js).PublishMsg(&nats.Msg{Subject: "goods", Data: []byte("payload")})
+	if !errors.Is(err, nats.ErrNoStreamResponse) {
+		t.Errorf("expected %v, got %v", nats.ErrNoStreamResponse, err)
+	}
+}
+
+// TestEnsureStream проверяет создание отсутствующего стрима и пропуск существующего
+func TestEnsureStream(t *testing.T) {
+	// стрим уже существует
+	js := &mockJetStream{}
+	if err := EnsureStream(js, "GOODS", "goods"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if js.added != nil {
+		t.Error("existing stream must not be recreated")
+	}
+
+	// стрим отсутствует
+	js = &mockJetStream{infoErr: nats.ErrStreamNotFound}
+	if err := EnsureStream(js, "GOODS", "goods"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if js.added == nil || js.added.Name != "GOODS" || len(js.added.Subjects) != 1 || js.added.Subjects[0] != "goods" {
+		t.Errorf("unexpected stream config %+v", js.added)
+	}
+
+	// прочие ошибки StreamInfo прокидываются
+	js = &mockJetStream{infoErr: nats.ErrJetStreamNotEnabled}
+	if err := EnsureStream(js, "GOODS", "goods"); !errors.Is(err, nats.ErrJetStreamNotEnabled) {
+		t.Errorf("expected %v, got %v", nats.ErrJetStreamNotEnabled, err)
+	}
+}
+
+// mockConsumers реализует интерфейс JetStreamConsumers и запоминает созданный и обновлённый консьюмер
+type mockConsumers struct {
+	info      *nats.ConsumerInfo
+	infoErr   error
+	added     *nats.ConsumerConfig
+	updated   *nats.ConsumerConfig
+	updateErr error
+}
+
+func (m *mockConsumers) ConsumerInfo(stream, name string, opts ...nats.JSOpt) (*nats.ConsumerInfo, error) {
+	return m.info, m.infoErr
+}
+
+func (m *mockConsumers) AddConsumer(stream string, cfg *nats.ConsumerConfig, opts ...nats.JSOpt) (*nats.ConsumerInfo, error) {
+	m.added = cfg
+	return &nats.ConsumerInfo{}, nil
+}
+
+func (m *mockConsumers) UpdateConsumer(stream string, cfg *nats.ConsumerConfig, opts ...nats.JSOpt) (*nats.ConsumerInfo, error) {
+	m.updated = cfg
+	return &nats.ConsumerInfo{}, m.updateErr
+}
+
+// TestEnsureConsumer проверяет создание отсутствующего консьюмера и обновление изменённых настроек существующего
+func TestEnsureConsumer(t *testing.T) {
+	cfg := &nats.ConsumerConfig{Durable: "goods-consumer", FilterSubject: "goods", AckPolicy: nats.AckExplicitPolicy, AckWait: 30 * time.Second, MaxDeliver: -1}
+
+	// консьюмер отсутствует
+	js := &mockConsumers{infoErr: nats.ErrConsumerNotFound}
+	if err := EnsureConsumer(js, "GOODS", cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if js.added != cfg || js.updated != nil {
+		t.Errorf("expected consumer to be added, got added=%+v updated=%+v", js.added, js.updated)
+	}
+
+	// настройки совпадают — консьюмер не меняется
+	js = &mockConsumers{info: &nats.ConsumerInfo{Config: *cfg}}
+	if err := EnsureConsumer(js, "GOODS", cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if js.added != nil || js.updated != nil {
+		t.Error("unchanged consumer must not be updated")
+	}
+
+	// изменились AckWait и MaxDeliver — прочие настройки сохраняются
+	current := *cfg
+	current.AckWait = 10 * time.Second
+	current.MaxDeliver = 5
+	current.MaxAckPending = 100
+	js = &mockConsumers{info: &nats.ConsumerInfo{Config: current}}
+	if err := EnsureConsumer(js, "GOODS", cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if js.updated == nil || js.updated.AckWait != 30*time.Second || js.updated.MaxDeliver != -1 || js.updated.MaxAckPending != 100 {
+		t.Errorf("unexpected updated config %+v", js.updated)
+	}
+
+	// сервер отклонил обновление — ошибка прокидывается
+	js = &mockConsumers{info: &nats.ConsumerInfo{Config: current}, updateErr: nats.ErrBadRequest}
+	if err := EnsureConsumer(js, "GOODS", cfg); !errors.Is(err, nats.ErrBadRequest) {
+		t.Errorf("expected %v, got %v", nats.ErrBadRequest, err)
+	}
+
+	// прочие ошибки ConsumerInfo прокидываются
+	js = &mockConsumers{infoErr: nats.ErrJetStreamNotEnabled}
+	if err := EnsureConsumer(js, "GOODS", cfg); !errors.Is(err, nats.ErrJetStreamNotEnabled) {
+		t.Errorf("expected %v, got %v", nats.ErrJetStreamNotEnabled, err)
+	}
+}
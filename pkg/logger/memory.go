@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// MemoryLogger сохраняет последние опубликованные сообщения в памяти и пишет их в лог slog
+// Используется вместо NATS при локальном запуске без инфраструктуры и в тестах
+type MemoryLogger struct {
+	mu       sync.Mutex
+	capacity int
+	messages [][]byte
+}
+
+// NewMemoryLogger создаёт MemoryLogger, хранящий не более capacity последних сообщений
+func NewMemoryLogger(capacity int) *MemoryLogger {
+	return &MemoryLogger{capacity: capacity}
+}
+
+// PublishLog сохраняет копию сообщения, вытесняя самое старое при превышении capacity
+func (l *MemoryLogger) PublishLog(ctx context.Context, data []byte) error {
+	msg := append([]byte(nil), data...)
+	l.mu.Lock()
+	l.messages = append(l.messages, msg)
+	if len(l.messages) > l.capacity {
+		l.messages = l.messages[len(l.messages)-l.capacity:]
+	}
+	l.mu.Unlock()
+	slog.InfoContext(ctx, "event published", slog.String("event", string(msg)))
+	return nil
+}
+
+// Messages возвращает сохранённые сообщения от старых к новым
+func (l *MemoryLogger) Messages() [][]byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([][]byte(nil), l.messages...)
+}
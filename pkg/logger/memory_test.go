@@ -0,0 +1,24 @@
+// Пакет logger содержит unit-тесты для MemoryLogger
+package logger
+
+import (
+	"context"
+	"testing"
+)
+
+// TestMemoryLogger_KeepsLastMessages проверяет сохранение копий и вытеснение старых сообщений
+func TestMemoryLogger_KeepsLastMessages(t *testing.T) {
+	l := NewMemoryLogger(2)
+	data := []byte("first")
+	for _, msg := range [][]byte{data, []byte("second"), []byte("third")} {
+		if err := l.PublishLog(context.Background(), msg); err != nil {
+			t.Fatalf("PublishLog error: %v", err)
+		}
+	}
+	// изменение буфера вызывающим не влияет на сохранённое сообщение
+	data[0] = 'X'
+	got := l.Messages()
+	if len(got) != 2 || string(got[0]) != "second" || string(got[1]) != "third" {
+		t.Errorf("unexpected messages: %q", got)
+	}
+}
@@ -1,12 +1,31 @@
 // Пакет logger предоставляет обёртку для публикации логов и событий в NATS
 package logger
 
+import (
+	"context"
+
+	"HezzlTestTask/pkg/tracing"
+
+	nats "github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// publishFailures считает неудачные публикации в NATS по теме
+var publishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "nats_publish_failures_total",
+	Help: "Number of failed NATS publish attempts by subject.",
+}, []string{"subject"})
+
 // Conn определяет минимальный интерфейс для работы с NATS-подключением
-// Любая реализация Conn (например *nats.Conn) должна предоставлять метод Publish
-// subject — тема (топик), data — байтовый массив сообщения
-// Publish возвращает ошибку при неудаче публикации
+// Любая реализация Conn (например *nats.Conn) должна предоставлять метод PublishMsg
+// msg содержит тему (топик), данные и заголовки сообщения
+// PublishMsg возвращает ошибку при неудаче публикации
 type Conn interface {
-	Publish(subject string, data []byte) error
+	PublishMsg(msg *nats.Msg) error
 }
 
 // NATSClient хранит Conn и тему subject для публикации логов
@@ -21,7 +40,19 @@ func NewClient(conn Conn, subject string) *NATSClient {
 }
 
 // PublishLog отправляет данные в указанный subject в NATS
-// Возвращает ошибку, если публикация не удалась
-func (n *NATSClient) PublishLog(data []byte) error {
-	return n.conn.Publish(n.subject, data)
+// Публикация выполняется в спане producer, контекст трассировки передаётся в заголовках сообщения (traceparent)
+// Возвращает ошибку, если публикация не удалась; неудачи учитываются в метрике nats_publish_failures_total
+func (n *NATSClient) PublishLog(ctx context.Context, data []byte) error {
+	ctx, span := tracing.Start(ctx, "NATSClient.PublishLog",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(semconv.MessagingSystemKey.String("nats"), semconv.MessagingDestinationName(n.subject)),
+	)
+	msg := &nats.Msg{Subject: n.subject, Data: data, Header: nats.Header{}}
+	tracing.Inject(ctx, propagation.HeaderCarrier(msg.Header))
+	err := n.conn.PublishMsg(msg)
+	if err != nil {
+		publishFailures.WithLabelValues(n.subject).Inc()
+	}
+	tracing.End(span, err)
+	return err
 }
@@ -3,34 +3,44 @@ package logger
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"testing"
+
+	nats "github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// mockConn реализует интерфейс Conn и позволяет перехватывать вызовы Publish
-// Мы сохраняем переданный subject и данные для проверки в тестах
+// mockConn реализует интерфейс Conn и позволяет перехватывать вызовы PublishMsg
+// Мы сохраняем переданный subject, данные и заголовки для проверки в тестах
 type mockConn struct {
-	publishedSubject string // тема, переданная в Publish
-	publishedData    []byte // данные, переданные в Publish
-	returnErr        error  // ошибка, которую вернет Publish
+	publishedSubject string      // тема, переданная в PublishMsg
+	publishedData    []byte      // данные, переданные в PublishMsg
+	publishedHeader  nats.Header // заголовки, переданные в PublishMsg
+	returnErr        error       // ошибка, которую вернет PublishMsg
 }
 
-// Publish сохраняет параметры вызова в полях mockConn и возвращает заранее заданную ошибку
-func (m *mockConn) Publish(subject string, data []byte) error {
-	m.publishedSubject = subject
-	m.publishedData = data
+// PublishMsg сохраняет параметры вызова в полях mockConn и возвращает заранее заданную ошибку
+func (m *mockConn) PublishMsg(msg *nats.Msg) error {
+	m.publishedSubject = msg.Subject
+	m.publishedData = msg.Data
+	m.publishedHeader = msg.Header
 	return m.returnErr
 }
 
 // TestPublishLog_Success проверяет успешную публикацию данных
-// Проверяем, что PublishLog корректно вызывает Publish с тем же subject и данными без ошибок
+// Проверяем, что PublishLog корректно вызывает PublishMsg с тем же subject и данными без ошибок
 func TestPublishLog_Success(t *testing.T) {
 	subject := "test.subject"
 	data := []byte("payload")
 	mock := &mockConn{returnErr: nil}
 	client := NewClient(mock, subject)
 
-	err := client.PublishLog(data)
+	err := client.PublishLog(context.Background(), data)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -42,8 +52,8 @@ func TestPublishLog_Success(t *testing.T) {
 	}
 }
 
-// TestPublishLog_Error проверяет прокидку ошибки из Conn.Publish
-// Если underlying Publish возвращает ошибку, PublishLog должен вернуть ту же ошибку
+// TestPublishLog_Error проверяет прокидку ошибки из Conn.PublishMsg
+// Если underlying PublishMsg возвращает ошибку, PublishLog должен вернуть ту же ошибку
 func TestPublishLog_Error(t *testing.T) {
 	subject := "test.subject"
 	data := []byte("payload")
@@ -51,10 +61,15 @@ func TestPublishLog_Error(t *testing.T) {
 	mock := &mockConn{returnErr: expErr}
 	client := NewClient(mock, subject)
 
-	err := client.PublishLog(data)
+	before := testutil.ToFloat64(publishFailures.WithLabelValues(subject))
+	err := client.PublishLog(context.Background(), data)
 	if !errors.Is(err, expErr) {
 		t.Errorf("expected error %v, got %v", expErr, err)
 	}
+	// неудачная публикация учитывается в метрике
+	if got := testutil.ToFloat64(publishFailures.WithLabelValues(subject)) - before; got != 1 {
+		t.Errorf("expected 1 publish failure, got %v", got)
+	}
 }
 
 // TestPublishLog_EmptySubject проверяет сценарий с пустым subject
@@ -64,7 +79,7 @@ func TestPublishLog_EmptySubject(t *testing.T) {
 	mock := &mockConn{returnErr: nil}
 	client := NewClient(mock, "")
 
-	err := client.PublishLog(data)
+	err := client.PublishLog(context.Background(), data)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -83,7 +98,7 @@ func TestPublishLog_NilData(t *testing.T) {
 	mock := &mockConn{returnErr: nil}
 	client := NewClient(mock, subject)
 
-	err := client.PublishLog(nil)
+	err := client.PublishLog(context.Background(), nil)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -94,3 +109,29 @@ func TestPublishLog_NilData(t *testing.T) {
 		t.Errorf("expected nil data, got %v", mock.publishedData)
 	}
 }
+
+// TestPublishLog_TraceContext проверяет спан публикации и передачу контекста трассировки в заголовках
+func TestPublishLog_TraceContext(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	mock := &mockConn{}
+	client := NewClient(mock, "goods")
+
+	if err := client.PublishLog(ctx, []byte("payload")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parent.End()
+	spans := rec.Ended()
+	if len(spans) != 2 || spans[0].Name() != "NATSClient.PublishLog" || spans[0].SpanKind() != trace.SpanKindProducer {
+		t.Fatalf("unexpected spans %v", spans)
+	}
+	publish := spans[0].SpanContext()
+	if publish.TraceID() != parent.SpanContext().TraceID() {
+		t.Errorf("publish span must continue the request trace")
+	}
+	want := "00-" + publish.TraceID().String() + "-" + publish.SpanID().String() + "-01"
+	if got := mock.publishedHeader.Get("Traceparent"); got != want {
+		t.Errorf("expected traceparent %s, got %q", want, got)
+	}
+}
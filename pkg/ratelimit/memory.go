@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval — как часто MemoryLimiter удаляет состояние клиентов с полностью восстановленной квотой
+const sweepInterval = time.Minute
+
+// MemoryLimiter ограничивает запросы в памяти процесса; лимиты действуют отдельно в каждой реплике
+// Предназначен для запуска без Redis (STORAGE=memory) и для тестов
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	tat       map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryLimiter создаёт ограничитель в памяти
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{tat: make(map[string]time.Time), now: time.Now}
+}
+
+// Allow проверяет очередной запрос клиента key против лимита limit
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+	res, tat := gcra(now, l.tat[key], limit)
+	l.tat[key] = tat
+	return res, nil
+}
+
+// sweep удаляет клиентов, TAT которых уже прошёл: их состояние не отличается от отсутствующего
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, tat := range l.tat {
+		if !tat.After(now) {
+			delete(l.tat, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,33 @@
+// Пакет ratelimit содержит unit-тесты ограничителя в памяти MemoryLimiter
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMemoryLimiter проверяет раздельные квоты клиентов и удаление восстановившихся клиентов
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Now()
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Limit{Requests: 1, Period: time.Second}
+
+	if res, _ := l.Allow(ctx, "a", limit); !res.Allowed {
+		t.Fatalf("expected first request allowed, got %+v", res)
+	}
+	if res, _ := l.Allow(ctx, "a", limit); res.Allowed || res.RetryAfter != time.Second {
+		t.Fatalf("expected second request rejected, got %+v", res)
+	}
+	if res, _ := l.Allow(ctx, "b", limit); !res.Allowed {
+		t.Fatalf("expected other client allowed, got %+v", res)
+	}
+
+	now = now.Add(sweepInterval)
+	_, _ = l.Allow(ctx, "c", limit)
+	if len(l.tat) != 1 {
+		t.Errorf("expected recovered clients to be swept, got %v", l.tat)
+	}
+}
@@ -0,0 +1,111 @@
+// Пакет ratelimit ограничивает частоту запросов алгоритмом GCRA — token bucket, состояние которого
+// хранится одним числом: теоретическим временем прибытия следующего запроса (TAT)
+// RedisLimiter хранит состояние в Redis и ограничивает клиента суммарно по всем репликам,
+// MemoryLimiter — в памяти процесса для запуска без Redis
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit — не больше Requests запросов за Period; все Requests можно отправить одной пачкой,
+// после чего квота восстанавливается равномерно: по одному запросу каждые Period/Requests
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// String возвращает лимит в формате конфигурации "<Requests>/<Period>"
+func (l Limit) String() string {
+	return strconv.Itoa(l.Requests) + "/" + l.Period.String()
+}
+
+// interval возвращает время восстановления одного запроса квоты
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// Result — результат проверки запроса
+type Result struct {
+	Allowed    bool          // запрос укладывается в лимит
+	Remaining  int           // сколько ещё запросов можно отправить сразу
+	RetryAfter time.Duration // через сколько можно повторить отклонённый запрос
+	Reset      time.Duration // через сколько квота восстановится полностью
+}
+
+// gcra проверяет запрос в момент now при сохранённом TAT tat (нулевое значение — клиента ещё не было)
+// и возвращает результат и новый TAT; у отклонённого запроса TAT не меняется
+func gcra(now, tat time.Time, limit Limit) (Result, time.Time) {
+	interval := limit.interval()
+	if tat.Before(now) {
+		tat = now
+	}
+	// запрос разрешён, если после него TAT опередит now не больше чем на Period
+	if allowAt := tat.Add(interval - limit.Period); now.Before(allowAt) {
+		return Result{RetryAfter: allowAt.Sub(now), Reset: tat.Sub(now)}, tat
+	}
+	tat = tat.Add(interval)
+	remaining := int(now.Sub(tat.Add(-limit.Period)) / interval)
+	return Result{Allowed: true, Remaining: remaining, Reset: tat.Sub(now)}, tat
+}
+
+// Rules — лимиты по маршрутам; Default применяется к маршрутам без своего лимита,
+// нулевой Default означает, что такие маршруты не ограничиваются
+type Rules struct {
+	Default Limit
+	Routes  map[string]Limit
+}
+
+// For возвращает лимит маршрута route (шаблона пути gorilla/mux) и признак, что он ограничен
+func (r Rules) For(route string) (Limit, bool) {
+	if l, ok := r.Routes[route]; ok {
+		return l, true
+	}
+	return r.Default, r.Default.Requests > 0
+}
+
+// ParseRules разбирает лимиты из строки вида "default=100/1m,/goods/list=20/1s,/good/reprioritize=5/1s":
+// маршрут (или default) и лимит в формате "<число запросов>/<период time.Duration>"
+func ParseRules(s string) (Rules, error) {
+	rules := Rules{Routes: make(map[string]Limit)}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		route, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return Rules{}, fmt.Errorf("invalid rate limit %q: expected <route>=<requests>/<period>", item)
+		}
+		limit, err := parseLimit(value)
+		if err != nil {
+			return Rules{}, fmt.Errorf("invalid rate limit for %s: %w", route, err)
+		}
+		if route == "default" {
+			rules.Default = limit
+		} else {
+			rules.Routes[route] = limit
+		}
+	}
+	return rules, nil
+}
+
+// parseLimit разбирает лимит "<число запросов>/<период>"
+func parseLimit(s string) (Limit, error) {
+	n, p, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("expected <requests>/<period>, got %q", s)
+	}
+	requests, err := strconv.Atoi(n)
+	if err != nil || requests < 1 {
+		return Limit{}, fmt.Errorf("requests must be a positive integer, got %q", n)
+	}
+	period, err := time.ParseDuration(p)
+	if err != nil || period < time.Millisecond {
+		return Limit{}, fmt.Errorf("period must be a duration of at least 1ms, got %q", p)
+	}
+	return Limit{Requests: requests, Period: period}, nil
+}
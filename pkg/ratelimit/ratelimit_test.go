@@ -0,0 +1,58 @@
+// Пакет ratelimit содержит unit-тесты алгоритма GCRA и разбора лимитов
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// TestGCRA проверяет пачку до Requests запросов, отказ с RetryAfter и равномерное восстановление квоты
+func TestGCRA(t *testing.T) {
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+	now := time.Now()
+	var tat time.Time
+	for want := 2; want >= 0; want-- {
+		var res Result
+		res, tat = gcra(now, tat, limit)
+		if !res.Allowed || res.Remaining != want {
+			t.Fatalf("expected allowed with %d remaining, got %+v", want, res)
+		}
+	}
+	res, tat2 := gcra(now, tat, limit)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 3*time.Second || !tat2.Equal(tat) {
+		t.Fatalf("expected rejection with RetryAfter 1s and Reset 3s, got %+v", res)
+	}
+	// через интервал восстанавливается ровно один запрос
+	now = now.Add(time.Second)
+	if res, tat = gcra(now, tat, limit); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected one request after interval, got %+v", res)
+	}
+	// после полного периода простоя квота восстанавливается целиком
+	now = now.Add(10 * time.Second)
+	if res, _ = gcra(now, tat, limit); !res.Allowed || res.Remaining != 2 || res.Reset != time.Second {
+		t.Fatalf("expected full quota, got %+v", res)
+	}
+}
+
+// TestParseRules проверяет лимит по умолчанию, лимиты маршрутов и ошибки формата
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("default=100/1m, /goods/list=20/1s,/good/reprioritize=5/1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l, ok := rules.For("/goods/list"); !ok || l != (Limit{20, time.Second}) {
+		t.Errorf("unexpected /goods/list limit %v %v", l, ok)
+	}
+	if l, ok := rules.For("/good/get"); !ok || l.String() != "100/1m0s" {
+		t.Errorf("unexpected default limit %v %v", l, ok)
+	}
+	rules, _ = ParseRules("/goods/list=20/1s")
+	if _, ok := rules.For("/good/get"); ok {
+		t.Error("route without limit and default must not be limited")
+	}
+	for _, s := range []string{"default", "default=100", "default=0/1s", "default=x/1s", "default=1/soon", "default=1/0s"} {
+		if _, err := ParseRules(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
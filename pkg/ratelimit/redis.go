@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"HezzlTestTask/pkg/tracing"
+
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// keyPrefix — префикс ключей Redis с TAT клиентов
+const keyPrefix = "ratelimit:"
+
+// allowScript выполняет GCRA атомарно на стороне Redis: ARGV[1] — Period, ARGV[2] — Requests лимита
+// Время берётся командой TIME сервера Redis, поэтому расхождение часов реплик не влияет на лимит
+// TAT хранится в миллисекундах и истекает, когда квота клиента полностью восстановлена
+// Скрипт возвращает {разрешён (0/1), осталось запросов, RetryAfter мс, Reset мс}
+var allowScript = redis.NewScript(`
+local period = tonumber(ARGV[1])
+local interval = period / tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+local tat = tonumber(redis.call('GET', KEYS[1])) or now
+if tat < now then
+  tat = now
+end
+local allow_at = tat + interval - period
+if now < allow_at then
+  return {0, 0, math.ceil(allow_at - now), math.ceil(tat - now)}
+end
+tat = tat + interval
+redis.call('SET', KEYS[1], tostring(tat), 'PX', math.ceil(tat - now))
+return {1, math.floor((now - tat + period) / interval), 0, math.ceil(tat - now)}
+`)
+
+// RedisLimiter ограничивает запросы, храня TAT клиентов в Redis: лимит общий для всех реплик
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter создаёт ограничитель поверх клиента Redis
+func NewRedisLimiter(client *redis.Client) *RedisLimiter {
+	return &RedisLimiter{client: client}
+}
+
+// Allow проверяет очередной запрос клиента key против лимита limit
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (_ Result, err error) {
+	ctx, span := tracing.Start(ctx, "RedisLimiter.Allow", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("ratelimit.key", key), semconv.DBSystemRedis))
+	defer func() { tracing.End(span, err) }()
+	vals, err := allowScript.Run(ctx, l.client, []string{keyPrefix + key},
+		limit.Period.Milliseconds(), limit.Requests).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if len(vals) != 4 {
+		return Result{}, fmt.Errorf("failed to check rate limit: unexpected script result %v", vals)
+	}
+	return Result{
+		Allowed:    vals[0] == 1,
+		Remaining:  int(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+		Reset:      time.Duration(vals[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,41 @@
+// Пакет ratelimit содержит unit-тесты ограничителя RedisLimiter
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	redismock "github.com/go-redis/redismock/v8"
+)
+
+// TestRedisLimiter проверяет аргументы Lua-скрипта, разбор результата и прокидывание ошибки Redis
+func TestRedisLimiter(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	l := NewRedisLimiter(db)
+	ctx := context.Background()
+	limit := Limit{Requests: 20, Period: time.Second}
+	keys := []string{"ratelimit:/goods/list:ip:10.0.0.1"}
+
+	mock.ExpectEvalSha(allowScript.Hash(), keys, int64(1000), 20).SetVal([]interface{}{int64(1), int64(19), int64(0), int64(50)})
+	res, err := l.Allow(ctx, "/goods/list:ip:10.0.0.1", limit)
+	if err != nil || res != (Result{Allowed: true, Remaining: 19, Reset: 50 * time.Millisecond}) {
+		t.Errorf("unexpected result %+v, %v", res, err)
+	}
+
+	mock.ExpectEvalSha(allowScript.Hash(), keys, int64(1000), 20).SetVal([]interface{}{int64(0), int64(0), int64(30), int64(1000)})
+	res, err = l.Allow(ctx, "/goods/list:ip:10.0.0.1", limit)
+	if err != nil || res.Allowed || res.RetryAfter != 30*time.Millisecond {
+		t.Errorf("unexpected result %+v, %v", res, err)
+	}
+
+	mock.ExpectEvalSha(allowScript.Hash(), keys, int64(1000), 20).SetErr(errors.New("connection refused"))
+	if _, err := l.Allow(ctx, "/goods/list:ip:10.0.0.1", limit); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("expected Redis error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+// Пакет tracing содержит unit-тесты для помощников трассировки
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestInit_WithoutEndpoint проверяет, что без OTEL_EXPORTER_OTLP_ENDPOINT экспорт не настраивается
+func TestInit_WithoutEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	shutdown, err := Init(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+}
+
+// TestStartEnd проверяет дочерний спан и отметку ошибки
+func TestStartEnd(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	End(child, errors.New("boom"))
+	End(parent, nil)
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("child span must have parent span")
+	}
+	if spans[0].Status().Code != codes.Error || spans[0].Status().Description != "boom" || len(spans[0].Events()) != 1 {
+		t.Errorf("expected error status on child, got %+v", spans[0].Status())
+	}
+	if spans[1].Status().Code != codes.Unset {
+		t.Errorf("expected unset status on parent, got %+v", spans[1].Status())
+	}
+}
+
+// TestInjectExtract проверяет передачу контекста трассировки через заголовки
+func TestInjectExtract(t *testing.T) {
+	provider := sdktrace.NewTracerProvider()
+	ctx, span := provider.Tracer("test").Start(context.Background(), "publish")
+	defer span.End()
+
+	carrier := propagation.MapCarrier{}
+	Inject(ctx, carrier)
+	if carrier["traceparent"] == "" {
+		t.Fatalf("expected traceparent header, got %v", carrier)
+	}
+	remote := trace.SpanContextFromContext(Extract(context.Background(), carrier))
+	if !remote.IsRemote() || remote.TraceID() != span.SpanContext().TraceID() || remote.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("unexpected extracted span context %+v", remote)
+	}
+
+	// без заголовков контекст трассировки не появляется
+	if sc := trace.SpanContextFromContext(Extract(context.Background(), propagation.MapCarrier{})); sc.IsValid() {
+		t.Errorf("expected empty span context, got %+v", sc)
+	}
+}